@@ -46,7 +46,8 @@ func ValidateToken(tokenString string) (*models.User, error) {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+	// Tokens with an audience, such as preview tokens, are not sessions.
+	if claims, ok := token.Claims.(*Claims); ok && token.Valid && len(claims.Audience) == 0 {
 		return &models.User{
 			ID:    claims.UserID,
 			Email: claims.Email,
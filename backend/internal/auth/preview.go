@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"saas-management-api/internal/models"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// PreviewTokenTTL bounds how long a preview link works.
+const PreviewTokenTTL = 10 * time.Minute
+
+// previewAudience marks preview tokens, which ValidateToken rejects.
+const previewAudience = "preview"
+
+// PreviewClaims let one user preview one website.
+type PreviewClaims struct {
+	UserID    int    `json:"user_id"`
+	Role      string `json:"role"`
+	WebsiteID int    `json:"website_id"`
+	jwt.RegisteredClaims
+}
+
+// GeneratePreviewToken returns a short-lived token that only opens the
+// preview of websiteID, so a leaked preview link grants nothing else.
+func GeneratePreviewToken(user *models.User, websiteID int) (string, error) {
+	now := time.Now()
+	claims := PreviewClaims{
+		UserID:    user.ID,
+		Role:      user.Role,
+		WebsiteID: websiteID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{previewAudience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(PreviewTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(jwtSecret)
+}
+
+// ValidatePreviewToken returns the user a preview token of websiteID was
+// issued to.
+func ValidatePreviewToken(tokenString string, websiteID int) (*models.User, error) {
+	var claims PreviewClaims
+	_, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
+		return jwtSecret, nil
+	}, jwt.WithAudience(previewAudience), jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return nil, err
+	}
+	if claims.WebsiteID != websiteID {
+		return nil, errors.New("preview token is for another website")
+	}
+	return &models.User{ID: claims.UserID, Role: claims.Role}, nil
+}
@@ -0,0 +1,152 @@
+// Package certs issues and renews TLS certificates for verified custom
+// domains over ACME, answering HTTP-01 challenges on the sites server.
+//
+// Account keys and certificates are kept encrypted in Postgres so every
+// replica serves the same certificates. Replicas do not coordinate
+// issuance; one that finds no certificate in the cache may order its own,
+// and the last one stored wins.
+package certs
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"saas-management-api/internal/config"
+	"saas-management-api/internal/models"
+	"saas-management-api/internal/store"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// Cache is an autocert.Cache that encrypts entries with AES-GCM before
+// storing them. The entry key is authenticated along with the data, so
+// rows cannot be swapped between keys.
+type Cache struct {
+	Store *store.Store
+	aead  cipher.AEAD
+}
+
+// NewCache returns a Cache encrypting with a key derived from secret.
+func NewCache(s *store.Store, secret string) (*Cache, error) {
+	if secret == "" {
+		return nil, errors.New("certs: an encryption secret is required")
+	}
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cache{Store: s, aead: aead}, nil
+}
+
+func (c *Cache) Get(ctx context.Context, key string) ([]byte, error) {
+	sealed, err := c.Store.TLSCache.Get(ctx, key)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, autocert.ErrCacheMiss
+	}
+	if err != nil {
+		return nil, err
+	}
+	size := c.aead.NonceSize()
+	if len(sealed) < size {
+		return nil, fmt.Errorf("certs: cache entry %q is truncated", key)
+	}
+	data, err := c.aead.Open(nil, sealed[:size], sealed[size:], []byte(key))
+	if err != nil {
+		return nil, fmt.Errorf("certs: cache entry %q: %w", key, err)
+	}
+	return data, nil
+}
+
+func (c *Cache) Put(ctx context.Context, key string, data []byte) error {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	return c.Store.TLSCache.Put(ctx, key, c.aead.Seal(nonce, nonce, data, []byte(key)))
+}
+
+func (c *Cache) Delete(ctx context.Context, key string) error {
+	return c.Store.TLSCache.Delete(ctx, key)
+}
+
+// NewManager returns an autocert.Manager that obtains certificates for
+// verified domains only. Certificates are renewed ahead of expiry by the
+// replicas serving them.
+func NewManager(s *store.Store, cfg *config.Config) (*autocert.Manager, error) {
+	cache, err := NewCache(s, cfg.TLSCacheKey)
+	if err != nil {
+		return nil, err
+	}
+	client := &acme.Client{DirectoryURL: cfg.ACMEDirectoryURL}
+	if cfg.ACMECACertFile != "" {
+		if client.HTTPClient, err = trustingClient(cfg.ACMECACertFile); err != nil {
+			return nil, err
+		}
+	}
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      cache,
+		HostPolicy: HostPolicy(s, cfg),
+		Email:      cfg.ACMEEmail,
+		Client:     client,
+	}, nil
+}
+
+// HostPolicy allows certificates only for domains that are verified.
+func HostPolicy(s *store.Store, cfg *config.Config) autocert.HostPolicy {
+	return func(ctx context.Context, host string) error {
+		if cfg.QueryTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, cfg.QueryTimeout)
+			defer cancel()
+		}
+		domain, err := s.Domains.GetByName(ctx, strings.ToLower(host))
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			return err
+		}
+		if err != nil || domain.Status != models.DomainVerified {
+			return fmt.Errorf("certs: %q is not a verified domain", host)
+		}
+		return nil
+	}
+}
+
+// TLSConfig returns the configuration of the TLS listener serving sites.
+// Its GetCertificate hook loads, issues and renews certificates through m.
+func TLSConfig(m *autocert.Manager) *tls.Config {
+	config := m.TLSConfig()
+	config.MinVersion = tls.VersionTLS12
+	return config
+}
+
+// trustingClient returns an HTTP client that trusts the CA certificates in
+// file, e.g. those of a local test ACME server such as Pebble.
+func trustingClient(file string) (*http.Client, error) {
+	pem, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("certs: no certificates in %s", file)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	return &http.Client{Transport: transport}, nil
+}
@@ -0,0 +1,157 @@
+package certs
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"saas-management-api/internal/config"
+	"saas-management-api/internal/models"
+	"saas-management-api/internal/store"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+func TestCacheEncryptsEntries(t *testing.T) {
+	st := store.NewMemory()
+	cache, err := NewCache(st, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if _, err := cache.Get(ctx, "missing"); !errors.Is(err, autocert.ErrCacheMiss) {
+		t.Fatalf("Get of a missing key = %v, want ErrCacheMiss", err)
+	}
+	if err := cache.Put(ctx, "a", []byte("certificate")); err != nil {
+		t.Fatal(err)
+	}
+	data, err := cache.Get(ctx, "a")
+	if err != nil || string(data) != "certificate" {
+		t.Fatalf("Get = %q, %v, want the stored data", data, err)
+	}
+	sealed, _ := st.TLSCache.Get(ctx, "a")
+	if bytes.Contains(sealed, []byte("certificate")) {
+		t.Fatal("the entry is stored in the clear")
+	}
+
+	// An entry moved to another key does not decrypt.
+	if err := st.TLSCache.Put(ctx, "b", sealed); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.Get(ctx, "b"); err == nil {
+		t.Fatal("an entry moved to another key decrypted")
+	}
+	other, _ := NewCache(st, "other secret")
+	if _, err := other.Get(ctx, "a"); err == nil {
+		t.Fatal("an entry decrypted with another secret")
+	}
+}
+
+func TestHostPolicyAllowsVerifiedDomains(t *testing.T) {
+	st := store.NewMemory()
+	ctx := context.Background()
+	for name, status := range map[string]string{"verified.example.com": models.DomainVerified, "pending.example.com": models.DomainPending} {
+		if err := st.Domains.Create(ctx, &models.Domain{DomainName: name, Status: status}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	policy := HostPolicy(st, &config.Config{QueryTimeout: time.Second})
+
+	if err := policy(ctx, "Verified.Example.com"); err != nil {
+		t.Errorf("verified domain rejected: %v", err)
+	}
+	for _, host := range []string{"pending.example.com", "unknown.example.com"} {
+		if err := policy(ctx, host); err == nil {
+			t.Errorf("%s allowed", host)
+		}
+	}
+}
+
+// TestIssueWithPebble orders a certificate from a Pebble ACME server. It
+// runs when PEBBLE_DIRECTORY_URL (e.g. https://localhost:14000/dir) and
+// PEBBLE_CA_CERT (Pebble's pebble.minica.pem) are set. Pebble must either
+// run with PEBBLE_VA_ALWAYS_VALID=1 or resolve PEBBLE_DOMAIN to this host,
+// where HTTP-01 challenges are answered on PEBBLE_HTTP_ADDR (default
+// :5002, Pebble's challenge port).
+func TestIssueWithPebble(t *testing.T) {
+	directory, caCert := os.Getenv("PEBBLE_DIRECTORY_URL"), os.Getenv("PEBBLE_CA_CERT")
+	if directory == "" || caCert == "" {
+		t.Skip("PEBBLE_DIRECTORY_URL and PEBBLE_CA_CERT are not set")
+	}
+	domain := os.Getenv("PEBBLE_DOMAIN")
+	if domain == "" {
+		domain = "shop.pebble.test"
+	}
+	addr := os.Getenv("PEBBLE_HTTP_ADDR")
+	if addr == "" {
+		addr = ":5002"
+	}
+
+	st := store.NewMemory()
+	ctx := context.Background()
+	err := st.Domains.Create(ctx, &models.Domain{DomainName: domain, IsCustom: true, Status: models.DomainVerified})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager, err := NewManager(st, &config.Config{
+		ACMEDirectoryURL: directory,
+		ACMECACertFile:   caCert,
+		TLSCacheKey:      "pebble test key",
+		QueryTimeout:     5 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manager.Client.HTTPClient.Transport = pebbleFinalize{manager.Client.HTTPClient.Transport}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("listening for challenges: %v", err)
+	}
+	server := &http.Server{Handler: manager.HTTPHandler(nil)}
+	go server.Serve(listener)
+	defer server.Close()
+
+	cert, err := manager.GetCertificate(&tls.ClientHelloInfo{ServerName: domain, CipherSuites: []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256}})
+	if err != nil {
+		t.Fatalf("issuing a certificate for %s: %v", domain, err)
+	}
+	if cert.Leaf == nil || !slices.Contains(cert.Leaf.DNSNames, domain) {
+		t.Fatalf("certificate does not cover %s", domain)
+	}
+	if _, err := manager.Cache.Get(ctx, domain); err != nil {
+		t.Fatalf("issued certificate is not cached: %v", err)
+	}
+
+	if _, err := manager.GetCertificate(&tls.ClientHelloInfo{ServerName: "unverified." + domain}); err == nil {
+		t.Fatal("a certificate was issued for an unverified domain")
+	}
+}
+
+// pebbleFinalize adds the order URL that Pebble leaves out of its
+// finalize responses. Pebble issues asynchronously and answers "processing",
+// so the ACME client polls the order at the response's Location.
+type pebbleFinalize struct{ http.RoundTripper }
+
+func (t pebbleFinalize) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.RoundTripper.RoundTrip(req)
+	if err != nil || res.Header.Get("Location") != "" {
+		return res, err
+	}
+	if id, ok := strings.CutPrefix(req.URL.Path, "/finalize-order/"); ok {
+		order := *req.URL
+		order.Path = "/my-order/" + id
+		res.Header.Set("Location", order.String())
+	}
+	return res, nil
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseRouteTimeouts(t *testing.T) {
+	got := parseRouteTimeouts(" GET  /api/messages=2s, POST /api/websites/:id/publish=30s,,GET /api/export=0s, bad, GET /x=soon")
+	want := map[string]time.Duration{
+		"GET /api/messages":              2 * time.Second,
+		"POST /api/websites/:id/publish": 30 * time.Second,
+		"GET /api/export":                0,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("parseRouteTimeouts = %v, want %v", got, want)
+	}
+}
+
+func TestQueryTimeoutFor(t *testing.T) {
+	cfg := &Config{
+		QueryTimeout:       5 * time.Second,
+		RouteQueryTimeouts: map[string]time.Duration{"GET /api/export": 0, "POST /api/websites/:id/publish": time.Minute},
+	}
+	for _, tt := range []struct {
+		method, path string
+		want         time.Duration
+	}{
+		{"GET", "/api/businesses", 5 * time.Second},
+		{"GET", "/api/export", 0},
+		{"POST", "/api/websites/:id/publish", time.Minute},
+		{"GET", "/api/websites/:id/publish", 5 * time.Second},
+	} {
+		if got := cfg.QueryTimeoutFor(tt.method, tt.path); got != tt.want {
+			t.Errorf("QueryTimeoutFor(%s %s) = %s, want %s", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
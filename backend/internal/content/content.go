@@ -0,0 +1,246 @@
+// Package content defines the structured content of website pages. A page
+// stores its content as JSONB of the form
+//
+//	{"sections": [{"type": "hero", "heading": "Welcome", ...}, ...]}
+//
+// where the keys of each section depend on its type.
+package content
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+
+	"saas-management-api/internal/models"
+)
+
+// Section types.
+const (
+	TypeHero        = "hero"
+	TypeGallery     = "gallery"
+	TypeContactForm = "contact_form"
+	TypePricing     = "pricing"
+)
+
+// MaxSections and MaxSize bound the size of a page.
+const (
+	MaxSections = 50
+	MaxSize     = 256 << 10
+)
+
+// Document is the parsed content of a page.
+type Document struct {
+	Sections []Section
+}
+
+// Section is one block of a page. Exactly the field matching Type is set.
+type Section struct {
+	Type        string
+	Hero        *Hero
+	Gallery     *Gallery
+	ContactForm *ContactForm
+	Pricing     *Pricing
+}
+
+type Hero struct {
+	Heading    string `json:"heading"`
+	Subheading string `json:"subheading,omitempty"`
+	ImageURL   string `json:"image_url,omitempty"`
+	CTALabel   string `json:"cta_label,omitempty"`
+	CTAURL     string `json:"cta_url,omitempty"`
+}
+
+type Gallery struct {
+	Heading string         `json:"heading,omitempty"`
+	Images  []GalleryImage `json:"images"`
+}
+
+type GalleryImage struct {
+	URL     string `json:"url"`
+	Caption string `json:"caption,omitempty"`
+}
+
+type ContactForm struct {
+	Heading string `json:"heading,omitempty"`
+	// Email receives the submissions.
+	Email  string      `json:"email"`
+	Fields []FormField `json:"fields"`
+}
+
+type FormField struct {
+	Name     string `json:"name"`
+	Label    string `json:"label"`
+	Type     string `json:"type"`
+	Required bool   `json:"required,omitempty"`
+}
+
+type Pricing struct {
+	Heading string        `json:"heading,omitempty"`
+	Tiers   []PricingTier `json:"tiers"`
+}
+
+type PricingTier struct {
+	Name        string   `json:"name"`
+	Price       float64  `json:"price"`
+	Period      string   `json:"period,omitempty"`
+	Features    []string `json:"features,omitempty"`
+	Highlighted bool     `json:"highlighted,omitempty"`
+}
+
+var formFieldTypes = map[string]bool{"text": true, "email": true, "tel": true, "textarea": true}
+
+// Parse decodes and validates page content. Unknown section types and
+// unknown keys are rejected so typos surface when saving instead of
+// silently disappearing from the rendered page.
+func Parse(raw models.JSONB) (*Document, error) {
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxSize {
+		return nil, fmt.Errorf("content: at most %d KiB is allowed", MaxSize>>10)
+	}
+	var envelope struct {
+		Sections []map[string]json.RawMessage `json:"sections"`
+	}
+	if err := strictUnmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("content: %v", err)
+	}
+	if len(envelope.Sections) > MaxSections {
+		return nil, fmt.Errorf("content: at most %d sections are allowed", MaxSections)
+	}
+
+	doc := &Document{Sections: make([]Section, 0, len(envelope.Sections))}
+	for i, fields := range envelope.Sections {
+		section, err := parseSection(fields)
+		if err != nil {
+			return nil, fmt.Errorf("sections[%d]: %v", i, err)
+		}
+		doc.Sections = append(doc.Sections, section)
+	}
+	return doc, nil
+}
+
+func parseSection(fields map[string]json.RawMessage) (Section, error) {
+	var section Section
+	if err := json.Unmarshal(fields["type"], &section.Type); err != nil {
+		return section, fmt.Errorf("type is required")
+	}
+	delete(fields, "type")
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return section, err
+	}
+
+	var target interface{ validate() error }
+	switch section.Type {
+	case TypeHero:
+		section.Hero = &Hero{}
+		target = section.Hero
+	case TypeGallery:
+		section.Gallery = &Gallery{}
+		target = section.Gallery
+	case TypeContactForm:
+		section.ContactForm = &ContactForm{}
+		target = section.ContactForm
+	case TypePricing:
+		section.Pricing = &Pricing{}
+		target = section.Pricing
+	default:
+		return section, fmt.Errorf("unknown section type %q", section.Type)
+	}
+	if err := strictUnmarshal(data, target); err != nil {
+		return section, fmt.Errorf("%s: %v", section.Type, err)
+	}
+	if err := target.validate(); err != nil {
+		return section, fmt.Errorf("%s: %v", section.Type, err)
+	}
+	return section, nil
+}
+
+func strictUnmarshal(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+func (h *Hero) validate() error {
+	if strings.TrimSpace(h.Heading) == "" {
+		return fmt.Errorf("heading is required")
+	}
+	if err := checkURL("image_url", h.ImageURL); err != nil {
+		return err
+	}
+	if (h.CTALabel == "") != (h.CTAURL == "") {
+		return fmt.Errorf("cta_label and cta_url go together")
+	}
+	return checkURL("cta_url", h.CTAURL)
+}
+
+func (g *Gallery) validate() error {
+	if len(g.Images) == 0 {
+		return fmt.Errorf("at least one image is required")
+	}
+	for i, image := range g.Images {
+		if image.URL == "" {
+			return fmt.Errorf("images[%d].url is required", i)
+		}
+		if err := checkURL(fmt.Sprintf("images[%d].url", i), image.URL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *ContactForm) validate() error {
+	if _, err := mail.ParseAddress(f.Email); err != nil {
+		return fmt.Errorf("email must be a valid address")
+	}
+	if len(f.Fields) == 0 {
+		return fmt.Errorf("at least one field is required")
+	}
+	names := map[string]bool{}
+	for i, field := range f.Fields {
+		switch {
+		case field.Name == "":
+			return fmt.Errorf("fields[%d].name is required", i)
+		case names[field.Name]:
+			return fmt.Errorf("fields[%d].name %q is repeated", i, field.Name)
+		case !formFieldTypes[field.Type]:
+			return fmt.Errorf("fields[%d].type must be text, email, tel or textarea", i)
+		}
+		names[field.Name] = true
+	}
+	return nil
+}
+
+func (p *Pricing) validate() error {
+	if len(p.Tiers) == 0 {
+		return fmt.Errorf("at least one tier is required")
+	}
+	for i, tier := range p.Tiers {
+		if strings.TrimSpace(tier.Name) == "" {
+			return fmt.Errorf("tiers[%d].name is required", i)
+		}
+		if tier.Price < 0 {
+			return fmt.Errorf("tiers[%d].price must not be negative", i)
+		}
+	}
+	return nil
+}
+
+// checkURL accepts empty values, site-relative paths and http(s) URLs, which
+// keeps javascript: and data: links out of rendered pages.
+func checkURL(field, value string) error {
+	if value == "" || (strings.HasPrefix(value, "/") && !strings.HasPrefix(value, "//")) {
+		return nil
+	}
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%s must be an http(s) URL or a path", field)
+	}
+	return nil
+}
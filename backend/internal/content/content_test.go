@@ -0,0 +1,101 @@
+package content
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"saas-management-api/internal/models"
+)
+
+// doc decodes raw JSON into the JSONB a page stores.
+func doc(t *testing.T, raw string) models.JSONB {
+	t.Helper()
+	var content models.JSONB
+	if err := json.Unmarshal([]byte(raw), &content); err != nil {
+		t.Fatal(err)
+	}
+	return content
+}
+
+func TestParseValidDocument(t *testing.T) {
+	parsed, err := Parse(doc(t, `{"sections": [
+		{"type": "hero", "heading": "Welcome", "image_url": "/uploads/hero.jpg", "cta_label": "Book", "cta_url": "https://example.com/book"},
+		{"type": "gallery", "images": [{"url": "https://example.com/a.jpg", "caption": "A"}]},
+		{"type": "contact_form", "email": "hi@example.com", "fields": [{"name": "email", "label": "Email", "type": "email", "required": true}]},
+		{"type": "pricing", "tiers": [{"name": "Basic", "price": 9, "features": ["One site"]}]}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed.Sections) != 4 {
+		t.Fatalf("parsed %d sections, want 4", len(parsed.Sections))
+	}
+	hero, gallery, form, pricing := parsed.Sections[0], parsed.Sections[1], parsed.Sections[2], parsed.Sections[3]
+	if hero.Type != TypeHero || hero.Hero.Heading != "Welcome" || hero.Hero.CTAURL != "https://example.com/book" {
+		t.Errorf("hero = %+v", hero.Hero)
+	}
+	if gallery.Gallery == nil || gallery.Gallery.Images[0].Caption != "A" {
+		t.Errorf("gallery = %+v", gallery.Gallery)
+	}
+	if form.ContactForm == nil || !form.ContactForm.Fields[0].Required {
+		t.Errorf("contact form = %+v", form.ContactForm)
+	}
+	if pricing.Pricing == nil || pricing.Pricing.Tiers[0].Price != 9 {
+		t.Errorf("pricing = %+v", pricing.Pricing)
+	}
+
+	if empty, err := Parse(doc(t, `{"sections": []}`)); err != nil || len(empty.Sections) != 0 {
+		t.Errorf("empty content = %+v, %v", empty, err)
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{"unknown section type", `{"sections": [{"type": "carousel"}]}`, `unknown section type "carousel"`},
+		{"missing type", `{"sections": [{"heading": "Welcome"}]}`, "type is required"},
+		{"unknown top-level key", `{"sections": [], "footer": "x"}`, `unknown field "footer"`},
+		{"unknown section key", `{"sections": [{"type": "hero", "heading": "Hi", "headline": "Hi"}]}`, `unknown field "headline"`},
+		{"unknown nested key", `{"sections": [{"type": "gallery", "images": [{"url": "/a.jpg", "alt": "A"}]}]}`, `unknown field "alt"`},
+		{"hero without heading", `{"sections": [{"type": "hero", "heading": " "}]}`, "heading is required"},
+		{"half a call to action", `{"sections": [{"type": "hero", "heading": "Hi", "cta_label": "Go"}]}`, "cta_label and cta_url go together"},
+		{"script URL", `{"sections": [{"type": "hero", "heading": "Hi", "image_url": "javascript:alert(1)"}]}`, "image_url must be"},
+		{"gallery without images", `{"sections": [{"type": "gallery", "images": []}]}`, "at least one image is required"},
+		{"image without URL", `{"sections": [{"type": "gallery", "images": [{"caption": "A"}]}]}`, "images[0].url is required"},
+		{"form without email", `{"sections": [{"type": "contact_form", "fields": [{"name": "n", "label": "N", "type": "text"}]}]}`, "email must be a valid address"},
+		{"form without fields", `{"sections": [{"type": "contact_form", "email": "hi@example.com", "fields": []}]}`, "at least one field is required"},
+		{"repeated field", `{"sections": [{"type": "contact_form", "email": "hi@example.com", "fields": [{"name": "n", "label": "N", "type": "text"}, {"name": "n", "label": "N", "type": "text"}]}]}`, `fields[1].name "n" is repeated`},
+		{"unknown field type", `{"sections": [{"type": "contact_form", "email": "hi@example.com", "fields": [{"name": "n", "label": "N", "type": "file"}]}]}`, "fields[0].type must be"},
+		{"pricing without tiers", `{"sections": [{"type": "pricing", "tiers": []}]}`, "at least one tier is required"},
+		{"negative price", `{"sections": [{"type": "pricing", "tiers": [{"name": "Basic", "price": -1}]}]}`, "tiers[0].price must not be negative"},
+		{"wrong value type", `{"sections": [{"type": "pricing", "tiers": [{"name": "Basic", "price": "free"}]}]}`, "pricing:"},
+	} {
+		_, err := Parse(doc(t, tt.raw))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: Parse = %v, want an error containing %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestParseRejectsOversizedContent(t *testing.T) {
+	hero := map[string]any{"type": TypeHero, "heading": "Hi"}
+	sections := make([]any, MaxSections+1)
+	for i := range sections {
+		sections[i] = hero
+	}
+	if _, err := Parse(models.JSONB{"sections": sections}); err == nil {
+		t.Errorf("parsed %d sections", len(sections))
+	}
+	if _, err := Parse(models.JSONB{"sections": sections[:MaxSections]}); err != nil {
+		t.Errorf("%d sections: %v", MaxSections, err)
+	}
+
+	big := map[string]any{"type": TypeHero, "heading": strings.Repeat("x", MaxSize)}
+	if _, err := Parse(models.JSONB{"sections": []any{big}}); err == nil {
+		t.Errorf("parsed a %d byte heading", MaxSize)
+	}
+}
@@ -1,9 +1,20 @@
 package database
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
+// maxTxAttempts bounds how often WithTx runs a transaction that keeps
+// failing with serialization errors.
+const maxTxAttempts = 3
+
 type DB struct {
 	*sqlx.DB
 }
@@ -12,3 +23,51 @@ func NewDB(db *sqlx.DB) *DB {
 	return &DB{DB: db}
 }
 
+// WithTx runs fn inside a serializable transaction, committing if fn returns
+// nil and rolling back otherwise. Serialization failures and deadlocks are
+// retried, so fn may run more than once and must not have side effects
+// outside the transaction.
+func (db *DB) WithTx(ctx context.Context, fn func(tx *sqlx.Tx) error) error {
+	var err error
+	for attempt := 1; attempt <= maxTxAttempts; attempt++ {
+		err = db.runTx(ctx, fn)
+		if err == nil || !isRetryable(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt) * 10 * time.Millisecond):
+		}
+	}
+	return fmt.Errorf("transaction failed after %d attempts: %w", maxTxAttempts, err)
+}
+
+func (db *DB) runTx(ctx context.Context, fn func(tx *sqlx.Tx) error) error {
+	tx, err := db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// isRetryable reports whether err is a transient conflict between concurrent
+// transactions.
+func isRetryable(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	switch pqErr.Code {
+	case "40001", // serialization_failure
+		"40P01": // deadlock_detected
+		return true
+	}
+	return false
+}
@@ -0,0 +1,158 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// fakeDriver opens connections that only begin, commit and roll back
+// transactions, counting each.
+type fakeDriver struct {
+	mu                         sync.Mutex
+	begins, commits, rollbacks int
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) { return fakeConn{d}, nil }
+
+type fakeConn struct{ d *fakeDriver }
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("fake driver: statements are not supported")
+}
+
+func (c fakeConn) Close() error { return nil }
+
+func (c fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c fakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if sql.IsolationLevel(opts.Isolation) != sql.LevelSerializable {
+		return nil, fmt.Errorf("fake driver: isolation %v, want serializable", sql.IsolationLevel(opts.Isolation))
+	}
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+	c.d.begins++
+	return fakeTx{c.d}, nil
+}
+
+type fakeTx struct{ d *fakeDriver }
+
+func (tx fakeTx) Commit() error {
+	tx.d.mu.Lock()
+	defer tx.d.mu.Unlock()
+	tx.d.commits++
+	return nil
+}
+
+func (tx fakeTx) Rollback() error {
+	tx.d.mu.Lock()
+	defer tx.d.mu.Unlock()
+	tx.d.rollbacks++
+	return nil
+}
+
+var fakeDrivers = 0
+
+// newFakeDB returns a DB on a freshly registered fakeDriver.
+func newFakeDB(t *testing.T) (*DB, *fakeDriver) {
+	t.Helper()
+	fakeDrivers++
+	name := fmt.Sprintf("fake-%d", fakeDrivers)
+	d := &fakeDriver{}
+	sql.Register(name, d)
+	db, err := sqlx.Open(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return NewDB(db), d
+}
+
+func TestIsRetryable(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"serialization failure", &pq.Error{Code: "40001"}, true},
+		{"deadlock", &pq.Error{Code: "40P01"}, true},
+		{"wrapped serialization failure", fmt.Errorf("saving: %w", &pq.Error{Code: "40001"}), true},
+		{"doubly wrapped deadlock", fmt.Errorf("a: %w", fmt.Errorf("b: %w", &pq.Error{Code: "40P01"})), true},
+		{"unique violation", &pq.Error{Code: "23505"}, false},
+		{"other transaction rollback", &pq.Error{Code: "40002"}, false},
+		{"not a Postgres error", errors.New("40001"), false},
+		{"no rows", sql.ErrNoRows, false},
+		{"nil", nil, false},
+	} {
+		if got := isRetryable(tt.err); got != tt.want {
+			t.Errorf("%s: isRetryable = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWithTx(t *testing.T) {
+	conflict := &pq.Error{Code: "40001"}
+	for _, tt := range []struct {
+		name     string
+		failures []error
+		runs     int
+		commits  int
+		err      error
+	}{
+		{"commits", nil, 1, 1, nil},
+		{"retries a conflict", []error{conflict}, 2, 1, nil},
+		{"retries a deadlock", []error{&pq.Error{Code: "40P01"}, conflict}, 3, 1, nil},
+		{"gives up", []error{conflict, conflict, conflict, conflict}, maxTxAttempts, 0, conflict},
+		{"does not retry other errors", []error{&pq.Error{Code: "23505"}}, 1, 0, &pq.Error{Code: "23505"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			db, d := newFakeDB(t)
+			runs := 0
+			err := db.WithTx(context.Background(), func(tx *sqlx.Tx) error {
+				runs++
+				if runs <= len(tt.failures) {
+					return tt.failures[runs-1]
+				}
+				return nil
+			})
+
+			var pqErr *pq.Error
+			switch {
+			case tt.err == nil && err != nil:
+				t.Fatalf("WithTx = %v", err)
+			case tt.err != nil && (!errors.As(err, &pqErr) || pqErr.Code != tt.err.(*pq.Error).Code):
+				t.Fatalf("WithTx = %v, want %v", err, tt.err)
+			}
+			if runs != tt.runs {
+				t.Errorf("fn ran %d times, want %d", runs, tt.runs)
+			}
+			if d.begins != tt.runs || d.commits != tt.commits || d.rollbacks != tt.runs-tt.commits {
+				t.Errorf("%d begins, %d commits and %d rollbacks, want %d, %d and %d",
+					d.begins, d.commits, d.rollbacks, tt.runs, tt.commits, tt.runs-tt.commits)
+			}
+		})
+	}
+}
+
+func TestWithTxStopsWhenContextEnds(t *testing.T) {
+	db, _ := newFakeDB(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	runs := 0
+	err := db.WithTx(ctx, func(tx *sqlx.Tx) error {
+		runs++
+		cancel()
+		return &pq.Error{Code: "40001"}
+	})
+	if !errors.Is(err, context.Canceled) || runs != 1 {
+		t.Fatalf("WithTx = %v after %d runs, want context.Canceled after 1", err, runs)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -176,7 +177,7 @@ func SeedDatabase(db *sqlx.DB) error {
 			"Consulting",
 			"+1-555-0101",
 			"123 Business St, New York, NY 10001",
-			"active",
+			"approved",
 		},
 		{
 			ownerIDs[1],
@@ -186,7 +187,7 @@ func SeedDatabase(db *sqlx.DB) error {
 			"Marketing",
 			"+1-555-0102",
 			"456 Marketing Ave, Los Angeles, CA 90001",
-			"active",
+			"approved",
 		},
 		{
 			ownerIDs[2],
@@ -196,7 +197,7 @@ func SeedDatabase(db *sqlx.DB) error {
 			"Technology",
 			"+1-555-0103",
 			"789 Tech Blvd, San Francisco, CA 94102",
-			"active",
+			"approved",
 		},
 		{
 			0,
@@ -381,6 +382,13 @@ func SeedDatabase(db *sqlx.DB) error {
 						conversationIDs = append(conversationIDs, convID)
 					}
 				}
+				if err == nil {
+					db.Exec(`
+						INSERT INTO conversation_participants (conversation_id, user_id)
+						VALUES ($1, $2), ($1, $3)
+						ON CONFLICT DO NOTHING
+					`, convID, user1ID, user2ID)
+				}
 			}
 		}
 
@@ -465,6 +473,18 @@ func SeedDatabase(db *sqlx.DB) error {
 			}
 		}
 		
+		// Participants have read everything before their oldest unread message
+		db.Exec(`
+			UPDATE conversation_participants p
+			SET last_read_message_id = COALESCE(
+				(SELECT MIN(m.id) - 1 FROM messages m
+				 WHERE m.conversation_id = p.conversation_id AND m.receiver_id = p.user_id AND m.is_read = FALSE),
+				(SELECT MAX(m.id) FROM messages m WHERE m.conversation_id = p.conversation_id),
+				0
+			)
+			WHERE p.conversation_id = ANY($1)
+		`, pq.Array(conversationIDs))
+
 		log.Println("✅ Created fake conversations and messages!")
 	}
 
@@ -0,0 +1,90 @@
+// Package events is an in-process bus for domain events. Handlers publish
+// an event after the change it describes is committed, and subscribers such
+// as the WebSocket hub react to it, whichever path produced the change.
+package events
+
+import "sync"
+
+// Event types.
+const (
+	// MessageCreated carries the *models.Message that was sent.
+	MessageCreated = "message.created"
+	// MessageDelivered carries the *models.MessageReceipt of messages that
+	// reached their receiver's socket.
+	MessageDelivered = "message.delivered"
+	// MessageRead carries the *models.MessageReceipt of messages another
+	// participant read.
+	MessageRead = "message.read"
+	// MessageUpdated carries the *models.Message that was edited, deleted
+	// for everyone or reacted to.
+	MessageUpdated = "message.updated"
+	// MessageHidden carries the *models.HiddenMessage a user deleted for
+	// themselves.
+	MessageHidden = "message.hidden"
+	// ConversationUpdated carries the *models.Conversation, with its
+	// participants, that was created, renamed or joined.
+	ConversationUpdated = "conversation.updated"
+	// ParticipantRemoved carries the *models.ConversationParticipant that
+	// left or was removed from a conversation.
+	ParticipantRemoved = "conversation.participant_removed"
+	// NotificationCreated carries the *models.Notification that was created.
+	NotificationCreated = "notification.created"
+	// NotificationUpdated carries the *models.Notification that was changed,
+	// e.g. marked read.
+	NotificationUpdated = "notification.updated"
+	// NotificationDeleted carries the *models.Notification that was deleted.
+	NotificationDeleted = "notification.deleted"
+	// SubscriptionUpdated carries the *models.Subscription that was created
+	// or changed.
+	SubscriptionUpdated = "subscription.updated"
+	// WebsitePublished carries the *models.Website whose published revision
+	// changed, by publishing or rolling back.
+	WebsitePublished = "website.published"
+	// InvoicePaid carries the *models.Invoice that was settled.
+	InvoicePaid = "invoice.paid"
+)
+
+// Event is something that happened to a record.
+type Event struct {
+	Type    string
+	Payload any
+}
+
+// Handler reacts to an event. Handlers run synchronously in the publisher's
+// goroutine, so they must hand slow work off.
+type Handler func(Event)
+
+// Bus delivers published events to the handlers subscribed to their type.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+func NewBus() *Bus {
+	return &Bus{handlers: map[string][]Handler{}}
+}
+
+// Subscribe calls handler for every event of the given types.
+func (b *Bus) Subscribe(handler Handler, types ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, t := range types {
+		b.handlers[t] = append(b.handlers[t], handler)
+	}
+}
+
+// Publish passes an event with payload to the handlers of eventType. It does
+// nothing on a nil Bus.
+func (b *Bus) Publish(eventType string, payload any) {
+	if b == nil {
+		return
+	}
+	b.mu.RLock()
+	handlers := b.handlers[eventType]
+	b.mu.RUnlock()
+
+	e := Event{Type: eventType, Payload: payload}
+	for _, handle := range handlers {
+		handle(e)
+	}
+}
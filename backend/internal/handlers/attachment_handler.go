@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"saas-management-api/internal/models"
+	"saas-management-api/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxAttachmentSize is the largest file that can be attached to a message.
+const maxAttachmentSize = 25 << 20
+
+// AttachmentHandler stores message attachments as files in Dir, which must
+// not be served publicly: downloads go through Download, which checks that
+// the caller may see the attachment.
+type AttachmentHandler struct {
+	Store *store.Store
+	Dir   string
+}
+
+func NewAttachmentHandler(s *store.Store, dir string) *AttachmentHandler {
+	return &AttachmentHandler{Store: s, Dir: dir}
+}
+
+// Upload stores the multipart file "file" as an attachment of the caller.
+// It can then be sent with a message by passing its ID in attachment_ids.
+func (h *AttachmentHandler) Upload(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxAttachmentSize+1<<20)
+	header, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Attachments can be at most 25 MB"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file is received"})
+		return
+	}
+	if header.Size > maxAttachmentSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Attachments can be at most 25 MB"})
+		return
+	}
+
+	attachment := models.Attachment{
+		OwnerID:  c.GetInt("user_id"),
+		FileName: fileName(header.Filename),
+		Size:     header.Size,
+	}
+	if err := inspect(header, &attachment); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unable to read the file"})
+		return
+	}
+
+	key := make([]byte, 16)
+	rand.Read(key)
+	attachment.StorageKey = hex.EncodeToString(key)
+	if err := os.MkdirAll(h.Dir, 0o700); err != nil {
+		log.Printf("Error creating attachment directory: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to save the file"})
+		return
+	}
+	path := filepath.Join(h.Dir, attachment.StorageKey)
+	if err := c.SaveUploadedFile(header, path); err != nil {
+		log.Printf("Error saving attachment: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to save the file"})
+		return
+	}
+
+	if err := h.Store.Attachments.Create(c.Request.Context(), &attachment); err != nil {
+		os.Remove(path)
+		log.Printf("Error creating attachment: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to save the file"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, attachment)
+}
+
+// Download serves an attachment to its owner or, once sent, to the
+// participants of its conversation. Images are shown inline and other files
+// downloaded.
+func (h *AttachmentHandler) Download(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	attachment, err := h.Store.AttachmentForUser(c.Request.Context(), id, c.GetInt("user_id"))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+			return
+		}
+		log.Printf("Error fetching attachment %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch attachment"})
+		return
+	}
+
+	disposition := "attachment"
+	if attachment.IsImage() {
+		disposition = "inline"
+	}
+	c.Header("Content-Type", attachment.MimeType)
+	c.Header("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": attachment.FileName}))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Cache-Control", "private, max-age=86400")
+	c.File(filepath.Join(h.Dir, attachment.StorageKey))
+}
+
+// fileName returns the base name of an uploaded file, cut to fit the
+// attachments table.
+func fileName(name string) string {
+	name = filepath.Base(filepath.Clean("/" + name))
+	if name == "/" || name == "." {
+		return "file"
+	}
+	runes := []rune(name)
+	if len(runes) > 255 {
+		runes = runes[len(runes)-255:]
+	}
+	return string(runes)
+}
+
+// inspect sniffs the MIME type of an uploaded file and, for images, reads
+// their dimensions.
+func inspect(header *multipart.FileHeader, attachment *models.Attachment) error {
+	file, err := header.Open()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	sniff := make([]byte, 512)
+	n, err := io.ReadFull(file, sniff)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return err
+	}
+	attachment.MimeType = http.DetectContentType(sniff[:n])
+	if !attachment.IsImage() {
+		return nil
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if config, _, err := image.DecodeConfig(file); err == nil {
+		attachment.Width, attachment.Height = &config.Width, &config.Height
+	}
+	return nil
+}
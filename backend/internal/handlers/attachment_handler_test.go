@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"saas-management-api/internal/events"
+	"saas-management-api/internal/models"
+	"saas-management-api/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newAttachmentRouter(st *store.Store, dir string, user *models.User) *gin.Engine {
+	h := NewAttachmentHandler(st, dir)
+	messages := NewMessageHandler(st, events.NewBus())
+	r := gin.New()
+	r.Use(asUser(user))
+	r.POST("/attachments", h.Upload)
+	r.GET("/attachments/:id", h.Download)
+	r.POST("/send", messages.SendMessage)
+	return r
+}
+
+// upload posts content as the multipart file "file" named name.
+func upload(t *testing.T, r http.Handler, name string, content []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(content)
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/attachments", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestUploadLimitsSize(t *testing.T) {
+	st := store.NewMemory()
+	r := newAttachmentRouter(st, t.TempDir(), newTestUser(t, st, "alice", "owner"))
+
+	// Just over the limit is caught by the file's size, far over it while
+	// reading the body.
+	for _, size := range []int{maxAttachmentSize + 1, maxAttachmentSize + 2<<20} {
+		w := upload(t, r, "big.bin", make([]byte, size))
+		expectStatus(t, w, http.StatusRequestEntityTooLarge)
+	}
+	expectStatus(t, upload(t, r, "limit.bin", make([]byte, maxAttachmentSize)), http.StatusCreated)
+
+	w := serve(t, r, http.MethodPost, "/attachments", nil)
+	expectStatus(t, w, http.StatusBadRequest)
+}
+
+func TestUploadSniffsType(t *testing.T) {
+	st := store.NewMemory()
+	r := newAttachmentRouter(st, t.TempDir(), newTestUser(t, st, "alice", "owner"))
+	var picture bytes.Buffer
+	if err := png.Encode(&picture, image.NewRGBA(image.Rect(0, 0, 3, 2))); err != nil {
+		t.Fatal(err)
+	}
+
+	w := upload(t, r, "../../photo.txt", picture.Bytes())
+	expectStatus(t, w, http.StatusCreated)
+	var photo models.Attachment
+	decode(t, w, &photo)
+	if photo.MimeType != "image/png" || photo.Width == nil || *photo.Width != 3 || *photo.Height != 2 {
+		t.Fatalf("attachment = %+v, want a 3x2 PNG", photo)
+	}
+	if photo.FileName != "photo.txt" || photo.Size != int64(picture.Len()) {
+		t.Fatalf("attachment = %+v, want the base name and size of the upload", photo)
+	}
+
+	// A file named like an image is still sniffed by its content.
+	w = upload(t, r, "fake.png", []byte("<html><script>alert(1)</script></html>"))
+	expectStatus(t, w, http.StatusCreated)
+	var page models.Attachment
+	decode(t, w, &page)
+	if page.IsImage() || page.Width != nil || !strings.HasPrefix(page.MimeType, "text/html") {
+		t.Fatalf("attachment = %+v, want an HTML file without dimensions", page)
+	}
+}
+
+func TestDownloadRequiresParticipant(t *testing.T) {
+	st := store.NewMemory()
+	alice := newTestUser(t, st, "alice", "owner")
+	bob := newTestUser(t, st, "bob", "owner")
+	carol := newTestUser(t, st, "carol", "owner")
+	dir := t.TempDir()
+	r := newAttachmentRouter(st, dir, alice)
+
+	w := upload(t, r, "notes.txt", []byte("meeting notes"))
+	expectStatus(t, w, http.StatusCreated)
+	var notes models.Attachment
+	decode(t, w, &notes)
+	path := "/attachments/" + strconv.Itoa(notes.ID)
+
+	// Until it's sent, only the owner may download it.
+	bobRouter := newAttachmentRouter(st, dir, bob)
+	expectStatus(t, serve(t, bobRouter, http.MethodGet, path, nil), http.StatusNotFound)
+	expectStatus(t, serve(t, r, http.MethodGet, path, nil), http.StatusOK)
+
+	w = serve(t, bobRouter, http.MethodPost, "/send", models.MessageCreate{ReceiverID: alice.ID, AttachmentIDs: []int{notes.ID}})
+	expectStatus(t, w, http.StatusConflict)
+	w = serve(t, r, http.MethodPost, "/send", models.MessageCreate{ReceiverID: bob.ID, AttachmentIDs: []int{notes.ID}})
+	expectStatus(t, w, http.StatusCreated)
+	var sent models.Message
+	decode(t, w, &sent)
+	if sent.MessageType != "file" {
+		t.Fatalf("message type = %q, want file", sent.MessageType)
+	}
+
+	w = serve(t, bobRouter, http.MethodGet, path, nil)
+	expectStatus(t, w, http.StatusOK)
+	if w.Body.String() != "meeting notes" || !strings.HasPrefix(w.Header().Get("Content-Disposition"), "attachment") {
+		t.Fatalf("download = %q with disposition %q, want the notes as a download",
+			w.Body.String(), w.Header().Get("Content-Disposition"))
+	}
+	expectStatus(t, serve(t, newAttachmentRouter(st, dir, carol), http.MethodGet, path, nil), http.StatusNotFound)
+	expectStatus(t, serve(t, bobRouter, http.MethodGet, "/attachments/99", nil), http.StatusNotFound)
+}
@@ -1,25 +1,24 @@
 package handlers
 
 import (
+	"errors"
 	"log"
 	"net/http"
-	"strings"
-	"time"
 
 	"saas-management-api/internal/auth"
-	"saas-management-api/internal/database"
 	"saas-management-api/internal/models"
+	"saas-management-api/internal/store"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type AuthHandler struct {
-	DB *database.DB
+	Store *store.Store
 }
 
-func NewAuthHandler(db *database.DB) *AuthHandler {
-	return &AuthHandler{DB: db}
+func NewAuthHandler(s *store.Store) *AuthHandler {
+	return &AuthHandler{Store: s}
 }
 
 type RegisterRequest struct {
@@ -43,9 +42,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	}
 
 	// Check if user exists
-	var existingUser models.User
-	err := h.DB.DB.Get(&existingUser, "SELECT id FROM users WHERE email = $1", req.Email)
-	if err == nil {
+	if _, err := h.Store.Users.GetByEmail(c.Request.Context(), req.Email); err == nil {
 		c.JSON(http.StatusConflict, gin.H{"error": "User already exists"})
 		return
 	}
@@ -64,25 +61,32 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	}
 
 	// Insert user
-	var user models.User
-	err = h.DB.DB.Get(&user, `
-		INSERT INTO users (name, email, password, role, business_name, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $6)
-		RETURNING id, name, email, role, business_name, created_at, updated_at
-	`, req.Name, req.Email, string(hashedPassword), role, req.BusinessName, time.Now())
-	if err != nil {
+	user := models.User{
+		Name:         req.Name,
+		Email:        req.Email,
+		Password:     string(hashedPassword),
+		Role:         role,
+		BusinessName: req.BusinessName,
+	}
+	if err := h.Store.Users.Create(c.Request.Context(), &user); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": "User already exists"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
 		return
 	}
 
-	// Also create a business record if business name is provided
+	// Also create a business record if business name is provided. It stays
+	// pending until an admin approves it.
 	if req.BusinessName != nil && *req.BusinessName != "" {
-		slug := strings.ToLower(strings.ReplaceAll(*req.BusinessName, " ", "-"))
-		h.DB.DB.Exec(`
-			INSERT INTO businesses (user_id, name, slug, status, created_at, updated_at)
-			VALUES ($1, $2, $3, 'active', $4, $4)
-			ON CONFLICT (slug) DO NOTHING
-		`, user.ID, *req.BusinessName, slug, time.Now())
+		business := models.Business{
+			UserID: &user.ID,
+			Name:   *req.BusinessName,
+		}
+		if err := h.Store.CreateBusiness(c.Request.Context(), &business); err != nil {
+			log.Printf("Error creating business for user %d: %v", user.ID, err)
+		}
 	}
 
 	// Generate token
@@ -106,8 +110,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	}
 
 	// Get user
-	var user models.User
-	err := h.DB.DB.Get(&user, "SELECT id, name, email, password, role FROM users WHERE email = $1", req.Email)
+	user, err := h.Store.Users.GetByEmail(c.Request.Context(), req.Email)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
@@ -121,7 +124,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	}
 
 	// Generate token
-	token, err := auth.GenerateToken(&user)
+	token, err := auth.GenerateToken(user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
@@ -149,24 +152,13 @@ func (h *AuthHandler) SearchUsers(c *gin.Context) {
 		return
 	}
 
-	// Make search more flexible by replacing spaces with %
-	flexibleSearch := "%" + strings.ReplaceAll(query, " ", "%") + "%"
-	var users []models.User
-	err := h.DB.DB.Select(&users, `
-		SELECT id, name, email, role, business_name, created_at, updated_at
-		FROM users
-		WHERE (name ILIKE $1 OR business_name ILIKE $1)
-		AND role != 'admin'
-		LIMIT 10
-	`, flexibleSearch)
-
+	users, err := h.Store.Users.Search(c.Request.Context(), query, 10)
 	if err != nil {
 		log.Printf("Search failed: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search users"})
 		return
 	}
 
-	log.Printf("Found %d users for query: %s (pattern: %s)", len(users), query, flexibleSearch)
+	log.Printf("Found %d users for query: %s", len(users), query)
 	c.JSON(http.StatusOK, users)
 }
-
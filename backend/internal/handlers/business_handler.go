@@ -1,32 +1,89 @@
 package handlers
 
 import (
+	"errors"
+	"log"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
-	"saas-management-api/internal/database"
+	"saas-management-api/internal/events"
+	"saas-management-api/internal/listing"
 	"saas-management-api/internal/models"
+	"saas-management-api/internal/store"
 
 	"github.com/gin-gonic/gin"
 )
 
+// ReviewRequest is the body of the approve and reject endpoints.
+type ReviewRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// businessStatuses are the review statuses admins may set directly.
+var businessStatuses = []string{models.BusinessPending, models.BusinessApproved, models.BusinessRejected}
+
+// validBusinessStatus answers 400 and returns false when an admin sets a
+// status that is not a review status. An empty status is left to the
+// store.
+func validBusinessStatus(c *gin.Context, status string) bool {
+	if status != "" && !slices.Contains(businessStatuses, status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Status must be one of " + strings.Join(businessStatuses, ", ")})
+		return false
+	}
+	return true
+}
+
 type BusinessHandler struct {
-	DB *database.DB
+	Store  *store.Store
+	Events *events.Bus
 }
 
-func NewBusinessHandler(db *database.DB) *BusinessHandler {
-	return &BusinessHandler{DB: db}
+func NewBusinessHandler(s *store.Store, bus *events.Bus) *BusinessHandler {
+	return &BusinessHandler{Store: s, Events: bus}
 }
 
 func (h *BusinessHandler) List(c *gin.Context) {
-	var businesses []models.Business
-	err := h.DB.DB.Select(&businesses, "SELECT * FROM businesses ORDER BY created_at DESC")
+	spec, ok := parseListSpec(c, store.BusinessListing)
+	if !ok {
+		return
+	}
+
+	page, err := h.Store.Businesses.List(c.Request.Context(), spec)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch businesses"})
 		return
 	}
-	c.JSON(http.StatusOK, businesses)
+	writePage(c, page)
+}
+
+// Search is the public business search. It accepts q, industry and limit
+// and returns ranked results with industry facet counts.
+func (h *BusinessHandler) Search(c *gin.Context) {
+	limit := 20
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		limit = min(n, 100)
+	}
+
+	result, err := h.Store.Businesses.Search(c.Request.Context(), store.BusinessSearch{
+		Query:    c.Query("q"),
+		Industry: c.Query("industry"),
+		Status:   models.BusinessApproved,
+		Limit:    limit,
+	})
+	if err != nil {
+		log.Printf("Error searching businesses: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search businesses"})
+		return
+	}
+	c.JSON(http.StatusOK, result)
 }
 
 func (h *BusinessHandler) Get(c *gin.Context) {
@@ -36,8 +93,7 @@ func (h *BusinessHandler) Get(c *gin.Context) {
 		return
 	}
 
-	var business models.Business
-	err = h.DB.DB.Get(&business, "SELECT * FROM businesses WHERE id = $1", id)
+	business, err := h.Store.Businesses.Get(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Business not found"})
 		return
@@ -45,6 +101,31 @@ func (h *BusinessHandler) Get(c *gin.Context) {
 	c.JSON(http.StatusOK, business)
 }
 
+// GetBySlug resolves a business by its current or a former slug. When a
+// former slug is used, redirect is true and slug holds the canonical slug
+// clients should link to instead.
+func (h *BusinessHandler) GetBySlug(c *gin.Context) {
+	requested := c.Param("slug")
+	business, err := h.Store.Businesses.GetBySlug(c.Request.Context(), requested)
+	if err == nil && business.Status != models.BusinessApproved {
+		err = store.ErrNotFound
+	}
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Business not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch business"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"business": business,
+		"slug":     business.Slug,
+		"redirect": business.Slug != requested,
+	})
+}
+
 func (h *BusinessHandler) Create(c *gin.Context) {
 	var business models.Business
 	if err := c.ShouldBindJSON(&business); err != nil {
@@ -52,16 +133,17 @@ func (h *BusinessHandler) Create(c *gin.Context) {
 		return
 	}
 
-	user, _ := c.Get("user")
-	u := user.(*models.User)
-	business.UserID = &u.ID
+	userID := c.GetInt("user_id")
+	business.UserID = &userID
+	// Only admins decide on review status.
+	if !isAdmin(c) {
+		business.Status = models.BusinessPending
+	} else if !validBusinessStatus(c, business.Status) {
+		return
+	}
 
-	err := h.DB.DB.Get(&business, `
-		INSERT INTO businesses (user_id, name, slug, description, logo, industry, phone, address, status, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $10)
-		RETURNING id, user_id, name, slug, description, logo, industry, phone, address, status, created_at, updated_at
-	`, business.UserID, business.Name, business.Slug, business.Description, business.Logo, business.Industry, business.Phone, business.Address, business.Status, time.Now())
-	if err != nil {
+	if err := h.Store.CreateBusiness(c.Request.Context(), &business); err != nil {
+		log.Printf("Error creating business: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create business"})
 		return
 	}
@@ -81,17 +163,100 @@ func (h *BusinessHandler) Update(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	business.ID = id
+	// Only admins decide on review status. Material edits by others send an
+	// approved business back to review.
+	if !isAdmin(c) {
+		business.Status = ""
+	} else if !validBusinessStatus(c, business.Status) {
+		return
+	}
+	if _, ok := authorizeBusiness(c, h.Store, id); !ok {
+		return
+	}
+
+	if err := h.Store.UpdateBusiness(c.Request.Context(), &business); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Business not found"})
+		case errors.Is(err, store.ErrConflict):
+			c.JSON(http.StatusConflict, gin.H{"error": "Slug already in use"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update business"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, business)
+}
+
+// ListPending is the admin review queue: pending businesses, oldest first
+// unless another sort is requested.
+func (h *BusinessHandler) ListPending(c *gin.Context) {
+	query := c.Request.URL.Query()
+	if query.Get("sort") == "" {
+		query.Set("sort", "created_at")
+	}
+	spec, err := listing.Parse(query, store.BusinessListing)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	spec.Filters = slices.DeleteFunc(spec.Filters, func(f listing.Filter) bool { return f.Field == "status" })
+	spec.Filters = append(spec.Filters, listing.Filter{Field: "status", Value: models.BusinessPending})
 
-	err = h.DB.DB.Get(&business, `
-		UPDATE businesses 
-		SET name = $1, slug = $2, description = $3, logo = $4, industry = $5, phone = $6, address = $7, status = $8, updated_at = $9
-		WHERE id = $10
-		RETURNING id, user_id, name, slug, description, logo, industry, phone, address, status, created_at, updated_at
-	`, business.Name, business.Slug, business.Description, business.Logo, business.Industry, business.Phone, business.Address, business.Status, time.Now(), id)
+	page, err := h.Store.Businesses.List(c.Request.Context(), spec)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update business"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pending businesses"})
 		return
 	}
+	writePage(c, page)
+}
+
+// Approve approves a business. A reason is required and sent to the owner.
+func (h *BusinessHandler) Approve(c *gin.Context) {
+	h.review(c, models.BusinessApproved)
+}
+
+// Reject rejects a business. A reason is required and sent to the owner.
+func (h *BusinessHandler) Reject(c *gin.Context) {
+	h.review(c, models.BusinessRejected)
+}
+
+func (h *BusinessHandler) review(c *gin.Context, status string) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	var req ReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Reason) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A reason is required"})
+		return
+	}
+
+	business, notification, err := h.Store.ReviewBusiness(c.Request.Context(), id, store.BusinessReview{
+		Status:     status,
+		ReviewerID: c.GetInt("user_id"),
+		Reason:     strings.TrimSpace(req.Reason),
+		At:         time.Now(),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Business not found"})
+		case errors.Is(err, store.ErrConflict):
+			c.JSON(http.StatusConflict, gin.H{"error": "Business is already " + status})
+		default:
+			log.Printf("Error reviewing business %d: %v", id, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to review business"})
+		}
+		return
+	}
+	if notification != nil {
+		h.Events.Publish(events.NotificationCreated, notification)
+	}
 
 	c.JSON(http.StatusOK, business)
 }
@@ -102,13 +267,18 @@ func (h *BusinessHandler) Delete(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
 		return
 	}
+	if _, ok := authorizeBusiness(c, h.Store, id); !ok {
+		return
+	}
 
-	_, err = h.DB.DB.Exec("DELETE FROM businesses WHERE id = $1", id)
-	if err != nil {
+	if err := h.Store.Businesses.Delete(c.Request.Context(), id); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Business not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete business"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Business deleted successfully"})
 }
-
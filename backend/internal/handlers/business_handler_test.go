@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"saas-management-api/internal/events"
+	"saas-management-api/internal/models"
+	"saas-management-api/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newBusinessRouter(st *store.Store, user *models.User) *gin.Engine {
+	h := NewBusinessHandler(st, events.NewBus())
+	r := gin.New()
+	r.Use(asUser(user))
+	r.GET("/businesses", h.List)
+	r.GET("/businesses/search", h.Search)
+	r.GET("/businesses/by-slug/:slug", h.GetBySlug)
+	r.GET("/businesses/:id", h.Get)
+	r.POST("/businesses", h.Create)
+	r.PUT("/businesses/:id", h.Update)
+	r.DELETE("/businesses/:id", h.Delete)
+	return r
+}
+
+func TestBusinessListKeysetPages(t *testing.T) {
+	st := store.NewMemory()
+	owner := newTestUser(t, st, "owner", "owner")
+	r := newBusinessRouter(st, owner)
+	for _, name := range []string{"Delta", "Alpha", "Echo", "Charlie", "Bravo"} {
+		expectStatus(t, serve(t, r, http.MethodPost, "/businesses", models.Business{Name: name}), http.StatusCreated)
+	}
+
+	var names []string
+	path := "/businesses?sort=name&limit=2"
+	for pages := 0; path != ""; pages++ {
+		if pages == 5 {
+			t.Fatalf("pagination did not end, got %v", names)
+		}
+		w := serve(t, r, http.MethodGet, path, nil)
+		expectStatus(t, w, http.StatusOK)
+		var page []models.Business
+		decode(t, w, &page)
+		if len(page) > 2 {
+			t.Fatalf("page of %d businesses, want at most 2", len(page))
+		}
+		for _, b := range page {
+			names = append(names, b.Name)
+		}
+		path = ""
+		if cursor := w.Header().Get("X-Next-Cursor"); cursor != "" {
+			path = "/businesses?sort=name&limit=2&cursor=" + url.QueryEscape(cursor)
+		}
+	}
+
+	want := []string{"Alpha", "Bravo", "Charlie", "Delta", "Echo"}
+	if len(names) != len(want) {
+		t.Fatalf("names = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("names = %v, want %v", names, want)
+		}
+	}
+}
+
+func TestBusinessListRejectsUnknownSort(t *testing.T) {
+	st := store.NewMemory()
+	r := newBusinessRouter(st, newTestUser(t, st, "owner", "owner"))
+
+	expectStatus(t, serve(t, r, http.MethodGet, "/businesses?sort=password", nil), http.StatusBadRequest)
+}
+
+func TestBusinessUpdateMapsStoreErrors(t *testing.T) {
+	st := store.NewMemory()
+	owner := newTestUser(t, st, "owner", "owner")
+	r := newBusinessRouter(st, owner)
+	expectStatus(t, serve(t, r, http.MethodPost, "/businesses", models.Business{Name: "Acme"}), http.StatusCreated)
+	expectStatus(t, serve(t, r, http.MethodPost, "/businesses", models.Business{Name: "Globex"}), http.StatusCreated)
+
+	w := serve(t, r, http.MethodPut, "/businesses/2", models.Business{Name: "Globex", Slug: "acme"})
+	expectStatus(t, w, http.StatusConflict)
+	business, err := st.Businesses.Get(context.Background(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if business.Slug != "globex" {
+		t.Fatalf("slug after conflict = %q, want globex", business.Slug)
+	}
+
+	expectStatus(t, serve(t, r, http.MethodPut, "/businesses/9", models.Business{Name: "Initech"}), http.StatusNotFound)
+	expectStatus(t, serve(t, r, http.MethodDelete, "/businesses/9", nil), http.StatusNotFound)
+	expectStatus(t, serve(t, r, http.MethodGet, "/businesses/9", nil), http.StatusNotFound)
+}
+
+func TestBusinessCreateIsPendingForOwners(t *testing.T) {
+	st := store.NewMemory()
+	r := newBusinessRouter(st, newTestUser(t, st, "owner", "owner"))
+
+	w := serve(t, r, http.MethodPost, "/businesses", models.Business{Name: "Acme", Status: models.BusinessApproved})
+	expectStatus(t, w, http.StatusCreated)
+	var business models.Business
+	decode(t, w, &business)
+	if business.Status != models.BusinessPending || business.Slug != "acme" {
+		t.Fatalf("business = %+v, want a pending business with slug acme", business)
+	}
+}
+
+func TestBusinessUpdateSendsMaterialEditsToReview(t *testing.T) {
+	st := store.NewMemory()
+	owner := newTestUser(t, st, "owner", "owner")
+	admin := newTestUser(t, st, "admin", "admin")
+	phone := "555-0100"
+	business := &models.Business{UserID: &owner.ID, Name: "Acme", Phone: &phone, Status: models.BusinessApproved}
+	if err := st.CreateBusiness(context.Background(), business); err != nil {
+		t.Fatal(err)
+	}
+	path := "/businesses/" + strconv.Itoa(business.ID)
+
+	status := func(r *gin.Engine, edit models.Business) string {
+		t.Helper()
+		w := serve(t, r, http.MethodPut, path, edit)
+		expectStatus(t, w, http.StatusOK)
+		var updated models.Business
+		decode(t, w, &updated)
+		return updated.Status
+	}
+
+	ownerRouter := newBusinessRouter(st, owner)
+	if got := status(ownerRouter, models.Business{Name: "Acme", Phone: &phone, Status: models.BusinessApproved}); got != models.BusinessApproved {
+		t.Fatalf("status after an unchanged update = %q, want approved", got)
+	}
+	if got := status(newBusinessRouter(st, admin), models.Business{Name: "Acme Corp", Phone: &phone, Status: models.BusinessApproved}); got != models.BusinessApproved {
+		t.Fatalf("status after an admin edit = %q, want approved", got)
+	}
+	if got := status(ownerRouter, models.Business{Name: "Acme Corp", Status: models.BusinessApproved}); got != models.BusinessPending {
+		t.Fatalf("status after removing the phone = %q, want pending", got)
+	}
+}
+
+func TestBusinessUpdateAndDeleteRequireOwner(t *testing.T) {
+	st := store.NewMemory()
+	owner := newTestUser(t, st, "owner", "owner")
+	other := newTestUser(t, st, "other", "owner")
+	admin := newTestUser(t, st, "admin", "admin")
+	acme := newTestBusiness(t, st, owner, "Acme")
+	globex := newTestBusiness(t, st, owner, "Globex")
+
+	otherRouter := newBusinessRouter(st, other)
+	expectStatus(t, serve(t, otherRouter, http.MethodPut, "/businesses/"+strconv.Itoa(acme.ID),
+		models.Business{Name: "Mine now"}), http.StatusForbidden)
+	expectStatus(t, serve(t, otherRouter, http.MethodDelete, "/businesses/"+strconv.Itoa(acme.ID), nil), http.StatusForbidden)
+	if business, err := st.Businesses.Get(context.Background(), acme.ID); err != nil || business.Name != "Acme" {
+		t.Fatalf("business after another owner's edits = %+v, %v", business, err)
+	}
+
+	expectStatus(t, serve(t, newBusinessRouter(st, owner), http.MethodPut, "/businesses/"+strconv.Itoa(acme.ID),
+		models.Business{Name: "Acme Corp"}), http.StatusOK)
+	expectStatus(t, serve(t, newBusinessRouter(st, owner), http.MethodDelete, "/businesses/"+strconv.Itoa(acme.ID), nil), http.StatusOK)
+	expectStatus(t, serve(t, newBusinessRouter(st, admin), http.MethodDelete, "/businesses/"+strconv.Itoa(globex.ID), nil), http.StatusOK)
+}
+
+func TestBusinessAdminStatusMustBeReviewStatus(t *testing.T) {
+	st := store.NewMemory()
+	admin := newTestUser(t, st, "admin", "admin")
+	r := newBusinessRouter(st, admin)
+
+	expectStatus(t, serve(t, r, http.MethodPost, "/businesses",
+		models.Business{Name: "Acme", Status: "vip"}), http.StatusBadRequest)
+	w := serve(t, r, http.MethodPost, "/businesses", models.Business{Name: "Acme", Status: models.BusinessApproved})
+	expectStatus(t, w, http.StatusCreated)
+	var business models.Business
+	decode(t, w, &business)
+
+	path := "/businesses/" + strconv.Itoa(business.ID)
+	expectStatus(t, serve(t, r, http.MethodPut, path, models.Business{Name: "Acme", Status: "archived"}), http.StatusBadRequest)
+	w = serve(t, r, http.MethodPut, path, models.Business{Name: "Acme", Status: models.BusinessRejected})
+	expectStatus(t, w, http.StatusOK)
+	decode(t, w, &business)
+	if business.Status != models.BusinessRejected {
+		t.Fatalf("status = %q, want rejected", business.Status)
+	}
+}
+
+func TestBusinessSearchShowsApprovedBusinesses(t *testing.T) {
+	st := store.NewMemory()
+	ctx := context.Background()
+	for _, b := range []models.Business{
+		{Name: "Acme Bakery", Status: models.BusinessApproved},
+		{Name: "Acme Hidden", Status: models.BusinessPending},
+		{Name: "Acme Rejected", Status: models.BusinessRejected},
+	} {
+		if err := st.CreateBusiness(ctx, &b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := newBusinessRouter(st, newTestUser(t, st, "visitor", "owner"))
+
+	w := serve(t, r, http.MethodGet, "/businesses/search?q=acme", nil)
+	expectStatus(t, w, http.StatusOK)
+	var result models.BusinessSearchResult
+	decode(t, w, &result)
+	if len(result.Results) != 1 || result.Results[0].Name != "Acme Bakery" {
+		t.Fatalf("results = %+v, want only the approved business", result.Results)
+	}
+
+	for _, limit := range []string{"0", "-1", "ten"} {
+		expectStatus(t, serve(t, r, http.MethodGet, "/businesses/search?q=acme&limit="+limit, nil), http.StatusBadRequest)
+	}
+}
+
+func TestBusinessBySlugHintsRedirect(t *testing.T) {
+	st := store.NewMemory()
+	ctx := context.Background()
+	business := &models.Business{Name: "Acme", Status: models.BusinessApproved}
+	if err := st.CreateBusiness(ctx, business); err != nil {
+		t.Fatal(err)
+	}
+	business.Name = "Acme Bakery"
+	business.Slug = ""
+	if err := st.UpdateBusiness(ctx, business); err != nil {
+		t.Fatal(err)
+	}
+	pending := &models.Business{Name: "Hidden"}
+	if err := st.CreateBusiness(ctx, pending); err != nil {
+		t.Fatal(err)
+	}
+	r := newBusinessRouter(st, newTestUser(t, st, "visitor", "owner"))
+
+	for _, tt := range []struct {
+		slug     string
+		redirect bool
+	}{
+		{"acme-bakery", false},
+		{"acme", true},
+	} {
+		w := serve(t, r, http.MethodGet, "/businesses/by-slug/"+tt.slug, nil)
+		expectStatus(t, w, http.StatusOK)
+		var got struct {
+			Business models.Business `json:"business"`
+			Slug     string          `json:"slug"`
+			Redirect bool            `json:"redirect"`
+		}
+		decode(t, w, &got)
+		if got.Business.ID != business.ID || got.Slug != "acme-bakery" || got.Redirect != tt.redirect {
+			t.Errorf("%s resolved to %+v", tt.slug, got)
+		}
+	}
+	expectStatus(t, serve(t, r, http.MethodGet, "/businesses/by-slug/hidden", nil), http.StatusNotFound)
+	expectStatus(t, serve(t, r, http.MethodGet, "/businesses/by-slug/nobody", nil), http.StatusNotFound)
+}
@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"saas-management-api/internal/models"
+	"saas-management-api/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DomainRequest is the body of the add domain endpoint.
+type DomainRequest struct {
+	DomainName string `json:"domain_name" binding:"required"`
+}
+
+// DomainHandler attaches custom domains to businesses. A domain serves the
+// business's site, and gets a certificate, once its owner proves control of
+// it with a DNS TXT record.
+type DomainHandler struct {
+	Store *store.Store
+	// BaseDomain is where businesses get subdomains; it and its
+	// subdomains cannot be added as custom domains.
+	BaseDomain string
+	// LookupTXT resolves TXT records, net.DefaultResolver.LookupTXT unless
+	// replaced in tests.
+	LookupTXT func(ctx context.Context, name string) ([]string, error)
+}
+
+func NewDomainHandler(s *store.Store, baseDomain string) *DomainHandler {
+	return &DomainHandler{Store: s, BaseDomain: baseDomain, LookupTXT: net.DefaultResolver.LookupTXT}
+}
+
+// List returns the domains of a business.
+func (h *DomainHandler) List(c *gin.Context) {
+	businessID, ok := h.businessParam(c)
+	if !ok {
+		return
+	}
+
+	domains, err := h.Store.Domains.ListForBusiness(c.Request.Context(), businessID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch domains"})
+		return
+	}
+	c.JSON(http.StatusOK, domains)
+}
+
+// Create attaches a pending domain to a business. The response holds the
+// TXT record to publish before calling Verify.
+func (h *DomainHandler) Create(c *gin.Context) {
+	businessID, ok := h.businessParam(c)
+	if !ok {
+		return
+	}
+
+	var req DomainRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	name, ok := normalizeDomain(req.DomainName)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "domain_name must be a host name like www.example.com"})
+		return
+	}
+	if h.BaseDomain != "" && (name == h.BaseDomain || strings.HasSuffix(name, "."+h.BaseDomain)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Subdomains of " + h.BaseDomain + " are assigned automatically"})
+		return
+	}
+
+	token := make([]byte, 16)
+	rand.Read(token)
+	domain := models.Domain{
+		BusinessID:        &businessID,
+		DomainName:        name,
+		IsCustom:          true,
+		Status:            models.DomainPending,
+		VerificationToken: hex.EncodeToString(token),
+	}
+	if err := h.Store.Domains.Create(c.Request.Context(), &domain); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Domain is already in use"})
+			return
+		}
+		log.Printf("Error creating domain %q: %v", name, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add domain"})
+		return
+	}
+	c.JSON(http.StatusCreated, domainResponse(&domain))
+}
+
+// Verify looks up the domain's TXT record and marks the domain verified
+// when it holds the verification token.
+func (h *DomainHandler) Verify(c *gin.Context) {
+	domain, ok := h.authorizeDomain(c)
+	if !ok {
+		return
+	}
+	if domain.Status == models.DomainVerified {
+		c.JSON(http.StatusOK, domainResponse(domain))
+		return
+	}
+
+	records, err := h.LookupTXT(c.Request.Context(), domain.VerificationRecord())
+	if err != nil || !slices.Contains(records, domain.VerificationToken) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":  "The TXT record was not found; DNS changes can take a while to propagate",
+			"record": domainRecord(domain),
+		})
+		return
+	}
+
+	verified, err := h.Store.Domains.MarkVerified(c.Request.Context(), domain.ID, time.Now())
+	if err != nil {
+		log.Printf("Error verifying domain %d: %v", domain.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify domain"})
+		return
+	}
+	c.JSON(http.StatusOK, domainResponse(verified))
+}
+
+// Delete detaches a domain from its business.
+func (h *DomainHandler) Delete(c *gin.Context) {
+	domain, ok := h.authorizeDomain(c)
+	if !ok {
+		return
+	}
+	if err := h.Store.Domains.Delete(c.Request.Context(), domain.ID); err != nil && !errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete domain"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Domain deleted successfully"})
+}
+
+// businessParam reads the business ID parameter. It answers 400 or 403 and
+// returns false unless the caller may manage the business.
+func (h *DomainHandler) businessParam(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return 0, false
+	}
+	if !ownsBusiness(c, h.Store, id) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not own this business"})
+		return 0, false
+	}
+	return id, true
+}
+
+// authorizeDomain loads the domain of the ID parameter. It answers 400, 404
+// or 403 and returns false unless the caller may manage its business.
+func (h *DomainHandler) authorizeDomain(c *gin.Context) (*models.Domain, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return nil, false
+	}
+	domain, err := h.Store.Domains.Get(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Domain not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch domain"})
+		}
+		return nil, false
+	}
+	if domain.BusinessID == nil || !ownsBusiness(c, h.Store, *domain.BusinessID) {
+		if !isAdmin(c) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You do not own this domain"})
+			return nil, false
+		}
+	}
+	return domain, true
+}
+
+// normalizeDomain lowercases a host name and drops a trailing dot. It
+// reports false for anything but a multi-label DNS name.
+func normalizeDomain(name string) (string, bool) {
+	name = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+	if len(name) > 253 || !strings.Contains(name, ".") {
+		return "", false
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return "", false
+		}
+		for _, r := range label {
+			if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+				return "", false
+			}
+		}
+	}
+	return name, true
+}
+
+func domainRecord(domain *models.Domain) gin.H {
+	return gin.H{"type": "TXT", "name": domain.VerificationRecord(), "value": domain.VerificationToken}
+}
+
+// domainResponse is a domain with the TXT record that verifies it.
+func domainResponse(domain *models.Domain) gin.H {
+	return gin.H{"domain": domain, "record": domainRecord(domain)}
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"saas-management-api/internal/models"
+	"saas-management-api/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newDomainRouter(h *DomainHandler, user *models.User) *gin.Engine {
+	r := gin.New()
+	r.Use(asUser(user))
+	r.GET("/businesses/:id/domains", h.List)
+	r.POST("/businesses/:id/domains", h.Create)
+	r.POST("/domains/:id/verify", h.Verify)
+	r.DELETE("/domains/:id", h.Delete)
+	return r
+}
+
+func TestDomainVerification(t *testing.T) {
+	st := store.NewMemory()
+	owner := newTestUser(t, st, "owner", "owner")
+	other := newTestUser(t, st, "other", "owner")
+	newTestBusiness(t, st, owner, "Acme")
+	records := map[string][]string{}
+	h := NewDomainHandler(st, "sites.example.com")
+	h.LookupTXT = func(_ context.Context, name string) ([]string, error) {
+		if txt, ok := records[name]; ok {
+			return txt, nil
+		}
+		return nil, errors.New("no such host")
+	}
+	r := newDomainRouter(h, owner)
+
+	expectStatus(t, serve(t, newDomainRouter(h, other), http.MethodPost, "/businesses/1/domains",
+		DomainRequest{DomainName: "shop.example.org"}), http.StatusForbidden)
+	for _, name := range []string{"localhost", "bad_name.example.org", "acme.sites.example.com"} {
+		expectStatus(t, serve(t, r, http.MethodPost, "/businesses/1/domains", DomainRequest{DomainName: name}), http.StatusBadRequest)
+	}
+
+	w := serve(t, r, http.MethodPost, "/businesses/1/domains", DomainRequest{DomainName: "Shop.Example.org."})
+	expectStatus(t, w, http.StatusCreated)
+	var created struct {
+		Domain models.Domain `json:"domain"`
+	}
+	decode(t, w, &created)
+	domain := created.Domain
+	if domain.DomainName != "shop.example.org" || domain.Status != models.DomainPending || domain.VerificationToken == "" {
+		t.Fatalf("domain = %+v, want a pending shop.example.org with a token", domain)
+	}
+	expectStatus(t, serve(t, r, http.MethodPost, "/businesses/1/domains",
+		DomainRequest{DomainName: "shop.example.org"}), http.StatusConflict)
+
+	expectStatus(t, serve(t, r, http.MethodPost, "/domains/1/verify", nil), http.StatusUnprocessableEntity)
+	records["_site-verification.shop.example.org"] = []string{"other", "wrong-token"}
+	expectStatus(t, serve(t, r, http.MethodPost, "/domains/1/verify", nil), http.StatusUnprocessableEntity)
+	expectStatus(t, serve(t, newDomainRouter(h, other), http.MethodPost, "/domains/1/verify", nil), http.StatusForbidden)
+
+	records["_site-verification.shop.example.org"] = []string{"other", domain.VerificationToken}
+	expectStatus(t, serve(t, r, http.MethodPost, "/domains/1/verify", nil), http.StatusOK)
+	stored, err := st.Domains.GetByName(context.Background(), "shop.example.org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != models.DomainVerified || stored.VerifiedAt == nil {
+		t.Fatalf("domain = %+v, want it verified", stored)
+	}
+
+	w = serve(t, r, http.MethodGet, "/businesses/1/domains", nil)
+	expectStatus(t, w, http.StatusOK)
+	var domains []models.Domain
+	decode(t, w, &domains)
+	if len(domains) != 1 || domains[0].Status != models.DomainVerified {
+		t.Fatalf("domains = %+v, want the verified domain", domains)
+	}
+
+	expectStatus(t, serve(t, r, http.MethodDelete, "/domains/1", nil), http.StatusOK)
+	expectStatus(t, serve(t, r, http.MethodPost, "/domains/1/verify", nil), http.StatusNotFound)
+}
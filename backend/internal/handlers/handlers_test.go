@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"saas-management-api/internal/models"
+	"saas-management-api/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// newTestUser creates a user in st with the given role.
+func newTestUser(t *testing.T, st *store.Store, name, role string) *models.User {
+	t.Helper()
+	user := &models.User{Name: name, Email: name + "@example.com", Role: role}
+	if err := st.Users.Create(context.Background(), user); err != nil {
+		t.Fatalf("creating user %s: %v", name, err)
+	}
+	return user
+}
+
+// asUser returns middleware that authenticates every request as user, the
+// way AuthMiddleware does for a valid token.
+func asUser(user *models.User) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("user", user)
+		c.Set("user_id", user.ID)
+		c.Set("user_role", user.Role)
+		c.Next()
+	}
+}
+
+// serve sends a request with an optional JSON body to r and returns the
+// recorded response.
+func serve(t *testing.T, r http.Handler, method, path string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	var reader *bytes.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("encoding body: %v", err)
+		}
+		reader = bytes.NewReader(raw)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// decode unmarshals the body of w into v.
+func decode(t *testing.T, w *httptest.ResponseRecorder, v any) {
+	t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("decoding %q: %v", w.Body.String(), err)
+	}
+}
+
+// expectStatus fails the test unless w has the given status.
+func expectStatus(t *testing.T, w *httptest.ResponseRecorder, status int) {
+	t.Helper()
+	if w.Code != status {
+		t.Fatalf("status = %d, want %d; body %s", w.Code, status, w.Body.String())
+	}
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"saas-management-api/internal/events"
+	"saas-management-api/internal/models"
+	"saas-management-api/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InvoiceRequest is the body of the create invoice endpoint.
+type InvoiceRequest struct {
+	BusinessID int        `json:"business_id" binding:"required"`
+	Amount     float64    `json:"amount" binding:"required,gt=0"`
+	DueAt      *time.Time `json:"due_at"`
+}
+
+// PaymentRequest is the body of the pay invoice endpoint. The payment
+// always settles the invoice's full amount.
+type PaymentRequest struct {
+	PaymentMethod *string `json:"payment_method"`
+	TransactionID *string `json:"transaction_id"`
+}
+
+// InvoiceHandler bills businesses. Admins issue invoices and record their
+// payments; owners see the invoices of their businesses.
+type InvoiceHandler struct {
+	Store  *store.Store
+	Events *events.Bus
+}
+
+func NewInvoiceHandler(s *store.Store, bus *events.Bus) *InvoiceHandler {
+	return &InvoiceHandler{Store: s, Events: bus}
+}
+
+// List returns the invoices of a business, newest first.
+func (h *InvoiceHandler) List(c *gin.Context) {
+	businessID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	if !ownsBusiness(c, h.Store, businessID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not own this business"})
+		return
+	}
+
+	invoices, err := h.Store.Invoices.ListForBusiness(c.Request.Context(), businessID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invoices"})
+		return
+	}
+	c.JSON(http.StatusOK, invoices)
+}
+
+// Create issues an unpaid invoice to a business.
+func (h *InvoiceHandler) Create(c *gin.Context) {
+	var req InvoiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx := c.Request.Context()
+	if _, err := h.Store.Businesses.Get(ctx, req.BusinessID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Business not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch business"})
+		return
+	}
+
+	invoice := models.Invoice{BusinessID: req.BusinessID, Amount: req.Amount, DueAt: req.DueAt}
+	if err := h.Store.Invoices.Create(ctx, &invoice); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invoice"})
+		return
+	}
+	c.JSON(http.StatusCreated, invoice)
+}
+
+// Pay records the payment of an invoice and marks it paid, which pushes an
+// invoice_paid frame to the business owner.
+func (h *InvoiceHandler) Pay(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	var req PaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	payment := models.Payment{InvoiceID: id, PaymentMethod: req.PaymentMethod, TransactionID: req.TransactionID}
+	invoice, err := h.Store.PayInvoice(c.Request.Context(), &payment, time.Now())
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
+		case errors.Is(err, store.ErrConflict):
+			c.JSON(http.StatusConflict, gin.H{"error": "Invoice is already paid"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record payment"})
+		}
+		return
+	}
+	h.Events.Publish(events.InvoicePaid, invoice)
+
+	c.JSON(http.StatusCreated, gin.H{"invoice": invoice, "payment": payment})
+}
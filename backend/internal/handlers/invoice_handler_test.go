@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"saas-management-api/internal/events"
+	"saas-management-api/internal/models"
+	"saas-management-api/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newInvoiceRouter(st *store.Store, bus *events.Bus, user *models.User) *gin.Engine {
+	h := NewInvoiceHandler(st, bus)
+	r := gin.New()
+	r.Use(asUser(user))
+	r.GET("/businesses/:id/invoices", h.List)
+	r.POST("/admin/invoices", h.Create)
+	r.POST("/admin/invoices/:id/pay", h.Pay)
+	return r
+}
+
+func TestInvoicePayPublishesOnce(t *testing.T) {
+	st := store.NewMemory()
+	owner := newTestUser(t, st, "owner", "owner")
+	other := newTestUser(t, st, "other", "owner")
+	admin := newTestUser(t, st, "admin", "admin")
+	business := newTestBusiness(t, st, owner, "Acme")
+	bus := events.NewBus()
+	var paid []*models.Invoice
+	bus.Subscribe(func(e events.Event) { paid = append(paid, e.Payload.(*models.Invoice)) }, events.InvoicePaid)
+	r := newInvoiceRouter(st, bus, admin)
+
+	w := serve(t, r, http.MethodPost, "/admin/invoices", InvoiceRequest{BusinessID: business.ID, Amount: 49})
+	expectStatus(t, w, http.StatusCreated)
+	var invoice models.Invoice
+	decode(t, w, &invoice)
+	if invoice.Status != models.InvoiceUnpaid {
+		t.Fatalf("new invoice is %q", invoice.Status)
+	}
+
+	path := "/admin/invoices/" + strconv.Itoa(invoice.ID) + "/pay"
+	w = serve(t, r, http.MethodPost, path, PaymentRequest{})
+	expectStatus(t, w, http.StatusCreated)
+	var got struct {
+		Invoice models.Invoice `json:"invoice"`
+		Payment models.Payment `json:"payment"`
+	}
+	decode(t, w, &got)
+	if got.Invoice.Status != models.InvoicePaid || got.Payment.Amount != 49 {
+		t.Fatalf("paid invoice %+v with payment %+v", got.Invoice, got.Payment)
+	}
+	expectStatus(t, serve(t, r, http.MethodPost, path, PaymentRequest{}), http.StatusConflict)
+	expectStatus(t, serve(t, r, http.MethodPost, "/admin/invoices/999/pay", PaymentRequest{}), http.StatusNotFound)
+	if len(paid) != 1 || paid[0].ID != invoice.ID {
+		t.Fatalf("published %d invoice.paid events, want 1", len(paid))
+	}
+
+	list := "/businesses/" + strconv.Itoa(business.ID) + "/invoices"
+	expectStatus(t, serve(t, newInvoiceRouter(st, bus, other), http.MethodGet, list, nil), http.StatusForbidden)
+	w = serve(t, newInvoiceRouter(st, bus, owner), http.MethodGet, list, nil)
+	expectStatus(t, w, http.StatusOK)
+	var invoices []models.Invoice
+	decode(t, w, &invoices)
+	if len(invoices) != 1 || invoices[0].Status != models.InvoicePaid {
+		t.Fatalf("owner sees %+v", invoices)
+	}
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+
+	"saas-management-api/internal/listing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parseListSpec reads the limit, cursor, sort and filter[...] parameters of
+// a list request. It answers 400 and returns false when they are invalid.
+func parseListSpec[T any](c *gin.Context, schema *listing.Schema[T]) (listing.Spec, bool) {
+	spec, err := listing.Parse(c.Request.URL.Query(), schema)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return listing.Spec{}, false
+	}
+	return spec, true
+}
+
+// writePage responds with the items of page. The body stays a plain array;
+// the next page is advertised through a Link header and X-Next-Cursor.
+func writePage[T any](c *gin.Context, page listing.Page[T]) {
+	if page.NextCursor != "" {
+		next := *c.Request.URL
+		query := next.Query()
+		query.Set("cursor", page.NextCursor)
+		next.RawQuery = query.Encode()
+
+		c.Header("Link", "<"+next.RequestURI()+`>; rel="next"`)
+		c.Header("X-Next-Cursor", page.NextCursor)
+	}
+	c.JSON(http.StatusOK, page.Items)
+}
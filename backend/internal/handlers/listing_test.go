@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"saas-management-api/internal/listing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestWritePageAdvertisesNextPage(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		page   listing.Page[int]
+		link   string
+		cursor string
+	}{
+		{"more pages", listing.Page[int]{Items: []int{1, 2}, NextCursor: "abc"}, `</items?cursor=abc&limit=2&sort=name>; rel="next"`, "abc"},
+		{"last page", listing.Page[int]{Items: []int{3}}, "", ""},
+	} {
+		r := gin.New()
+		r.GET("/items", func(c *gin.Context) { writePage(c, tt.page) })
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/items?sort=name&limit=2&cursor=old", nil))
+
+		expectStatus(t, w, http.StatusOK)
+		if got := w.Header().Get("Link"); got != tt.link {
+			t.Errorf("%s: Link = %q, want %q", tt.name, got, tt.link)
+		}
+		if got := w.Header().Get("X-Next-Cursor"); got != tt.cursor {
+			t.Errorf("%s: X-Next-Cursor = %q, want %q", tt.name, got, tt.cursor)
+		}
+	}
+}
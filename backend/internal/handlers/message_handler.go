@@ -1,24 +1,28 @@
 package handlers
 
 import (
-	"database/sql"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
-	"saas-management-api/internal/database"
+	"saas-management-api/internal/events"
+	"saas-management-api/internal/listing"
 	"saas-management-api/internal/models"
+	"saas-management-api/internal/store"
 
 	"github.com/gin-gonic/gin"
 )
 
 type MessageHandler struct {
-	DB *database.DB
+	Store  *store.Store
+	Events *events.Bus
 }
 
-func NewMessageHandler(db *database.DB) *MessageHandler {
-	return &MessageHandler{DB: db}
+func NewMessageHandler(s *store.Store, bus *events.Bus) *MessageHandler {
+	return &MessageHandler{Store: s, Events: bus}
 }
 
 // Get or create conversation between two users
@@ -29,7 +33,6 @@ func (h *MessageHandler) GetOrCreateConversation(c *gin.Context) {
 		return
 	}
 	user := val.(*models.User)
-	currentUserID := user.ID
 
 	var req struct {
 		OtherUserID int `json:"other_user_id"`
@@ -39,51 +42,8 @@ func (h *MessageHandler) GetOrCreateConversation(c *gin.Context) {
 		return
 	}
 
-	otherUserID := req.OtherUserID
-
-	// Ensure user1_id < user2_id for consistency
-	user1ID := currentUserID
-	user2ID := otherUserID
-	if currentUserID > otherUserID {
-		user1ID = otherUserID
-		user2ID = currentUserID
-	}
-
-	// Try to get existing conversation
-	var conversation models.Conversation
-	query := `
-		SELECT c.*,
-		       u1.name as user1_name,
-		       u2.name as user2_name
-		FROM conversations c
-		LEFT JOIN users u1 ON c.user1_id = u1.id
-		LEFT JOIN users u2 ON c.user2_id = u2.id
-		WHERE c.user1_id = $1 AND c.user2_id = $2
-	`
-	err := h.DB.DB.Get(&conversation, query, user1ID, user2ID)
-
-	if err == sql.ErrNoRows {
-		// Create new conversation
-		insertQuery := `
-			INSERT INTO conversations (user1_id, user2_id, created_at, updated_at)
-			VALUES ($1, $2, $3, $4)
-			RETURNING *
-		`
-		now := time.Now()
-		err = h.DB.DB.Get(&conversation, insertQuery, user1ID, user2ID, now, now)
-		if err != nil {
-			log.Printf("Error creating conversation: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create conversation"})
-			return
-		}
-
-		// Get user names
-		var user1Name, user2Name string
-		h.DB.DB.Get(&user1Name, "SELECT name FROM users WHERE id = $1", user1ID)
-		h.DB.DB.Get(&user2Name, "SELECT name FROM users WHERE id = $1", user2ID)
-		conversation.User1Name = &user1Name
-		conversation.User2Name = &user2Name
-	} else if err != nil {
+	conversation, err := h.Store.Conversations.GetOrCreate(c.Request.Context(), user.ID, req.OtherUserID)
+	if err != nil {
 		log.Printf("Error fetching conversation: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch conversation"})
 		return
@@ -100,203 +60,203 @@ func (h *MessageHandler) ListConversations(c *gin.Context) {
 		return
 	}
 	user := val.(*models.User)
-	currentUserID := user.ID
 
-	// Fetch all conversations for this user with a simplified, robust query
-	query := `
-		SELECT 
-			c.id,
-			c.user1_id,
-			c.user2_id,
-			c.last_message_at,
-			c.created_at,
-			c.updated_at,
-			u1.name as user1_name,
-			u2.name as user2_name
-		FROM conversations c
-		LEFT JOIN users u1 ON c.user1_id = u1.id
-		LEFT JOIN users u2 ON c.user2_id = u2.id
-		WHERE c.user1_id = $1 OR c.user2_id = $1
-		ORDER BY COALESCE(c.last_message_at, c.created_at) DESC
-	`
-
-	var conversations []models.Conversation
-	err := h.DB.DB.Select(&conversations, query, currentUserID)
+	conversations, err := h.Store.Conversations.ListForUser(c.Request.Context(), user.ID)
 	if err != nil {
 		log.Printf("Error fetching conversations: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch conversations"})
 		return
 	}
 
-	// Manually populate unread_count and last_message for each conversation
-	for i := range conversations {
-		// Get unread count
-		var unreadCount int
-		h.DB.DB.Get(&unreadCount,
-			"SELECT COUNT(*) FROM messages WHERE conversation_id = $1 AND receiver_id = $2 AND is_read = FALSE",
-			conversations[i].ID, currentUserID)
-			conversations[i].UnreadCount = unreadCount
-
-		// Get last message
-		var lastMsg sql.NullString
-		h.DB.DB.Get(&lastMsg,
-			"SELECT message FROM messages WHERE conversation_id = $1 ORDER BY created_at DESC LIMIT 1",
-			conversations[i].ID)
-		if lastMsg.Valid {
-			conversations[i].LastMessage = &lastMsg.String
-		}
-	}
-
 	c.JSON(http.StatusOK, conversations)
 }
 
-// Helper function to ensure conversations exist for messages
-func (h *MessageHandler) ensureConversationsFromMessages(currentUserID int) error {
-	// Check if messages table exists and has data
-	var messageCount int
-	err := h.DB.DB.Get(&messageCount,
-		"SELECT COUNT(*) FROM messages WHERE sender_id = $1 OR receiver_id = $1", currentUserID)
-	if err != nil {
-		// Table might not exist or query failed - that's okay
-		return nil
+// Get messages for a conversation
+func (h *MessageHandler) GetMessages(c *gin.Context) {
+	val, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
 	}
+	user := val.(*models.User)
+	currentUserID := user.ID
 
-	if messageCount == 0 {
-		// No messages for this user - nothing to do
-		return nil
+	conversationID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conversation ID"})
+		return
 	}
 
-	// Find unique conversation pairs from messages
-	type MessagePair struct {
-		User1ID  int       `db:"u1_id"`
-		User2ID  int       `db:"u2_id"`
-		FirstMsg time.Time `db:"first_msg"`
-		LastMsg  time.Time `db:"last_msg"`
+	// Verify user is part of this conversation
+	if _, err := h.Store.Conversations.GetForUser(c.Request.Context(), conversationID, currentUserID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Conversation not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify conversation"})
+		return
 	}
 
-	var messagePairs []MessagePair
-	findPairsQuery := `
-		SELECT 
-			LEAST(sender_id, receiver_id) as u1_id,
-			GREATEST(sender_id, receiver_id) as u2_id,
-			MIN(created_at) as first_msg,
-			MAX(created_at) as last_msg
-		FROM messages
-		WHERE (sender_id = $1 OR receiver_id = $1)
-		GROUP BY LEAST(sender_id, receiver_id), GREATEST(sender_id, receiver_id)
-	`
+	spec, ok := parseListSpec(c, store.MessageListing)
+	if !ok {
+		return
+	}
 
-	err = h.DB.DB.Select(&messagePairs, findPairsQuery, currentUserID)
-	if err != nil {
-		return err
-	}
-
-	// Create conversations for pairs that don't exist
-	for _, pair := range messagePairs {
-		var existingID int
-		checkQuery := "SELECT id FROM conversations WHERE user1_id = $1 AND user2_id = $2"
-		err := h.DB.DB.Get(&existingID, checkQuery, pair.User1ID, pair.User2ID)
-
-		if err == sql.ErrNoRows {
-			// Create new conversation
-			insertQuery := `
-				INSERT INTO conversations (user1_id, user2_id, created_at, updated_at, last_message_at)
-				VALUES ($1, $2, $3, $4, $5)
-				ON CONFLICT (user1_id, user2_id) DO NOTHING
-			`
-			_, err = h.DB.DB.Exec(insertQuery, pair.User1ID, pair.User2ID, pair.FirstMsg, pair.LastMsg, pair.LastMsg)
-			if err != nil {
-				log.Printf("Error creating conversation for users %d-%d: %v", pair.User1ID, pair.User2ID, err)
+	var page listing.Page[models.Message]
+	var window *store.MessageWindow
+	if raw := c.Query("around"); raw != "" {
+		aroundID, err := strconv.Atoi(raw)
+		if err != nil || aroundID <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid around message ID"})
+			return
+		}
+		if c.Query("sort") != "" || c.Query("cursor") != "" || len(spec.Filters) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "around can't be combined with sort, filter or cursor"})
+			return
+		}
+		window, err = h.Store.MessagesAround(c.Request.Context(), conversationID, currentUserID, aroundID, spec.Limit)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+				return
 			}
-		} else if err != nil && err != sql.ErrNoRows {
-			log.Printf("Error checking conversation: %v", err)
+			log.Printf("Error fetching messages around %d: %v", aroundID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch messages"})
+			return
+		}
+		page.Items = window.Items
+	} else {
+		var err error
+		page, err = h.Store.Conversations.Messages(c.Request.Context(), conversationID, currentUserID, spec)
+		if err != nil {
+			log.Printf("Error fetching messages: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch messages"})
+			return
 		}
 	}
 
-	// Update conversation IDs in messages that might have NULL or wrong conversation_id
-	updateMessagesQuery := `
-		UPDATE messages m
-		SET conversation_id = c.id
-		FROM conversations c
-		WHERE (m.conversation_id IS NULL OR m.conversation_id = 0)
-		AND (
-			(c.user1_id = LEAST(m.sender_id, m.receiver_id)
-			 AND c.user2_id = GREATEST(m.sender_id, m.receiver_id))
-		)
-		AND (m.sender_id = $1 OR m.receiver_id = $1)
-	`
-	_, err = h.DB.DB.Exec(updateMessagesQuery, currentUserID)
-	if err != nil {
-		log.Printf("Warning: Error updating message conversation_ids: %v", err)
+	// Mark the messages up to the newest one returned as read
+	now := time.Now()
+	upToID := 0
+	for _, message := range page.Items {
+		upToID = max(upToID, message.ID)
+	}
+	if upToID > 0 {
+		receipts, err := h.Store.ReadMessages(c.Request.Context(), conversationID, currentUserID, upToID, now)
+		if err != nil {
+			log.Printf("Warning: Could not mark messages as read: %v", err)
+		}
+		h.publishReceipts(receipts)
+	}
+
+	// Update conversation last_message_at
+	if err := h.Store.Conversations.Touch(c.Request.Context(), conversationID, now); err != nil {
+		log.Printf("Warning: Could not update conversation: %v", err)
+	}
+
+	if window != nil {
+		writeWindow(c, window)
+		return
 	}
+	writePage(c, page)
+}
 
-	return nil
+// writeWindow responds with the messages of a window. Like writePage, it
+// advertises the newer messages through a rel="next" link and
+// X-Next-Cursor, and the older ones through a rel="prev" link and
+// X-Prev-Cursor.
+func writeWindow(c *gin.Context, window *store.MessageWindow) {
+	link := func(sort, cursor, rel string) string {
+		u := *c.Request.URL
+		query := u.Query()
+		query.Del("around")
+		query.Set("cursor", cursor)
+		if sort != "" {
+			query.Set("sort", sort)
+		}
+		u.RawQuery = query.Encode()
+		return "<" + u.RequestURI() + `>; rel="` + rel + `"`
+	}
+
+	var links []string
+	if window.NextCursor != "" {
+		links = append(links, link("", window.NextCursor, "next"))
+		c.Header("X-Next-Cursor", window.NextCursor)
+	}
+	if window.PrevCursor != "" {
+		links = append(links, link("-created_at", window.PrevCursor, "prev"))
+		c.Header("X-Prev-Cursor", window.PrevCursor)
+	}
+	if len(links) > 0 {
+		c.Header("Link", strings.Join(links, ", "))
+	}
+	c.JSON(http.StatusOK, window.Items)
 }
 
-// Get messages for a conversation
-func (h *MessageHandler) GetMessages(c *gin.Context) {
-	val, exists := c.Get("user")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+// SearchMessages searches the text of the messages the caller can see.
+// Every word of q must start a word of a message. Hits are paged like
+// other lists, newest first, and carry a highlighted snippet and the
+// conversation's title; clients jump to a hit with the around parameter of
+// GetMessages.
+func (h *MessageHandler) SearchMessages(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
+	}
+	spec, ok := parseListSpec(c, store.MessageSearchListing)
+	if !ok {
 		return
 	}
-	user := val.(*models.User)
-	currentUserID := user.ID
 
+	page, err := h.Store.Conversations.SearchMessages(c.Request.Context(), c.GetInt("user_id"), query, spec)
+	if err != nil {
+		log.Printf("Error searching messages: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search messages"})
+		return
+	}
+	writePage(c, page)
+}
+
+// MarkReadRequest is the body of the read endpoint.
+type MarkReadRequest struct {
+	UpToID int `json:"up_to_id" binding:"required,min=1"`
+}
+
+// MarkRead marks the messages of others in a conversation with IDs up to
+// up_to_id as read by the caller and sends read receipts to their senders.
+func (h *MessageHandler) MarkRead(c *gin.Context) {
 	conversationID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conversation ID"})
 		return
 	}
 
-	// Verify user is part of this conversation
-	var conversation models.Conversation
-	checkQuery := "SELECT * FROM conversations WHERE id = $1 AND (user1_id = $2 OR user2_id = $2)"
-	err = h.DB.DB.Get(&conversation, checkQuery, conversationID, currentUserID)
+	var req MarkReadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	receipts, err := h.Store.ReadMessages(c.Request.Context(), conversationID, c.GetInt("user_id"), req.UpToID, time.Now())
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, store.ErrNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Conversation not found"})
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify conversation"})
+		log.Printf("Error marking messages as read: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark messages as read"})
 		return
 	}
+	h.publishReceipts(receipts)
 
-	// Get messages
-	query := `
-		SELECT m.*,
-		       u1.name as sender_name,
-		       u2.name as receiver_name
-		FROM messages m
-		LEFT JOIN users u1 ON m.sender_id = u1.id
-		LEFT JOIN users u2 ON m.receiver_id = u2.id
-		WHERE m.conversation_id = $1
-		ORDER BY m.created_at ASC
-	`
+	c.JSON(http.StatusOK, receipts)
+}
 
-	var messages []models.Message
-	err = h.DB.DB.Select(&messages, query, conversationID)
-	if err != nil {
-		log.Printf("Error fetching messages: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch messages"})
-		return
+func (h *MessageHandler) publishReceipts(receipts []models.MessageReceipt) {
+	for i := range receipts {
+		h.Events.Publish(events.MessageRead, &receipts[i])
 	}
-
-	// Mark messages as read
-	updateQuery := `
-		UPDATE messages 
-		SET is_read = TRUE, read_at = $1
-		WHERE conversation_id = $2 
-		AND receiver_id = $3 
-		AND is_read = FALSE
-	`
-	now := time.Now()
-	h.DB.DB.Exec(updateQuery, now, conversationID, currentUserID)
-
-	// Update conversation last_message_at
-	h.DB.DB.Exec("UPDATE conversations SET last_message_at = $1, updated_at = $1 WHERE id = $2", now, conversationID)
-
-	c.JSON(http.StatusOK, messages)
 }
 
 // Send a message
@@ -314,70 +274,399 @@ func (h *MessageHandler) SendMessage(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	receiverID := req.ReceiverID
 
-	// Ensure user1_id < user2_id for consistency
-	user1ID := senderID
-	user2ID := receiverID
-	if senderID > receiverID {
-		user1ID = receiverID
-		user2ID = senderID
+	if req.ConversationID <= 0 && req.ReceiverID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "conversation_id or receiver_id is required"})
+		return
 	}
 
-	// Get or create conversation
-	var conversation models.Conversation
-	query := "SELECT * FROM conversations WHERE user1_id = $1 AND user2_id = $2"
-	err := h.DB.DB.Get(&conversation, query, user1ID, user2ID)
+	if strings.TrimSpace(req.Message) == "" && len(req.AttachmentIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "message or attachment_ids is required"})
+		return
+	}
+	if req.MessageType != "" && !models.ValidMessageType(req.MessageType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "message_type must be text, image or file"})
+		return
+	}
 
-	if err == sql.ErrNoRows {
-		// Create new conversation
-		insertQuery := `
-			INSERT INTO conversations (user1_id, user2_id, created_at, updated_at, last_message_at)
-			VALUES ($1, $2, $3, $3, $3)
-			RETURNING *
-		`
-		now := time.Now()
-		err = h.DB.DB.Get(&conversation, insertQuery, user1ID, user2ID, now)
-		if err != nil {
-			log.Printf("Error creating conversation: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create conversation"})
+	message := models.Message{
+		SenderID:    senderID,
+		Message:     req.Message,
+		MessageType: req.MessageType,
+	}
+	if req.ConversationID > 0 {
+		message.ConversationID = req.ConversationID
+	} else {
+		message.ReceiverID = &req.ReceiverID
+	}
+	if req.ClientID != "" {
+		message.ClientID = &req.ClientID
+	}
+	if err := h.Store.SendMessage(c.Request.Context(), &message, req.AttachmentIDs...); err != nil {
+		// A retried send returns the message stored the first time.
+		if errors.Is(err, store.ErrDuplicate) {
+			c.JSON(http.StatusOK, message)
+			return
+		}
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Conversation not found"})
 			return
 		}
+		if errors.Is(err, store.ErrAttachmentUnavailable) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Attachments must be uploaded by the sender and not sent yet"})
+			return
+		}
+		log.Printf("Error sending message: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send message"})
+		return
+	}
+	h.Events.Publish(events.MessageCreated, &message)
+
+	c.JSON(http.StatusCreated, message)
+}
+
+// MessageEditRequest is the body of the message edit endpoint.
+type MessageEditRequest struct {
+	Message string `json:"message" binding:"required"`
+}
+
+// ReactionRequest is the body of the add reaction endpoint.
+type ReactionRequest struct {
+	Emoji string `json:"emoji" binding:"required"`
+}
+
+// EditMessage replaces the text of a text message the caller sent. The
+// previous text is kept in the message's edit history.
+func (h *MessageHandler) EditMessage(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
 	}
 
-	// Create message
-	insertMessageQuery := `
-		INSERT INTO messages (conversation_id, sender_id, receiver_id, message, message_type, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $6)
-		RETURNING id, conversation_id, sender_id, receiver_id, message, message_type, is_read, read_at, created_at, updated_at
-	`
+	var req MessageEditRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Message) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A message is required"})
+		return
+	}
+
+	message, err := h.Store.EditMessage(c.Request.Context(), id, c.GetInt("user_id"), req.Message, time.Now())
+	if err != nil {
+		messageError(c, err, "Only text messages that weren't deleted can be edited", "Failed to edit message")
+		return
+	}
+	h.Events.Publish(events.MessageUpdated, message)
+
+	c.JSON(http.StatusOK, message)
+}
+
+// MessageEdits returns the earlier texts of a message, oldest first.
+func (h *MessageHandler) MessageEdits(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	if _, err := h.Store.Conversations.MessageForUser(c.Request.Context(), id, c.GetInt("user_id")); err != nil {
+		messageError(c, err, "", "Failed to fetch message")
+		return
+	}
+	edits, err := h.Store.Conversations.MessageEdits(c.Request.Context(), id)
+	if err != nil {
+		log.Printf("Error fetching edits of message %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch message edits"})
+		return
+	}
+
+	c.JSON(http.StatusOK, edits)
+}
+
+// DeleteMessage deletes a message for the caller only or, with
+// ?for=everyone, for every participant. Only the sender can delete a
+// message for everyone, which leaves a tombstone without its text.
+func (h *MessageHandler) DeleteMessage(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+	scope := c.DefaultQuery("for", "me")
+	if scope != "me" && scope != "everyone" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "for must be me or everyone"})
+		return
+	}
+
+	userID := c.GetInt("user_id")
 	now := time.Now()
-	var message models.Message
-	msgType := req.MessageType
-	if msgType == "" {
-		msgType = "text"
+	message, err := h.Store.DeleteMessage(c.Request.Context(), id, userID, scope == "everyone", now)
+	if err != nil {
+		messageError(c, err, "", "Failed to delete message")
+		return
+	}
+
+	if scope == "me" {
+		h.Events.Publish(events.MessageHidden, &models.HiddenMessage{
+			MessageID:      message.ID,
+			ConversationID: message.ConversationID,
+			UserID:         userID,
+			HiddenAt:       now,
+		})
+		c.JSON(http.StatusOK, gin.H{"message": "Message deleted"})
+		return
 	}
-	err = h.DB.DB.Get(&message, insertMessageQuery, conversation.ID, senderID, receiverID, req.Message, msgType, now)
+	h.Events.Publish(events.MessageUpdated, message)
+
+	c.JSON(http.StatusOK, message)
+}
+
+// AddReaction reacts to a message with an emoji. Reacting again with the
+// same emoji changes nothing.
+func (h *MessageHandler) AddReaction(c *gin.Context) {
+	var req ReactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.react(c, req.Emoji, false)
+}
+
+// RemoveReaction takes back the caller's reaction with an emoji.
+func (h *MessageHandler) RemoveReaction(c *gin.Context) {
+	h.react(c, c.Param("emoji"), true)
+}
+
+func (h *MessageHandler) react(c *gin.Context, emoji string, remove bool) {
+	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		log.Printf("Error creating message: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send message"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+	if !models.ValidEmoji(emoji) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid emoji"})
 		return
 	}
 
-	// Update conversation last_message_at
-	h.DB.DB.Exec("UPDATE conversations SET last_message_at = $1, updated_at = $1 WHERE id = $2", now, conversation.ID)
+	message, err := h.Store.ReactToMessage(c.Request.Context(), id, c.GetInt("user_id"), emoji, remove, time.Now())
+	if err != nil {
+		messageError(c, err, "Deleted messages can't be reacted to", "Failed to update reaction")
+		return
+	}
+	h.Events.Publish(events.MessageUpdated, message)
 
-	// Get sender name
-	var senderName string
-	err = h.DB.DB.Get(&senderName, "SELECT name FROM users WHERE id = $1", senderID)
-	if err == nil {
-	message.SenderName = &senderName
-	} else {
-		// Log but don't fail the entire request
-		log.Printf("Warning: Could not get sender name: %v", err)
+	c.JSON(http.StatusOK, message)
+}
+
+// messageError answers a failed change to a message. conflict explains
+// ErrConflict, and failure any unexpected error.
+func messageError(c *gin.Context, err error, conflict, failure string) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+	case errors.Is(err, store.ErrNotSender):
+		c.JSON(http.StatusForbidden, gin.H{"error": "You did not send this message"})
+	case errors.Is(err, store.ErrConflict) && conflict != "":
+		c.JSON(http.StatusConflict, gin.H{"error": conflict})
+	default:
+		log.Printf("%s: %v", failure, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": failure})
 	}
+}
 
-	c.JSON(http.StatusCreated, message)
+// GroupCreateRequest is the body of the group create endpoint.
+type GroupCreateRequest struct {
+	Title          string `json:"title" binding:"required,max=255"`
+	ParticipantIDs []int  `json:"participant_ids" binding:"required,min=1"`
+}
+
+// GroupRenameRequest is the body of the group rename endpoint.
+type GroupRenameRequest struct {
+	Title string `json:"title" binding:"required,max=255"`
+}
+
+// ParticipantsRequest is the body of the add participants endpoint.
+type ParticipantsRequest struct {
+	UserIDs []int `json:"user_ids" binding:"required,min=1"`
+}
+
+// CreateGroup creates a group conversation owned by the caller with the
+// given participants.
+func (h *MessageHandler) CreateGroup(c *gin.Context) {
+	var req GroupCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A title is required"})
+		return
+	}
+
+	conversation, err := h.Store.CreateGroup(c.Request.Context(), c.GetInt("user_id"), title, req.ParticipantIDs)
+	if err != nil {
+		if errors.Is(err, store.ErrUnknownUser) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown participant"})
+			return
+		}
+		log.Printf("Error creating group conversation: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create conversation"})
+		return
+	}
+	h.Events.Publish(events.ConversationUpdated, conversation)
+
+	c.JSON(http.StatusCreated, conversation)
+}
+
+// RenameGroup sets the title of a group conversation the caller
+// participates in.
+func (h *MessageHandler) RenameGroup(c *gin.Context) {
+	conversation, ok := h.group(c)
+	if !ok {
+		return
+	}
+
+	var req GroupRenameRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A title is required"})
+		return
+	}
+
+	now := time.Now()
+	if err := h.Store.Conversations.Rename(c.Request.Context(), conversation.ID, title, now); err != nil {
+		log.Printf("Error renaming conversation %d: %v", conversation.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rename conversation"})
+		return
+	}
+	conversation.Title = &title
+	conversation.UpdatedAt = now
+	h.Events.Publish(events.ConversationUpdated, conversation)
+
+	c.JSON(http.StatusOK, conversation)
+}
+
+// AddParticipants adds users to a group conversation, which takes the
+// group's owner or an admin. New participants have read the messages
+// sent before they joined.
+func (h *MessageHandler) AddParticipants(c *gin.Context) {
+	conversation, ok := h.group(c)
+	if !ok {
+		return
+	}
+	if !ownsGroup(c, conversation) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the group's owner can add participants"})
+		return
+	}
+
+	var req ParticipantsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	conversation, added, err := h.Store.AddParticipants(c.Request.Context(), conversation.ID, c.GetInt("user_id"), req.UserIDs)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Conversation not found"})
+		case errors.Is(err, store.ErrConflict):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Only group conversations have participants added"})
+		case errors.Is(err, store.ErrUnknownUser):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown participant"})
+		default:
+			log.Printf("Error adding participants to conversation %d: %v", conversation.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add participants"})
+		}
+		return
+	}
+	if len(added) > 0 {
+		h.Events.Publish(events.ConversationUpdated, conversation)
+	}
+
+	c.JSON(http.StatusOK, conversation)
+}
+
+// RemoveParticipant removes a user from a group conversation. Anyone may
+// leave; removing others takes the group's owner or an admin.
+func (h *MessageHandler) RemoveParticipant(c *gin.Context) {
+	conversation, ok := h.group(c)
+	if !ok {
+		return
+	}
+	userID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if userID != c.GetInt("user_id") && !ownsGroup(c, conversation) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the group's owner can remove others"})
+		return
+	}
+
+	if err := h.Store.Conversations.RemoveParticipant(c.Request.Context(), conversation.ID, userID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Participant not found"})
+			return
+		}
+		log.Printf("Error removing participant %d from conversation %d: %v", userID, conversation.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove participant"})
+		return
+	}
+
+	remaining := []models.ConversationParticipant{}
+	for _, p := range conversation.Participants {
+		if p.UserID == userID {
+			h.Events.Publish(events.ParticipantRemoved, &p)
+			continue
+		}
+		remaining = append(remaining, p)
+	}
+	conversation.Participants = remaining
+	h.Events.Publish(events.ConversationUpdated, conversation)
+
+	c.JSON(http.StatusOK, conversation)
+}
+
+// group loads the group conversation in the id parameter if the caller
+// participates in it. It answers 400 or 404 and returns false otherwise.
+func (h *MessageHandler) group(c *gin.Context) (*models.Conversation, bool) {
+	conversationID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conversation ID"})
+		return nil, false
+	}
+
+	conversation, err := h.Store.Conversations.GetForUser(c.Request.Context(), conversationID, c.GetInt("user_id"))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Conversation not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch conversation"})
+		return nil, false
+	}
+	if !conversation.IsGroup {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Not a group conversation"})
+		return nil, false
+	}
+	return conversation, true
+}
+
+// ownsGroup reports whether the caller owns conversation or is an admin.
+func ownsGroup(c *gin.Context, conversation *models.Conversation) bool {
+	callerID := c.GetInt("user_id")
+	for _, p := range conversation.Participants {
+		if p.UserID == callerID && p.Role == models.ParticipantOwner {
+			return true
+		}
+	}
+	return isAdmin(c)
 }
 
 // Get unread message count
@@ -388,10 +677,8 @@ func (h *MessageHandler) GetUnreadCount(c *gin.Context) {
 		return
 	}
 	user := val.(*models.User)
-	userID := user.ID
 
-	var count int
-	err := h.DB.DB.Get(&count, "SELECT COUNT(*) FROM messages WHERE receiver_id = $1 AND is_read = FALSE", userID)
+	count, err := h.Store.Conversations.UnreadCount(c.Request.Context(), user.ID)
 	if err != nil {
 		log.Printf("Error fetching unread count: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch unread count"})
@@ -410,40 +697,13 @@ func (h *MessageHandler) ListAll(c *gin.Context) {
 		return
 	}
 	user := val.(*models.User)
-	userID := user.ID
-	userRole := user.Role
-	isAdmin := userRole == "admin"
-
-	var messages []models.Message
-	var err error
-
-	if isAdmin {
-		// Admin sees all messages with sender and receiver names
-		query := `
-			SELECT m.*,
-			       u1.name as sender_name,
-			       u2.name as receiver_name
-			FROM messages m
-			LEFT JOIN users u1 ON m.sender_id = u1.id
-			LEFT JOIN users u2 ON m.receiver_id = u2.id
-			ORDER BY m.created_at DESC
-		`
-		err = h.DB.DB.Select(&messages, query)
-	} else {
-		// Regular users see only messages they sent or received
-		query := `
-			SELECT m.*,
-			       u1.name as sender_name,
-			       u2.name as receiver_name
-			FROM messages m
-			LEFT JOIN users u1 ON m.sender_id = u1.id
-			LEFT JOIN users u2 ON m.receiver_id = u2.id
-			WHERE m.sender_id = $1 OR m.receiver_id = $1
-			ORDER BY m.created_at DESC
-		`
-		err = h.DB.DB.Select(&messages, query, userID)
+
+	scope := user.ID
+	if user.IsAdmin() {
+		scope = 0
 	}
 
+	messages, err := h.Store.Conversations.ListMessages(c.Request.Context(), scope)
 	if err != nil {
 		log.Printf("Error fetching messages: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch messages"})
@@ -0,0 +1,306 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+
+	"saas-management-api/internal/events"
+	"saas-management-api/internal/models"
+	"saas-management-api/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newMessageRouter(st *store.Store, user *models.User) *gin.Engine {
+	h := NewMessageHandler(st, events.NewBus())
+	r := gin.New()
+	r.Use(asUser(user))
+	r.GET("/conversations", h.ListConversations)
+	r.GET("/search", h.SearchMessages)
+	r.GET("/conversations/:id/messages", h.GetMessages)
+	r.POST("/conversations/:id/read", h.MarkRead)
+	r.POST("/send", h.SendMessage)
+	r.POST("/conversations/:id/participants", h.AddParticipants)
+	r.DELETE("/conversations/:id/participants/:userId", h.RemoveParticipant)
+	r.PUT("/messages/:id", h.EditMessage)
+	r.DELETE("/messages/:id", h.DeleteMessage)
+	r.GET("/messages/:id/edits", h.MessageEdits)
+	r.POST("/messages/:id/reactions", h.AddReaction)
+	return r
+}
+
+// A send that fails after creating the conversation and message must leave
+// neither behind.
+func TestSendMessageRollsBack(t *testing.T) {
+	st := store.NewMemory()
+	alice := newTestUser(t, st, "alice", "owner")
+	bob := newTestUser(t, st, "bob", "owner")
+	r := newMessageRouter(st, alice)
+
+	w := serve(t, r, http.MethodPost, "/send", models.MessageCreate{
+		ReceiverID:    bob.ID,
+		Message:       "hello",
+		MessageType:   "text",
+		AttachmentIDs: []int{42},
+	})
+	expectStatus(t, w, http.StatusConflict)
+
+	w = serve(t, r, http.MethodGet, "/conversations", nil)
+	expectStatus(t, w, http.StatusOK)
+	var conversations []models.Conversation
+	decode(t, w, &conversations)
+	if len(conversations) != 0 {
+		t.Fatalf("conversations after rollback = %+v, want none", conversations)
+	}
+
+	w = serve(t, r, http.MethodPost, "/send", models.MessageCreate{ReceiverID: bob.ID, Message: "hello"})
+	expectStatus(t, w, http.StatusCreated)
+	var sent models.Message
+	decode(t, w, &sent)
+	if sent.ID != 1 || sent.ConversationID != 1 {
+		t.Fatalf("message = %+v, want the first message of the first conversation", sent)
+	}
+}
+
+func TestSendMessageDeduplicatesClientID(t *testing.T) {
+	st := store.NewMemory()
+	alice := newTestUser(t, st, "alice", "owner")
+	bob := newTestUser(t, st, "bob", "owner")
+	r := newMessageRouter(st, alice)
+
+	body := models.MessageCreate{ReceiverID: bob.ID, Message: "hello", ClientID: "c-1"}
+	expectStatus(t, serve(t, r, http.MethodPost, "/send", body), http.StatusCreated)
+	w := serve(t, r, http.MethodPost, "/send", body)
+	expectStatus(t, w, http.StatusOK)
+	var retried models.Message
+	decode(t, w, &retried)
+	if retried.ID != 1 {
+		t.Fatalf("retried message ID = %d, want 1", retried.ID)
+	}
+
+	w = serve(t, r, http.MethodGet, "/conversations/1/messages", nil)
+	expectStatus(t, w, http.StatusOK)
+	var messages []models.Message
+	decode(t, w, &messages)
+	if len(messages) != 1 {
+		t.Fatalf("messages = %+v, want one", messages)
+	}
+}
+
+// Concurrent retries of one send store one message and answer every retry
+// with it.
+func TestSendMessageDeduplicatesConcurrentRetries(t *testing.T) {
+	st := store.NewMemory()
+	alice := newTestUser(t, st, "alice", "owner")
+	bob := newTestUser(t, st, "bob", "owner")
+	r := newMessageRouter(st, alice)
+
+	body := models.MessageCreate{ReceiverID: bob.ID, Message: "hello", ClientID: "c-1"}
+	var wg sync.WaitGroup
+	responses := make([]models.Message, 8)
+	statuses := make([]int, len(responses))
+	for i := range responses {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := serve(t, r, http.MethodPost, "/send", body)
+			statuses[i] = w.Code
+			decode(t, w, &responses[i])
+		}()
+	}
+	wg.Wait()
+
+	created := 0
+	for i, status := range statuses {
+		switch status {
+		case http.StatusCreated:
+			created++
+		case http.StatusOK:
+		default:
+			t.Fatalf("send %d answered %d", i, status)
+		}
+		if responses[i].ID != responses[0].ID || responses[i].Message != "hello" {
+			t.Fatalf("send %d answered %+v, want message %d", i, responses[i], responses[0].ID)
+		}
+	}
+	if created != 1 {
+		t.Fatalf("%d sends created a message, want 1", created)
+	}
+	if _, err := st.Conversations.MessageByClientID(context.Background(), alice.ID, "c-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The store refuses a second insert with the same client ID outright.
+	clientID := "c-1"
+	duplicate := models.Message{ConversationID: responses[0].ConversationID, SenderID: alice.ID, Message: "again", MessageType: "text", ClientID: &clientID}
+	if err := st.Conversations.CreateMessage(context.Background(), &duplicate); !errors.Is(err, store.ErrDuplicate) {
+		t.Fatalf("CreateMessage with a used client ID = %v, want ErrDuplicate", err)
+	}
+}
+
+func TestGetMessagesOfOthersConversation(t *testing.T) {
+	st := store.NewMemory()
+	alice := newTestUser(t, st, "alice", "owner")
+	bob := newTestUser(t, st, "bob", "owner")
+	carol := newTestUser(t, st, "carol", "owner")
+	expectStatus(t, serve(t, newMessageRouter(st, alice), http.MethodPost, "/send",
+		models.MessageCreate{ReceiverID: bob.ID, Message: "hello"}), http.StatusCreated)
+
+	w := serve(t, newMessageRouter(st, carol), http.MethodGet, "/conversations/1/messages", nil)
+	expectStatus(t, w, http.StatusNotFound)
+}
+
+func TestGroupParticipantsRequireOwner(t *testing.T) {
+	st := store.NewMemory()
+	alice := newTestUser(t, st, "alice", "owner")
+	bob := newTestUser(t, st, "bob", "owner")
+	carol := newTestUser(t, st, "carol", "admin")
+	dave := newTestUser(t, st, "dave", "owner")
+	erin := newTestUser(t, st, "erin", "owner")
+	group, err := st.CreateGroup(context.Background(), alice.ID, "Team", []int{bob.ID, carol.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	participants := "/conversations/" + strconv.Itoa(group.ID) + "/participants"
+	add := func(user *models.User, ids ...int) *httptest.ResponseRecorder {
+		return serve(t, newMessageRouter(st, user), http.MethodPost, participants, ParticipantsRequest{UserIDs: ids})
+	}
+
+	expectStatus(t, add(bob, dave.ID), http.StatusForbidden)
+	expectStatus(t, serve(t, newMessageRouter(st, bob), http.MethodDelete, participants+"/"+strconv.Itoa(carol.ID), nil), http.StatusForbidden)
+	expectStatus(t, add(dave, dave.ID), http.StatusNotFound)
+
+	w := add(alice, dave.ID)
+	expectStatus(t, w, http.StatusOK)
+	var updated models.Conversation
+	decode(t, w, &updated)
+	if len(updated.Participants) != 4 {
+		t.Fatalf("participants = %+v, want 4", updated.Participants)
+	}
+	expectStatus(t, add(carol, erin.ID), http.StatusOK)
+
+	// Members may still leave on their own.
+	expectStatus(t, serve(t, newMessageRouter(st, bob), http.MethodDelete, participants+"/"+strconv.Itoa(bob.ID), nil), http.StatusOK)
+}
+
+func TestMarkReadReturnsReceipts(t *testing.T) {
+	st := store.NewMemory()
+	alice := newTestUser(t, st, "alice", "owner")
+	bob := newTestUser(t, st, "bob", "owner")
+	carol := newTestUser(t, st, "carol", "owner")
+	w := serve(t, newMessageRouter(st, alice), http.MethodPost, "/send", models.MessageCreate{ReceiverID: bob.ID, Message: "hello"})
+	expectStatus(t, w, http.StatusCreated)
+	var sent models.Message
+	decode(t, w, &sent)
+	read := "/conversations/" + strconv.Itoa(sent.ConversationID) + "/read"
+
+	r := newMessageRouter(st, bob)
+	expectStatus(t, serve(t, r, http.MethodPost, read, map[string]int{}), http.StatusBadRequest)
+	expectStatus(t, serve(t, newMessageRouter(st, carol), http.MethodPost, read, MarkReadRequest{UpToID: sent.ID}), http.StatusNotFound)
+
+	w = serve(t, r, http.MethodPost, read, MarkReadRequest{UpToID: sent.ID})
+	expectStatus(t, w, http.StatusOK)
+	var receipts []models.MessageReceipt
+	decode(t, w, &receipts)
+	if len(receipts) != 1 || receipts[0].SenderID != alice.ID || receipts[0].Status != models.ReceiptRead || receipts[0].MessageIDs[0] != sent.ID {
+		t.Fatalf("receipts = %+v, want one read receipt for alice", receipts)
+	}
+
+	message, err := st.Conversations.MessageForUser(context.Background(), sent.ID, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !message.IsRead || message.ReadAt == nil || message.DeliveredAt == nil {
+		t.Fatalf("message = %+v, want it read and delivered", message)
+	}
+}
+
+func TestEditAndDeleteRequireSender(t *testing.T) {
+	st := store.NewMemory()
+	alice := newTestUser(t, st, "alice", "owner")
+	bob := newTestUser(t, st, "bob", "owner")
+	carol := newTestUser(t, st, "carol", "owner")
+	w := serve(t, newMessageRouter(st, alice), http.MethodPost, "/send", models.MessageCreate{ReceiverID: bob.ID, Message: "helo"})
+	expectStatus(t, w, http.StatusCreated)
+	var sent models.Message
+	decode(t, w, &sent)
+	path := "/messages/" + strconv.Itoa(sent.ID)
+
+	r := newMessageRouter(st, bob)
+	expectStatus(t, serve(t, r, http.MethodPut, path, MessageEditRequest{Message: "hijacked"}), http.StatusForbidden)
+	expectStatus(t, serve(t, r, http.MethodDelete, path+"?for=everyone", nil), http.StatusForbidden)
+	expectStatus(t, serve(t, r, http.MethodDelete, path+"?for=all", nil), http.StatusBadRequest)
+	outsider := newMessageRouter(st, carol)
+	expectStatus(t, serve(t, outsider, http.MethodPut, path, MessageEditRequest{Message: "hijacked"}), http.StatusNotFound)
+	expectStatus(t, serve(t, outsider, http.MethodGet, path+"/edits", nil), http.StatusNotFound)
+	expectStatus(t, serve(t, outsider, http.MethodPost, path+"/reactions", ReactionRequest{Emoji: "👍"}), http.StatusNotFound)
+	message, err := st.Conversations.MessageForUser(context.Background(), sent.ID, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message.Message != "helo" || message.EditedAt != nil || message.DeletedAt != nil {
+		t.Fatalf("message after others' edits = %+v, want it unchanged", message)
+	}
+
+	// The receiver can react and delete the message for themselves.
+	expectStatus(t, serve(t, r, http.MethodPost, path+"/reactions", ReactionRequest{Emoji: "👍"}), http.StatusOK)
+	expectStatus(t, serve(t, r, http.MethodDelete, path, nil), http.StatusOK)
+	expectStatus(t, serve(t, r, http.MethodGet, path+"/edits", nil), http.StatusNotFound)
+
+	sender := newMessageRouter(st, alice)
+	expectStatus(t, serve(t, sender, http.MethodPut, path, MessageEditRequest{Message: "hello"}), http.StatusOK)
+	w = serve(t, sender, http.MethodGet, path+"/edits", nil)
+	expectStatus(t, w, http.StatusOK)
+	var edits []models.MessageEdit
+	decode(t, w, &edits)
+	if len(edits) != 1 || edits[0].Message != "helo" {
+		t.Fatalf("edits = %+v, want the original text", edits)
+	}
+	expectStatus(t, serve(t, sender, http.MethodDelete, path+"?for=everyone", nil), http.StatusOK)
+	expectStatus(t, serve(t, sender, http.MethodPut, path, MessageEditRequest{Message: "again"}), http.StatusConflict)
+}
+
+func TestSearchMessagesEscapesSnippets(t *testing.T) {
+	st := store.NewMemory()
+	alice := newTestUser(t, st, "alice", "owner")
+	bob := newTestUser(t, st, "bob", "owner")
+	carol := newTestUser(t, st, "carol", "owner")
+	r := newMessageRouter(st, alice)
+	for _, text := range []string{"<script>lunch()</script>", "lunch at noon", "dinner"} {
+		expectStatus(t, serve(t, r, http.MethodPost, "/send", models.MessageCreate{ReceiverID: bob.ID, Message: text}), http.StatusCreated)
+	}
+
+	expectStatus(t, serve(t, r, http.MethodGet, "/search?q=%20", nil), http.StatusBadRequest)
+	expectStatus(t, serve(t, r, http.MethodGet, "/search?q=lunch&sort=snippet", nil), http.StatusBadRequest)
+
+	w := serve(t, newMessageRouter(st, bob), http.MethodGet, "/search?q=Lunch&limit=1", nil)
+	expectStatus(t, w, http.StatusOK)
+	var hits []models.MessageSearchHit
+	decode(t, w, &hits)
+	if len(hits) != 1 || hits[0].Snippet != "<mark>lunch</mark> at noon" || *hits[0].ConversationTitle != "alice" {
+		t.Fatalf("first page = %+v, want the newest hit", hits)
+	}
+	cursor := w.Header().Get("X-Next-Cursor")
+	if cursor == "" {
+		t.Fatal("first page has no next cursor")
+	}
+	w = serve(t, newMessageRouter(st, bob), http.MethodGet, "/search?q=Lunch&limit=1&cursor="+url.QueryEscape(cursor), nil)
+	expectStatus(t, w, http.StatusOK)
+	decode(t, w, &hits)
+	if len(hits) != 1 || hits[0].Snippet != "&lt;script&gt;<mark>lunch</mark>()&lt;/script&gt;" {
+		t.Fatalf("second page = %+v, want the escaped script", hits)
+	}
+
+	w = serve(t, newMessageRouter(st, carol), http.MethodGet, "/search?q=lunch", nil)
+	expectStatus(t, w, http.StatusOK)
+	decode(t, w, &hits)
+	if len(hits) != 0 {
+		t.Fatalf("an outsider found %+v", hits)
+	}
+}
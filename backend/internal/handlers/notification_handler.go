@@ -1,73 +1,65 @@
 package handlers
 
 import (
-	"database/sql"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
-	"strings"
-	"time"
 
-	"saas-management-api/internal/database"
+	"saas-management-api/internal/events"
 	"saas-management-api/internal/models"
+	"saas-management-api/internal/store"
 
 	"github.com/gin-gonic/gin"
 )
 
+// errNoAdmins aborts an admin broadcast when there is nobody to notify.
+var errNoAdmins = errors.New("no admin users found")
+
 type NotificationHandler struct {
-	DB *database.DB
+	Store  *store.Store
+	Events *events.Bus
 }
 
-func NewNotificationHandler(db *database.DB) *NotificationHandler {
-	return &NotificationHandler{DB: db}
+func NewNotificationHandler(s *store.Store, bus *events.Bus) *NotificationHandler {
+	return &NotificationHandler{Store: s, Events: bus}
 }
 
-// List notifications - Admin can see all, users see only their own
-func (h *NotificationHandler) List(c *gin.Context) {
+// notificationScope returns the user whose notifications the caller may
+// access, or 0 for admins who may access every notification.
+func notificationScope(c *gin.Context) (int, bool) {
 	userID, exists := c.Get("user_id")
 	if !exists {
+		return 0, false
+	}
+	if isAdmin(c) {
+		return 0, true
+	}
+	return userID.(int), true
+}
+
+// List notifications - Admin can see all, users see only their own
+func (h *NotificationHandler) List(c *gin.Context) {
+	scope, ok := notificationScope(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	userRole, _ := c.Get("user_role")
-	isAdmin := userRole == "admin"
-
-	var notifications []models.Notification
-	var err error
-
-	if isAdmin {
-		// Admin sees all notifications with user names
-		query := `
-			SELECT n.*, 
-			       u1.name as user_name,
-			       u2.name as from_user_name
-			FROM notifications n
-			LEFT JOIN users u1 ON n.user_id = u1.id
-			LEFT JOIN users u2 ON n.from_user_id = u2.id
-			ORDER BY n.created_at DESC
-		`
-		err = h.DB.DB.Select(&notifications, query)
-	} else {
-		// Regular users see only their own notifications
-		query := `
-			SELECT n.*,
-			       u2.name as from_user_name
-			FROM notifications n
-			LEFT JOIN users u2 ON n.from_user_id = u2.id
-			WHERE n.user_id = $1
-			ORDER BY n.created_at DESC
-		`
-		err = h.DB.DB.Select(&notifications, query, userID)
+	spec, ok := parseListSpec(c, store.NotificationListing)
+	if !ok {
+		return
 	}
 
+	page, err := h.Store.Notifications.List(c.Request.Context(), scope, spec)
 	if err != nil {
 		log.Printf("Error fetching notifications: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
 		return
 	}
 
-	c.JSON(http.StatusOK, notifications)
+	writePage(c, page)
 }
 
 // Get single notification
@@ -78,40 +70,15 @@ func (h *NotificationHandler) Get(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("user_id")
-	if !exists {
+	scope, ok := notificationScope(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	userRole, _ := c.Get("user_role")
-	isAdmin := userRole == "admin"
-
-	var notification models.Notification
-	if isAdmin {
-		query := `
-			SELECT n.*,
-			       u1.name as user_name,
-			       u2.name as from_user_name
-			FROM notifications n
-			LEFT JOIN users u1 ON n.user_id = u1.id
-			LEFT JOIN users u2 ON n.from_user_id = u2.id
-			WHERE n.id = $1
-		`
-		err = h.DB.DB.Get(&notification, query, id)
-	} else {
-		query := `
-			SELECT n.*,
-			       u2.name as from_user_name
-			FROM notifications n
-			LEFT JOIN users u2 ON n.from_user_id = u2.id
-			WHERE n.id = $1 AND n.user_id = $2
-		`
-		err = h.DB.DB.Get(&notification, query, id, userID)
-	}
-
+	notification, err := h.Store.Notifications.Get(c.Request.Context(), id, scope)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, store.ErrNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
 			return
 		}
@@ -131,90 +98,72 @@ func (h *NotificationHandler) Create(c *gin.Context) {
 		return
 	}
 
-	fromUserID, exists := c.Get("user_id")
-	if !exists {
+	fromUserID := c.GetInt("user_id")
+	if fromUserID == 0 {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	// If user_id is null or 0, send to all admins
-	if req.UserID == nil || *req.UserID == 0 {
-		// Get all admin user IDs
-		var adminIDs []int
-		err := h.DB.DB.Select(&adminIDs, "SELECT id FROM users WHERE role = 'admin'")
-		if err != nil {
-			log.Printf("Error fetching admin IDs: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send notification"})
-			return
-		}
-
-		if len(adminIDs) == 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "No admin users found"})
-			return
+	newNotification := func(userID int) models.Notification {
+		return models.Notification{
+			UserID:     userID,
+			FromUserID: &fromUserID,
+			Subject:    req.Subject,
+			Message:    req.Message,
+			Type:       req.Type,
+			Status:     "unread",
 		}
+	}
 
-		// Create notification for each admin
-		notifications := []models.Notification{}
-		for _, adminID := range adminIDs {
-			query := `
-				INSERT INTO notifications (user_id, from_user_id, subject, message, type, status, is_read, created_at, updated_at)
-				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-				RETURNING *
-			`
-			now := time.Now()
-			var notification models.Notification
-			err := h.DB.DB.Get(&notification, query,
-				adminID,
-				fromUserID,
-				req.Subject,
-				req.Message,
-				req.Type,
-				"unread",
-				false,
-				now,
-				now,
-			)
+	// If user_id is null or 0, send to all admins
+	if req.UserID == nil || *req.UserID == 0 {
+		var notifications []models.Notification
+		ctx := c.Request.Context()
+		err := h.Store.WithTx(ctx, func(tx *store.Store) error {
+			adminIDs, err := tx.Users.AdminIDs(ctx)
 			if err != nil {
-				log.Printf("Error creating notification for admin %d: %v", adminID, err)
-				continue
+				return err
+			}
+			if len(adminIDs) == 0 {
+				return errNoAdmins
 			}
-			notifications = append(notifications, notification)
-		}
 
-		if len(notifications) == 0 {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create notifications"})
+			// Create notification for each admin
+			notifications = make([]models.Notification, 0, len(adminIDs))
+			for _, adminID := range adminIDs {
+				notification := newNotification(adminID)
+				if err := tx.Notifications.Create(ctx, &notification); err != nil {
+					return fmt.Errorf("notify admin %d: %w", adminID, err)
+				}
+				notifications = append(notifications, notification)
+			}
+			return nil
+		})
+		if err != nil {
+			if errors.Is(err, errNoAdmins) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "No admin users found"})
+				return
+			}
+			log.Printf("Error creating admin notifications: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send notification"})
 			return
 		}
+		for i := range notifications {
+			h.Events.Publish(events.NotificationCreated, &notifications[i])
+		}
 
 		c.JSON(http.StatusCreated, gin.H{"message": "Notifications sent", "notifications": notifications})
 		return
 	}
 
 	// Send to specific user
-	query := `
-		INSERT INTO notifications (user_id, from_user_id, subject, message, type, status, is_read, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-		RETURNING *
-	`
-	now := time.Now()
-	var notification models.Notification
-	err := h.DB.DB.Get(&notification, query,
-		req.UserID,
-		fromUserID,
-		req.Subject,
-		req.Message,
-		req.Type,
-		"unread",
-		false,
-		now,
-		now,
-	)
-
-	if err != nil {
+	notification := newNotification(*req.UserID)
+	if err := h.Store.Notifications.Create(c.Request.Context(), &notification); err != nil {
 		log.Printf("Error creating notification: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create notification"})
 		return
 	}
+	h.Events.Publish(events.NotificationCreated, &notification)
 
 	c.JSON(http.StatusCreated, notification)
 }
@@ -227,15 +176,12 @@ func (h *NotificationHandler) Update(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("user_id")
-	if !exists {
+	scope, ok := notificationScope(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	userRole, _ := c.Get("user_role")
-	isAdmin := userRole == "admin"
-
 	var req struct {
 		IsRead *bool   `json:"is_read,omitempty"`
 		Status *string `json:"status,omitempty"`
@@ -246,110 +192,27 @@ func (h *NotificationHandler) Update(c *gin.Context) {
 		return
 	}
 
-	// Check if user has access to this notification
-	var notification models.Notification
-	checkQuery := "SELECT * FROM notifications WHERE id = $1"
-	if !isAdmin {
-		checkQuery += " AND user_id = $2"
-		err = h.DB.DB.Get(&notification, checkQuery, id, userID)
-	} else {
-		err = h.DB.DB.Get(&notification, checkQuery, id)
+	if req.IsRead == nil && req.Status == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No fields to update"})
+		return
 	}
 
+	notification, err := h.Store.Notifications.Update(c.Request.Context(), id, scope, store.NotificationUpdate{
+		IsRead: req.IsRead,
+		Status: req.Status,
+	})
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, store.ErrNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
 			return
 		}
-		log.Printf("Error fetching notification: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification"})
-		return
-	}
-
-	// Build update query
-	updates := []string{}
-	args := []interface{}{}
-	argIndex := 1
-
-	if req.IsRead != nil {
-		updates = append(updates, "is_read = $"+strconv.Itoa(argIndex))
-		args = append(args, *req.IsRead)
-		argIndex++
-		if *req.IsRead {
-			updates = append(updates, "status = $"+strconv.Itoa(argIndex))
-			args = append(args, "read")
-			argIndex++
-		}
-	}
-
-	if req.Status != nil {
-		updates = append(updates, "status = $"+strconv.Itoa(argIndex))
-		args = append(args, *req.Status)
-		argIndex++
-	}
-
-	if len(updates) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No fields to update"})
-		return
-	}
-
-	updates = append(updates, "updated_at = $"+strconv.Itoa(argIndex))
-	args = append(args, time.Now())
-	argIndex++
-
-	args = append(args, id)
-
-	query := "UPDATE notifications SET " + strings.Join(updates, ", ") + " WHERE id = $" + strconv.Itoa(argIndex)
-	if !isAdmin {
-		argIndex++
-		args = append(args, userID)
-		query += " AND user_id = $" + strconv.Itoa(argIndex)
-	}
-
-	result, err := h.DB.DB.Exec(query, args...)
-	if err != nil {
 		log.Printf("Error updating notification: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification"})
 		return
 	}
+	h.Events.Publish(events.NotificationUpdated, notification)
 
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
-		return
-	}
-
-	// Fetch updated notification
-	var updatedNotification models.Notification
-	if isAdmin {
-		query = `
-			SELECT n.*,
-			       u1.name as user_name,
-			       u2.name as from_user_name
-			FROM notifications n
-			LEFT JOIN users u1 ON n.user_id = u1.id
-			LEFT JOIN users u2 ON n.from_user_id = u2.id
-			WHERE n.id = $1
-		`
-		err = h.DB.DB.Get(&updatedNotification, query, id)
-	} else {
-		query = `
-			SELECT n.*,
-			       u2.name as from_user_name
-			FROM notifications n
-			LEFT JOIN users u2 ON n.from_user_id = u2.id
-			WHERE n.id = $1 AND n.user_id = $2
-		`
-		err = h.DB.DB.Get(&updatedNotification, query, id, userID)
-	}
-
-	if err != nil {
-		log.Printf("Error fetching updated notification: %v", err)
-		c.JSON(http.StatusOK, gin.H{"message": "Notification updated"})
-		return
-	}
-
-	c.JSON(http.StatusOK, updatedNotification)
+	c.JSON(http.StatusOK, notification)
 }
 
 // Delete notification
@@ -360,48 +223,48 @@ func (h *NotificationHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("user_id")
-	if !exists {
+	scope, ok := notificationScope(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	userRole, _ := c.Get("user_role")
-	isAdmin := userRole == "admin"
-
-	query := "DELETE FROM notifications WHERE id = $1"
-	args := []interface{}{id}
-	if !isAdmin {
-		query += " AND user_id = $2"
-		args = append(args, userID)
-	}
-
-	result, err := h.DB.DB.Exec(query, args...)
+	// Load the notification first to learn whose unread count changes.
+	notification, err := h.Store.Notifications.Get(c.Request.Context(), id, scope)
 	if err != nil {
-		log.Printf("Error deleting notification: %v", err)
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+			return
+		}
+		log.Printf("Error fetching notification: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete notification"})
 		return
 	}
 
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+	if err := h.Store.Notifications.Delete(c.Request.Context(), id, scope); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+			return
+		}
+		log.Printf("Error deleting notification: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete notification"})
 		return
 	}
 
+	h.Events.Publish(events.NotificationDeleted, notification)
+
 	c.JSON(http.StatusOK, gin.H{"message": "Notification deleted"})
 }
 
 // Get unread count
 func (h *NotificationHandler) UnreadCount(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	userID := c.GetInt("user_id")
+	if userID == 0 {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	var count int
-	err := h.DB.DB.Get(&count, "SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND is_read = FALSE", userID)
+	count, err := h.Store.Notifications.UnreadCount(c.Request.Context(), userID)
 	if err != nil {
 		log.Printf("Error fetching unread count: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch unread count"})
@@ -410,4 +273,3 @@ func (h *NotificationHandler) UnreadCount(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"count": count})
 }
-
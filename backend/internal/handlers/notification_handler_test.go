@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"saas-management-api/internal/events"
+	"saas-management-api/internal/models"
+	"saas-management-api/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newNotificationRouter serves the notification routes as user and records
+// the notifications published for each event type.
+func newNotificationRouter(st *store.Store, user *models.User) (*gin.Engine, map[string][]int) {
+	bus := events.NewBus()
+	published := map[string][]int{}
+	bus.Subscribe(func(e events.Event) {
+		published[e.Type] = append(published[e.Type], e.Payload.(*models.Notification).UserID)
+	}, events.NotificationCreated, events.NotificationUpdated, events.NotificationDeleted)
+
+	h := NewNotificationHandler(st, bus)
+	r := gin.New()
+	r.Use(asUser(user))
+	r.POST("/notifications", h.Create)
+	r.PUT("/notifications/:id", h.Update)
+	r.DELETE("/notifications/:id", h.Delete)
+	return r, published
+}
+
+func TestNotificationCreatePublishesToRecipients(t *testing.T) {
+	st := store.NewMemory()
+	owner := newTestUser(t, st, "owner", "owner")
+	other := newTestUser(t, st, "other", "owner")
+
+	// Without admins a broadcast has nobody to reach.
+	r, published := newNotificationRouter(st, owner)
+	expectStatus(t, serve(t, r, http.MethodPost, "/notifications", models.NotificationCreate{Subject: "Help", Message: "Please"}), http.StatusBadRequest)
+	if len(published) != 0 {
+		t.Fatalf("published %v without admins", published)
+	}
+
+	first := newTestUser(t, st, "first", "admin")
+	second := newTestUser(t, st, "second", "admin")
+	expectStatus(t, serve(t, r, http.MethodPost, "/notifications", models.NotificationCreate{Subject: "Help", Message: "Please"}), http.StatusCreated)
+	if got := published[events.NotificationCreated]; len(got) != 2 || got[0] != first.ID || got[1] != second.ID {
+		t.Fatalf("broadcast published for users %v, want both admins", got)
+	}
+
+	published[events.NotificationCreated] = nil
+	expectStatus(t, serve(t, r, http.MethodPost, "/notifications", models.NotificationCreate{UserID: &other.ID, Subject: "Hi", Message: "Hello"}), http.StatusCreated)
+	if got := published[events.NotificationCreated]; len(got) != 1 || got[0] != other.ID {
+		t.Fatalf("direct notification published for users %v, want %d", got, other.ID)
+	}
+}
+
+func TestNotificationChangesArePublished(t *testing.T) {
+	st := store.NewMemory()
+	sender := newTestUser(t, st, "sender", "owner")
+	recipient := newTestUser(t, st, "recipient", "owner")
+	other := newTestUser(t, st, "other", "owner")
+	r, _ := newNotificationRouter(st, sender)
+	w := serve(t, r, http.MethodPost, "/notifications", models.NotificationCreate{UserID: &recipient.ID, Subject: "Hi", Message: "Hello"})
+	expectStatus(t, w, http.StatusCreated)
+	var notification models.Notification
+	decode(t, w, &notification)
+	path := "/notifications/" + strconv.Itoa(notification.ID)
+
+	// Others can neither read nor delete it, and nothing is pushed.
+	r, published := newNotificationRouter(st, other)
+	expectStatus(t, serve(t, r, http.MethodPut, path, map[string]bool{"is_read": true}), http.StatusNotFound)
+	expectStatus(t, serve(t, r, http.MethodDelete, path, nil), http.StatusNotFound)
+	if len(published) != 0 {
+		t.Fatalf("published %v for another user's changes", published)
+	}
+
+	r, published = newNotificationRouter(st, recipient)
+	expectStatus(t, serve(t, r, http.MethodPut, path, map[string]bool{"is_read": true}), http.StatusOK)
+	expectStatus(t, serve(t, r, http.MethodDelete, path, nil), http.StatusOK)
+	for _, eventType := range []string{events.NotificationUpdated, events.NotificationDeleted} {
+		if got := published[eventType]; len(got) != 1 || got[0] != recipient.ID {
+			t.Errorf("%s published for users %v, want %d", eventType, got, recipient.ID)
+		}
+	}
+}
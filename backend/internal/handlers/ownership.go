@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"saas-management-api/internal/models"
+	"saas-management-api/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// authorizeWebsite loads a website the caller may manage: admins manage
+// every website, owners the websites of their businesses. It answers 404 or
+// 403 and returns false otherwise.
+func authorizeWebsite(c *gin.Context, s *store.Store, id int) (*models.Website, bool) {
+	website, err := s.Websites.Get(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Website not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch website"})
+		}
+		return nil, false
+	}
+	if website.BusinessID == nil || !ownsBusiness(c, s, *website.BusinessID) {
+		if !isAdmin(c) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You do not own this website"})
+			return nil, false
+		}
+	}
+	return website, true
+}
+
+// authorizeBusiness loads a business the caller may manage: admins manage
+// every business, owners their own. It answers 404 or 403 and returns false
+// otherwise.
+func authorizeBusiness(c *gin.Context, s *store.Store, id int) (*models.Business, bool) {
+	business, err := s.Businesses.Get(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Business not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch business"})
+		}
+		return nil, false
+	}
+	if business.UserID == nil || *business.UserID != c.GetInt("user_id") {
+		if !isAdmin(c) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You do not own this business"})
+			return nil, false
+		}
+	}
+	return business, true
+}
+
+// isAdmin reports whether the authenticated caller is an admin.
+func isAdmin(c *gin.Context) bool {
+	user, ok := c.Get("user")
+	if !ok {
+		return false
+	}
+	u, ok := user.(*models.User)
+	return ok && u.IsAdmin()
+}
+
+// ownsBusiness reports whether the caller is an admin or owns the business.
+func ownsBusiness(c *gin.Context, s *store.Store, businessID int) bool {
+	if isAdmin(c) {
+		return true
+	}
+	business, err := s.Businesses.Get(c.Request.Context(), businessID)
+	return err == nil && business.UserID != nil && *business.UserID == c.GetInt("user_id")
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"saas-management-api/internal/content"
+	"saas-management-api/internal/models"
+	"saas-management-api/internal/slug"
+	"saas-management-api/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PageRequest is the body of the page create and update endpoints. An
+// empty slug is derived from the title.
+type PageRequest struct {
+	Slug     string       `json:"slug"`
+	Title    string       `json:"title" binding:"required"`
+	Position int          `json:"position"`
+	Content  models.JSONB `json:"content"`
+}
+
+type PageHandler struct {
+	Store *store.Store
+}
+
+func NewPageHandler(s *store.Store) *PageHandler {
+	return &PageHandler{Store: s}
+}
+
+// List the pages of a website's draft
+func (h *PageHandler) List(c *gin.Context) {
+	websiteID, ok := h.website(c)
+	if !ok {
+		return
+	}
+
+	pages, err := h.Store.Pages.List(c.Request.Context(), websiteID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pages"})
+		return
+	}
+	c.JSON(http.StatusOK, pages)
+}
+
+func (h *PageHandler) Get(c *gin.Context) {
+	websiteID, ok := h.website(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(c.Param("pageId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page ID"})
+		return
+	}
+
+	page, err := h.Store.Pages.Get(c.Request.Context(), websiteID, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Page not found"})
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+func (h *PageHandler) Create(c *gin.Context) {
+	websiteID, ok := h.website(c)
+	if !ok {
+		return
+	}
+	page, ok := bindPage(c)
+	if !ok {
+		return
+	}
+	page.WebsiteID = websiteID
+
+	if err := h.Store.CreatePage(c.Request.Context(), page, c.GetInt("user_id")); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": "A page with this slug already exists"})
+			return
+		}
+		log.Printf("Error creating page: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create page"})
+		return
+	}
+	c.JSON(http.StatusCreated, page)
+}
+
+func (h *PageHandler) Update(c *gin.Context) {
+	websiteID, ok := h.website(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(c.Param("pageId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page ID"})
+		return
+	}
+	page, ok := bindPage(c)
+	if !ok {
+		return
+	}
+	page.ID = id
+	page.WebsiteID = websiteID
+
+	if err := h.Store.UpdatePage(c.Request.Context(), page, c.GetInt("user_id")); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Page not found"})
+		case errors.Is(err, store.ErrConflict):
+			c.JSON(http.StatusConflict, gin.H{"error": "A page with this slug already exists"})
+		default:
+			log.Printf("Error updating page %d: %v", id, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update page"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+func (h *PageHandler) Delete(c *gin.Context) {
+	websiteID, ok := h.website(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(c.Param("pageId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page ID"})
+		return
+	}
+
+	if err := h.Store.DeletePage(c.Request.Context(), websiteID, id, c.GetInt("user_id")); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Page not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete page"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Page deleted successfully"})
+}
+
+// website returns the ID of the website in the path if the caller may
+// manage it.
+func (h *PageHandler) website(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return 0, false
+	}
+	if _, ok := authorizeWebsite(c, h.Store, id); !ok {
+		return 0, false
+	}
+	return id, true
+}
+
+// pageSlugFallback is the slug of pages whose title has nothing to build a
+// slug from.
+const pageSlugFallback = "page"
+
+// bindPage reads and validates a page from the request body.
+func bindPage(c *gin.Context) (*models.WebsitePage, bool) {
+	var req PageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	if req.Slug == "" {
+		req.Slug = slug.MakeOr(req.Title, pageSlugFallback)
+	} else if !slug.Valid(req.Slug) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Slug may only contain lowercase letters, digits and hyphens"})
+		return nil, false
+	}
+	if req.Content == nil {
+		req.Content = models.JSONB{"sections": []interface{}{}}
+	}
+	if _, err := content.Parse(req.Content); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	return &models.WebsitePage{
+		Slug:     req.Slug,
+		Title:    req.Title,
+		Position: req.Position,
+		Content:  req.Content,
+	}, true
+}
@@ -1,29 +1,26 @@
 package handlers
 
 import (
-	"database/sql"
-	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
-	"time"
 
-	"saas-management-api/internal/database"
 	"saas-management-api/internal/models"
+	"saas-management-api/internal/store"
 
 	"github.com/gin-gonic/gin"
 )
 
 type PlanHandler struct {
-	DB *database.DB
+	Store *store.Store
 }
 
-func NewPlanHandler(db *database.DB) *PlanHandler {
-	return &PlanHandler{DB: db}
+func NewPlanHandler(s *store.Store) *PlanHandler {
+	return &PlanHandler{Store: s}
 }
 
 func (h *PlanHandler) List(c *gin.Context) {
-	var plans []models.Plan
-	err := h.DB.DB.Select(&plans, "SELECT * FROM plans ORDER BY price ASC")
+	plans, err := h.Store.Plans.List(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch plans"})
 		return
@@ -38,8 +35,7 @@ func (h *PlanHandler) Get(c *gin.Context) {
 		return
 	}
 
-	var plan models.Plan
-	err = h.DB.DB.Get(&plan, "SELECT * FROM plans WHERE id = $1", id)
+	plan, err := h.Store.Plans.Get(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Plan not found"})
 		return
@@ -54,18 +50,7 @@ func (h *PlanHandler) Create(c *gin.Context) {
 		return
 	}
 
-	var featuresJSON sql.NullString
-	if plan.Features != nil {
-		featuresBytes, _ := json.Marshal(plan.Features)
-		featuresJSON = sql.NullString{String: string(featuresBytes), Valid: true}
-	}
-
-	err := h.DB.DB.Get(&plan, `
-		INSERT INTO plans (name, description, price, billing_cycle, features, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $6)
-		RETURNING id, name, description, price, billing_cycle, features, created_at, updated_at
-	`, plan.Name, plan.Description, plan.Price, plan.BillingCycle, featuresJSON, time.Now())
-	if err != nil {
+	if err := h.Store.Plans.Create(c.Request.Context(), &plan); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create plan"})
 		return
 	}
@@ -85,20 +70,13 @@ func (h *PlanHandler) Update(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	plan.ID = id
 
-	var featuresJSON sql.NullString
-	if plan.Features != nil {
-		featuresBytes, _ := json.Marshal(plan.Features)
-		featuresJSON = sql.NullString{String: string(featuresBytes), Valid: true}
-	}
-
-	err = h.DB.DB.Get(&plan, `
-		UPDATE plans 
-		SET name = $1, description = $2, price = $3, billing_cycle = $4, features = $5, updated_at = $6
-		WHERE id = $7
-		RETURNING id, name, description, price, billing_cycle, features, created_at, updated_at
-	`, plan.Name, plan.Description, plan.Price, plan.BillingCycle, featuresJSON, time.Now(), id)
-	if err != nil {
+	if err := h.Store.Plans.Update(c.Request.Context(), &plan); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Plan not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update plan"})
 		return
 	}
@@ -113,12 +91,14 @@ func (h *PlanHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	_, err = h.DB.DB.Exec("DELETE FROM plans WHERE id = $1", id)
-	if err != nil {
+	if err := h.Store.Plans.Delete(c.Request.Context(), id); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Plan not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete plan"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Plan deleted successfully"})
 }
-
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"saas-management-api/internal/models"
+	"saas-management-api/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newPlanRouter(st *store.Store) *gin.Engine {
+	h := NewPlanHandler(st)
+	r := gin.New()
+	r.GET("/plans", h.List)
+	r.GET("/plans/:id", h.Get)
+	r.POST("/plans", h.Create)
+	r.PUT("/plans/:id", h.Update)
+	r.DELETE("/plans/:id", h.Delete)
+	return r
+}
+
+func TestPlanCRUD(t *testing.T) {
+	r := newPlanRouter(store.NewMemory())
+
+	w := serve(t, r, http.MethodPost, "/plans", models.Plan{Name: "Pro", Price: 29, BillingCycle: "monthly"})
+	expectStatus(t, w, http.StatusCreated)
+	var created models.Plan
+	decode(t, w, &created)
+	if created.ID == 0 || created.CreatedAt.IsZero() {
+		t.Fatalf("created plan = %+v, want an ID and timestamps", created)
+	}
+	serve(t, r, http.MethodPost, "/plans", models.Plan{Name: "Basic", Price: 9, BillingCycle: "monthly"})
+
+	w = serve(t, r, http.MethodGet, "/plans", nil)
+	expectStatus(t, w, http.StatusOK)
+	var plans []models.Plan
+	decode(t, w, &plans)
+	if len(plans) != 2 || plans[0].Name != "Basic" || plans[1].Name != "Pro" {
+		t.Fatalf("plans = %+v, want Basic then Pro by price", plans)
+	}
+
+	w = serve(t, r, http.MethodPut, "/plans/1", models.Plan{Name: "Pro", Price: 39, BillingCycle: "monthly"})
+	expectStatus(t, w, http.StatusOK)
+	w = serve(t, r, http.MethodGet, "/plans/1", nil)
+	expectStatus(t, w, http.StatusOK)
+	var updated models.Plan
+	decode(t, w, &updated)
+	if updated.Price != 39 || !updated.CreatedAt.Equal(created.CreatedAt) {
+		t.Fatalf("updated plan = %+v, want price 39 and the original created_at", updated)
+	}
+
+	expectStatus(t, serve(t, r, http.MethodDelete, "/plans/1", nil), http.StatusOK)
+	expectStatus(t, serve(t, r, http.MethodGet, "/plans/1", nil), http.StatusNotFound)
+}
+
+func TestPlanMissing(t *testing.T) {
+	r := newPlanRouter(store.NewMemory())
+
+	expectStatus(t, serve(t, r, http.MethodGet, "/plans/7", nil), http.StatusNotFound)
+	expectStatus(t, serve(t, r, http.MethodPut, "/plans/7", models.Plan{Name: "Gone"}), http.StatusNotFound)
+	expectStatus(t, serve(t, r, http.MethodDelete, "/plans/7", nil), http.StatusNotFound)
+	expectStatus(t, serve(t, r, http.MethodGet, "/plans/x", nil), http.StatusBadRequest)
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"container/list"
+	"sync"
+
+	"saas-management-api/internal/render"
+)
+
+// maxSiteCacheBytes bounds the rendered pages a SiteHandler keeps in memory.
+const maxSiteCacheBytes = 64 << 20
+
+// renderedSite is a rendered published revision. A revision never changes,
+// so it stays valid until the website publishes another one.
+type renderedSite struct {
+	websiteID int
+	revision  int
+	files     render.Files
+	etags     map[string]string
+	size      int
+}
+
+// siteCache holds rendered sites by website ID and evicts the least
+// recently used ones once their pages take more than maxBytes.
+type siteCache struct {
+	mu       sync.Mutex
+	maxBytes int
+	bytes    int
+	order    *list.List // of *renderedSite, most recently used first
+	entries  map[int]*list.Element
+}
+
+func newSiteCache(maxBytes int) *siteCache {
+	return &siteCache{maxBytes: maxBytes, order: list.New(), entries: map[int]*list.Element{}}
+}
+
+// get returns the cached site of a website if it is the given revision.
+func (c *siteCache) get(websiteID, revision int) (*renderedSite, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[websiteID]
+	if !ok {
+		return nil, false
+	}
+	site := element.Value.(*renderedSite)
+	if site.revision != revision {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return site, true
+}
+
+// put caches site, replacing the website's previous revision. Sites larger
+// than the whole cache are not kept.
+func (c *siteCache) put(site *renderedSite) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.removeLocked(site.websiteID)
+	if site.size > c.maxBytes {
+		return
+	}
+	c.entries[site.websiteID] = c.order.PushFront(site)
+	c.bytes += site.size
+	for c.bytes > c.maxBytes {
+		c.removeLocked(c.order.Back().Value.(*renderedSite).websiteID)
+	}
+}
+
+// remove drops the cached site of a website.
+func (c *siteCache) remove(websiteID int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.removeLocked(websiteID)
+}
+
+func (c *siteCache) removeLocked(websiteID int) {
+	element, ok := c.entries[websiteID]
+	if !ok {
+		return
+	}
+	c.order.Remove(element)
+	delete(c.entries, websiteID)
+	c.bytes -= element.Value.(*renderedSite).size
+}
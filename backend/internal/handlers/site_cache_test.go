@@ -0,0 +1,50 @@
+package handlers
+
+import "testing"
+
+func TestSiteCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newSiteCache(100)
+	cache.put(&renderedSite{websiteID: 1, revision: 1, size: 40})
+	cache.put(&renderedSite{websiteID: 2, revision: 1, size: 40})
+	if _, ok := cache.get(1, 1); !ok {
+		t.Fatal("site 1 is not cached")
+	}
+	cache.put(&renderedSite{websiteID: 3, revision: 1, size: 40})
+
+	if _, ok := cache.get(2, 1); ok {
+		t.Error("site 2, the least recently used, was not evicted")
+	}
+	for _, id := range []int{1, 3} {
+		if _, ok := cache.get(id, 1); !ok {
+			t.Errorf("site %d was evicted", id)
+		}
+	}
+	if cache.bytes != 80 {
+		t.Errorf("cache holds %d bytes, want 80", cache.bytes)
+	}
+}
+
+func TestSiteCacheReplacesRevisions(t *testing.T) {
+	cache := newSiteCache(100)
+	cache.put(&renderedSite{websiteID: 1, revision: 1, size: 40})
+	cache.put(&renderedSite{websiteID: 1, revision: 2, size: 50})
+
+	if _, ok := cache.get(1, 1); ok {
+		t.Error("the old revision is still served")
+	}
+	if _, ok := cache.get(1, 2); !ok {
+		t.Error("the new revision is not cached")
+	}
+	if cache.bytes != 50 {
+		t.Errorf("cache holds %d bytes, want 50", cache.bytes)
+	}
+
+	cache.put(&renderedSite{websiteID: 2, revision: 1, size: 200})
+	if _, ok := cache.get(2, 1); ok || cache.bytes != 50 {
+		t.Error("a site larger than the cache was kept")
+	}
+	cache.remove(1)
+	if cache.bytes != 0 || cache.order.Len() != 0 {
+		t.Errorf("cache holds %d bytes in %d sites after removal", cache.bytes, cache.order.Len())
+	}
+}
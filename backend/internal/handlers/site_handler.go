@@ -0,0 +1,391 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"saas-management-api/internal/auth"
+	"saas-management-api/internal/events"
+	"saas-management-api/internal/models"
+	"saas-management-api/internal/render"
+	"saas-management-api/internal/store"
+	"saas-management-api/internal/themes"
+
+	"github.com/gin-gonic/gin"
+)
+
+// siteCacheControl lets browsers and CDNs reuse a page briefly and
+// revalidate it against its ETag afterwards, so publishing shows up within
+// a minute.
+const siteCacheControl = "public, max-age=60, stale-while-revalidate=300"
+
+// beacon reports a pageview of every served page and lets pages report
+// custom events with siteEvent("name"). It sets no cookies.
+const beacon = `<script>(function(){function send(d){d.path=location.pathname;navigator.sendBeacon("/_collect",JSON.stringify(d))}` +
+	`window.siteEvent=function(n){send({type:"event",name:String(n)})};send({type:"pageview",referrer:document.referrer})})();</script>`
+
+// maxBeaconSize bounds the body of a beacon.
+const maxBeaconSize = 4 << 10
+
+// botMarkers identify crawlers and headless browsers in user agents, whose
+// beacons are ignored.
+var botMarkers = []string{"bot", "crawl", "spider", "slurp", "headless", "lighthouse"}
+
+func isBot(userAgent string) bool {
+	userAgent = strings.ToLower(userAgent)
+	for _, marker := range botMarkers {
+		if strings.Contains(userAgent, marker) {
+			return true
+		}
+	}
+	return userAgent == ""
+}
+
+// SiteHandler serves websites rendered by the server-side themes, so sites
+// can be viewed without the React frontend.
+type SiteHandler struct {
+	Store    *store.Store
+	Renderer *render.Renderer
+	// BaseDomain serves approved businesses at <slug>.<BaseDomain>. Empty
+	// disables subdomains.
+	BaseDomain string
+
+	cache *siteCache
+}
+
+// NewSiteHandler returns a SiteHandler that drops a website's rendered pages
+// from its cache when bus reports that another revision was published.
+// Revisions published by other servers are noticed when the website is next
+// loaded.
+func NewSiteHandler(s *store.Store, registry *themes.Registry, baseDomain string, bus *events.Bus) *SiteHandler {
+	h := &SiteHandler{
+		Store:      s,
+		Renderer:   render.New(registry),
+		BaseDomain: baseDomain,
+		cache:      newSiteCache(maxSiteCacheBytes),
+	}
+	if bus != nil {
+		bus.Subscribe(func(e events.Event) {
+			h.cache.remove(e.Payload.(*models.Website).ID)
+		}, events.WebsitePublished)
+	}
+	return h
+}
+
+// ListThemes returns the manifests of the available themes.
+func (h *SiteHandler) ListThemes(c *gin.Context) {
+	c.JSON(http.StatusOK, h.Renderer.Themes.List())
+}
+
+// PreviewCookie carries the preview token of a preview link, so the links
+// between its pages, which drop the query, stay authorized.
+const PreviewCookie = "preview_token"
+
+// PreviewLink returns a link to the preview of a website for its owners and
+// admins. The link holds a preview token that expires after
+// auth.PreviewTokenTTL and opens nothing but this preview.
+func (h *SiteHandler) PreviewLink(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	if _, ok := authorizeWebsite(c, h.Store, id); !ok {
+		return
+	}
+
+	user := &models.User{ID: c.GetInt("user_id"), Role: c.GetString("user_role")}
+	token, err := auth.GeneratePreviewToken(user, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create preview link"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"url":        "/preview/" + strconv.Itoa(id) + "/?token=" + url.QueryEscape(token),
+		"expires_at": time.Now().Add(auth.PreviewTokenTTL),
+	})
+}
+
+// Preview serves a page of the published revision of a website as HTML,
+// e.g. /preview/12/ for the home page and /preview/12/about/ for the page
+// with slug "about". Unclaimed demos are public. Other websites are only
+// shown to their owners and admins, who open them with a preview link; its
+// token is moved into a cookie scoped to the preview.
+func (h *SiteHandler) Preview(c *gin.Context) {
+	// Neither the preview link nor the preview leaks through Referer.
+	c.Header("Referrer-Policy", "no-referrer")
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	if token := c.Query("token"); token != "" {
+		if _, err := auth.ValidatePreviewToken(token, id); err != nil {
+			c.String(http.StatusUnauthorized, "Invalid or expired preview link")
+			return
+		}
+		c.SetSameSite(http.SameSiteStrictMode)
+		c.SetCookie(PreviewCookie, token, int(auth.PreviewTokenTTL.Seconds()), "/preview/"+strconv.Itoa(id), "", c.Request.TLS != nil, true)
+		target := *c.Request.URL
+		query := target.Query()
+		query.Del("token")
+		target.RawQuery = query.Encode()
+		c.Redirect(http.StatusFound, target.String())
+		return
+	}
+
+	website, err := h.Store.Websites.Get(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.String(http.StatusNotFound, "Website not found")
+			return
+		}
+		c.String(http.StatusInternalServerError, "Failed to fetch website")
+		return
+	}
+	if !website.IsDemo || website.BusinessID != nil {
+		token, _ := c.Cookie(PreviewCookie)
+		user, err := auth.ValidatePreviewToken(token, id)
+		if err != nil {
+			c.String(http.StatusUnauthorized, "Open the preview with a preview link")
+			return
+		}
+		// The token's user may have lost the website since it was issued.
+		c.Set("user", user)
+		c.Set("user_id", user.ID)
+		c.Set("user_role", user.Role)
+		if _, ok := authorizeWebsite(c, h.Store, id); !ok {
+			return
+		}
+	}
+	h.serve(c, website, c.Param("path"), "private, no-cache", false)
+}
+
+// Serve is the entry point of the sites server. It serves the published
+// website of the business the Host header belongs to, either through a
+// verified domain or a <slug>.<BaseDomain> subdomain.
+func (h *SiteHandler) Serve(c *gin.Context) {
+	requested := &url.URL{Host: c.Request.Host}
+	website, host, canonical, err := h.hostWebsite(c.Request.Context(), requested)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.String(http.StatusNotFound, "Site not found")
+			return
+		}
+		log.Printf("ERROR resolving site host %q: %v", host, err)
+		c.String(http.StatusInternalServerError, "Failed to load site")
+		return
+	}
+
+	// Subdomains of former slugs move to the current one.
+	if canonical != host {
+		target := *c.Request.URL
+		target.Scheme = "http"
+		if c.Request.TLS != nil {
+			target.Scheme = "https"
+		}
+		target.Host = canonical
+		if port := requested.Port(); port != "" {
+			target.Host += ":" + port
+		}
+		c.Redirect(http.StatusMovedPermanently, target.String())
+		return
+	}
+	h.serve(c, website, c.Param("path"), siteCacheControl, true)
+}
+
+// CollectRequest is a beacon sent by the pages of served sites. Type is
+// "pageview" or "event"; events carry a Name.
+type CollectRequest struct {
+	Type     string `json:"type"`
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	Referrer string `json:"referrer"`
+}
+
+// Collect records a pageview or event of the site the Host header belongs
+// to. Beacons are sent as text/plain, so the body is decoded regardless of
+// its content type.
+func (h *SiteHandler) Collect(c *gin.Context) {
+	if isBot(c.Request.UserAgent()) {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	var req CollectRequest
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, maxBeaconSize)
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		c.String(http.StatusBadRequest, "Invalid beacon")
+		return
+	}
+	if req.Type != "pageview" && (req.Type != "event" || strings.TrimSpace(req.Name) == "") {
+		c.String(http.StatusBadRequest, "type must be pageview, or event with a name")
+		return
+	}
+
+	ctx := c.Request.Context()
+	website, host, _, err := h.hostWebsite(ctx, &url.URL{Host: c.Request.Host})
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.String(http.StatusNotFound, "Site not found")
+			return
+		}
+		log.Printf("ERROR resolving site host %q: %v", host, err)
+		c.String(http.StatusInternalServerError, "Failed to load site")
+		return
+	}
+
+	hit := store.AnalyticsHit{
+		WebsiteID: website.ID,
+		Path:      req.Path,
+		Referrer:  req.Referrer,
+		Host:      host,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		At:        time.Now(),
+	}
+	if req.Type == "event" {
+		hit.Event = req.Name
+	}
+	if err := h.Store.RecordHit(ctx, hit); err != nil {
+		log.Printf("ERROR recording analytics for website %d: %v", website.ID, err)
+		c.String(http.StatusInternalServerError, "Failed to record")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// hostWebsite returns the published website of the approved business a
+// request's host belongs to, along with the normalized host and the host
+// the business is canonically served at.
+func (h *SiteHandler) hostWebsite(ctx context.Context, requested *url.URL) (*models.Website, string, string, error) {
+	host := strings.TrimSuffix(strings.ToLower(requested.Hostname()), ".")
+	business, canonical, err := h.resolveHost(ctx, host)
+	if err != nil {
+		return nil, host, "", err
+	}
+	if business.Status != models.BusinessApproved {
+		return nil, host, "", store.ErrNotFound
+	}
+	website, err := h.Store.Websites.Published(ctx, business.ID)
+	if err != nil {
+		return nil, host, "", err
+	}
+	return website, host, canonical, nil
+}
+
+// resolveHost finds the business host belongs to, along with the host the
+// business is canonically served at.
+func (h *SiteHandler) resolveHost(ctx context.Context, host string) (*models.Business, string, error) {
+	if h.BaseDomain != "" {
+		label, ok := strings.CutSuffix(host, "."+h.BaseDomain)
+		if ok && label != "" && !strings.Contains(label, ".") {
+			business, err := h.Store.Businesses.GetBySlug(ctx, label)
+			if err != nil {
+				return nil, "", err
+			}
+			return business, business.Slug + "." + h.BaseDomain, nil
+		}
+	}
+
+	domain, err := h.Store.Domains.GetByName(ctx, host)
+	if err != nil {
+		return nil, "", err
+	}
+	if domain.Status != models.DomainVerified || domain.BusinessID == nil {
+		return nil, "", store.ErrNotFound
+	}
+	business, err := h.Store.Businesses.Get(ctx, *domain.BusinessID)
+	if err != nil {
+		return nil, "", err
+	}
+	return business, host, nil
+}
+
+// serve writes the page at path of the published revision of website,
+// with the analytics beacon if track is set. Conditional and HEAD requests
+// are answered by http.ServeContent.
+func (h *SiteHandler) serve(c *gin.Context, website *models.Website, path, cacheControl string, track bool) {
+	// Pages link to each other relatively, which needs the trailing slash.
+	if !strings.HasSuffix(path, "/") && !strings.HasSuffix(path, render.IndexFile) {
+		target := *c.Request.URL
+		target.Path += "/"
+		c.Redirect(http.StatusMovedPermanently, target.String())
+		return
+	}
+
+	site, err := h.rendered(c.Request.Context(), website)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.String(http.StatusNotFound, "Website not published")
+			return
+		}
+		log.Printf("ERROR rendering website %d: %v", website.ID, err)
+		c.String(http.StatusInternalServerError, "Failed to render website")
+		return
+	}
+
+	file, html, ok := site.files.Lookup(path)
+	if !ok {
+		c.String(http.StatusNotFound, "Page not found")
+		return
+	}
+
+	if track {
+		html = bytes.Replace(html, []byte("</body>"), []byte(beacon+"</body>"), 1)
+	}
+
+	var modified time.Time
+	if website.PublishedAt != nil {
+		modified = *website.PublishedAt
+	}
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Header("Cache-Control", cacheControl)
+	c.Header("ETag", site.etags[file])
+	http.ServeContent(c.Writer, c.Request, file, modified, bytes.NewReader(html))
+}
+
+// rendered returns the rendered published revision of website, rendering
+// it on first use.
+func (h *SiteHandler) rendered(ctx context.Context, website *models.Website) (*renderedSite, error) {
+	if website.PublishedRevision == nil {
+		return nil, store.ErrNotFound
+	}
+	if site, ok := h.cache.get(website.ID, *website.PublishedRevision); ok {
+		return site, nil
+	}
+
+	content, err := h.Store.PublishedContent(ctx, website)
+	if err != nil {
+		return nil, err
+	}
+	files, err := h.Renderer.Site(content)
+	if err != nil {
+		return nil, err
+	}
+	site := &renderedSite{
+		websiteID: website.ID,
+		revision:  *website.PublishedRevision,
+		files:     files,
+		etags:     map[string]string{},
+	}
+	for file, html := range files {
+		sum := sha256.Sum256(html)
+		site.etags[file] = `"` + hex.EncodeToString(sum[:12]) + `"`
+		site.size += len(file) + len(html)
+	}
+
+	h.cache.put(site)
+	return site, nil
+}
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"saas-management-api/internal/auth"
+	"saas-management-api/internal/events"
+	"saas-management-api/internal/models"
+	"saas-management-api/internal/store"
+	"saas-management-api/internal/themes"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newPublishedWebsite creates a published website of a business of owner.
+func newPublishedWebsite(t *testing.T, st *store.Store, owner *models.User, title string) *models.Website {
+	t.Helper()
+	business := newTestBusiness(t, st, owner, title)
+	website := &models.Website{Title: title, BusinessID: &business.ID}
+	ctx := context.Background()
+	if err := st.CreateWebsite(ctx, website, owner.ID); err != nil {
+		t.Fatal(err)
+	}
+	website, err := st.PublishWebsite(ctx, website.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return website
+}
+
+// previewRouter serves previews and, for user, preview links.
+func previewRouter(h *SiteHandler, user *models.User) *gin.Engine {
+	r := gin.New()
+	r.GET("/preview/:id/*path", h.Preview)
+	r.POST("/websites/:id/preview", asUser(user), h.PreviewLink)
+	return r
+}
+
+// openPreview follows a preview link and returns the preview cookie it set.
+func openPreview(t *testing.T, r http.Handler, link string) *http.Cookie {
+	t.Helper()
+	w := serve(t, r, http.MethodGet, link, nil)
+	expectStatus(t, w, http.StatusFound)
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == PreviewCookie {
+			return cookie
+		}
+	}
+	t.Fatalf("preview link set no cookie: %v", w.Header())
+	return nil
+}
+
+// getPreview requests path with cookie, if any.
+func getPreview(r http.Handler, path string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestPreviewIsForOwnersAndAdmins(t *testing.T) {
+	auth.InitJWT("test-secret")
+	st := store.NewMemory()
+	owner := newTestUser(t, st, "owner", "owner")
+	other := newTestUser(t, st, "other", "owner")
+	admin := newTestUser(t, st, "admin", "admin")
+	newPublishedWebsite(t, st, owner, "Acme")
+	h := NewSiteHandler(st, themes.Builtin(), "", nil)
+
+	expectStatus(t, getPreview(previewRouter(h, owner), "/preview/1/", nil), http.StatusUnauthorized)
+	for _, tt := range []struct {
+		user   *models.User
+		status int
+	}{
+		{owner, http.StatusOK},
+		{admin, http.StatusOK},
+		{other, http.StatusForbidden},
+	} {
+		r := previewRouter(h, tt.user)
+		w := serve(t, r, http.MethodPost, "/websites/1/preview", nil)
+		expectStatus(t, w, tt.status)
+		if tt.status != http.StatusOK {
+			continue
+		}
+		var link struct {
+			URL string `json:"url"`
+		}
+		decode(t, w, &link)
+		w = getPreview(r, "/preview/1/", openPreview(t, r, link.URL))
+		expectStatus(t, w, http.StatusOK)
+		if !strings.Contains(w.Body.String(), "Acme") {
+			t.Errorf("preview for %s does not show the site:\n%s", tt.user.Name, w.Body.String())
+		}
+	}
+}
+
+func TestPreviewMovesTokenIntoCookie(t *testing.T) {
+	auth.InitJWT("test-secret")
+	st := store.NewMemory()
+	owner := newTestUser(t, st, "owner", "owner")
+	newPublishedWebsite(t, st, owner, "Acme")
+	newPublishedWebsite(t, st, owner, "Other")
+	h := NewSiteHandler(st, themes.Builtin(), "", nil)
+	r := previewRouter(h, owner)
+
+	token, err := auth.GeneratePreviewToken(owner, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := serve(t, r, http.MethodGet, "/preview/1/?token="+token, nil)
+	expectStatus(t, w, http.StatusFound)
+	if location := w.Header().Get("Location"); location != "/preview/1/" {
+		t.Errorf("redirect to %q, want /preview/1/", location)
+	}
+	if policy := w.Header().Get("Referrer-Policy"); policy != "no-referrer" {
+		t.Errorf("Referrer-Policy = %q, want no-referrer", policy)
+	}
+	cookie := w.Header().Get("Set-Cookie")
+	for _, want := range []string{PreviewCookie + "=" + token, "Path=/preview/1", "Max-Age=600", "HttpOnly", "SameSite=Strict"} {
+		if !strings.Contains(cookie, want) {
+			t.Errorf("cookie %q lacks %q", cookie, want)
+		}
+	}
+
+	// Neither session tokens nor the previews of other websites open it.
+	session, err := auth.GenerateToken(owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectStatus(t, serve(t, r, http.MethodGet, "/preview/1/?token="+session, nil), http.StatusUnauthorized)
+	expectStatus(t, serve(t, r, http.MethodGet, "/preview/2/?token="+token, nil), http.StatusUnauthorized)
+	expectStatus(t, getPreview(r, "/preview/2/", &http.Cookie{Name: PreviewCookie, Value: token}), http.StatusUnauthorized)
+	if _, err := auth.ValidateToken(token); err == nil {
+		t.Error("a preview token is accepted as a session")
+	}
+}
+
+func TestDemoPreviewIsPublic(t *testing.T) {
+	st := store.NewMemory()
+	admin := newTestUser(t, st, "admin", "admin")
+	ctx := context.Background()
+	demo := &models.Website{Title: "Demo Bakery", IsDemo: true}
+	if err := st.CreateWebsite(ctx, demo, admin.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.PublishWebsite(ctx, demo.ID); err != nil {
+		t.Fatal(err)
+	}
+	h := NewSiteHandler(st, themes.Builtin(), "", nil)
+
+	w := getPreview(previewRouter(h, admin), "/preview/1/", nil)
+	expectStatus(t, w, http.StatusOK)
+	if !strings.Contains(w.Body.String(), "Demo Bakery") {
+		t.Errorf("demo preview does not show the site:\n%s", w.Body.String())
+	}
+}
+
+func TestPublishEvictsRenderedSite(t *testing.T) {
+	st := store.NewMemory()
+	owner := newTestUser(t, st, "owner", "owner")
+	website := newPublishedWebsite(t, st, owner, "Acme")
+	bus := events.NewBus()
+	h := NewSiteHandler(st, themes.Builtin(), "", bus)
+
+	if _, err := h.rendered(context.Background(), website); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := h.cache.get(website.ID, *website.PublishedRevision); !ok {
+		t.Fatal("the rendered site is not cached")
+	}
+
+	bus.Publish(events.WebsitePublished, website)
+	if _, ok := h.cache.get(website.ID, *website.PublishedRevision); ok {
+		t.Error("the rendered site is still cached after publishing")
+	}
+}
+
+func TestCollectCountsVisitorsByRemoteAddress(t *testing.T) {
+	st := store.NewMemory()
+	owner := newTestUser(t, st, "owner", "owner")
+	website := newPublishedWebsite(t, st, owner, "Acme")
+	ctx := context.Background()
+	review := store.BusinessReview{Status: models.BusinessApproved, ReviewerID: owner.ID, At: time.Now()}
+	if _, err := st.Businesses.SetReview(ctx, *website.BusinessID, review); err != nil {
+		t.Fatal(err)
+	}
+	h := NewSiteHandler(st, themes.Builtin(), "sites.test", nil)
+	r := gin.New()
+	r.SetTrustedProxies(nil)
+	r.POST("/_collect", h.Collect)
+
+	// Without trusted proxies a forged X-Forwarded-For makes no new visitor.
+	for _, forwarded := range []string{"", "198.51.100.1", "198.51.100.2"} {
+		req := httptest.NewRequest(http.MethodPost, "http://acme.sites.test/_collect", strings.NewReader(`{"type":"pageview","path":"/"}`))
+		req.Header.Set("User-Agent", "Mozilla/5.0")
+		if forwarded != "" {
+			req.Header.Set("X-Forwarded-For", forwarded)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		expectStatus(t, w, http.StatusNoContent)
+	}
+
+	today := store.AnalyticsDay(time.Now())
+	report, err := st.AnalyticsReport(ctx, website.ID, today, today)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Pageviews != 3 || report.DailyVisitors != 1 {
+		t.Fatalf("pageviews = %d, daily visitors = %d, want 3 and 1", report.Pageviews, report.DailyVisitors)
+	}
+}
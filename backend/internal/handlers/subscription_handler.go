@@ -1,32 +1,38 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
-	"time"
 
-	"saas-management-api/internal/database"
+	"saas-management-api/internal/events"
 	"saas-management-api/internal/models"
+	"saas-management-api/internal/store"
 
 	"github.com/gin-gonic/gin"
 )
 
 type SubscriptionHandler struct {
-	DB *database.DB
+	Store  *store.Store
+	Events *events.Bus
 }
 
-func NewSubscriptionHandler(db *database.DB) *SubscriptionHandler {
-	return &SubscriptionHandler{DB: db}
+func NewSubscriptionHandler(s *store.Store, bus *events.Bus) *SubscriptionHandler {
+	return &SubscriptionHandler{Store: s, Events: bus}
 }
 
 func (h *SubscriptionHandler) List(c *gin.Context) {
-	var subscriptions []models.Subscription
-	err := h.DB.DB.Select(&subscriptions, "SELECT * FROM subscriptions ORDER BY created_at DESC")
+	spec, ok := parseListSpec(c, store.SubscriptionListing)
+	if !ok {
+		return
+	}
+
+	page, err := h.Store.Subscriptions.List(c.Request.Context(), spec)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch subscriptions"})
 		return
 	}
-	c.JSON(http.StatusOK, subscriptions)
+	writePage(c, page)
 }
 
 func (h *SubscriptionHandler) Get(c *gin.Context) {
@@ -36,8 +42,7 @@ func (h *SubscriptionHandler) Get(c *gin.Context) {
 		return
 	}
 
-	var subscription models.Subscription
-	err = h.DB.DB.Get(&subscription, "SELECT * FROM subscriptions WHERE id = $1", id)
+	subscription, err := h.Store.Subscriptions.Get(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
 		return
@@ -52,15 +57,11 @@ func (h *SubscriptionHandler) Create(c *gin.Context) {
 		return
 	}
 
-	err := h.DB.DB.Get(&subscription, `
-		INSERT INTO subscriptions (business_id, plan_id, status, ends_at, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $5)
-		RETURNING id, business_id, plan_id, status, ends_at, created_at, updated_at
-	`, subscription.BusinessID, subscription.PlanID, subscription.Status, subscription.EndsAt, time.Now())
-	if err != nil {
+	if err := h.Store.Subscriptions.Create(c.Request.Context(), &subscription); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create subscription"})
 		return
 	}
+	h.Events.Publish(events.SubscriptionUpdated, &subscription)
 
 	c.JSON(http.StatusCreated, subscription)
 }
@@ -77,17 +78,17 @@ func (h *SubscriptionHandler) Update(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	subscription.ID = id
 
-	err = h.DB.DB.Get(&subscription, `
-		UPDATE subscriptions 
-		SET status = $1, ends_at = $2, updated_at = $3
-		WHERE id = $4
-		RETURNING id, business_id, plan_id, status, ends_at, created_at, updated_at
-	`, subscription.Status, subscription.EndsAt, time.Now(), id)
-	if err != nil {
+	if err := h.Store.Subscriptions.Update(c.Request.Context(), &subscription); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update subscription"})
 		return
 	}
+	h.Events.Publish(events.SubscriptionUpdated, &subscription)
 
 	c.JSON(http.StatusOK, subscription)
 }
@@ -99,12 +100,14 @@ func (h *SubscriptionHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	_, err = h.DB.DB.Exec("DELETE FROM subscriptions WHERE id = $1", id)
-	if err != nil {
+	if err := h.Store.Subscriptions.Delete(c.Request.Context(), id); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete subscription"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Subscription deleted successfully"})
 }
-
@@ -1,35 +1,92 @@
 package handlers
 
 import (
-	"database/sql"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
-	"saas-management-api/internal/database"
+	"saas-management-api/internal/events"
+	"saas-management-api/internal/listing"
 	"saas-management-api/internal/models"
+	"saas-management-api/internal/store"
+	"saas-management-api/internal/themes"
+	"saas-management-api/internal/thumbnails"
 
 	"github.com/gin-gonic/gin"
 )
 
+// ClaimRequest is the body of the claim endpoint. BusinessID may be left
+// out by callers who own a single business.
+type ClaimRequest struct {
+	PlanID     int  `json:"plan_id" binding:"required"`
+	BusinessID *int `json:"business_id"`
+}
+
 type WebsiteHandler struct {
-	DB *database.DB
+	Store *store.Store
+	// Themes validates the theme settings of websites.
+	Themes *themes.Registry
+	// Thumbnails regenerates a website's thumbnails when it is published.
+	// Nil disables thumbnails.
+	Thumbnails *thumbnails.Pipeline
+	Events     *events.Bus
+}
+
+func NewWebsiteHandler(s *store.Store, registry *themes.Registry, pipeline *thumbnails.Pipeline, bus *events.Bus) *WebsiteHandler {
+	return &WebsiteHandler{Store: s, Themes: registry, Thumbnails: pipeline, Events: bus}
+}
+
+// normalizeWebsite trims the URL, turns empty optional strings into NULLs
+// and gives websites without a theme the default one.
+func normalizeWebsite(website *models.Website) {
+	if website.ThemeName == "" {
+		website.ThemeName = themes.DefaultName
+	}
+	if website.URL != nil {
+		trimmedURL := strings.TrimSpace(*website.URL)
+		if trimmedURL == "" {
+			website.URL = nil
+		} else {
+			website.URL = &trimmedURL
+		}
+	}
+	if website.ImageURL != nil && *website.ImageURL == "" {
+		website.ImageURL = nil
+	}
 }
 
-func NewWebsiteHandler(db *database.DB) *WebsiteHandler {
-	return &WebsiteHandler{DB: db}
+// validThemeSettings checks that website uses a known theme and that its
+// theme settings are valid for it. It answers 400 and returns false
+// otherwise.
+func (h *WebsiteHandler) validThemeSettings(c *gin.Context, website *models.Website) bool {
+	theme, ok := h.Themes.Get(website.ThemeName)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown theme %q", website.ThemeName)})
+		return false
+	}
+	if err := theme.Validate(website.ThemeSettings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
 }
 
 func (h *WebsiteHandler) List(c *gin.Context) {
-	var websites []models.Website
-	err := h.DB.DB.Select(&websites, "SELECT * FROM websites ORDER BY created_at DESC")
+	spec, ok := parseListSpec(c, store.WebsiteListing)
+	if !ok {
+		return
+	}
+
+	page, err := h.Store.Websites.List(c.Request.Context(), spec)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch websites"})
 		return
 	}
-	c.JSON(http.StatusOK, websites)
+	writePage(c, page)
 }
 
 func (h *WebsiteHandler) Get(c *gin.Context) {
@@ -39,8 +96,7 @@ func (h *WebsiteHandler) Get(c *gin.Context) {
 		return
 	}
 
-	var website models.Website
-	err = h.DB.DB.Get(&website, "SELECT * FROM websites WHERE id = $1", id)
+	website, err := h.Store.Websites.Get(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Website not found"})
 		return
@@ -48,6 +104,8 @@ func (h *WebsiteHandler) Get(c *gin.Context) {
 	c.JSON(http.StatusOK, website)
 }
 
+// Create adds a website to a business the caller owns. Only admins may
+// leave out the business, to create unclaimed demo websites.
 func (h *WebsiteHandler) Create(c *gin.Context) {
 	var website models.Website
 	if err := c.ShouldBindJSON(&website); err != nil {
@@ -55,118 +113,39 @@ func (h *WebsiteHandler) Create(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-
-	urlStr := ""
-	if website.URL != nil {
-		urlStr = *website.URL
+	normalizeWebsite(&website)
+	if !h.validThemeSettings(c, &website) {
+		return
 	}
-	log.Printf("Creating website with data: Title=%s, URL=%s, BusinessID=%v, ThemeName=%s, Status=%s",
-		website.Title, urlStr, website.BusinessID, website.ThemeName, website.Status)
 
-	var urlSQL sql.NullString
-	if website.URL != nil && *website.URL != "" {
-		// Trim whitespace from URL
-		trimmedURL := strings.TrimSpace(*website.URL)
-		if trimmedURL != "" {
-			urlSQL = sql.NullString{String: trimmedURL, Valid: true}
+	if !isAdmin(c) {
+		if website.BusinessID == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "business_id is required"})
+			return
 		}
+		website.IsDemo = false
+		website.IsClaimed = false
+		website.Status = "pending"
+	}
+	if website.BusinessID != nil && !ownsBusiness(c, h.Store, *website.BusinessID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not own this business"})
+		return
 	}
 
-	var businessIDSQL sql.NullInt64
-	if website.BusinessID != nil {
-		businessIDSQL = sql.NullInt64{Int64: int64(*website.BusinessID), Valid: true}
-	}
-
-	// Ensure URL column exists (migration check)
-	_, _ = h.DB.DB.Exec(`
-		DO $$ 
-		BEGIN 
-			IF NOT EXISTS (
-				SELECT 1 FROM information_schema.columns 
-				WHERE table_name='websites' AND column_name='url'
-			) THEN
-				ALTER TABLE websites ADD COLUMN url VARCHAR(500);
-			END IF;
-		END $$;
-	`)
-
-	log.Printf("Executing INSERT: businessID=%v (Valid=%v), title=%s, url=%v (Valid=%v), theme=%s, is_demo=%v, is_claimed=%v, status=%s",
-		businessIDSQL, businessIDSQL.Valid, website.Title, urlSQL, urlSQL.Valid, website.ThemeName, website.IsDemo, website.IsClaimed, website.Status)
-
-	// Use RETURNING with sql.Null types for proper NULL handling
-	type WebsiteResult struct {
-		ID         int            `db:"id"`
-		BusinessID sql.NullInt64  `db:"business_id"`
-		Title      string         `db:"title"`
-		URL        sql.NullString `db:"url"`
-		ImageURL   sql.NullString `db:"image_url"`
-		ThemeName  string         `db:"theme_name"`
-		IsDemo     bool           `db:"is_demo"`
-		IsClaimed  bool           `db:"is_claimed"`
-		Status     string         `db:"status"`
-		CreatedAt  time.Time      `db:"created_at"`
-		UpdatedAt  time.Time      `db:"updated_at"`
-	}
-
-	var imageURLSQL sql.NullString
-	if website.ImageURL != nil {
-		imageURLSQL = sql.NullString{String: *website.ImageURL, Valid: true}
-	}
-
-	var result WebsiteResult
-	err := h.DB.DB.Get(&result, `
-		INSERT INTO websites (business_id, title, url, image_url, theme_name, is_demo, is_claimed, status, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9)
-		RETURNING id, business_id, title, url, image_url, theme_name, is_demo, is_claimed, status, created_at, updated_at
-	`, businessIDSQL, website.Title, urlSQL, imageURLSQL, website.ThemeName, website.IsDemo, website.IsClaimed, website.Status, time.Now())
+	log.Printf("Creating website with data: Title=%s, URL=%v, BusinessID=%v, ThemeName=%s, Status=%s",
+		website.Title, website.URL, website.BusinessID, website.ThemeName, website.Status)
 
-	if err != nil {
+	if err := h.Store.CreateWebsite(c.Request.Context(), &website, c.GetInt("user_id")); err != nil {
 		log.Printf("ERROR creating website: %v", err)
-		log.Printf("Error type: %T", err)
-		log.Printf("Full error details: %+v", err)
-		errorMsg := err.Error()
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to create website: " + errorMsg,
-		})
-		return
-	}
-
-	// Convert result to Website model
-	website.ID = result.ID
-	website.Title = result.Title
-	website.ThemeName = result.ThemeName
-	website.IsDemo = result.IsDemo
-	website.IsClaimed = result.IsClaimed
-	website.Status = result.Status
-	website.CreatedAt = result.CreatedAt
-	website.UpdatedAt = result.UpdatedAt
-
-	// Handle nullable business_id
-	if result.BusinessID.Valid {
-		businessID := int(result.BusinessID.Int64)
-		website.BusinessID = &businessID
-	} else {
-		website.BusinessID = nil
-	}
-
-	// Handle nullable url
-	if result.URL.Valid && result.URL.String != "" {
-		website.URL = &result.URL.String
-	} else {
-		website.URL = nil
-	}
-
-	// Handle nullable image_url
-	if result.ImageURL.Valid && result.ImageURL.String != "" {
-		website.ImageURL = &result.ImageURL.String
-	} else {
-		website.ImageURL = nil
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create website: " + err.Error()})
+		return
 	}
 
 	log.Printf("Website created successfully with ID: %d", website.ID)
 	c.JSON(http.StatusCreated, website)
 }
 
+// Update saves the editable draft fields of a website as a new revision.
 func (h *WebsiteHandler) Update(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -174,113 +153,323 @@ func (h *WebsiteHandler) Update(c *gin.Context) {
 		return
 	}
 
-	var website models.Website
-	if err := c.ShouldBindJSON(&website); err != nil {
+	website, ok := authorizeWebsite(c, h.Store, id)
+	if !ok {
+		return
+	}
+
+	var req models.WebsiteUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
 		log.Printf("Error binding JSON: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	website.Title = req.Title
+	website.URL = req.URL
+	website.ImageURL = req.ImageURL
+	website.ThemeName = req.ThemeName
+	website.ThemeSettings = req.ThemeSettings
+	normalizeWebsite(website)
+	if !h.validThemeSettings(c, website) {
+		return
+	}
 
-	log.Printf("Updating website ID %d with data: Title=%s, URL=%v, ThemeName=%s, Status=%s",
-		id, website.Title, website.URL, website.ThemeName, website.Status)
+	log.Printf("Updating website ID %d with data: Title=%s, URL=%v, ThemeName=%s",
+		id, website.Title, website.URL, website.ThemeName)
 
-	var urlSQL sql.NullString
-	if website.URL != nil && *website.URL != "" {
-		// Trim whitespace from URL
-		trimmedURL := strings.TrimSpace(*website.URL)
-		if trimmedURL != "" {
-			urlSQL = sql.NullString{String: trimmedURL, Valid: true}
-			log.Printf("Setting URL to: %s", trimmedURL)
-		} else {
-			log.Printf("URL is empty after trimming, will set to NULL")
+	if err := h.Store.SaveWebsite(c.Request.Context(), website, c.GetInt("user_id")); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Website not found"})
+			return
 		}
-	} else {
-		log.Printf("URL is empty or nil, will set to NULL")
-	}
-
-	log.Printf("Executing UPDATE: id=%d, title=%s, url=%v (Valid=%v), theme=%s, status=%s",
-		id, website.Title, urlSQL, urlSQL.Valid, website.ThemeName, website.Status)
-
-	// Use intermediate struct with sql.Null types for proper NULL handling
-	type WebsiteResult struct {
-		ID         int            `db:"id"`
-		BusinessID sql.NullInt64  `db:"business_id"`
-		Title      string         `db:"title"`
-		URL        sql.NullString `db:"url"`
-		ImageURL   sql.NullString `db:"image_url"`
-		ThemeName  string         `db:"theme_name"`
-		IsDemo     bool           `db:"is_demo"`
-		IsClaimed  bool           `db:"is_claimed"`
-		Status     string         `db:"status"`
-		CreatedAt  time.Time      `db:"created_at"`
-		UpdatedAt  time.Time      `db:"updated_at"`
-	}
-
-	var imageURLSQL sql.NullString
-	if website.ImageURL != nil {
-		imageURLSQL = sql.NullString{String: *website.ImageURL, Valid: true}
-	}
-
-	var result WebsiteResult
-	err = h.DB.DB.Get(&result, `
-		UPDATE websites 
-		SET title = $1, url = $2, image_url = $3, theme_name = $4, is_demo = $5, is_claimed = $6, status = $7, updated_at = $8
-		WHERE id = $9
-		RETURNING id, business_id, title, url, image_url, theme_name, is_demo, is_claimed, status, created_at, updated_at
-	`, website.Title, urlSQL, imageURLSQL, website.ThemeName, website.IsDemo, website.IsClaimed, website.Status, time.Now(), id)
-	if err != nil {
 		log.Printf("ERROR updating website: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update website: " + err.Error()})
 		return
 	}
 
-	// Convert result to Website model
-	website.ID = result.ID
-	website.Title = result.Title
-	website.ThemeName = result.ThemeName
-	website.IsDemo = result.IsDemo
-	website.IsClaimed = result.IsClaimed
-	website.Status = result.Status
-	website.CreatedAt = result.CreatedAt
-	website.UpdatedAt = result.UpdatedAt
+	log.Printf("Website updated successfully with ID: %d, URL: %v", website.ID, website.URL)
+	c.JSON(http.StatusOK, website)
+}
 
-	// Handle nullable business_id
-	if result.BusinessID.Valid {
-		businessID := int(result.BusinessID.Int64)
-		website.BusinessID = &businessID
-	} else {
-		website.BusinessID = nil
+// Publish makes the current draft the version visitors see.
+func (h *WebsiteHandler) Publish(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	if _, ok := authorizeWebsite(c, h.Store, id); !ok {
+		return
 	}
 
-	// Handle nullable url
-	if result.URL.Valid && result.URL.String != "" {
-		website.URL = &result.URL.String
-		log.Printf("Updated URL is: %s", result.URL.String)
-	} else {
-		website.URL = nil
-		log.Printf("Updated URL is NULL")
+	website, err := h.Store.PublishWebsite(c.Request.Context(), id)
+	if err != nil {
+		log.Printf("ERROR publishing website %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish website"})
+		return
 	}
+	h.Events.Publish(events.WebsitePublished, website)
+	h.Thumbnails.Enqueue(website.ID)
+	c.JSON(http.StatusOK, website)
+}
 
-	// Handle nullable image_url
-	if result.ImageURL.Valid && result.ImageURL.String != "" {
-		website.ImageURL = &result.ImageURL.String
-	} else {
-		website.ImageURL = nil
+// ListRevisions returns the saved states of a website, newest first.
+func (h *WebsiteHandler) ListRevisions(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	if _, ok := authorizeWebsite(c, h.Store, id); !ok {
+		return
 	}
 
-	log.Printf("Website updated successfully with ID: %d, URL: %v", website.ID, website.URL)
+	revisions, err := h.Store.Websites.Revisions(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch revisions"})
+		return
+	}
+	c.JSON(http.StatusOK, revisions)
+}
+
+// Diff compares two revisions given as ?from=&to=. By default it compares
+// the published revision with the latest draft.
+func (h *WebsiteHandler) Diff(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	website, ok := authorizeWebsite(c, h.Store, id)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	revisions, err := h.Store.Websites.Revisions(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch revisions"})
+		return
+	}
+	if len(revisions) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Website has no revisions"})
+		return
+	}
+
+	to := revisions[0].Revision
+	from := to
+	if website.PublishedRevision != nil {
+		from = *website.PublishedRevision
+	}
+	for param, target := range map[string]*int{"from": &from, "to": &to} {
+		if raw := c.Query(param); raw != "" {
+			if *target, err = strconv.Atoi(raw); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param + " revision"})
+				return
+			}
+		}
+	}
+
+	fromRevision, err := h.Store.Websites.Revision(ctx, id, from)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+		return
+	}
+	toRevision, err := h.Store.Websites.Revision(ctx, id, to)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":    from,
+		"to":      to,
+		"changes": store.DiffRevisions(fromRevision, toRevision),
+	})
+}
+
+// Rollback restores an earlier revision and publishes it.
+func (h *WebsiteHandler) Rollback(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	revision, err := strconv.Atoi(c.Param("rev"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision"})
+		return
+	}
+	if _, ok := authorizeWebsite(c, h.Store, id); !ok {
+		return
+	}
+
+	website, err := h.Store.RollbackWebsite(c.Request.Context(), id, revision, c.GetInt("user_id"))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+			return
+		}
+		log.Printf("ERROR rolling back website %d to revision %d: %v", id, revision, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to roll back website"})
+		return
+	}
+	h.Events.Publish(events.WebsitePublished, website)
+	h.Thumbnails.Enqueue(website.ID)
 	c.JSON(http.StatusOK, website)
 }
 
-func (h *WebsiteHandler) Delete(c *gin.Context) {
+// maxAnalyticsDays bounds the range of an analytics report.
+const maxAnalyticsDays = 366
+
+// Analytics reports a website's traffic per day between the from and to
+// query dates (YYYY-MM-DD, inclusive), defaulting to the last 30 days.
+func (h *WebsiteHandler) Analytics(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	to := store.AnalyticsDay(time.Now())
+	if value := c.Query("to"); value != "" {
+		if to, err = time.Parse(time.DateOnly, value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be a date like 2024-01-31"})
+			return
+		}
+	}
+	from := to.AddDate(0, 0, -29)
+	if value := c.Query("from"); value != "" {
+		if from, err = time.Parse(time.DateOnly, value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be a date like 2024-01-01"})
+			return
+		}
+	}
+	if to.Before(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must not be after to"})
+		return
+	}
+	if to.Sub(from) >= maxAnalyticsDays*24*time.Hour {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Range must not exceed 366 days"})
+		return
+	}
+
+	if _, ok := authorizeWebsite(c, h.Store, id); !ok {
+		return
+	}
+	report, err := h.Store.AnalyticsReport(c.Request.Context(), id, from, to)
+	if err != nil {
+		log.Printf("ERROR loading analytics of website %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load analytics"})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// Claim takes over an unclaimed demo website for one of the caller's
+// businesses and starts a trial subscription on the chosen plan.
+func (h *WebsiteHandler) Claim(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
 		return
 	}
 
-	_, err = h.DB.DB.Exec("DELETE FROM websites WHERE id = $1", id)
+	var req ClaimRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetInt("user_id")
+	businessID, ok := h.claimingBusiness(c, userID, req.BusinessID)
+	if !ok {
+		return
+	}
+
+	if _, err := h.Store.Plans.Get(c.Request.Context(), req.PlanID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Plan not found"})
+		return
+	}
+
+	claimed, err := h.Store.ClaimWebsite(c.Request.Context(), store.WebsiteClaim{
+		WebsiteID:  id,
+		BusinessID: businessID,
+		PlanID:     req.PlanID,
+		ClaimedBy:  userID,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Website not found"})
+		case errors.Is(err, store.ErrConflict):
+			c.JSON(http.StatusConflict, gin.H{"error": "Website is not available to claim"})
+		default:
+			log.Printf("ERROR claiming website %d: %v", id, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to claim website"})
+		}
+		return
+	}
+
+	h.Events.Publish(events.SubscriptionUpdated, claimed.Subscription)
+	for i := range claimed.Notifications {
+		h.Events.Publish(events.NotificationCreated, &claimed.Notifications[i])
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"website":      claimed.Website,
+		"subscription": claimed.Subscription,
+	})
+}
+
+// claimingBusiness resolves the business a claim is made for: the requested
+// one if the caller owns it, otherwise the caller's only business.
+func (h *WebsiteHandler) claimingBusiness(c *gin.Context, userID int, requested *int) (int, bool) {
+	if requested != nil {
+		business, err := h.Store.Businesses.Get(c.Request.Context(), *requested)
+		if err != nil || business.UserID == nil || *business.UserID != userID {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Business not found"})
+			return 0, false
+		}
+		return business.ID, true
+	}
+
+	page, err := h.Store.Businesses.List(c.Request.Context(), listing.Spec{
+		Limit:   1,
+		Sort:    "id",
+		Filters: []listing.Filter{{Field: "user_id", Value: userID}},
+	})
 	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch businesses"})
+		return 0, false
+	}
+	switch {
+	case len(page.Items) == 0:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Create a business before claiming a website"})
+		return 0, false
+	case page.NextCursor != "":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "business_id is required when you own several businesses"})
+		return 0, false
+	}
+	return page.Items[0].ID, true
+}
+
+func (h *WebsiteHandler) Delete(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	if _, ok := authorizeWebsite(c, h.Store, id); !ok {
+		return
+	}
+
+	if err := h.Store.Websites.Delete(c.Request.Context(), id); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Website not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete website"})
 		return
 	}
@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"saas-management-api/internal/events"
+	"saas-management-api/internal/models"
+	"saas-management-api/internal/store"
+	"saas-management-api/internal/themes"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newWebsiteRouter(st *store.Store, user *models.User) *gin.Engine {
+	h := NewWebsiteHandler(st, themes.Builtin(), nil, events.NewBus())
+	r := gin.New()
+	r.Use(asUser(user))
+	r.POST("/websites", h.Create)
+	r.PUT("/websites/:id", h.Update)
+	r.GET("/websites/:id/diff", h.Diff)
+	r.POST("/websites/:id/publish", h.Publish)
+	r.GET("/websites/:id/revisions", h.ListRevisions)
+	r.POST("/websites/:id/rollback/:rev", h.Rollback)
+	return r
+}
+
+func newTestBusiness(t *testing.T, st *store.Store, owner *models.User, name string) *models.Business {
+	t.Helper()
+	business := &models.Business{UserID: &owner.ID, Name: name}
+	if err := st.CreateBusiness(context.Background(), business); err != nil {
+		t.Fatalf("creating business %s: %v", name, err)
+	}
+	return business
+}
+
+func TestWebsiteCreateRequiresOwnedBusiness(t *testing.T) {
+	st := store.NewMemory()
+	owner := newTestUser(t, st, "owner", "owner")
+	other := newTestUser(t, st, "other", "owner")
+	admin := newTestUser(t, st, "admin", "admin")
+	business := newTestBusiness(t, st, owner, "Acme")
+
+	expectStatus(t, serve(t, newWebsiteRouter(st, owner), http.MethodPost, "/websites",
+		models.Website{Title: "No business"}), http.StatusBadRequest)
+	expectStatus(t, serve(t, newWebsiteRouter(st, other), http.MethodPost, "/websites",
+		models.Website{Title: "Not mine", BusinessID: &business.ID}), http.StatusForbidden)
+
+	w := serve(t, newWebsiteRouter(st, owner), http.MethodPost, "/websites",
+		models.Website{Title: "Acme", BusinessID: &business.ID, IsDemo: true, IsClaimed: true, Status: "approved"})
+	expectStatus(t, w, http.StatusCreated)
+	var website models.Website
+	decode(t, w, &website)
+	if website.IsDemo || website.IsClaimed || website.Status != "pending" {
+		t.Fatalf("website = %+v, want a pending, non-demo website", website)
+	}
+
+	w = serve(t, newWebsiteRouter(st, admin), http.MethodPost, "/websites",
+		models.Website{Title: "Demo", IsDemo: true})
+	expectStatus(t, w, http.StatusCreated)
+	var demo models.Website
+	decode(t, w, &demo)
+	if !demo.IsDemo || demo.BusinessID != nil {
+		t.Fatalf("admin website = %+v, want a demo without a business", demo)
+	}
+}
+
+func TestWebsiteUpdateKeepsStatusFields(t *testing.T) {
+	st := store.NewMemory()
+	owner := newTestUser(t, st, "owner", "owner")
+	business := newTestBusiness(t, st, owner, "Acme")
+	website := &models.Website{Title: "Acme", BusinessID: &business.ID, Status: "approved", IsClaimed: true}
+	if err := st.CreateWebsite(context.Background(), website, owner.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	w := serve(t, newWebsiteRouter(st, owner), http.MethodPut, "/websites/1", map[string]any{
+		"title":      "Acme Inc",
+		"theme_name": "modern",
+		"is_demo":    true,
+		"is_claimed": false,
+		"status":     "rejected",
+	})
+	expectStatus(t, w, http.StatusOK)
+
+	updated, err := st.Websites.Get(context.Background(), website.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Title != "Acme Inc" || updated.ThemeName != "modern" {
+		t.Fatalf("website = %+v, want the new title and theme", updated)
+	}
+	if updated.IsDemo || !updated.IsClaimed || updated.Status != "approved" {
+		t.Fatalf("website = %+v, want demo, claim and status unchanged", updated)
+	}
+}
+
+func TestWebsiteUpdateValidatesThemeSettings(t *testing.T) {
+	st := store.NewMemory()
+	owner := newTestUser(t, st, "owner", "owner")
+	business := newTestBusiness(t, st, owner, "Acme")
+	website := &models.Website{Title: "Acme", BusinessID: &business.ID, ThemeName: "modern"}
+	if err := st.CreateWebsite(context.Background(), website, owner.ID); err != nil {
+		t.Fatal(err)
+	}
+	r := newWebsiteRouter(st, owner)
+
+	for _, settings := range []map[string]any{
+		{"primary_color": "#ff0000"},
+		{"accent_color": "tomato"},
+	} {
+		w := serve(t, r, http.MethodPut, "/websites/1", map[string]any{
+			"title": "Acme", "theme_name": "modern", "theme_settings": settings,
+		})
+		expectStatus(t, w, http.StatusBadRequest)
+	}
+
+	w := serve(t, r, http.MethodPut, "/websites/1", map[string]any{
+		"title": "Acme", "theme_name": "modern", "theme_settings": map[string]any{"accent_color": "#ff0000"},
+	})
+	expectStatus(t, w, http.StatusOK)
+	updated, err := st.Websites.Get(context.Background(), website.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.ThemeSettings["accent_color"] != "#ff0000" {
+		t.Fatalf("theme settings = %v, want the chosen accent color", updated.ThemeSettings)
+	}
+}
+
+func TestWebsiteRejectsUnknownTheme(t *testing.T) {
+	st := store.NewMemory()
+	owner := newTestUser(t, st, "owner", "owner")
+	business := newTestBusiness(t, st, owner, "Acme")
+	r := newWebsiteRouter(st, owner)
+
+	w := serve(t, r, http.MethodPost, "/websites",
+		models.Website{Title: "Acme", BusinessID: &business.ID, ThemeName: "fitness"})
+	expectStatus(t, w, http.StatusBadRequest)
+	if !strings.Contains(w.Body.String(), "Unknown theme") {
+		t.Errorf("error %s does not name the unknown theme", w.Body.String())
+	}
+
+	// Without a theme, websites get the default one.
+	w = serve(t, r, http.MethodPost, "/websites", models.Website{Title: "Acme", BusinessID: &business.ID})
+	expectStatus(t, w, http.StatusCreated)
+	var website models.Website
+	decode(t, w, &website)
+	if website.ThemeName != themes.DefaultName {
+		t.Fatalf("theme = %q, want %q", website.ThemeName, themes.DefaultName)
+	}
+
+	expectStatus(t, serve(t, r, http.MethodPut, "/websites/"+strconv.Itoa(website.ID),
+		map[string]any{"title": "Acme", "theme_name": "fitness"}), http.StatusBadRequest)
+}
+
+func TestWebsiteDiffWithoutRevisions(t *testing.T) {
+	st := store.NewMemory()
+	owner := newTestUser(t, st, "owner", "owner")
+	business := newTestBusiness(t, st, owner, "Acme")
+	// Created without CreateWebsite, so no revision was recorded.
+	website := &models.Website{Title: "Acme", BusinessID: &business.ID, ThemeName: themes.DefaultName}
+	if err := st.Websites.Create(context.Background(), website); err != nil {
+		t.Fatal(err)
+	}
+
+	w := serve(t, newWebsiteRouter(st, owner), http.MethodGet, "/websites/"+strconv.Itoa(website.ID)+"/diff", nil)
+	expectStatus(t, w, http.StatusNotFound)
+}
+
+// diffFields returns the fields changed in a diff response.
+func diffFields(t *testing.T, r *gin.Engine, path string) []string {
+	t.Helper()
+	w := serve(t, r, http.MethodGet, path, nil)
+	expectStatus(t, w, http.StatusOK)
+	var diff struct {
+		Changes []models.RevisionChange `json:"changes"`
+	}
+	decode(t, w, &diff)
+	fields := []string{}
+	for _, change := range diff.Changes {
+		fields = append(fields, change.Field)
+	}
+	return fields
+}
+
+func TestWebsitePublishDiffAndRollback(t *testing.T) {
+	st := store.NewMemory()
+	owner := newTestUser(t, st, "owner", "owner")
+	business := newTestBusiness(t, st, owner, "Acme")
+	r := newWebsiteRouter(st, owner)
+
+	w := serve(t, r, http.MethodPost, "/websites", models.Website{Title: "Acme", BusinessID: &business.ID})
+	expectStatus(t, w, http.StatusCreated)
+	var website models.Website
+	decode(t, w, &website)
+	base := "/websites/" + strconv.Itoa(website.ID)
+
+	w = serve(t, r, http.MethodPost, base+"/publish", nil)
+	expectStatus(t, w, http.StatusOK)
+	decode(t, w, &website)
+	if website.PublishedRevision == nil || *website.PublishedRevision != 1 {
+		t.Fatalf("published revision = %v, want 1", website.PublishedRevision)
+	}
+
+	// Saving makes a draft revision that leaves the published one alone.
+	expectStatus(t, serve(t, r, http.MethodPut, base, map[string]any{"title": "Acme Inc", "theme_name": "modern"}), http.StatusOK)
+	w = serve(t, r, http.MethodGet, base+"/revisions", nil)
+	expectStatus(t, w, http.StatusOK)
+	var revisions []models.WebsiteRevision
+	decode(t, w, &revisions)
+	if len(revisions) != 2 || revisions[0].Revision != 2 || revisions[0].CreatedBy == nil || *revisions[0].CreatedBy != owner.ID {
+		t.Fatalf("revisions = %+v, want 2 then 1, saved by the owner", revisions)
+	}
+	if got, _ := st.Websites.Get(context.Background(), website.ID); *got.PublishedRevision != 1 {
+		t.Fatalf("saving moved the published revision to %d", *got.PublishedRevision)
+	}
+
+	// By default the diff runs from the published revision to the draft.
+	if got := strings.Join(diffFields(t, r, base+"/diff"), ","); got != "theme_name,title" {
+		t.Fatalf("changed fields = %s, want theme_name,title", got)
+	}
+	if got := diffFields(t, r, base+"/diff?from=2&to=2"); len(got) != 0 {
+		t.Fatalf("a revision differs from itself in %v", got)
+	}
+	expectStatus(t, serve(t, r, http.MethodGet, base+"/diff?from=9", nil), http.StatusNotFound)
+	expectStatus(t, serve(t, r, http.MethodGet, base+"/diff?to=two", nil), http.StatusBadRequest)
+
+	// Rolling back restores revision 1 as a new, published revision 3.
+	w = serve(t, r, http.MethodPost, base+"/rollback/1", nil)
+	expectStatus(t, w, http.StatusOK)
+	decode(t, w, &website)
+	if website.Title != "Acme" || website.ThemeName != themes.DefaultName || *website.PublishedRevision != 3 {
+		t.Fatalf("rolled back website = %+v, want revision 1 republished as 3", website)
+	}
+	if got := diffFields(t, r, base+"/diff?from=1&to=3"); len(got) != 0 {
+		t.Fatalf("the rollback differs from revision 1 in %v", got)
+	}
+	expectStatus(t, serve(t, r, http.MethodPost, base+"/rollback/9", nil), http.StatusNotFound)
+}
+
+func TestWebsiteRevisionsRequireOwner(t *testing.T) {
+	st := store.NewMemory()
+	owner := newTestUser(t, st, "owner", "owner")
+	other := newTestUser(t, st, "other", "owner")
+	business := newTestBusiness(t, st, owner, "Acme")
+	website := &models.Website{Title: "Acme", BusinessID: &business.ID, ThemeName: themes.DefaultName}
+	if err := st.CreateWebsite(context.Background(), website, owner.ID); err != nil {
+		t.Fatal(err)
+	}
+	r := newWebsiteRouter(st, other)
+	base := "/websites/" + strconv.Itoa(website.ID)
+
+	expectStatus(t, serve(t, r, http.MethodPost, base+"/publish", nil), http.StatusForbidden)
+	expectStatus(t, serve(t, r, http.MethodGet, base+"/revisions", nil), http.StatusForbidden)
+	expectStatus(t, serve(t, r, http.MethodGet, base+"/diff", nil), http.StatusForbidden)
+	expectStatus(t, serve(t, r, http.MethodPost, base+"/rollback/1", nil), http.StatusForbidden)
+	if got, _ := st.Websites.Get(context.Background(), website.ID); got.PublishedRevision != nil {
+		t.Fatalf("another owner published revision %d", *got.PublishedRevision)
+	}
+}
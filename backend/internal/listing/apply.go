@@ -0,0 +1,136 @@
+package listing
+
+import (
+	"cmp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SQL appends the filter and keyset conditions of spec to conds and args and
+// renders them as a WHERE clause. The returned tail orders the rows and
+// fetches one row beyond the page so Paginate can tell whether another page
+// follows.
+func (s *Schema[T]) SQL(spec Spec, conds []string, args []any) (where, tail string, outArgs []any) {
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	for _, filter := range spec.Filters {
+		field, _ := s.field(filter.Field)
+		conds = append(conds, field.Column+" = "+arg(filter.Value))
+	}
+
+	sortField, _ := s.field(spec.Sort)
+	dir := "ASC"
+	if spec.Desc {
+		dir = "DESC"
+	}
+	if spec.After != nil {
+		conds = append(conds, s.keyset(sortField, spec, arg))
+	}
+
+	if len(conds) > 0 {
+		where = " WHERE " + strings.Join(conds, " AND ")
+	}
+	tail = " ORDER BY " + sortField.Column + " " + dir + ", " + s.IDColumn + " " + dir +
+		" LIMIT " + strconv.Itoa(spec.Limit+1)
+	return where, tail, args
+}
+
+// keyset renders the condition selecting the rows after spec.After. NULLs
+// sort last, as in Postgres: they follow every value in ascending order
+// and precede them in descending order.
+func (s *Schema[T]) keyset(field *Field[T], spec Spec, arg func(any) string) string {
+	switch {
+	case spec.After.Value == nil && spec.Desc:
+		return "(" + field.Column + " IS NOT NULL OR " + s.IDColumn + " < " + arg(spec.After.ID) + ")"
+	case spec.After.Value == nil:
+		return "(" + field.Column + " IS NULL AND " + s.IDColumn + " > " + arg(spec.After.ID) + ")"
+	case spec.Desc:
+		return "(" + field.Column + ", " + s.IDColumn + ") < (" + arg(spec.After.Value) + ", " + arg(spec.After.ID) + ")"
+	}
+	cond := "(" + field.Column + ", " + s.IDColumn + ") > (" + arg(spec.After.Value) + ", " + arg(spec.After.ID) + ")"
+	if field.Nullable {
+		cond = "(" + cond + " OR " + field.Column + " IS NULL)"
+	}
+	return cond
+}
+
+// Apply filters, sorts and pages rows in memory with the same semantics as
+// SQL followed by Paginate.
+func Apply[T any](rows []T, spec Spec, schema *Schema[T]) Page[T] {
+	sortField, _ := schema.field(spec.Sort)
+	less := func(aValue any, aID int, bValue any, bID int) bool {
+		c := compare(sortField.Kind, aValue, bValue)
+		if c == 0 {
+			c = cmp.Compare(aID, bID)
+		}
+		if spec.Desc {
+			c = -c
+		}
+		return c < 0
+	}
+
+	matched := make([]T, 0, len(rows))
+	for _, row := range rows {
+		if !schema.matches(row, spec.Filters) {
+			continue
+		}
+		if spec.After != nil && !less(spec.After.Value, spec.After.ID, sortField.Value(row), schema.ID(row)) {
+			continue
+		}
+		matched = append(matched, row)
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		return less(sortField.Value(matched[i]), schema.ID(matched[i]), sortField.Value(matched[j]), schema.ID(matched[j]))
+	})
+	if len(matched) > spec.Limit+1 {
+		matched = matched[:spec.Limit+1]
+	}
+	return Paginate(matched, spec, schema)
+}
+
+func (s *Schema[T]) matches(row T, filters []Filter) bool {
+	for _, filter := range filters {
+		field, _ := s.field(filter.Field)
+		value := field.Value(row)
+		if value == nil || compare(field.Kind, value, filter.Value) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// compare orders two values of the same kind. NULLs sort last, as they do
+// in Postgres for ascending order.
+func compare(kind Kind, a, b any) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	switch kind {
+	case Int:
+		return cmp.Compare(a.(int), b.(int))
+	case Bool:
+		x, y := a.(bool), b.(bool)
+		if x == y {
+			return 0
+		}
+		if !x {
+			return -1
+		}
+		return 1
+	case Time:
+		return a.(time.Time).Compare(b.(time.Time))
+	default:
+		return strings.Compare(a.(string), b.(string))
+	}
+}
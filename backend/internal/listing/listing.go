@@ -0,0 +1,245 @@
+// Package listing implements the shared query language of list endpoints:
+//
+//	?limit=20&sort=-created_at&filter[status]=active&cursor=<opaque>
+//
+// Each resource declares a Schema whitelisting the fields that may be sorted
+// and filtered on. Pages are keyset-paginated on the sort field with the row
+// ID as tie-breaker, and the position is carried in an opaque cursor.
+package listing
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultLimit = 50
+	MaxLimit     = 200
+)
+
+// ErrInvalid is wrapped by every error caused by a malformed query.
+var ErrInvalid = errors.New("invalid list query")
+
+// Kind is the type of a field's values.
+type Kind int
+
+const (
+	String Kind = iota
+	Int
+	Bool
+	Time
+)
+
+// Field describes one listable attribute of T.
+type Field[T any] struct {
+	// Name is the field's name in query strings.
+	Name string
+	// Column is the SQL expression the field maps to.
+	Column string
+	Kind   Kind
+	// Value extracts the field from a row. It returns nil for NULL.
+	Value func(T) any
+	// Nullable marks columns that may be NULL. NULLs sort last, so keyset
+	// conditions on nullable sort fields must let them through.
+	Nullable   bool
+	Sortable   bool
+	Filterable bool
+}
+
+// Schema whitelists the fields of a resource.
+type Schema[T any] struct {
+	Fields []Field[T]
+	// IDColumn is the SQL expression of the unique row ID used to break
+	// ties between equal sort keys.
+	IDColumn string
+	ID       func(T) int
+	// DefaultSort is used when the query has no sort parameter, e.g.
+	// "-created_at".
+	DefaultSort string
+}
+
+func (s *Schema[T]) field(name string) (*Field[T], bool) {
+	for i := range s.Fields {
+		if s.Fields[i].Name == name {
+			return &s.Fields[i], true
+		}
+	}
+	return nil, false
+}
+
+// Filter restricts a list to rows whose field equals Value.
+type Filter struct {
+	Field string
+	Value any
+}
+
+// Cursor is the keyset position after which the next page starts.
+type Cursor struct {
+	Value any
+	ID    int
+}
+
+// Spec is a parsed list query.
+type Spec struct {
+	Limit   int
+	Sort    string
+	Desc    bool
+	Filters []Filter
+	After   *Cursor
+}
+
+// sortParam renders the sort of spec the way it appears in a query string.
+func (spec Spec) sortParam() string {
+	if spec.Desc {
+		return "-" + spec.Sort
+	}
+	return spec.Sort
+}
+
+// Page is one page of a list.
+type Page[T any] struct {
+	Items []T
+	// NextCursor is empty on the last page.
+	NextCursor string
+}
+
+// Parse reads a list query, rejecting fields the schema does not allow.
+func Parse[T any](query url.Values, schema *Schema[T]) (Spec, error) {
+	spec := Spec{Limit: DefaultLimit}
+
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 {
+			return Spec{}, fmt.Errorf("%w: limit must be a positive integer", ErrInvalid)
+		}
+		spec.Limit = min(limit, MaxLimit)
+	}
+
+	sortParam := query.Get("sort")
+	if sortParam == "" {
+		sortParam = schema.DefaultSort
+	}
+	spec.Sort, spec.Desc = strings.TrimPrefix(sortParam, "-"), strings.HasPrefix(sortParam, "-")
+	sortField, ok := schema.field(spec.Sort)
+	if !ok || !sortField.Sortable {
+		return Spec{}, fmt.Errorf("%w: cannot sort by %q", ErrInvalid, spec.Sort)
+	}
+
+	var filterNames []string
+	for key := range query {
+		if strings.HasPrefix(key, "filter[") && strings.HasSuffix(key, "]") {
+			filterNames = append(filterNames, key[len("filter["):len(key)-1])
+		}
+	}
+	sort.Strings(filterNames)
+	for _, name := range filterNames {
+		field, ok := schema.field(name)
+		if !ok || !field.Filterable {
+			return Spec{}, fmt.Errorf("%w: cannot filter by %q", ErrInvalid, name)
+		}
+		value, err := parseValue(field.Kind, query.Get("filter["+name+"]"))
+		if err != nil {
+			return Spec{}, fmt.Errorf("%w: filter %q: %v", ErrInvalid, name, err)
+		}
+		spec.Filters = append(spec.Filters, Filter{Field: name, Value: value})
+	}
+
+	if raw := query.Get("cursor"); raw != "" {
+		cursor, err := decodeCursor(raw, spec.sortParam(), sortField.Kind)
+		if err != nil {
+			return Spec{}, err
+		}
+		spec.After = cursor
+	}
+
+	return spec, nil
+}
+
+// cursorPayload is the JSON form of a cursor. The sort it was issued for is
+// included so a cursor cannot be replayed against a different ordering.
+type cursorPayload struct {
+	Sort  string  `json:"s"`
+	Value *string `json:"v"`
+	ID    int     `json:"id"`
+}
+
+func encodeCursor(sortParam string, kind Kind, value any, id int) string {
+	payload := cursorPayload{Sort: sortParam, ID: id}
+	if value != nil {
+		formatted := formatValue(kind, value)
+		payload.Value = &formatted
+	}
+	data, _ := json.Marshal(payload)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(raw, sortParam string, kind Kind) (*Cursor, error) {
+	invalid := fmt.Errorf("%w: malformed cursor", ErrInvalid)
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, invalid
+	}
+	var payload cursorPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, invalid
+	}
+	if payload.Sort != sortParam {
+		return nil, fmt.Errorf("%w: cursor was issued for sort %q", ErrInvalid, payload.Sort)
+	}
+	// A page can end on a row whose sort field is NULL.
+	cursor := &Cursor{ID: payload.ID}
+	if payload.Value != nil {
+		if cursor.Value, err = parseValue(kind, *payload.Value); err != nil {
+			return nil, invalid
+		}
+	}
+	return cursor, nil
+}
+
+func parseValue(kind Kind, raw string) (any, error) {
+	switch kind {
+	case Int:
+		return strconv.Atoi(raw)
+	case Bool:
+		return strconv.ParseBool(raw)
+	case Time:
+		return time.Parse(time.RFC3339Nano, raw)
+	default:
+		return raw, nil
+	}
+}
+
+func formatValue(kind Kind, value any) string {
+	switch kind {
+	case Time:
+		return value.(time.Time).Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// Paginate trims rows, fetched with one extra row beyond spec.Limit, to a
+// page and computes the cursor of the next page.
+func Paginate[T any](rows []T, spec Spec, schema *Schema[T]) Page[T] {
+	if rows == nil {
+		rows = []T{}
+	}
+	if len(rows) <= spec.Limit {
+		return Page[T]{Items: rows}
+	}
+
+	rows = rows[:spec.Limit]
+	last := rows[len(rows)-1]
+	sortField, _ := schema.field(spec.Sort)
+	return Page[T]{
+		Items:      rows,
+		NextCursor: encodeCursor(spec.sortParam(), sortField.Kind, sortField.Value(last), schema.ID(last)),
+	}
+}
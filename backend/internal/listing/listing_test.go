@@ -0,0 +1,314 @@
+package listing
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"testing"
+	"time"
+)
+
+type item struct {
+	ID     int
+	Name   string
+	Score  *int
+	At     time.Time
+	Active bool
+}
+
+var items = &Schema[item]{
+	Fields: []Field[item]{
+		{Name: "id", Column: "id", Kind: Int, Value: func(i item) any { return i.ID }, Sortable: true},
+		{Name: "name", Column: "name", Kind: String, Value: func(i item) any { return i.Name }, Sortable: true, Filterable: true},
+		{Name: "score", Column: "score", Kind: Int, Nullable: true, Sortable: true, Value: func(i item) any {
+			if i.Score == nil {
+				return nil
+			}
+			return *i.Score
+		}},
+		{Name: "at", Column: "at", Kind: Time, Value: func(i item) any { return i.At }, Sortable: true},
+		{Name: "active", Column: "active", Kind: Bool, Value: func(i item) any { return i.Active }, Filterable: true},
+	},
+	IDColumn:    "id",
+	ID:          func(i item) int { return i.ID },
+	DefaultSort: "-at",
+}
+
+func score(n int) *int { return &n }
+
+// testItems have duplicate names, scores and times, and NULL scores, so
+// ties are broken by ID.
+func testItems() []item {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	return []item{
+		{ID: 1, Name: "delta", Score: score(3), At: base, Active: true},
+		{ID: 2, Name: "alpha", Score: nil, At: base.Add(time.Hour), Active: false},
+		{ID: 3, Name: "charlie", Score: score(1), At: base, Active: true},
+		{ID: 4, Name: "alpha", Score: score(3), At: base.Add(2 * time.Hour), Active: true},
+		{ID: 5, Name: "bravo", Score: nil, At: base.Add(time.Hour), Active: false},
+		{ID: 6, Name: "echo", Score: score(2), At: base.Add(3 * time.Hour), Active: true},
+	}
+}
+
+func parse(t *testing.T, raw string) Spec {
+	t.Helper()
+	query, err := url.ParseQuery(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, err := Parse(query, items)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", raw, err)
+	}
+	return spec
+}
+
+func TestParse(t *testing.T) {
+	for _, tt := range []struct {
+		query string
+		want  Spec
+	}{
+		{"", Spec{Limit: DefaultLimit, Sort: "at", Desc: true}},
+		{"limit=10&sort=name", Spec{Limit: 10, Sort: "name"}},
+		{"limit=1000&sort=-score", Spec{Limit: MaxLimit, Sort: "score", Desc: true}},
+		{"filter[name]=alpha&filter[active]=true", Spec{Limit: DefaultLimit, Sort: "at", Desc: true,
+			Filters: []Filter{{Field: "active", Value: true}, {Field: "name", Value: "alpha"}}}},
+	} {
+		if got := parse(t, tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	for _, query := range []string{
+		"limit=0",
+		"limit=-5",
+		"limit=ten",
+		"sort=password",
+		"sort=-password",
+		// Filterable but not sortable.
+		"sort=active",
+		// Sortable but not filterable.
+		"filter[id]=1",
+		"filter[password]=x",
+		"filter[active]=maybe",
+		"cursor=not-a-cursor",
+	} {
+		values, _ := url.ParseQuery(query)
+		if _, err := Parse(values, items); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Parse(%q) = %v, want ErrInvalid", query, err)
+		}
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	at := time.Date(2026, 3, 4, 5, 6, 7, 8, time.UTC)
+	for _, tt := range []struct {
+		sort  string
+		kind  Kind
+		value any
+	}{
+		{"name", String, "alpha, with punctuation & \"quotes\""},
+		{"-id", Int, 42},
+		{"score", Int, 7},
+		{"score", Int, nil},
+		{"-score", Int, nil},
+		{"at", Time, at},
+	} {
+		cursor := encodeCursor(tt.sort, tt.kind, tt.value, 9)
+		spec := parse(t, "sort="+url.QueryEscape(tt.sort)+"&cursor="+cursor)
+		if want := (&Cursor{Value: tt.value, ID: 9}); !reflect.DeepEqual(spec.After, want) {
+			t.Errorf("cursor for %s %v decoded to %+v, want %+v", tt.sort, tt.value, spec.After, want)
+		}
+	}
+}
+
+func TestTamperedCursor(t *testing.T) {
+	encode := func(payload string) string { return base64.RawURLEncoding.EncodeToString([]byte(payload)) }
+	for _, tt := range []struct {
+		name, query string
+	}{
+		{"another sort", "sort=-name&cursor=" + encodeCursor("name", String, "alpha", 1)},
+		{"another field", "sort=id&cursor=" + encodeCursor("name", String, "alpha", 1)},
+		{"value of the wrong kind", "sort=id&cursor=" + encode(`{"s":"id","v":"alpha","id":1}`)},
+		{"bad time", "sort=at&cursor=" + encode(`{"s":"at","v":"yesterday","id":1}`)},
+		{"not JSON", "sort=id&cursor=" + encode(`{"s":"id",`)},
+		{"not base64", "sort=id&cursor=%21%21%21"},
+	} {
+		values, _ := url.ParseQuery(tt.query)
+		if _, err := Parse(values, items); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: Parse = %v, want ErrInvalid", tt.name, err)
+		}
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	spec := parse(t, "sort=name&limit=2")
+
+	if page := Paginate[item](nil, spec, items); page.Items == nil || len(page.Items) != 0 || page.NextCursor != "" {
+		t.Errorf("empty page = %+v, want no items and no cursor", page)
+	}
+	// The last page has no more than the limit, and no cursor.
+	last := Paginate(testItems()[:2], spec, items)
+	if len(last.Items) != 2 || last.NextCursor != "" {
+		t.Errorf("last page = %+v, want 2 items and no cursor", last)
+	}
+
+	page := Paginate(testItems()[:3], spec, items)
+	if len(page.Items) != 2 || page.NextCursor == "" {
+		t.Fatalf("page = %+v, want 2 items and a cursor", page)
+	}
+	next := parse(t, "sort=name&limit=2&cursor="+page.NextCursor)
+	if want := (&Cursor{Value: "alpha", ID: 2}); !reflect.DeepEqual(next.After, want) {
+		t.Errorf("cursor points after %+v, want %+v", next.After, want)
+	}
+}
+
+func TestSQL(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		spec   Spec
+		where  string
+		tail   string
+		values []any
+	}{
+		{
+			name:   "first page",
+			spec:   Spec{Limit: 10, Sort: "name", Filters: []Filter{{Field: "active", Value: true}}},
+			where:  " WHERE active = $1",
+			tail:   " ORDER BY name ASC, id ASC LIMIT 11",
+			values: []any{true},
+		},
+		{
+			name:   "after a value",
+			spec:   Spec{Limit: 2, Sort: "name", After: &Cursor{Value: "bravo", ID: 5}},
+			where:  " WHERE (name, id) > ($1, $2)",
+			tail:   " ORDER BY name ASC, id ASC LIMIT 3",
+			values: []any{"bravo", 5},
+		},
+		{
+			name:   "descending after a value",
+			spec:   Spec{Limit: 2, Sort: "score", Desc: true, After: &Cursor{Value: 3, ID: 4}},
+			where:  " WHERE (score, id) < ($1, $2)",
+			tail:   " ORDER BY score DESC, id DESC LIMIT 3",
+			values: []any{3, 4},
+		},
+		{
+			name:   "nullable after a value",
+			spec:   Spec{Limit: 2, Sort: "score", After: &Cursor{Value: 3, ID: 4}},
+			where:  " WHERE ((score, id) > ($1, $2) OR score IS NULL)",
+			tail:   " ORDER BY score ASC, id ASC LIMIT 3",
+			values: []any{3, 4},
+		},
+		{
+			name:   "after NULL",
+			spec:   Spec{Limit: 2, Sort: "score", After: &Cursor{Value: nil, ID: 2}},
+			where:  " WHERE (score IS NULL AND id > $1)",
+			tail:   " ORDER BY score ASC, id ASC LIMIT 3",
+			values: []any{2},
+		},
+		{
+			name:   "descending after NULL",
+			spec:   Spec{Limit: 2, Sort: "score", Desc: true, After: &Cursor{Value: nil, ID: 5}},
+			where:  " WHERE (score IS NOT NULL OR id < $1)",
+			tail:   " ORDER BY score DESC, id DESC LIMIT 3",
+			values: []any{5},
+		},
+	} {
+		where, tail, args := items.SQL(tt.spec, nil, nil)
+		if where != tt.where || tail != tt.tail || !reflect.DeepEqual(args, tt.values) {
+			t.Errorf("%s: SQL = %q %q %v, want %q %q %v", tt.name, where, tail, args, tt.where, tt.tail, tt.values)
+		}
+	}
+}
+
+// orderBy matches the ORDER BY clause SQL renders.
+var orderBy = regexp.MustCompile(`ORDER BY (\w+) (ASC|DESC), (\w+) (ASC|DESC)`)
+
+// sqlOrder sorts rows the way Postgres executes the ORDER BY clause SQL
+// renders for spec: NULLs last when ascending and first when descending,
+// ties broken by ID.
+func sqlOrder(t *testing.T, rows []item, spec Spec) []int {
+	t.Helper()
+	_, tail, _ := items.SQL(spec, nil, nil)
+	m := orderBy.FindStringSubmatch(tail)
+	if m == nil {
+		t.Fatalf("no ORDER BY in %q", tail)
+	}
+	field, ok := items.field(m[1])
+	if !ok {
+		t.Fatalf("ORDER BY unknown column %q", m[1])
+	}
+	desc := m[2] == "DESC"
+	sorted := slices.Clone(rows)
+	slices.SortFunc(sorted, func(a, b item) int {
+		c := compare(field.Kind, field.Value(a), field.Value(b))
+		if c == 0 {
+			c = a.ID - b.ID
+		}
+		if desc {
+			c = -c
+		}
+		return c
+	})
+	ids := make([]int, 0, len(sorted))
+	for _, row := range sorted {
+		if items.matches(row, spec.Filters) {
+			ids = append(ids, row.ID)
+		}
+	}
+	return ids
+}
+
+func TestApplyMatchesSQLOrder(t *testing.T) {
+	for _, query := range []string{
+		"sort=name",
+		"sort=-name",
+		"sort=score",
+		"sort=-score",
+		"sort=at",
+		"sort=-at&filter[active]=true",
+		"sort=-id",
+	} {
+		t.Run(query, func(t *testing.T) {
+			rows := testItems()
+			want := sqlOrder(t, rows, parse(t, query))
+
+			// Walk the pages, each starting after the previous cursor.
+			var got []int
+			cursor := ""
+			for pages := 0; pages == 0 || cursor != ""; pages++ {
+				if pages > len(rows) {
+					t.Fatalf("pagination did not end, got %v", got)
+				}
+				q := query + "&limit=2"
+				if cursor != "" {
+					q += "&cursor=" + cursor
+				}
+				page := Apply(rows, parse(t, q), items)
+				for _, row := range page.Items {
+					got = append(got, row.ID)
+				}
+				cursor = page.NextCursor
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("pages of %q = %v, want %v", query, got, want)
+			}
+		})
+	}
+}
+
+func TestSQLAppendsToConditions(t *testing.T) {
+	spec := Spec{Limit: 5, Sort: "name", Filters: []Filter{{Field: "name", Value: "alpha"}}}
+	where, _, args := items.SQL(spec, []string{"owner_id = $1"}, []any{7})
+	if want := " WHERE owner_id = $1 AND name = $2"; where != want {
+		t.Errorf("where = %q, want %q", where, want)
+	}
+	if !reflect.DeepEqual(args, []any{7, "alpha"}) {
+		t.Errorf("args = %v", args)
+	}
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// acmeChallengePath is where ACME servers fetch HTTP-01 challenge tokens.
+const acmeChallengePath = "/.well-known/acme-challenge/"
+
+// ACMEChallenges answers HTTP-01 challenges for m and passes every other
+// request on.
+func ACMEChallenges(m *autocert.Manager) gin.HandlerFunc {
+	challenges := gin.WrapH(m.HTTPHandler(nil))
+	return func(c *gin.Context) {
+		if !strings.HasPrefix(c.Request.URL.Path, acmeChallengePath) {
+			c.Next()
+			return
+		}
+		challenges(c)
+		c.Abort()
+	}
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// redactedParams are query parameters carrying credentials, such as the
+// token of a WebSocket or preview URL.
+var redactedParams = []string{"token"}
+
+// Logger is gin's request logger with the values of credential query
+// parameters replaced, so tokens passed in URLs stay out of the logs.
+func Logger() gin.HandlerFunc {
+	return gin.LoggerWithFormatter(func(p gin.LogFormatterParams) string {
+		return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v\n%s",
+			p.TimeStamp.Format("2006/01/02 - 15:04:05"),
+			p.StatusCode,
+			p.Latency,
+			p.ClientIP,
+			p.Method,
+			RedactQuery(p.Path),
+			p.ErrorMessage,
+		)
+	})
+}
+
+// RedactQuery replaces the values of credential query parameters in a
+// request path with "REDACTED".
+func RedactQuery(path string) string {
+	base, rawQuery, ok := strings.Cut(path, "?")
+	if !ok {
+		return path
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return base + "?REDACTED"
+	}
+	redacted := false
+	for _, param := range redactedParams {
+		if query.Has(param) {
+			query.Set(param, "REDACTED")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return path
+	}
+	return base + "?" + query.Encode()
+}
@@ -0,0 +1,17 @@
+package middleware
+
+import "testing"
+
+func TestRedactQuery(t *testing.T) {
+	for _, tt := range []struct{ path, want string }{
+		{"/preview/1/", "/preview/1/"},
+		{"/api/ws?v=1", "/api/ws?v=1"},
+		{"/api/ws?v=1&token=secret", "/api/ws?token=REDACTED&v=1"},
+		{"/preview/1/?token=a.b.c", "/preview/1/?token=REDACTED"},
+		{"/preview/1/?token=%zz", "/preview/1/?REDACTED"},
+	} {
+		if got := RedactQuery(tt.path); got != tt.want {
+			t.Errorf("RedactQuery(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
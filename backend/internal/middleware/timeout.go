@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"context"
+
+	"saas-management-api/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// QueryTimeout attaches a deadline to the request context so that database
+// calls made with c.Request.Context() are cancelled when the route's query
+// timeout elapses or the client goes away.
+func QueryTimeout(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		timeout := cfg.QueryTimeoutFor(c.Request.Method, c.FullPath())
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"saas-management-api/internal/config"
+	"saas-management-api/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestQueryTimeout(t *testing.T) {
+	cfg := &config.Config{
+		QueryTimeout: time.Minute,
+		RouteQueryTimeouts: map[string]time.Duration{
+			"GET /slow/:id": 10 * time.Millisecond,
+			"GET /export":   0,
+		},
+	}
+	st := store.NewMemory()
+	deadlines := map[string]time.Duration{}
+	var lookupErr error
+	r := gin.New()
+	r.Use(QueryTimeout(cfg))
+	record := func(c *gin.Context) {
+		deadline, ok := c.Request.Context().Deadline()
+		if ok {
+			deadlines[c.FullPath()] = time.Until(deadline)
+		}
+	}
+	r.GET("/fast", record)
+	r.GET("/export", record)
+	r.GET("/slow/:id", func(c *gin.Context) {
+		record(c)
+		<-c.Request.Context().Done()
+		_, lookupErr = st.Users.Get(c.Request.Context(), 1)
+	})
+
+	for _, path := range []string{"/fast", "/export", "/slow/1"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	if d := deadlines["/fast"]; d <= 50*time.Second || d > time.Minute {
+		t.Errorf("default deadline in %s, want about a minute", d)
+	}
+	if d, ok := deadlines["/export"]; ok {
+		t.Errorf("route without a timeout has a deadline in %s", d)
+	}
+	if d := deadlines["/slow/:id"]; d <= 0 || d > 10*time.Millisecond {
+		t.Errorf("route deadline in %s, want at most 10ms", d)
+	}
+	// The store gives up on the expired request.
+	if !errors.Is(lookupErr, context.DeadlineExceeded) {
+		t.Errorf("lookup after the deadline = %v, want context.DeadlineExceeded", lookupErr)
+	}
+}
+
+func TestQueryTimeoutKeepsCancellation(t *testing.T) {
+	cfg := &config.Config{QueryTimeout: time.Minute}
+	var err error
+	r := gin.New()
+	r.Use(QueryTimeout(cfg))
+	r.GET("/", func(c *gin.Context) { err = c.Request.Context().Err() })
+
+	// The client went away before the handler ran.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("request context error = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,41 @@
+package models
+
+// Analytics dimensions counted per day.
+const (
+	DimensionPage     = "page"
+	DimensionReferrer = "referrer"
+	DimensionEvent    = "event"
+)
+
+// AnalyticsDay holds a website's totals for one day. A visit is a pageview
+// arriving from another site or none.
+type AnalyticsDay struct {
+	Day       string `json:"day" db:"day"`
+	Pageviews int    `json:"pageviews" db:"pageviews"`
+	Visits    int    `json:"visits" db:"visits"`
+	Visitors  int    `json:"visitors" db:"visitors"`
+	Events    int    `json:"events" db:"events"`
+}
+
+// AnalyticsCount is how often a page, referrer or event occurred.
+type AnalyticsCount struct {
+	Value string `json:"value" db:"value"`
+	Count int    `json:"count" db:"count"`
+}
+
+// AnalyticsReport summarizes a website's traffic over a range of days.
+// Unique visitors are only known per day, in Days. DailyVisitors sums them,
+// so someone visiting on two days counts twice; it is not the number of
+// unique visitors over the range.
+type AnalyticsReport struct {
+	From          string           `json:"from"`
+	To            string           `json:"to"`
+	Pageviews     int              `json:"pageviews"`
+	Visits        int              `json:"visits"`
+	DailyVisitors int              `json:"daily_visitors"`
+	Events        int              `json:"events"`
+	Days          []AnalyticsDay   `json:"days"`
+	TopPages      []AnalyticsCount `json:"top_pages"`
+	Referrers     []AnalyticsCount `json:"referrers"`
+	TopEvents     []AnalyticsCount `json:"top_events"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Attachment is a file uploaded for a message. Until MessageID is set it is
+// only visible to its owner; afterwards it is visible to the participants
+// of the message's conversation.
+type Attachment struct {
+	ID        int    `json:"id" db:"id"`
+	OwnerID   int    `json:"owner_id" db:"owner_id"`
+	MessageID *int   `json:"message_id,omitempty" db:"message_id"`
+	FileName  string `json:"file_name" db:"file_name"`
+	// StorageKey names the stored file. It is never sent to clients, who
+	// download attachments by ID.
+	StorageKey string    `json:"-" db:"storage_key"`
+	MimeType   string    `json:"mime_type" db:"mime_type"`
+	Size       int64     `json:"size" db:"size_bytes"`
+	Width      *int      `json:"width,omitempty" db:"width"`
+	Height     *int      `json:"height,omitempty" db:"height"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// IsImage reports whether the attachment is an image.
+func (a *Attachment) IsImage() bool {
+	return strings.HasPrefix(a.MimeType, "image/")
+}
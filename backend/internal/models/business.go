@@ -5,17 +5,44 @@ import (
 )
 
 type Business struct {
-	ID          int       `json:"id" db:"id"`
-	UserID      *int      `json:"user_id,omitempty" db:"user_id"`
-	Name        string    `json:"name" db:"name"`
-	Slug        string    `json:"slug" db:"slug"`
-	Description *string   `json:"description,omitempty" db:"description"`
-	Logo        *string   `json:"logo,omitempty" db:"logo"`
-	Industry    *string   `json:"industry,omitempty" db:"industry"`
-	Phone       *string   `json:"phone,omitempty" db:"phone"`
-	Address     *string   `json:"address,omitempty" db:"address"`
-	Status      string    `json:"status" db:"status"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	ID           int        `json:"id" db:"id"`
+	UserID       *int       `json:"user_id,omitempty" db:"user_id"`
+	Name         string     `json:"name" db:"name"`
+	Slug         string     `json:"slug" db:"slug"`
+	Description  *string    `json:"description,omitempty" db:"description"`
+	Logo         *string    `json:"logo,omitempty" db:"logo"`
+	Industry     *string    `json:"industry,omitempty" db:"industry"`
+	Phone        *string    `json:"phone,omitempty" db:"phone"`
+	Address      *string    `json:"address,omitempty" db:"address"`
+	Status       string     `json:"status" db:"status"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
+	ReviewedBy   *int       `json:"reviewed_by,omitempty" db:"reviewed_by"`
+	ReviewedAt   *time.Time `json:"reviewed_at,omitempty" db:"reviewed_at"`
+	ReviewReason *string    `json:"review_reason,omitempty" db:"review_reason"`
 }
 
+// Business review statuses. Only approved businesses are shown publicly.
+const (
+	BusinessPending  = "pending"
+	BusinessApproved = "approved"
+	BusinessRejected = "rejected"
+)
+
+// BusinessSearchHit is a business matched by a search, with its relevance.
+type BusinessSearchHit struct {
+	Business
+	Rank float64 `json:"rank" db:"rank"`
+}
+
+// Facet counts the search hits sharing one value of a field.
+type Facet struct {
+	Value string `json:"value" db:"value"`
+	Count int    `json:"count" db:"count"`
+}
+
+// BusinessSearchResult is the response of the public business search.
+type BusinessSearchResult struct {
+	Results    []BusinessSearchHit `json:"results"`
+	Industries []Facet             `json:"industries"`
+}
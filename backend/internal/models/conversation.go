@@ -2,12 +2,26 @@ package models
 
 import (
 	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Conversation roles of participants. The owner is the user who created a
+// group.
+const (
+	ParticipantOwner  = "owner"
+	ParticipantMember = "member"
 )
 
 type Conversation struct {
 	ID           int        `json:"id" db:"id"`
-	User1ID      int        `json:"user1_id" db:"user1_id"`
-	User2ID      int        `json:"user2_id" db:"user2_id"`
+	// User1ID and User2ID are the users of a direct conversation, with
+	// User1ID the lower. Both are nil for groups.
+	User1ID      *int       `json:"user1_id" db:"user1_id"`
+	User2ID      *int       `json:"user2_id" db:"user2_id"`
+	IsGroup      bool       `json:"is_group" db:"is_group"`
+	Title        *string    `json:"title,omitempty" db:"title"`
+	CreatedBy    *int       `json:"created_by,omitempty" db:"created_by"`
 	LastMessageAt *time.Time `json:"last_message_at,omitempty" db:"last_message_at"`
 	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
@@ -19,28 +33,166 @@ type Conversation struct {
 	User2Avatar  *string `json:"user2_avatar,omitempty" db:"user2_avatar"`
 	UnreadCount  int     `json:"unread_count" db:"unread_count"`
 	LastMessage  *string `json:"last_message,omitempty" db:"last_message"`
+
+	Participants []ConversationParticipant `json:"participants" db:"-"`
+}
+
+// Peer returns the other user of a direct conversation, or nil for groups.
+func (c *Conversation) Peer(userID int) *int {
+	if c.IsGroup || c.User1ID == nil || c.User2ID == nil {
+		return nil
+	}
+	if *c.User1ID == userID {
+		return c.User2ID
+	}
+	return c.User1ID
+}
+
+// HasParticipant reports whether userID is among the loaded participants.
+func (c *Conversation) HasParticipant(userID int) bool {
+	for _, p := range c.Participants {
+		if p.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// ParticipantIDs returns the user IDs of the loaded participants.
+func (c *Conversation) ParticipantIDs() []int {
+	ids := make([]int, len(c.Participants))
+	for i, p := range c.Participants {
+		ids[i] = p.UserID
+	}
+	return ids
+}
+
+// ConversationParticipant is a user's membership in a conversation. The
+// user has read the messages of others with IDs up to LastReadMessageID.
+type ConversationParticipant struct {
+	ConversationID    int       `json:"conversation_id" db:"conversation_id"`
+	UserID            int       `json:"user_id" db:"user_id"`
+	Role              string    `json:"role" db:"role"`
+	LastReadMessageID int       `json:"last_read_message_id" db:"last_read_message_id"`
+	JoinedAt          time.Time `json:"joined_at" db:"joined_at"`
+
+	// Join fields
+	UserName *string `json:"user_name,omitempty" db:"user_name"`
 }
 
 type Message struct {
 	ID            int       `json:"id" db:"id"`
 	ConversationID int      `json:"conversation_id" db:"conversation_id"`
 	SenderID      int       `json:"sender_id" db:"sender_id"`
-	ReceiverID    int       `json:"receiver_id" db:"receiver_id"`
+	// ReceiverID is the other user of a direct conversation, and nil in
+	// groups.
+	ReceiverID    *int      `json:"receiver_id" db:"receiver_id"`
 	Message       string    `json:"message" db:"message"`
-	MessageType   string    `json:"message_type" db:"message_type"` // "text", "image"
+	MessageType   string    `json:"message_type" db:"message_type"` // "text", "image", "file"
+	// ClientID is the sender's ID for the message, which makes retried
+	// sends idempotent.
+	ClientID      *string   `json:"client_id,omitempty" db:"client_id"`
 	IsRead        bool      `json:"is_read" db:"is_read"`
 	ReadAt        *time.Time `json:"read_at,omitempty" db:"read_at"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty" db:"delivered_at"`
+	EditedAt      *time.Time `json:"edited_at,omitempty" db:"edited_at"`
+	// DeletedAt is set when the sender deleted the message for everyone.
+	// Its text is then empty.
+	DeletedAt     *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
 	CreatedAt     time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
 	
 	// Join fields
 	SenderName    *string `json:"sender_name,omitempty" db:"sender_name"`
 	ReceiverName  *string `json:"receiver_name,omitempty" db:"receiver_name"`
+
+	Reactions   []Reaction   `json:"reactions,omitempty" db:"-"`
+	Attachments []Attachment `json:"attachments,omitempty" db:"-"`
+}
+
+// Reaction is the users who reacted to a message with an emoji.
+type Reaction struct {
+	Emoji   string `json:"emoji"`
+	Count   int    `json:"count"`
+	UserIDs []int  `json:"user_ids"`
+}
+
+// ValidMessageType reports whether t is a known message type.
+func ValidMessageType(t string) bool {
+	return t == "text" || t == "image" || t == "file"
+}
+
+// ValidEmoji reports whether s can be used as a reaction: a short symbol
+// such as "👍" or "1️⃣", without letters or spaces.
+func ValidEmoji(s string) bool {
+	if s == "" || len(s) > 32 || !utf8.ValidString(s) {
+		return false
+	}
+	symbol := false
+	for _, r := range s {
+		if unicode.IsSpace(r) || unicode.IsControl(r) || unicode.IsLetter(r) {
+			return false
+		}
+		symbol = symbol || r >= utf8.RuneSelf
+	}
+	return symbol
+}
+
+// MessageEdit is the text a message had before an edit.
+type MessageEdit struct {
+	ID        int       `json:"id" db:"id"`
+	MessageID int       `json:"message_id" db:"message_id"`
+	Message   string    `json:"message" db:"message"`
+	EditedAt  time.Time `json:"edited_at" db:"edited_at"`
+}
+
+// MessageSearchHit is a message matched by a search, with the conversation
+// it belongs to.
+type MessageSearchHit struct {
+	Message
+	// Snippet is the HTML-escaped text around the matches, each of which is
+	// wrapped in <mark> tags.
+	Snippet             string `json:"snippet" db:"snippet"`
+	ConversationIsGroup bool   `json:"conversation_is_group" db:"conversation_is_group"`
+	// ConversationTitle is a group's title, or the name of the other user
+	// of a direct conversation.
+	ConversationTitle *string `json:"conversation_title" db:"conversation_title"`
+}
+
+// HiddenMessage records that a user deleted a message for themselves.
+type HiddenMessage struct {
+	MessageID      int       `json:"message_id"`
+	ConversationID int       `json:"conversation_id"`
+	UserID         int       `json:"user_id"`
+	HiddenAt       time.Time `json:"hidden_at"`
+}
+
+// Message receipt statuses.
+const (
+	ReceiptDelivered = "delivered"
+	ReceiptRead      = "read"
+)
+
+// MessageReceipt reports that messages one user sent in a conversation were
+// delivered to or read by another participant, ReceiverID.
+type MessageReceipt struct {
+	Status         string    `json:"status"`
+	ConversationID int       `json:"conversation_id"`
+	SenderID       int       `json:"sender_id"`
+	ReceiverID     int       `json:"receiver_id"`
+	MessageIDs     []int     `json:"message_ids"`
+	At             time.Time `json:"at"`
 }
 
+// MessageCreate is the body of a sent message. It goes to ConversationID
+// when set, and otherwise to the direct conversation with ReceiverID.
+// AttachmentIDs are attachments the sender uploaded and hasn't sent yet.
 type MessageCreate struct {
 	ReceiverID int    `json:"receiver_id"`
+	ConversationID int `json:"conversation_id"`
 	Message    string `json:"message"`
 	MessageType string `json:"message_type"`
+	ClientID   string `json:"client_id" binding:"max=64"`
+	AttachmentIDs []int `json:"attachment_ids" binding:"max=10"`
 }
 
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Domain statuses. Only verified domains serve sites.
+const (
+	DomainPending  = "pending"
+	DomainVerified = "verified"
+)
+
+// DomainVerificationPrefix is prepended to a domain's name to get the name
+// of the TXT record proving control of it.
+const DomainVerificationPrefix = "_site-verification."
+
+// Domain is a host name attached to a business.
+type Domain struct {
+	ID         int    `json:"id" db:"id"`
+	BusinessID *int   `json:"business_id" db:"business_id"`
+	DomainName string `json:"domain_name" db:"domain_name"`
+	IsCustom   bool   `json:"is_custom" db:"is_custom"`
+	Status     string `json:"status" db:"status"`
+	// VerificationToken must be published in a TXT record at
+	// VerificationRecord to verify the domain.
+	VerificationToken string     `json:"verification_token" db:"verification_token"`
+	VerifiedAt        *time.Time `json:"verified_at,omitempty" db:"verified_at"`
+	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// VerificationRecord is the name of the TXT record that verifies the
+// domain.
+func (d *Domain) VerificationRecord() string {
+	return DomainVerificationPrefix + d.DomainName
+}
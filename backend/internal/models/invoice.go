@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+)
+
+// Invoice statuses.
+const (
+	InvoiceUnpaid = "unpaid"
+	InvoicePaid   = "paid"
+)
+
+type Invoice struct {
+	ID         int        `json:"id" db:"id"`
+	BusinessID int        `json:"business_id" db:"business_id"`
+	Amount     float64    `json:"amount" db:"amount"`
+	Status     string     `json:"status" db:"status"`
+	DueAt      *time.Time `json:"due_at,omitempty" db:"due_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// Payment settles an invoice.
+type Payment struct {
+	ID            int       `json:"id" db:"id"`
+	InvoiceID     int       `json:"invoice_id" db:"invoice_id"`
+	Amount        float64   `json:"amount" db:"amount"`
+	PaymentMethod *string   `json:"payment_method,omitempty" db:"payment_method"`
+	TransactionID *string   `json:"transaction_id,omitempty" db:"transaction_id"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+}
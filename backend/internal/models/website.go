@@ -5,15 +5,67 @@ import (
 )
 
 type Website struct {
-	ID         int       `json:"id" db:"id"`
-	BusinessID *int      `json:"business_id,omitempty" db:"business_id"`
-	Title      string    `json:"title" db:"title"`
-	URL        *string   `json:"url,omitempty" db:"url"`
-	ImageURL   *string   `json:"image_url,omitempty" db:"image_url"`
-	ThemeName  string    `json:"theme_name" db:"theme_name"`
-	IsDemo     bool      `json:"is_demo" db:"is_demo"`
-	IsClaimed  bool      `json:"is_claimed" db:"is_claimed"`
-	Status     string    `json:"status" db:"status"`
-	CreatedAt  time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+	ID         int     `json:"id" db:"id"`
+	BusinessID *int    `json:"business_id,omitempty" db:"business_id"`
+	Title      string  `json:"title" db:"title"`
+	URL        *string `json:"url,omitempty" db:"url"`
+	ImageURL   *string `json:"image_url,omitempty" db:"image_url"`
+	ThemeName  string  `json:"theme_name" db:"theme_name"`
+	// ThemeSettings holds the values chosen for settings of the theme, by
+	// key; see themes.Setting.
+	ThemeSettings JSONB     `json:"theme_settings" db:"theme_settings"`
+	IsDemo        bool      `json:"is_demo" db:"is_demo"`
+	IsClaimed     bool      `json:"is_claimed" db:"is_claimed"`
+	Status        string    `json:"status" db:"status"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+	// PublishedRevision is the revision visitors see; the fields above are
+	// the draft.
+	PublishedRevision *int       `json:"published_revision,omitempty" db:"published_revision"`
+	PublishedAt       *time.Time `json:"published_at,omitempty" db:"published_at"`
+	// Thumbnails maps thumbnail sizes, e.g. "small", to image URLs. They
+	// are generated from the published revision; clients show the medium
+	// one for websites without an ImageURL.
+	Thumbnails JSONB `json:"thumbnails,omitempty" db:"thumbnails"`
+}
+
+// WebsiteUpdate is the body of a website update: the draft fields owners
+// edit. Ownership, demo and review status change only by claiming and
+// publishing.
+type WebsiteUpdate struct {
+	Title         string  `json:"title" binding:"required"`
+	URL           *string `json:"url"`
+	ImageURL      *string `json:"image_url"`
+	ThemeName     string  `json:"theme_name"`
+	ThemeSettings JSONB   `json:"theme_settings"`
+}
+
+// WebsitePage is a page of a website. Content holds its sections; see
+// package content for the schema.
+type WebsitePage struct {
+	ID        int       `json:"id" db:"id"`
+	WebsiteID int       `json:"website_id" db:"website_id"`
+	Slug      string    `json:"slug" db:"slug"`
+	Title     string    `json:"title" db:"title"`
+	Position  int       `json:"position" db:"position"`
+	Content   JSONB     `json:"content" db:"content"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// WebsiteRevision is a saved state of a website's editable content.
+type WebsiteRevision struct {
+	ID        int       `json:"id" db:"id"`
+	WebsiteID int       `json:"website_id" db:"website_id"`
+	Revision  int       `json:"revision" db:"revision"`
+	Snapshot  JSONB     `json:"snapshot" db:"snapshot"`
+	CreatedBy *int      `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// RevisionChange is one field that differs between two revisions.
+type RevisionChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
 }
@@ -0,0 +1,167 @@
+// Package render turns the content of a website into a static HTML site
+// using the server-side themes.
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"saas-management-api/internal/content"
+	"saas-management-api/internal/store"
+	"saas-management-api/internal/themes"
+)
+
+// IndexFile is the file each page of a site is rendered to.
+const IndexFile = "index.html"
+
+// Files maps the paths of a static site, e.g. "index.html" or
+// "about/index.html", to their contents.
+type Files map[string][]byte
+
+// Renderer renders sites with the themes of a registry.
+type Renderer struct {
+	Themes *themes.Registry
+}
+
+func New(registry *themes.Registry) *Renderer {
+	return &Renderer{Themes: registry}
+}
+
+type layoutData struct {
+	Site       siteData
+	Page       pageData
+	Nav        []navLink
+	Sections   []template.HTML
+	Settings   map[string]string
+	Stylesheet template.CSS
+	// Root is the relative path from the page back to the site root, so the
+	// site works wherever it is served from.
+	Root string
+	Year int
+}
+
+type siteData struct {
+	Title string
+}
+
+type pageData struct {
+	Title string
+	Slug  string
+}
+
+type navLink struct {
+	Title   string
+	Href    string
+	Current bool
+}
+
+// Site renders every page of site. The first page by position becomes the
+// home page at index.html, the others live at <slug>/index.html. A site
+// without pages gets a home page with a hero showing its title. Sites whose
+// theme is unknown fall back to the default theme, settings the site did not
+// choose take the theme's defaults, and sections the theme does not support
+// are left out.
+func (r *Renderer) Site(site *store.SiteContent) (Files, error) {
+	theme := r.Themes.GetOrDefault(site.ThemeName)
+	settings := theme.Resolve(site.ThemeSettings)
+
+	pages := append([]store.PageContent(nil), site.Pages...)
+	sort.SliceStable(pages, func(i, j int) bool { return pages[i].Position < pages[j].Position })
+	documents := make([]*content.Document, len(pages))
+	for i, page := range pages {
+		doc, err := content.Parse(page.Content)
+		if err != nil {
+			return nil, fmt.Errorf("render: page %q: %w", page.Slug, err)
+		}
+		documents[i] = doc
+	}
+	if len(pages) == 0 {
+		pages = []store.PageContent{{Title: site.Title}}
+		documents = []*content.Document{{Sections: []content.Section{{
+			Type: content.TypeHero,
+			Hero: &content.Hero{Heading: site.Title},
+		}}}}
+	}
+
+	files := Files{}
+	year := time.Now().Year()
+	for i, page := range pages {
+		root := "./"
+		file := IndexFile
+		if i > 0 {
+			root = "../"
+			file = path.Join(page.Slug, IndexFile)
+		}
+
+		data := layoutData{
+			Site:       siteData{Title: site.Title},
+			Page:       pageData{Title: page.Title, Slug: page.Slug},
+			Settings:   settings,
+			Stylesheet: theme.Stylesheet(),
+			Root:       root,
+			Year:       year,
+		}
+		for j, other := range pages {
+			href := root
+			if j > 0 {
+				href += other.Slug + "/"
+			}
+			data.Nav = append(data.Nav, navLink{Title: other.Title, Href: href, Current: i == j})
+		}
+		for _, section := range documents[i].Sections {
+			if !theme.Supports(section.Type) {
+				continue
+			}
+			html, err := renderSection(theme, section)
+			if err != nil {
+				return nil, fmt.Errorf("render: page %q: %w", page.Slug, err)
+			}
+			data.Sections = append(data.Sections, html)
+		}
+
+		var buf bytes.Buffer
+		if err := theme.Execute(&buf, "layout", data); err != nil {
+			return nil, fmt.Errorf("render: page %q: %w", page.Slug, err)
+		}
+		files[file] = buf.Bytes()
+	}
+	return files, nil
+}
+
+func renderSection(theme *themes.Theme, section content.Section) (template.HTML, error) {
+	var data any
+	switch section.Type {
+	case content.TypeHero:
+		data = section.Hero
+	case content.TypeGallery:
+		data = section.Gallery
+	case content.TypeContactForm:
+		data = section.ContactForm
+	case content.TypePricing:
+		data = section.Pricing
+	}
+	var buf strings.Builder
+	if err := theme.Execute(&buf, "section_"+section.Type, data); err != nil {
+		return "", err
+	}
+	// The section template escaped its own output.
+	return template.HTML(buf.String()), nil
+}
+
+// Lookup returns the file serving the request path p of a site, e.g.
+// "about/index.html" for "/about/", and its contents.
+func (f Files) Lookup(p string) (string, []byte, bool) {
+	file := strings.Trim(path.Clean("/"+p), "/")
+	if file == "" {
+		file = IndexFile
+	} else if path.Base(file) != IndexFile {
+		file = path.Join(file, IndexFile)
+	}
+	data, ok := f[file]
+	return file, data, ok
+}
@@ -0,0 +1,29 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	"saas-management-api/internal/models"
+	"saas-management-api/internal/store"
+	"saas-management-api/internal/themes"
+)
+
+func TestSiteUsesThemeSettings(t *testing.T) {
+	r := New(themes.Builtin())
+	files, err := r.Site(&store.SiteContent{
+		Title:         "Acme",
+		ThemeName:     "default",
+		ThemeSettings: models.JSONB{"primary_color": "#ff0000"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	home := string(files[IndexFile])
+	if !strings.Contains(home, "--primary: #ff0000") {
+		t.Errorf("home page does not use the chosen primary color:\n%s", home)
+	}
+	if !strings.Contains(home, "--background: #ffffff") {
+		t.Errorf("home page does not use the default background color:\n%s", home)
+	}
+}
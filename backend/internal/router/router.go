@@ -4,17 +4,24 @@ import (
 	"log"
 	"saas-management-api/internal/config"
 	"saas-management-api/internal/database"
+	"saas-management-api/internal/events"
 	"saas-management-api/internal/handlers"
 	"saas-management-api/internal/middleware"
+	"saas-management-api/internal/render"
+	"saas-management-api/internal/store"
+	"saas-management-api/internal/themes"
+	"saas-management-api/internal/thumbnails"
 	"saas-management-api/internal/ws"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/jmoiron/sqlx"
+	"golang.org/x/crypto/acme/autocert"
 )
 
-func SetupRouter(db *sqlx.DB, cfg *config.Config) *gin.Engine {
-	r := gin.Default()
+// SetupRouter returns the JSON API. Handlers publish domain events on bus.
+func SetupRouter(db *sqlx.DB, cfg *config.Config, bus *events.Bus) *gin.Engine {
+	r := newEngine(cfg)
 
 	// Serve uploaded files
 	r.Static("/uploads", "./uploads")
@@ -24,23 +31,28 @@ func SetupRouter(db *sqlx.DB, cfg *config.Config) *gin.Engine {
 		AllowOrigins:     []string{"http://localhost:3000", "http://localhost:5173"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		ExposeHeaders:    []string{"Content-Length", "Link", "X-Next-Cursor", "X-Prev-Cursor"},
 		AllowCredentials: true,
 	}))
 
 	// Initialize handlers
-	dbWrapper := database.NewDB(db)
-	authHandler := handlers.NewAuthHandler(dbWrapper)
-	businessHandler := handlers.NewBusinessHandler(dbWrapper)
-	websiteHandler := handlers.NewWebsiteHandler(dbWrapper)
-	planHandler := handlers.NewPlanHandler(dbWrapper)
-	subscriptionHandler := handlers.NewSubscriptionHandler(dbWrapper)
-	notificationHandler := handlers.NewNotificationHandler(dbWrapper)
-	messageHandler := handlers.NewMessageHandler(dbWrapper)
+	st := store.NewPostgres(database.NewDB(db))
+	authHandler := handlers.NewAuthHandler(st)
+	businessHandler := handlers.NewBusinessHandler(st, bus)
+	websiteHandler := handlers.NewWebsiteHandler(st, themes.Builtin(), setupThumbnails(st, cfg), bus)
+	pageHandler := handlers.NewPageHandler(st)
+	planHandler := handlers.NewPlanHandler(st)
+	subscriptionHandler := handlers.NewSubscriptionHandler(st, bus)
+	invoiceHandler := handlers.NewInvoiceHandler(st, bus)
+	notificationHandler := handlers.NewNotificationHandler(st, bus)
+	messageHandler := handlers.NewMessageHandler(st, bus)
+	attachmentHandler := handlers.NewAttachmentHandler(st, "./attachments")
 	fileHandler := handlers.NewFileHandler()
+	siteHandler := handlers.NewSiteHandler(st, themes.Builtin(), cfg.SiteBaseDomain, bus)
+	domainHandler := handlers.NewDomainHandler(st, cfg.SiteBaseDomain)
 
 	// Initialize WebSocket
-	hub := ws.NewHub(dbWrapper)
+	hub := ws.NewHub(st, cfg.QueryTimeout, ws.NewPostgresBackplane(db, cfg.DatabaseURL), bus)
 	go hub.Run()
 	wsHandler := handlers.NewWsHandler(hub)
 
@@ -48,6 +60,7 @@ func SetupRouter(db *sqlx.DB, cfg *config.Config) *gin.Engine {
 
 	// Public routes
 	api := r.Group("/api")
+	api.Use(middleware.QueryTimeout(cfg))
 	{
 		// Auth routes
 		auth := api.Group("/auth")
@@ -57,9 +70,19 @@ func SetupRouter(db *sqlx.DB, cfg *config.Config) *gin.Engine {
 		}
 
 		// Public business search
-		api.GET("/businesses/search", businessHandler.List)
+		api.GET("/businesses/search", businessHandler.Search)
+		api.GET("/businesses/by-slug/:slug", businessHandler.GetBySlug)
 		api.GET("/websites/demos", websiteHandler.List)
 		api.GET("/plans", planHandler.List)
+		api.GET("/themes", siteHandler.ListThemes)
+	}
+
+	// Server-rendered previews, outside the JSON API. Demos are public,
+	// other websites need a preview link from /api/websites/:id/preview.
+	preview := r.Group("/preview")
+	preview.Use(middleware.QueryTimeout(cfg))
+	{
+		preview.GET("/:id/*path", siteHandler.Preview)
 	}
 
 	// Protected routes
@@ -79,6 +102,16 @@ func SetupRouter(db *sqlx.DB, cfg *config.Config) *gin.Engine {
 			businesses.POST("", businessHandler.Create)
 			businesses.PUT("/:id", businessHandler.Update)
 			businesses.DELETE("/:id", businessHandler.Delete)
+			businesses.GET("/:id/domains", domainHandler.List)
+			businesses.POST("/:id/domains", domainHandler.Create)
+			businesses.GET("/:id/invoices", invoiceHandler.List)
+		}
+
+		// Custom domain routes
+		domains := protected.Group("/domains")
+		{
+			domains.POST("/:id/verify", domainHandler.Verify)
+			domains.DELETE("/:id", domainHandler.Delete)
 		}
 
 		// Website routes
@@ -89,6 +122,19 @@ func SetupRouter(db *sqlx.DB, cfg *config.Config) *gin.Engine {
 			websites.POST("", websiteHandler.Create)
 			websites.PUT("/:id", websiteHandler.Update)
 			websites.DELETE("/:id", websiteHandler.Delete)
+			websites.POST("/:id/claim", websiteHandler.Claim)
+			websites.POST("/:id/publish", websiteHandler.Publish)
+			websites.GET("/:id/revisions", websiteHandler.ListRevisions)
+			websites.GET("/:id/diff", websiteHandler.Diff)
+			websites.POST("/:id/rollback/:rev", websiteHandler.Rollback)
+			websites.GET("/:id/analytics", websiteHandler.Analytics)
+			websites.POST("/:id/preview", siteHandler.PreviewLink)
+
+			websites.GET("/:id/pages", pageHandler.List)
+			websites.POST("/:id/pages", pageHandler.Create)
+			websites.GET("/:id/pages/:pageId", pageHandler.Get)
+			websites.PUT("/:id/pages/:pageId", pageHandler.Update)
+			websites.DELETE("/:id/pages/:pageId", pageHandler.Delete)
 		}
 
 		// Subscription routes
@@ -117,9 +163,22 @@ func SetupRouter(db *sqlx.DB, cfg *config.Config) *gin.Engine {
 		{
 			messages.GET("", messageHandler.ListAll)
 			messages.GET("/conversations", messageHandler.ListConversations)
+			messages.GET("/search", messageHandler.SearchMessages)
 			messages.POST("/conversations", messageHandler.GetOrCreateConversation)
 			messages.GET("/conversations/:id/messages", messageHandler.GetMessages)
+			messages.POST("/conversations/:id/read", messageHandler.MarkRead)
+			messages.POST("/groups", messageHandler.CreateGroup)
+			messages.PUT("/conversations/:id", messageHandler.RenameGroup)
+			messages.POST("/conversations/:id/participants", messageHandler.AddParticipants)
+			messages.DELETE("/conversations/:id/participants/:userId", messageHandler.RemoveParticipant)
 			messages.POST("/send", messageHandler.SendMessage)
+			messages.POST("/attachments", attachmentHandler.Upload)
+			messages.GET("/attachments/:id", attachmentHandler.Download)
+			messages.PUT("/:id", messageHandler.EditMessage)
+			messages.DELETE("/:id", messageHandler.DeleteMessage)
+			messages.GET("/:id/edits", messageHandler.MessageEdits)
+			messages.POST("/:id/reactions", messageHandler.AddReaction)
+			messages.DELETE("/:id/reactions/:emoji", messageHandler.RemoveReaction)
 			messages.GET("/unread-count", messageHandler.GetUnreadCount)
 		}
 	}
@@ -137,8 +196,73 @@ func SetupRouter(db *sqlx.DB, cfg *config.Config) *gin.Engine {
 			plans.PUT("/:id", planHandler.Update)
 			plans.DELETE("/:id", planHandler.Delete)
 		}
+
+		// Business review queue
+		adminBusinesses := admin.Group("/businesses")
+		{
+			adminBusinesses.GET("/pending", businessHandler.ListPending)
+			adminBusinesses.POST("/:id/approve", businessHandler.Approve)
+			adminBusinesses.POST("/:id/reject", businessHandler.Reject)
+		}
+
+		// Billing
+		invoices := admin.Group("/invoices")
+		{
+			invoices.POST("", invoiceHandler.Create)
+			invoices.POST("/:id/pay", invoiceHandler.Pay)
+		}
 	}
 
 	return r
 }
 
+// setupThumbnails starts the thumbnail pipeline, or returns nil when no
+// headless browser is available.
+func setupThumbnails(st *store.Store, cfg *config.Config) *thumbnails.Pipeline {
+	chrome, ok := thumbnails.FindChrome(cfg.ChromePath)
+	if !ok {
+		log.Println("No Chrome or Chromium found, website thumbnails are disabled")
+		return nil
+	}
+	pipeline := thumbnails.NewPipeline(st,
+		render.New(themes.Builtin()),
+		&thumbnails.Chrome{Path: chrome, BaseURL: cfg.ThumbnailBaseURL},
+		thumbnails.Dir{Path: "./uploads/thumbnails", URLPrefix: "/uploads/thumbnails"},
+	)
+	go pipeline.Run()
+	return pipeline
+}
+
+// SetupSites returns the public entry point for published websites. It is
+// served on its own port, apart from the API, and routes by Host header:
+// verified custom domains and <slug>.<SiteBaseDomain> subdomains. With a
+// certificate manager it also answers the manager's ACME challenges. Rendered
+// sites are evicted from its cache on the WebsitePublished events of bus.
+func SetupSites(db *sqlx.DB, cfg *config.Config, certManager *autocert.Manager, bus *events.Bus) *gin.Engine {
+	r := newEngine(cfg)
+	if certManager != nil {
+		r.Use(middleware.ACMEChallenges(certManager))
+	}
+	r.Use(middleware.QueryTimeout(cfg))
+
+	st := store.NewPostgres(database.NewDB(db))
+	siteHandler := handlers.NewSiteHandler(st, themes.Builtin(), cfg.SiteBaseDomain, bus)
+	r.GET("/*path", siteHandler.Serve)
+	r.HEAD("/*path", siteHandler.Serve)
+	r.POST("/_collect", siteHandler.Collect)
+
+	return r
+}
+
+// newEngine returns an engine that takes client IPs from X-Forwarded-For
+// only on connections from cfg.TrustedProxies, so clients cannot spoof
+// them, and keeps tokens in query strings out of its request log.
+func newEngine(cfg *config.Config) *gin.Engine {
+	r := gin.New()
+	r.Use(middleware.Logger(), gin.Recovery())
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Printf("Invalid TRUSTED_PROXIES, trusting none: %v", err)
+		r.SetTrustedProxies(nil)
+	}
+	return r
+}
@@ -0,0 +1,98 @@
+// Package slug turns display names into URL-safe identifiers.
+package slug
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Fallback is the business slug Make uses when nothing of a name survives
+// transliteration. Other slugs pass their own fallback to MakeOr.
+const Fallback = "business"
+
+// MaxLength keeps generated slugs, including collision suffixes, within the
+// businesses.slug column.
+const MaxLength = 200
+
+// transliterations covers letters that Unicode does not decompose into an
+// ASCII base letter plus marks.
+var transliterations = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d", 'ð': "d", 'ł': "l", 'þ': "th", 'ı': "i",
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh", 'з': "z",
+	'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r",
+	'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya", 'є': "ye", 'і': "i", 'ї': "yi", 'ґ': "g",
+}
+
+// Make lowercases name, transliterates it to ASCII and joins the remaining
+// words with hyphens, e.g. "Café Müller & Söhne" becomes
+// "cafe-muller-sohne".
+func Make(name string) string {
+	return MakeOr(name, Fallback)
+}
+
+// MakeOr is Make with fallback returned when nothing of name survives
+// transliteration.
+func MakeOr(name, fallback string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range norm.NFKD.String(strings.ToLower(name)) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		s, ok := transliterations[r]
+		if !ok {
+			s = string(r)
+		}
+		for _, c := range s {
+			if c < unicode.MaxASCII && (unicode.IsLetter(c) || unicode.IsDigit(c)) {
+				if hyphen && b.Len() > 0 {
+					b.WriteByte('-')
+				}
+				hyphen = false
+				b.WriteRune(c)
+			} else {
+				hyphen = true
+			}
+		}
+	}
+
+	slug := b.String()
+	if len(slug) > MaxLength {
+		slug = strings.TrimRight(slug[:MaxLength], "-")
+	}
+	if slug == "" {
+		return fallback
+	}
+	return slug
+}
+
+// WithSuffix returns the n-th alternative of base, e.g. "acme-2" for n = 2.
+// n = 1 is base itself.
+func WithSuffix(base string, n int) string {
+	if n <= 1 {
+		return base
+	}
+	return base + "-" + strconv.Itoa(n)
+}
+
+// FirstFree returns the first alternative of base not in taken.
+func FirstFree(base string, taken []string) string {
+	used := make(map[string]bool, len(taken))
+	for _, s := range taken {
+		used[s] = true
+	}
+	for n := 1; ; n++ {
+		if candidate := WithSuffix(base, n); !used[candidate] {
+			return candidate
+		}
+	}
+}
+
+// Valid reports whether s is already in the form Make produces.
+func Valid(s string) bool {
+	return s != "" && len(s) <= MaxLength && Make(s) == s
+}
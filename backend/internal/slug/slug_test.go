@@ -0,0 +1,73 @@
+package slug
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"Café Müller & Söhne", "cafe-muller-sohne"},
+		{"  Straße  der Einheit ", "strasse-der-einheit"},
+		{"Москва", "moskva"},
+		{"!!!", Fallback},
+		{"", Fallback},
+	}
+	for _, tt := range tests {
+		if got := Make(tt.name); got != tt.want {
+			t.Errorf("Make(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMakeOr(t *testing.T) {
+	if got := MakeOr("日本語", "page"); got != "page" {
+		t.Errorf("MakeOr(%q, page) = %q, want page", "日本語", got)
+	}
+	if got := MakeOr("About us", "page"); got != "about-us" {
+		t.Errorf("MakeOr(%q, page) = %q, want about-us", "About us", got)
+	}
+}
+
+func TestFirstFree(t *testing.T) {
+	for _, tt := range []struct {
+		taken []string
+		want  string
+	}{
+		{nil, "acme"},
+		{[]string{"acme"}, "acme-2"},
+		{[]string{"acme", "acme-2", "acme-3"}, "acme-4"},
+		{[]string{"acme", "acme-3"}, "acme-2"},
+		{[]string{"acme-2"}, "acme"},
+	} {
+		if got := FirstFree("acme", tt.taken); got != tt.want {
+			t.Errorf("FirstFree(acme, %v) = %q, want %q", tt.taken, got, tt.want)
+		}
+	}
+}
+
+func TestValid(t *testing.T) {
+	long := strings.Repeat("a", MaxLength)
+	for _, tt := range []struct {
+		slug string
+		want bool
+	}{
+		{"acme-2", true},
+		{long, true},
+		{long + "a", false},
+		{"", false},
+		{"Acme", false},
+		{"acme--2", false},
+		{"-acme", false},
+		{"café", false},
+	} {
+		if got := Valid(tt.slug); got != tt.want {
+			t.Errorf("Valid(%q) = %v, want %v", tt.slug, got, tt.want)
+		}
+	}
+	if got := Make(long + "-b"); got != long {
+		t.Errorf("Make of a long name = %q, want it cut to %d characters", got, MaxLength)
+	}
+}
@@ -0,0 +1,143 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"saas-management-api/internal/models"
+)
+
+// AnalyticsTopLimit is how many pages, referrers and events a report
+// lists.
+const AnalyticsTopLimit = 10
+
+const (
+	maxAnalyticsPath  = 500
+	maxAnalyticsEvent = 100
+)
+
+// AnalyticsHit is a pageview or event reported by a visitor's browser.
+type AnalyticsHit struct {
+	WebsiteID int
+	// Event names a custom event; empty for a pageview.
+	Event string
+	Path  string
+	// Referrer is the URL of the page the visitor came from, if any.
+	Referrer string
+	// Host is the host the site was served on. Referrers from it are
+	// navigation within the site.
+	Host      string
+	IP        string
+	UserAgent string
+	At        time.Time
+}
+
+// RecordHit counts hit in the daily rollups. No cookie or raw visitor data
+// is stored: visitors are told apart by a hash of their IP address and user
+// agent salted with a random value that is replaced every day. The visitor
+// and the rollups are updated in one transaction, so a failed hit counts
+// nowhere.
+func (s *Store) RecordHit(ctx context.Context, hit AnalyticsHit) error {
+	day := AnalyticsDay(hit.At)
+	fresh := make([]byte, 32)
+	if _, err := rand.Read(fresh); err != nil {
+		return err
+	}
+	return s.WithTx(ctx, func(tx *Store) error {
+		return tx.recordHit(ctx, hit, day, fresh)
+	})
+}
+
+func (s *Store) recordHit(ctx context.Context, hit AnalyticsHit, day time.Time, fresh []byte) error {
+	salt, err := s.Analytics.Salt(ctx, day, fresh)
+	if err != nil {
+		return err
+	}
+
+	hash := sha256.New()
+	hash.Write(salt)
+	hash.Write([]byte(strconv.Itoa(hit.WebsiteID) + "\x00" + hit.IP + "\x00" + hit.UserAgent))
+	record := AnalyticsRecord{
+		WebsiteID: hit.WebsiteID,
+		Day:       day,
+		Visitor:   hex.EncodeToString(hash.Sum(nil))[:32],
+		Event:     truncate(strings.TrimSpace(hit.Event), maxAnalyticsEvent),
+	}
+	if record.Event == "" {
+		record.Path = analyticsPath(hit.Path)
+		referrer := referrerHost(hit.Referrer)
+		if !strings.EqualFold(referrer, hit.Host) {
+			record.Visit = true
+			record.Referrer = referrer
+		}
+	}
+	return s.Analytics.Record(ctx, record)
+}
+
+// AnalyticsReport summarizes the traffic of a website from from to to,
+// inclusive.
+func (s *Store) AnalyticsReport(ctx context.Context, websiteID int, from, to time.Time) (*models.AnalyticsReport, error) {
+	report := &models.AnalyticsReport{From: from.Format(time.DateOnly), To: to.Format(time.DateOnly)}
+	var err error
+	if report.Days, err = s.Analytics.Days(ctx, websiteID, from, to); err != nil {
+		return nil, err
+	}
+	for _, day := range report.Days {
+		report.Pageviews += day.Pageviews
+		report.Visits += day.Visits
+		report.DailyVisitors += day.Visitors
+		report.Events += day.Events
+	}
+	if report.TopPages, err = s.Analytics.Top(ctx, websiteID, models.DimensionPage, from, to, AnalyticsTopLimit); err != nil {
+		return nil, err
+	}
+	if report.Referrers, err = s.Analytics.Top(ctx, websiteID, models.DimensionReferrer, from, to, AnalyticsTopLimit); err != nil {
+		return nil, err
+	}
+	if report.TopEvents, err = s.Analytics.Top(ctx, websiteID, models.DimensionEvent, from, to, AnalyticsTopLimit); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// AnalyticsDay returns the UTC day analytics count t in.
+func AnalyticsDay(t time.Time) time.Time {
+	year, month, day := t.UTC().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// analyticsPath keeps the path of a URL and drops query strings, which
+// often carry personal data.
+func analyticsPath(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Path == "" {
+		return "/"
+	}
+	path := u.Path
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return truncate(path, maxAnalyticsPath)
+}
+
+// referrerHost returns the host of a referring URL, or "" if there is none.
+func referrerHost(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(truncate(u.Hostname(), maxAnalyticsPath))
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
@@ -0,0 +1,125 @@
+package store
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"saas-management-api/internal/models"
+)
+
+// newSearchBusinesses stores approved businesses, one with a website,
+// along with a pending business named like the first.
+func newSearchBusinesses(t *testing.T, st *Store) {
+	t.Helper()
+	ctx := context.Background()
+	text := func(s string) *string { return &s }
+	for _, b := range []models.Business{
+		{Name: "Bakery Bliss", Industry: text("food"), Description: text("Sourdough and pastries"), Address: text("1 Main St")},
+		{Name: "Pastry Palace", Industry: text("food"), Description: text("Cakes")},
+		{Name: "Main Street Motors", Industry: text("automotive"), Description: text("We fix bakery vans too")},
+		{Name: "Urban Yoga", Industry: text("fitness"), Address: text("9 Bakery Lane")},
+		{Name: "Bakery Pending", Industry: text("food"), Status: models.BusinessPending},
+	} {
+		if b.Status == "" {
+			b.Status = models.BusinessApproved
+		}
+		if err := st.CreateBusiness(ctx, &b); err != nil {
+			t.Fatal(err)
+		}
+		if b.Name == "Urban Yoga" {
+			website := &models.Website{Title: "Zen Studio", BusinessID: &b.ID}
+			if err := st.Websites.Create(ctx, website); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func searchNames(result *models.BusinessSearchResult) []string {
+	names := []string{}
+	for _, hit := range result.Results {
+		names = append(names, hit.Name)
+	}
+	return names
+}
+
+func TestBusinessSearch(t *testing.T) {
+	st := NewMemory()
+	newSearchBusinesses(t, st)
+
+	for _, tt := range []struct {
+		name   string
+		search BusinessSearch
+		want   []string
+	}{
+		// Name matches outrank description and address matches.
+		{"ranked by field", BusinessSearch{Query: "bakery"}, []string{"Bakery Bliss", "Main Street Motors", "Urban Yoga"}},
+		{"prefixes", BusinessSearch{Query: "bak pas"}, []string{"Bakery Bliss"}},
+		{"website titles", BusinessSearch{Query: "zen"}, []string{"Urban Yoga"}},
+		{"every term must match", BusinessSearch{Query: "bakery cakes"}, []string{}},
+		{"typo", BusinessSearch{Query: "pastyr"}, []string{"Pastry Palace"}},
+		{"operators are separators", BusinessSearch{Query: "bakery & !(bliss)"}, []string{"Bakery Bliss"}},
+		{"industry filter", BusinessSearch{Query: "bakery", Industry: "food"}, []string{"Bakery Bliss"}},
+		{"limit", BusinessSearch{Query: "bakery", Limit: 1}, []string{"Bakery Bliss"}},
+	} {
+		search := tt.search
+		search.Status = models.BusinessApproved
+		if search.Limit == 0 {
+			search.Limit = 20
+		}
+		result, err := st.Businesses.Search(context.Background(), search)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := searchNames(result); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Search(%q) = %v, want %v", tt.name, tt.search.Query, got, tt.want)
+		}
+	}
+}
+
+func TestBusinessSearchFacets(t *testing.T) {
+	st := NewMemory()
+	newSearchBusinesses(t, st)
+
+	// The facets count every approved hit, whatever the industry filter.
+	result, err := st.Businesses.Search(context.Background(), BusinessSearch{Query: "bakery", Industry: "food", Status: models.BusinessApproved, Limit: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []models.Facet{{Value: "automotive", Count: 1}, {Value: "fitness", Count: 1}, {Value: "food", Count: 1}}
+	if !reflect.DeepEqual(result.Industries, want) {
+		t.Fatalf("industries = %+v, want %+v", result.Industries, want)
+	}
+
+	// Without a query every business matches, most common industry first.
+	result, err = st.Businesses.Search(context.Background(), BusinessSearch{Status: models.BusinessApproved, Limit: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Results) != 4 || result.Industries[0] != (models.Facet{Value: "food", Count: 2}) {
+		t.Fatalf("unfiltered search = %v with %+v", searchNames(result), result.Industries)
+	}
+}
+
+func TestWithinOneEdit(t *testing.T) {
+	for _, tt := range []struct {
+		a, b string
+		want bool
+	}{
+		{"bakery", "bakery", true},
+		{"bakery", "bakry", true},
+		{"bakery", "bakeery", true},
+		{"bakery", "bakerx", true},
+		{"bakery", "abkery", true},
+		{"bakery", "bkaery", true},
+		{"bakery", "bkry", false},
+		{"bakery", "abkeyr", false},
+		{"café", "cafe", true},
+		{"", "a", true},
+	} {
+		if got := withinOneEdit(tt.a, tt.b); got != tt.want {
+			t.Errorf("withinOneEdit(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
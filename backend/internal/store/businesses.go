@@ -0,0 +1,163 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"saas-management-api/internal/models"
+	"saas-management-api/internal/slug"
+)
+
+// CreateBusiness inserts business under a unique slug. The slug is derived
+// from the requested Slug, or from Name when none was given, and suffixed
+// with -2, -3, ... when taken by another business now or in the past. A
+// business without a status awaits review.
+func (s *Store) CreateBusiness(ctx context.Context, business *models.Business) error {
+	if business.Status == "" {
+		business.Status = models.BusinessPending
+	}
+	return s.WithTx(ctx, func(tx *Store) error {
+		source := business.Slug
+		if source == "" {
+			source = business.Name
+		}
+		free, err := tx.freeSlug(ctx, slug.Make(source), 0)
+		if err != nil {
+			return err
+		}
+		business.Slug = free
+		return tx.Businesses.Create(ctx, business)
+	})
+}
+
+// UpdateBusiness updates business and keeps its slug in step:
+//
+//   - an explicitly requested new slug is normalized and must be free,
+//     otherwise ErrConflict is returned;
+//   - a slug that was generated from the old name follows a rename;
+//   - any other slug is kept.
+//
+// A replaced slug is recorded so it keeps resolving to the business. An
+// empty Status keeps the current one, except that material edits to an
+// approved business send it back to review as pending.
+func (s *Store) UpdateBusiness(ctx context.Context, business *models.Business) error {
+	return s.WithTx(ctx, func(tx *Store) error {
+		existing, err := tx.Businesses.Get(ctx, business.ID)
+		if err != nil {
+			return err
+		}
+		if business.Status == "" {
+			business.Status = existing.Status
+			if existing.Status == models.BusinessApproved && materiallyChanged(existing, business) {
+				business.Status = models.BusinessPending
+			}
+		}
+
+		requested := existing.Slug
+		if business.Slug != "" {
+			requested = slug.Make(business.Slug)
+		}
+		switch {
+		case requested != existing.Slug:
+			taken, err := tx.Businesses.TakenSlugs(ctx, requested, business.ID)
+			if err != nil {
+				return err
+			}
+			if slices.Contains(taken, requested) {
+				return fmt.Errorf("%w: slug %q already taken", ErrConflict, requested)
+			}
+			business.Slug = requested
+		case business.Name != existing.Name && generatedFrom(existing.Slug, slug.Make(existing.Name)):
+			business.Slug, err = tx.freeSlug(ctx, slug.Make(business.Name), business.ID)
+			if err != nil {
+				return err
+			}
+		default:
+			business.Slug = existing.Slug
+		}
+
+		if business.Slug != existing.Slug {
+			if err := tx.Businesses.RecordSlugChange(ctx, business.ID, existing.Slug, business.Slug); err != nil {
+				return err
+			}
+		}
+		return tx.Businesses.Update(ctx, business)
+	})
+}
+
+// ReviewBusiness approves or rejects a business and notifies its owner of
+// the decision and reason. The notification is nil for businesses without an
+// owner. Reviewing a business into the status it already has returns
+// ErrConflict.
+func (s *Store) ReviewBusiness(ctx context.Context, id int, review BusinessReview) (*models.Business, *models.Notification, error) {
+	var business *models.Business
+	var notification *models.Notification
+	err := s.WithTx(ctx, func(tx *Store) error {
+		existing, err := tx.Businesses.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		if existing.Status == review.Status {
+			return fmt.Errorf("%w: business is already %s", ErrConflict, review.Status)
+		}
+
+		business, err = tx.Businesses.SetReview(ctx, id, review)
+		if err != nil || business.UserID == nil {
+			return err
+		}
+
+		subject := fmt.Sprintf("Your business %q was %s", business.Name, review.Status)
+		notification = &models.Notification{
+			UserID:     *business.UserID,
+			FromUserID: &review.ReviewerID,
+			Subject:    subject,
+			Message:    review.Reason,
+			Type:       "business_review",
+			Status:     "unread",
+		}
+		return tx.Notifications.Create(ctx, notification)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return business, notification, nil
+}
+
+// materiallyChanged reports whether an update changes what reviewers
+// approved: the business's public name, description, logo or contact
+// details.
+func materiallyChanged(existing, updated *models.Business) bool {
+	return existing.Name != updated.Name ||
+		deref(existing.Description) != deref(updated.Description) ||
+		deref(existing.Logo) != deref(updated.Logo) ||
+		deref(existing.Industry) != deref(updated.Industry) ||
+		deref(existing.Phone) != deref(updated.Phone) ||
+		deref(existing.Address) != deref(updated.Address)
+}
+
+// freeSlug returns the first alternative of base that no business other
+// than exceptID uses or used.
+func (s *Store) freeSlug(ctx context.Context, base string, exceptID int) (string, error) {
+	taken, err := s.Businesses.TakenSlugs(ctx, base, exceptID)
+	if err != nil {
+		return "", err
+	}
+	return slug.FirstFree(base, taken), nil
+}
+
+// generatedFrom reports whether s is base or one of its numbered
+// alternatives.
+func generatedFrom(s, base string) bool {
+	if s == base {
+		return true
+	}
+	n, ok := strings.CutPrefix(s, base+"-")
+	if !ok {
+		return false
+	}
+	_, err := strconv.Atoi(n)
+	return err == nil
+}
@@ -0,0 +1,121 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"saas-management-api/internal/models"
+)
+
+func createBusiness(t *testing.T, st *Store, business models.Business) *models.Business {
+	t.Helper()
+	if err := st.CreateBusiness(context.Background(), &business); err != nil {
+		t.Fatal(err)
+	}
+	return &business
+}
+
+// rename updates a business's name, and its slug if requested.
+func rename(t *testing.T, st *Store, business *models.Business, name, requested string) error {
+	t.Helper()
+	updated := *business
+	updated.Name, updated.Slug = name, requested
+	err := st.UpdateBusiness(context.Background(), &updated)
+	if err == nil {
+		*business = updated
+	}
+	return err
+}
+
+func resolves(t *testing.T, st *Store, slug string) int {
+	t.Helper()
+	business, err := st.Businesses.GetBySlug(context.Background(), slug)
+	if err != nil {
+		t.Fatalf("GetBySlug(%q): %v", slug, err)
+	}
+	return business.ID
+}
+
+func TestCreateBusinessSlugCollisions(t *testing.T) {
+	st := NewMemory()
+	for _, tt := range []struct {
+		business models.Business
+		want     string
+	}{
+		{models.Business{Name: "Café Acme"}, "cafe-acme"},
+		{models.Business{Name: "Cafe ACME!"}, "cafe-acme-2"},
+		{models.Business{Name: "Other", Slug: "Cafe Acme"}, "cafe-acme-3"},
+		{models.Business{Name: "Acme", Slug: "acme"}, "acme"},
+		{models.Business{Name: "日本"}, "business"},
+		{models.Business{Name: "中国"}, "business-2"},
+	} {
+		if got := createBusiness(t, st, tt.business); got.Slug != tt.want {
+			t.Errorf("%q got slug %q, want %q", tt.business.Name, got.Slug, tt.want)
+		}
+	}
+}
+
+func TestUpdateBusinessSlugFollowsRenames(t *testing.T) {
+	st := NewMemory()
+	acme := createBusiness(t, st, models.Business{Name: "Acme"})
+	createBusiness(t, st, models.Business{Name: "Acme Bakery"})
+
+	// A generated slug follows the name and the old one keeps resolving.
+	if err := rename(t, st, acme, "Acme Bakery", ""); err != nil {
+		t.Fatal(err)
+	}
+	if acme.Slug != "acme-bakery-2" {
+		t.Fatalf("renamed slug = %q, want acme-bakery-2", acme.Slug)
+	}
+	if resolves(t, st, "acme") != acme.ID {
+		t.Fatal("the former slug no longer resolves")
+	}
+
+	// Former slugs stay reserved for their business.
+	if got := createBusiness(t, st, models.Business{Name: "Acme"}); got.Slug != "acme-2" {
+		t.Fatalf("new business got %q, want acme-2 as acme is reserved", got.Slug)
+	}
+
+	// A chosen slug is kept across renames.
+	if err := rename(t, st, acme, "Acme Bakery", "Best Bread"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rename(t, st, acme, "Acme Pastry", ""); err != nil {
+		t.Fatal(err)
+	}
+	if acme.Slug != "best-bread" {
+		t.Fatalf("slug after renaming = %q, want best-bread", acme.Slug)
+	}
+	for _, former := range []string{"acme", "acme-bakery-2"} {
+		if resolves(t, st, former) != acme.ID {
+			t.Errorf("former slug %q no longer resolves", former)
+		}
+	}
+
+	// Taking back a former slug drops it from the history.
+	if err := rename(t, st, acme, "Acme Pastry", "acme"); err != nil {
+		t.Fatal(err)
+	}
+	if acme.Slug != "acme" || resolves(t, st, "best-bread") != acme.ID {
+		t.Fatalf("slug = %q, want acme with best-bread resolving", acme.Slug)
+	}
+}
+
+func TestUpdateBusinessRejectsTakenSlug(t *testing.T) {
+	st := NewMemory()
+	acme := createBusiness(t, st, models.Business{Name: "Acme"})
+	other := createBusiness(t, st, models.Business{Name: "Other"})
+	if err := rename(t, st, other, "Other Co", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, taken := range []string{"other-co", "other"} {
+		if err := rename(t, st, acme, "Acme", taken); !errors.Is(err, ErrConflict) {
+			t.Errorf("taking %q = %v, want ErrConflict", taken, err)
+		}
+	}
+	if resolves(t, st, "acme") != acme.ID {
+		t.Fatal("a rejected update changed the slug")
+	}
+}
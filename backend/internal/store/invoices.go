@@ -0,0 +1,31 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"saas-management-api/internal/models"
+)
+
+// PayInvoice records payment, which settles the full amount of its invoice,
+// and marks the invoice paid. It returns ErrConflict when the invoice was
+// already paid.
+func (s *Store) PayInvoice(ctx context.Context, payment *models.Payment, at time.Time) (*models.Invoice, error) {
+	var paid *models.Invoice
+	err := s.WithTx(ctx, func(tx *Store) error {
+		invoice, err := tx.Invoices.Get(ctx, payment.InvoiceID)
+		if err != nil {
+			return err
+		}
+		if invoice.Status == models.InvoicePaid {
+			return ErrConflict
+		}
+		payment.Amount = invoice.Amount
+		if err := tx.Invoices.CreatePayment(ctx, payment); err != nil {
+			return err
+		}
+		paid, err = tx.Invoices.SetStatus(ctx, invoice.ID, models.InvoicePaid, at)
+		return err
+	})
+	return paid, err
+}
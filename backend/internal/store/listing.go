@@ -0,0 +1,104 @@
+package store
+
+import (
+	"saas-management-api/internal/listing"
+	"saas-management-api/internal/models"
+)
+
+// The schemas below whitelist the fields each list endpoint can be sorted
+// and filtered by. Columns are qualified where the list query joins other
+// tables.
+
+var BusinessListing = &listing.Schema[models.Business]{
+	Fields: []listing.Field[models.Business]{
+		{Name: "id", Column: "id", Kind: listing.Int, Value: func(b models.Business) any { return b.ID }, Sortable: true},
+		{Name: "name", Column: "name", Kind: listing.String, Value: func(b models.Business) any { return b.Name }, Sortable: true},
+		{Name: "created_at", Column: "created_at", Kind: listing.Time, Value: func(b models.Business) any { return b.CreatedAt }, Sortable: true},
+		{Name: "status", Column: "status", Kind: listing.String, Value: func(b models.Business) any { return b.Status }, Filterable: true},
+		{Name: "industry", Column: "industry", Kind: listing.String, Value: func(b models.Business) any { return deref(b.Industry) }, Filterable: true},
+		{Name: "user_id", Column: "user_id", Kind: listing.Int, Value: func(b models.Business) any { return deref(b.UserID) }, Filterable: true},
+	},
+	IDColumn:    "id",
+	ID:          func(b models.Business) int { return b.ID },
+	DefaultSort: "-created_at",
+}
+
+var WebsiteListing = &listing.Schema[models.Website]{
+	Fields: []listing.Field[models.Website]{
+		{Name: "id", Column: "id", Kind: listing.Int, Value: func(w models.Website) any { return w.ID }, Sortable: true},
+		{Name: "title", Column: "title", Kind: listing.String, Value: func(w models.Website) any { return w.Title }, Sortable: true},
+		{Name: "created_at", Column: "created_at", Kind: listing.Time, Value: func(w models.Website) any { return w.CreatedAt }, Sortable: true},
+		{Name: "status", Column: "status", Kind: listing.String, Value: func(w models.Website) any { return w.Status }, Filterable: true},
+		{Name: "theme_name", Column: "theme_name", Kind: listing.String, Value: func(w models.Website) any { return w.ThemeName }, Filterable: true},
+		{Name: "is_demo", Column: "is_demo", Kind: listing.Bool, Value: func(w models.Website) any { return w.IsDemo }, Filterable: true},
+		{Name: "is_claimed", Column: "is_claimed", Kind: listing.Bool, Value: func(w models.Website) any { return w.IsClaimed }, Filterable: true},
+		{Name: "business_id", Column: "business_id", Kind: listing.Int, Value: func(w models.Website) any { return deref(w.BusinessID) }, Filterable: true},
+	},
+	IDColumn:    "id",
+	ID:          func(w models.Website) int { return w.ID },
+	DefaultSort: "-created_at",
+}
+
+var SubscriptionListing = &listing.Schema[models.Subscription]{
+	Fields: []listing.Field[models.Subscription]{
+		{Name: "id", Column: "id", Kind: listing.Int, Value: func(s models.Subscription) any { return s.ID }, Sortable: true},
+		{Name: "created_at", Column: "created_at", Kind: listing.Time, Value: func(s models.Subscription) any { return s.CreatedAt }, Sortable: true},
+		{Name: "status", Column: "status", Kind: listing.String, Value: func(s models.Subscription) any { return s.Status }, Filterable: true},
+		{Name: "business_id", Column: "business_id", Kind: listing.Int, Value: func(s models.Subscription) any { return s.BusinessID }, Filterable: true},
+		{Name: "plan_id", Column: "plan_id", Kind: listing.Int, Value: func(s models.Subscription) any { return s.PlanID }, Filterable: true},
+	},
+	IDColumn:    "id",
+	ID:          func(s models.Subscription) int { return s.ID },
+	DefaultSort: "-created_at",
+}
+
+var NotificationListing = &listing.Schema[models.Notification]{
+	Fields: []listing.Field[models.Notification]{
+		{Name: "id", Column: "n.id", Kind: listing.Int, Value: func(n models.Notification) any { return n.ID }, Sortable: true},
+		{Name: "created_at", Column: "n.created_at", Kind: listing.Time, Value: func(n models.Notification) any { return n.CreatedAt }, Sortable: true},
+		{Name: "type", Column: "n.type", Kind: listing.String, Value: func(n models.Notification) any { return n.Type }, Filterable: true},
+		{Name: "status", Column: "n.status", Kind: listing.String, Value: func(n models.Notification) any { return n.Status }, Filterable: true},
+		{Name: "is_read", Column: "n.is_read", Kind: listing.Bool, Value: func(n models.Notification) any { return n.IsRead }, Filterable: true},
+	},
+	IDColumn:    "n.id",
+	ID:          func(n models.Notification) int { return n.ID },
+	DefaultSort: "-created_at",
+}
+
+// MessageListing defaults to oldest first so a conversation reads top to
+// bottom; clients that page backwards from the latest message ask for
+// sort=-created_at.
+var MessageListing = &listing.Schema[models.Message]{
+	Fields: []listing.Field[models.Message]{
+		{Name: "id", Column: "m.id", Kind: listing.Int, Value: func(m models.Message) any { return m.ID }, Sortable: true},
+		{Name: "created_at", Column: "m.created_at", Kind: listing.Time, Value: func(m models.Message) any { return m.CreatedAt }, Sortable: true},
+		{Name: "message_type", Column: "m.message_type", Kind: listing.String, Value: func(m models.Message) any { return m.MessageType }, Filterable: true},
+		{Name: "sender_id", Column: "m.sender_id", Kind: listing.Int, Value: func(m models.Message) any { return m.SenderID }, Filterable: true},
+		{Name: "is_read", Column: "m.is_read", Kind: listing.Bool, Value: func(m models.Message) any { return m.IsRead }, Filterable: true},
+	},
+	IDColumn:    "m.id",
+	ID:          func(m models.Message) int { return m.ID },
+	DefaultSort: "created_at",
+}
+
+// MessageSearchListing orders message search hits newest first.
+var MessageSearchListing = &listing.Schema[models.MessageSearchHit]{
+	Fields: []listing.Field[models.MessageSearchHit]{
+		{Name: "id", Column: "m.id", Kind: listing.Int, Value: func(h models.MessageSearchHit) any { return h.ID }, Sortable: true},
+		{Name: "created_at", Column: "m.created_at", Kind: listing.Time, Value: func(h models.MessageSearchHit) any { return h.CreatedAt }, Sortable: true},
+		{Name: "conversation_id", Column: "m.conversation_id", Kind: listing.Int, Value: func(h models.MessageSearchHit) any { return h.ConversationID }, Filterable: true},
+		{Name: "sender_id", Column: "m.sender_id", Kind: listing.Int, Value: func(h models.MessageSearchHit) any { return h.SenderID }, Filterable: true},
+		{Name: "message_type", Column: "m.message_type", Kind: listing.String, Value: func(h models.MessageSearchHit) any { return h.MessageType }, Filterable: true},
+	},
+	IDColumn:    "m.id",
+	ID:          func(h models.MessageSearchHit) int { return h.ID },
+	DefaultSort: "-created_at",
+}
+
+// deref unwraps a nullable column, keeping NULL as an untyped nil.
+func deref[T any](p *T) any {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
@@ -0,0 +1,67 @@
+package store
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"saas-management-api/internal/listing"
+)
+
+// checkSchema parses every sort a schema allows and checks that its fields
+// extract values of their declared kind, which Apply relies on.
+func checkSchema[T any](t *testing.T, schema *listing.Schema[T]) {
+	t.Helper()
+	if _, err := listing.Parse(url.Values{}, schema); err != nil {
+		t.Errorf("default sort %q: %v", schema.DefaultSort, err)
+	}
+
+	var zero T
+	for _, field := range schema.Fields {
+		if field.Sortable {
+			for _, sort := range []string{field.Name, "-" + field.Name} {
+				spec, err := listing.Parse(url.Values{"sort": {sort}}, schema)
+				if err != nil {
+					t.Errorf("sort %q: %v", sort, err)
+					continue
+				}
+				if _, tail, _ := schema.SQL(spec, nil, nil); !strings.Contains(tail, "ORDER BY "+field.Column+" ") {
+					t.Errorf("sort %q renders %q", sort, tail)
+				}
+			}
+		}
+
+		value := field.Value(zero)
+		var ok bool
+		switch field.Kind {
+		case listing.Int:
+			_, ok = value.(int)
+		case listing.Bool:
+			_, ok = value.(bool)
+		case listing.Time:
+			_, ok = value.(time.Time)
+		default:
+			_, ok = value.(string)
+		}
+		if !ok && value != nil {
+			t.Errorf("field %q has kind %v but extracts %T", field.Name, field.Kind, value)
+		}
+	}
+
+	for _, query := range []string{"sort=password", "filter[password]=x", "limit=0"} {
+		values, _ := url.ParseQuery(query)
+		if _, err := listing.Parse(values, schema); err == nil {
+			t.Errorf("%q was accepted", query)
+		}
+	}
+}
+
+func TestListingSchemas(t *testing.T) {
+	t.Run("businesses", func(t *testing.T) { checkSchema(t, BusinessListing) })
+	t.Run("websites", func(t *testing.T) { checkSchema(t, WebsiteListing) })
+	t.Run("subscriptions", func(t *testing.T) { checkSchema(t, SubscriptionListing) })
+	t.Run("notifications", func(t *testing.T) { checkSchema(t, NotificationListing) })
+	t.Run("messages", func(t *testing.T) { checkSchema(t, MessageListing) })
+	t.Run("message search", func(t *testing.T) { checkSchema(t, MessageSearchListing) })
+}
@@ -0,0 +1,174 @@
+package store
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"time"
+
+	"saas-management-api/internal/models"
+)
+
+// memoryDB holds the tables of the in-memory store. A single mutex guards
+// every table so multi-table operations observe a consistent state, and
+// txMu serializes transactions.
+type memoryDB struct {
+	mu   sync.Mutex
+	txMu sync.Mutex
+	memoryTables
+}
+
+type memoryTables struct {
+	ids map[string]int
+
+	users         map[int]models.User
+	businesses    map[int]models.Business
+	slugHistory   map[string]int // former slug -> business ID
+	websites      map[int]models.Website
+	domains       map[int]models.Domain
+	tlsCache      map[string][]byte
+	revisions     map[int]models.WebsiteRevision
+	pages         map[int]models.WebsitePage
+	plans         map[int]models.Plan
+	subscriptions map[int]models.Subscription
+	invoices      map[int]models.Invoice
+	payments      map[int]models.Payment
+	notifications map[int]models.Notification
+	conversations map[int]models.Conversation
+	participants  map[participantKey]models.ConversationParticipant
+	messages      map[int]models.Message
+	messageEdits  map[int]models.MessageEdit
+	hidden        map[hiddenKey]time.Time
+	reactions     map[reactionKey]time.Time
+	attachments   map[int]models.Attachment
+
+	// Analytics tables, keyed by day as "2006-01-02".
+	analyticsSalts    map[string][]byte
+	analyticsVisitors map[analyticsVisitorKey]bool
+	analyticsDays     map[analyticsDayKey]models.AnalyticsDay
+	analyticsCounts   map[analyticsCountKey]int
+}
+
+// NewMemory returns an empty Store that keeps everything in process memory.
+// It is intended for tests and mirrors the behavior of the Postgres store,
+// including ErrNotFound and ErrConflict.
+func NewMemory() *Store {
+	db := &memoryDB{memoryTables: memoryTables{
+		ids:           map[string]int{},
+		users:         map[int]models.User{},
+		businesses:    map[int]models.Business{},
+		slugHistory:   map[string]int{},
+		websites:      map[int]models.Website{},
+		domains:       map[int]models.Domain{},
+		tlsCache:      map[string][]byte{},
+		revisions:     map[int]models.WebsiteRevision{},
+		pages:         map[int]models.WebsitePage{},
+		plans:         map[int]models.Plan{},
+		subscriptions: map[int]models.Subscription{},
+		invoices:      map[int]models.Invoice{},
+		payments:      map[int]models.Payment{},
+		notifications: map[int]models.Notification{},
+		conversations: map[int]models.Conversation{},
+		participants:  map[participantKey]models.ConversationParticipant{},
+		messages:      map[int]models.Message{},
+		messageEdits:  map[int]models.MessageEdit{},
+		hidden:        map[hiddenKey]time.Time{},
+		reactions:     map[reactionKey]time.Time{},
+		attachments:   map[int]models.Attachment{},
+
+		analyticsSalts:    map[string][]byte{},
+		analyticsVisitors: map[analyticsVisitorKey]bool{},
+		analyticsDays:     map[analyticsDayKey]models.AnalyticsDay{},
+		analyticsCounts:   map[analyticsCountKey]int{},
+	}}
+	s := newMemory(db)
+	s.withTx = func(_ context.Context, fn func(tx *Store) error) error {
+		db.txMu.Lock()
+		defer db.txMu.Unlock()
+
+		db.mu.Lock()
+		snapshot := db.memoryTables.clone()
+		db.mu.Unlock()
+
+		txStore := newMemory(db)
+		txStore.withTx = func(_ context.Context, fn func(tx *Store) error) error {
+			return fn(txStore)
+		}
+		if err := fn(txStore); err != nil {
+			db.mu.Lock()
+			db.memoryTables = snapshot
+			db.mu.Unlock()
+			return err
+		}
+		return nil
+	}
+	return s
+}
+
+func newMemory(db *memoryDB) *Store {
+	return &Store{
+		Users:         &memUsers{db: db},
+		Businesses:    &memBusinesses{db: db},
+		Websites:      &memWebsites{db: db},
+		Pages:         &memPages{db: db},
+		Domains:       &memDomains{db: db},
+		TLSCache:      &memTLSCache{db: db},
+		Analytics:     &memAnalytics{db: db},
+		Plans:         &memPlans{db: db},
+		Subscriptions: &memSubscriptions{db: db},
+		Invoices:      &memInvoices{db: db},
+		Notifications: &memNotifications{db: db},
+		Conversations: &memConversations{db: db},
+		Attachments:   &memAttachments{db: db},
+	}
+}
+
+// nextID returns the next serial value for table. Callers must hold mu.
+func (db *memoryDB) nextID(table string) int {
+	db.ids[table]++
+	return db.ids[table]
+}
+
+// userName returns the name of a user, or nil if the user does not exist.
+// Callers must hold mu.
+func (db *memoryDB) userName(id int) *string {
+	user, ok := db.users[id]
+	if !ok {
+		return nil
+	}
+	name := user.Name
+	return &name
+}
+
+// clone returns a copy of every table, used to roll back a failed
+// transaction.
+func (t memoryTables) clone() memoryTables {
+	return memoryTables{
+		ids:           maps.Clone(t.ids),
+		users:         maps.Clone(t.users),
+		businesses:    maps.Clone(t.businesses),
+		slugHistory:   maps.Clone(t.slugHistory),
+		websites:      maps.Clone(t.websites),
+		domains:       maps.Clone(t.domains),
+		tlsCache:      maps.Clone(t.tlsCache),
+		revisions:     maps.Clone(t.revisions),
+		pages:         maps.Clone(t.pages),
+		plans:         maps.Clone(t.plans),
+		subscriptions: maps.Clone(t.subscriptions),
+		invoices:      maps.Clone(t.invoices),
+		payments:      maps.Clone(t.payments),
+		notifications: maps.Clone(t.notifications),
+		conversations: maps.Clone(t.conversations),
+		participants:  maps.Clone(t.participants),
+		messages:      maps.Clone(t.messages),
+		messageEdits:  maps.Clone(t.messageEdits),
+		hidden:        maps.Clone(t.hidden),
+		reactions:     maps.Clone(t.reactions),
+		attachments:   maps.Clone(t.attachments),
+
+		analyticsSalts:    maps.Clone(t.analyticsSalts),
+		analyticsVisitors: maps.Clone(t.analyticsVisitors),
+		analyticsDays:     maps.Clone(t.analyticsDays),
+		analyticsCounts:   maps.Clone(t.analyticsCounts),
+	}
+}
@@ -0,0 +1,143 @@
+package store
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+
+	"saas-management-api/internal/models"
+)
+
+type analyticsVisitorKey struct {
+	websiteID int
+	day       string
+	visitor   string
+}
+
+type analyticsDayKey struct {
+	websiteID int
+	day       string
+}
+
+type analyticsCountKey struct {
+	websiteID int
+	day       string
+	dimension string
+	value     string
+}
+
+type memAnalytics struct {
+	db *memoryDB
+}
+
+func (r *memAnalytics) Salt(ctx context.Context, day time.Time, fresh []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	date := day.Format(time.DateOnly)
+	if salt, ok := r.db.analyticsSalts[date]; ok {
+		return slices.Clone(salt), nil
+	}
+	for other := range r.db.analyticsSalts {
+		if other < date {
+			delete(r.db.analyticsSalts, other)
+		}
+	}
+	for key := range r.db.analyticsVisitors {
+		if key.day < date {
+			delete(r.db.analyticsVisitors, key)
+		}
+	}
+	r.db.analyticsSalts[date] = slices.Clone(fresh)
+	return slices.Clone(fresh), nil
+}
+
+func (r *memAnalytics) Record(ctx context.Context, record AnalyticsRecord) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	date := record.Day.Format(time.DateOnly)
+	key := analyticsDayKey{websiteID: record.WebsiteID, day: date}
+	totals := r.db.analyticsDays[key]
+	totals.Day = date
+	visitor := analyticsVisitorKey{websiteID: record.WebsiteID, day: date, visitor: record.Visitor}
+	if !r.db.analyticsVisitors[visitor] {
+		r.db.analyticsVisitors[visitor] = true
+		totals.Visitors++
+	}
+
+	counts := map[string]string{models.DimensionReferrer: record.Referrer}
+	if record.Event != "" {
+		totals.Events++
+		counts[models.DimensionEvent] = record.Event
+	} else {
+		totals.Pageviews++
+		counts[models.DimensionPage] = record.Path
+	}
+	if record.Visit {
+		totals.Visits++
+	}
+	r.db.analyticsDays[key] = totals
+
+	for dimension, value := range counts {
+		if value != "" {
+			r.db.analyticsCounts[analyticsCountKey{websiteID: record.WebsiteID, day: date, dimension: dimension, value: value}]++
+		}
+	}
+	return nil
+}
+
+func (r *memAnalytics) Days(ctx context.Context, websiteID int, from, to time.Time) ([]models.AnalyticsDay, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	first, last := from.Format(time.DateOnly), to.Format(time.DateOnly)
+	days := []models.AnalyticsDay{}
+	for key, totals := range r.db.analyticsDays {
+		if key.websiteID == websiteID && key.day >= first && key.day <= last {
+			days = append(days, totals)
+		}
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Day < days[j].Day })
+	return days, nil
+}
+
+func (r *memAnalytics) Top(ctx context.Context, websiteID int, dimension string, from, to time.Time, limit int) ([]models.AnalyticsCount, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	first, last := from.Format(time.DateOnly), to.Format(time.DateOnly)
+	sums := map[string]int{}
+	for key, count := range r.db.analyticsCounts {
+		if key.websiteID == websiteID && key.dimension == dimension && key.day >= first && key.day <= last {
+			sums[key.value] += count
+		}
+	}
+	counts := make([]models.AnalyticsCount, 0, len(sums))
+	for value, count := range sums {
+		counts = append(counts, models.AnalyticsCount{Value: value, Count: count})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Value < counts[j].Value
+	})
+	if len(counts) > limit {
+		counts = counts[:limit]
+	}
+	return counts, nil
+}
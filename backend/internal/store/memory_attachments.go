@@ -0,0 +1,68 @@
+package store
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"saas-management-api/internal/models"
+)
+
+type memAttachments struct {
+	db *memoryDB
+}
+
+func (r *memAttachments) Create(ctx context.Context, attachment *models.Attachment) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, existing := range r.db.attachments {
+		if existing.StorageKey == attachment.StorageKey {
+			return ErrConflict
+		}
+	}
+	attachment.ID = r.db.nextID("attachments")
+	attachment.MessageID = nil
+	attachment.CreatedAt = time.Now()
+	r.db.attachments[attachment.ID] = *attachment
+	return nil
+}
+
+func (r *memAttachments) Get(ctx context.Context, id int) (*models.Attachment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	attachment, ok := r.db.attachments[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &attachment, nil
+}
+
+func (r *memAttachments) Attach(ctx context.Context, messageID, ownerID int, ids []int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	ids = slices.Compact(slices.Sorted(slices.Values(ids)))
+	for _, id := range ids {
+		attachment, ok := r.db.attachments[id]
+		if !ok || attachment.OwnerID != ownerID || attachment.MessageID != nil {
+			return ErrNotFound
+		}
+	}
+	for _, id := range ids {
+		attachment := r.db.attachments[id]
+		attachment.MessageID = &messageID
+		r.db.attachments[id] = attachment
+	}
+	return nil
+}
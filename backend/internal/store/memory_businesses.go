@@ -0,0 +1,364 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"saas-management-api/internal/listing"
+	"saas-management-api/internal/models"
+)
+
+type memBusinesses struct {
+	db *memoryDB
+}
+
+func (r *memBusinesses) List(ctx context.Context, spec listing.Spec) (listing.Page[models.Business], error) {
+	if err := ctx.Err(); err != nil {
+		return listing.Page[models.Business]{}, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	businesses := make([]models.Business, 0, len(r.db.businesses))
+	for _, business := range r.db.businesses {
+		businesses = append(businesses, business)
+	}
+	return listing.Apply(businesses, spec, BusinessListing), nil
+}
+
+func (r *memBusinesses) Get(ctx context.Context, id int) (*models.Business, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	business, ok := r.db.businesses[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &business, nil
+}
+
+func (r *memBusinesses) GetBySlug(ctx context.Context, slug string) (*models.Business, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, business := range r.db.businesses {
+		if business.Slug == slug {
+			return &business, nil
+		}
+	}
+	if id, ok := r.db.slugHistory[slug]; ok {
+		business := r.db.businesses[id]
+		return &business, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memBusinesses) TakenSlugs(ctx context.Context, base string, exceptID int) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	matches := func(slug string) bool {
+		return slug == base || strings.HasPrefix(slug, base+"-")
+	}
+	slugs := []string{}
+	for id, business := range r.db.businesses {
+		if id != exceptID && matches(business.Slug) {
+			slugs = append(slugs, business.Slug)
+		}
+	}
+	for slug, id := range r.db.slugHistory {
+		if id != exceptID && matches(slug) {
+			slugs = append(slugs, slug)
+		}
+	}
+	return slugs, nil
+}
+
+func (r *memBusinesses) RecordSlugChange(ctx context.Context, businessID int, oldSlug, newSlug string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if id, ok := r.db.slugHistory[newSlug]; ok && id == businessID {
+		delete(r.db.slugHistory, newSlug)
+	}
+	if _, ok := r.db.slugHistory[oldSlug]; ok {
+		return fmt.Errorf("%w: slug %q already recorded", ErrConflict, oldSlug)
+	}
+	r.db.slugHistory[oldSlug] = businessID
+	return nil
+}
+
+func (r *memBusinesses) Create(ctx context.Context, business *models.Business) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if err := r.checkSlug(business.Slug, 0); err != nil {
+		return err
+	}
+	now := time.Now()
+	business.ID = r.db.nextID("businesses")
+	business.CreatedAt = now
+	business.UpdatedAt = now
+	r.db.businesses[business.ID] = *business
+	return nil
+}
+
+func (r *memBusinesses) Update(ctx context.Context, business *models.Business) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	existing, ok := r.db.businesses[business.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	if err := r.checkSlug(business.Slug, business.ID); err != nil {
+		return err
+	}
+	business.UserID = existing.UserID
+	business.CreatedAt = existing.CreatedAt
+	business.UpdatedAt = time.Now()
+	business.ReviewedBy = existing.ReviewedBy
+	business.ReviewedAt = existing.ReviewedAt
+	business.ReviewReason = existing.ReviewReason
+	r.db.businesses[business.ID] = *business
+	return nil
+}
+
+func (r *memBusinesses) SetReview(ctx context.Context, id int, review BusinessReview) (*models.Business, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	business, ok := r.db.businesses[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	business.Status = review.Status
+	business.ReviewedBy = &review.ReviewerID
+	business.ReviewedAt = &review.At
+	business.ReviewReason = &review.Reason
+	business.UpdatedAt = review.At
+	r.db.businesses[id] = business
+	return &business, nil
+}
+
+func (r *memBusinesses) Delete(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.businesses[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.db.businesses, id)
+	for slug, businessID := range r.db.slugHistory {
+		if businessID == id {
+			delete(r.db.slugHistory, slug)
+		}
+	}
+	return nil
+}
+
+// Search approximates the Postgres full-text search: every term must prefix
+// a word of the business, weighted by the field it matched in, and a query
+// that misses falls back to matching words within one typo.
+func (r *memBusinesses) Search(ctx context.Context, search BusinessSearch) (*models.BusinessSearchResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	terms := searchTerms(search.Query)
+	result := &models.BusinessSearchResult{
+		Results:    []models.BusinessSearchHit{},
+		Industries: []models.Facet{},
+	}
+	industries := map[string]int{}
+	for _, business := range r.db.businesses {
+		if search.Status != "" && business.Status != search.Status {
+			continue
+		}
+		rank, ok := r.rank(business, terms)
+		if !ok {
+			continue
+		}
+		if business.Industry != nil {
+			industries[*business.Industry]++
+		}
+		if search.Industry == "" || (business.Industry != nil && *business.Industry == search.Industry) {
+			result.Results = append(result.Results, models.BusinessSearchHit{Business: business, Rank: rank})
+		}
+	}
+
+	for value, count := range industries {
+		result.Industries = append(result.Industries, models.Facet{Value: value, Count: count})
+	}
+	sort.Slice(result.Industries, func(i, j int) bool {
+		a, b := result.Industries[i], result.Industries[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Value < b.Value
+	})
+
+	hits := result.Results
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Rank != hits[j].Rank {
+			return hits[i].Rank > hits[j].Rank
+		}
+		return newerFirst(hits[i].CreatedAt, hits[i].ID, hits[j].CreatedAt, hits[j].ID)
+	})
+	if len(hits) > search.Limit {
+		result.Results = hits[:search.Limit]
+	}
+	return result, nil
+}
+
+// rank scores business against the search terms. Callers must hold mu.
+func (r *memBusinesses) rank(business models.Business, terms []string) (float64, bool) {
+	if len(terms) == 0 {
+		return 0, true
+	}
+
+	var titles []string
+	for _, website := range r.db.websites {
+		if website.BusinessID != nil && *website.BusinessID == business.ID {
+			titles = append(titles, website.Title)
+		}
+	}
+	optional := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return *s
+	}
+	// Weights follow the setweight labels of the business_search document.
+	fields := []struct {
+		text   string
+		weight float64
+	}{
+		{business.Name, 1},
+		{optional(business.Industry), 0.4},
+		{strings.Join(titles, " "), 0.4},
+		{optional(business.Description), 0.2},
+		{optional(business.Address), 0.1},
+	}
+
+	var rank float64
+	exact := true
+	for _, term := range terms {
+		best := 0.0
+		for _, field := range fields {
+			for _, word := range searchTerms(field.text) {
+				if strings.HasPrefix(word, term) {
+					best = max(best, field.weight)
+				}
+			}
+		}
+		if best == 0 {
+			exact = false
+			break
+		}
+		rank += best
+	}
+	if exact {
+		return rank, true
+	}
+
+	// Typo fallback over the same text as the trigram index.
+	fuzzy := searchTerms(business.Name + " " + optional(business.Industry) + " " + strings.Join(titles, " "))
+	for _, term := range terms {
+		found := false
+		for _, word := range fuzzy {
+			if withinOneEdit(term, word) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, false
+		}
+	}
+	return 0.1, true
+}
+
+// withinOneEdit reports whether a and b differ by at most one inserted,
+// deleted or substituted rune, or one swap of adjacent runes.
+func withinOneEdit(a, b string) bool {
+	x, y := []rune(a), []rune(b)
+	if len(x) > len(y) {
+		x, y = y, x
+	}
+	if len(y)-len(x) > 1 {
+		return false
+	}
+	i, j, edits := 0, 0, 0
+	for i < len(x) && j < len(y) {
+		if x[i] == y[j] {
+			i++
+			j++
+			continue
+		}
+		edits++
+		if edits > 1 {
+			return false
+		}
+		switch {
+		case len(x) == len(y) && i+1 < len(x) && x[i] == y[j+1] && x[i+1] == y[j]:
+			i += 2
+			j += 2
+		case len(x) == len(y):
+			i++
+			j++
+		default:
+			j++
+		}
+	}
+	return edits+(len(y)-j)+(len(x)-i) <= 1
+}
+
+// checkSlug enforces the unique slug constraint, ignoring the business being
+// updated. Callers must hold mu.
+func (r *memBusinesses) checkSlug(slug string, exceptID int) error {
+	for id, business := range r.db.businesses {
+		if id != exceptID && business.Slug == slug {
+			return fmt.Errorf("%w: slug %q already taken", ErrConflict, slug)
+		}
+	}
+	return nil
+}
+
+// newerFirst orders rows by creation time descending, breaking ties by ID so
+// results are deterministic.
+func newerFirst(aCreated time.Time, aID int, bCreated time.Time, bID int) bool {
+	if !aCreated.Equal(bCreated) {
+		return aCreated.After(bCreated)
+	}
+	return aID > bID
+}
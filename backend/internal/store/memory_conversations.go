@@ -0,0 +1,708 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"saas-management-api/internal/listing"
+	"saas-management-api/internal/models"
+)
+
+type memConversations struct {
+	db *memoryDB
+}
+
+// participantKey identifies a row of the participants table.
+type participantKey struct {
+	conversationID, userID int
+}
+
+// withNames fills in the names of a direct conversation's users and the
+// participants. Callers must hold mu.
+func (r *memConversations) withNames(conversation models.Conversation) models.Conversation {
+	if conversation.User1ID != nil {
+		conversation.User1Name = r.db.userName(*conversation.User1ID)
+	}
+	if conversation.User2ID != nil {
+		conversation.User2Name = r.db.userName(*conversation.User2ID)
+	}
+	conversation.Participants = r.participantsOf(conversation.ID)
+	return conversation
+}
+
+// participantsOf returns the participants of a conversation in the order
+// they joined. Callers must hold mu.
+func (r *memConversations) participantsOf(conversationID int) []models.ConversationParticipant {
+	participants := []models.ConversationParticipant{}
+	for key, participant := range r.db.participants {
+		if key.conversationID == conversationID {
+			participant.UserName = r.db.userName(participant.UserID)
+			participants = append(participants, participant)
+		}
+	}
+	sort.Slice(participants, func(i, j int) bool {
+		if !participants[i].JoinedAt.Equal(participants[j].JoinedAt) {
+			return participants[i].JoinedAt.Before(participants[j].JoinedAt)
+		}
+		return participants[i].UserID < participants[j].UserID
+	})
+	return participants
+}
+
+// participates reports whether userID participates in a conversation.
+// Callers must hold mu.
+func (r *memConversations) participates(conversationID, userID int) bool {
+	_, ok := r.db.participants[participantKey{conversationID, userID}]
+	return ok
+}
+
+// lastMessageID returns the ID of a conversation's latest message, or 0.
+// Callers must hold mu.
+func (r *memConversations) lastMessageID(conversationID int) int {
+	last := 0
+	for id, message := range r.db.messages {
+		if message.ConversationID == conversationID {
+			last = max(last, id)
+		}
+	}
+	return last
+}
+
+// hiddenKey identifies a message a user hid.
+type hiddenKey struct {
+	messageID, userID int
+}
+
+// reactionKey identifies a user's reaction to a message.
+type reactionKey struct {
+	messageID, userID int
+	emoji             string
+}
+
+// messageWithNames fills in the sender and receiver names, the reactions
+// and the attachments. Callers must hold mu.
+func (r *memConversations) messageWithNames(message models.Message) models.Message {
+	message.SenderName = r.db.userName(message.SenderID)
+	if message.ReceiverID != nil {
+		message.ReceiverName = r.db.userName(*message.ReceiverID)
+	}
+
+	keys := []reactionKey{}
+	for key := range r.db.reactions {
+		if key.messageID == message.ID {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := r.db.reactions[keys[i]], r.db.reactions[keys[j]]
+		if !a.Equal(b) {
+			return a.Before(b)
+		}
+		return keys[i].userID < keys[j].userID
+	})
+	message.Reactions = nil
+	for _, key := range keys {
+		message.Reactions = addReaction(message.Reactions, key.emoji, key.userID)
+	}
+
+	message.Attachments = nil
+	for _, attachment := range r.db.attachments {
+		if attachment.MessageID != nil && *attachment.MessageID == message.ID {
+			message.Attachments = append(message.Attachments, attachment)
+		}
+	}
+	sort.Slice(message.Attachments, func(i, j int) bool { return message.Attachments[i].ID < message.Attachments[j].ID })
+	return message
+}
+
+// visibleTo reports whether userID participates in the conversation of a
+// message and hasn't hidden it. Callers must hold mu.
+func (r *memConversations) visibleTo(message models.Message, userID int) bool {
+	_, hidden := r.db.hidden[hiddenKey{message.ID, userID}]
+	return !hidden && r.participates(message.ConversationID, userID)
+}
+
+// sortMessages orders messages by creation time, oldest first unless
+// newestFirst is set.
+func sortMessages(messages []models.Message, newestFirst bool) {
+	sort.Slice(messages, func(i, j int) bool {
+		if !newestFirst {
+			i, j = j, i
+		}
+		return newerFirst(messages[i].CreatedAt, messages[i].ID, messages[j].CreatedAt, messages[j].ID)
+	})
+}
+
+func (r *memConversations) GetOrCreate(ctx context.Context, userA, userB int) (*models.Conversation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	user1ID, user2ID := orderPair(userA, userB)
+	for _, conversation := range r.db.conversations {
+		if conversation.User1ID != nil && *conversation.User1ID == user1ID && conversation.User2ID != nil && *conversation.User2ID == user2ID {
+			conversation = r.withNames(conversation)
+			return &conversation, nil
+		}
+	}
+
+	now := time.Now()
+	conversation := models.Conversation{
+		ID:            r.db.nextID("conversations"),
+		User1ID:       &user1ID,
+		User2ID:       &user2ID,
+		LastMessageAt: &now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	r.db.conversations[conversation.ID] = conversation
+	for _, userID := range []int{user1ID, user2ID} {
+		r.db.participants[participantKey{conversation.ID, userID}] = models.ConversationParticipant{
+			ConversationID: conversation.ID,
+			UserID:         userID,
+			Role:           models.ParticipantMember,
+			JoinedAt:       now,
+		}
+	}
+	conversation = r.withNames(conversation)
+	return &conversation, nil
+}
+
+func (r *memConversations) GetDirect(ctx context.Context, userA, userB int) (*models.Conversation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	user1ID, user2ID := orderPair(userA, userB)
+	for _, conversation := range r.db.conversations {
+		if conversation.User1ID != nil && *conversation.User1ID == user1ID && conversation.User2ID != nil && *conversation.User2ID == user2ID {
+			conversation = r.withNames(conversation)
+			return &conversation, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memConversations) GetForUser(ctx context.Context, id, userID int) (*models.Conversation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	conversation, ok := r.db.conversations[id]
+	if !ok || !r.participates(id, userID) {
+		return nil, ErrNotFound
+	}
+	conversation = r.withNames(conversation)
+	return &conversation, nil
+}
+
+func (r *memConversations) ListForUser(ctx context.Context, userID int) ([]models.Conversation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	conversations := []models.Conversation{}
+	for _, conversation := range r.db.conversations {
+		participant, ok := r.db.participants[participantKey{conversation.ID, userID}]
+		if !ok {
+			continue
+		}
+		conversation = r.withNames(conversation)
+
+		var last *models.Message
+		for _, message := range r.db.messages {
+			if message.ConversationID != conversation.ID {
+				continue
+			}
+			if message.SenderID != userID && message.ID > participant.LastReadMessageID {
+				conversation.UnreadCount++
+			}
+			if last == nil || newerFirst(message.CreatedAt, message.ID, last.CreatedAt, last.ID) {
+				m := message
+				last = &m
+			}
+		}
+		if last != nil {
+			text := last.Message
+			conversation.LastMessage = &text
+		}
+		conversations = append(conversations, conversation)
+	}
+
+	activity := func(c models.Conversation) time.Time {
+		if c.LastMessageAt != nil {
+			return *c.LastMessageAt
+		}
+		return c.CreatedAt
+	}
+	sort.Slice(conversations, func(i, j int) bool {
+		return newerFirst(activity(conversations[i]), conversations[i].ID, activity(conversations[j]), conversations[j].ID)
+	})
+	return conversations, nil
+}
+
+func (r *memConversations) Touch(ctx context.Context, id int, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	conversation, ok := r.db.conversations[id]
+	if !ok {
+		return ErrNotFound
+	}
+	conversation.LastMessageAt = &at
+	conversation.UpdatedAt = at
+	r.db.conversations[id] = conversation
+	return nil
+}
+
+func (r *memConversations) CreateGroup(ctx context.Context, conversation *models.Conversation) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	now := time.Now()
+	conversation.ID = r.db.nextID("conversations")
+	conversation.User1ID = nil
+	conversation.User2ID = nil
+	conversation.IsGroup = true
+	conversation.LastMessageAt = &now
+	conversation.CreatedAt = now
+	conversation.UpdatedAt = now
+	conversation.Participants = nil
+	r.db.conversations[conversation.ID] = *conversation
+	return nil
+}
+
+func (r *memConversations) Rename(ctx context.Context, id int, title string, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	conversation, ok := r.db.conversations[id]
+	if !ok || !conversation.IsGroup {
+		return ErrNotFound
+	}
+	conversation.Title = &title
+	conversation.UpdatedAt = at
+	r.db.conversations[id] = conversation
+	return nil
+}
+
+func (r *memConversations) AddParticipants(ctx context.Context, conversationID int, userIDs []int, role string, at time.Time) ([]int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.conversations[conversationID]; !ok {
+		return nil, ErrNotFound
+	}
+	lastRead := r.lastMessageID(conversationID)
+	added := []int{}
+	for _, userID := range userIDs {
+		key := participantKey{conversationID, userID}
+		if _, ok := r.db.participants[key]; ok {
+			continue
+		}
+		r.db.participants[key] = models.ConversationParticipant{
+			ConversationID:    conversationID,
+			UserID:            userID,
+			Role:              role,
+			LastReadMessageID: lastRead,
+			JoinedAt:          at,
+		}
+		added = append(added, userID)
+	}
+	return added, nil
+}
+
+func (r *memConversations) RemoveParticipant(ctx context.Context, conversationID, userID int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	key := participantKey{conversationID, userID}
+	if _, ok := r.db.participants[key]; !ok {
+		return ErrNotFound
+	}
+	delete(r.db.participants, key)
+	return nil
+}
+
+func (r *memConversations) Participants(ctx context.Context, conversationID int) ([]models.ConversationParticipant, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	return r.participantsOf(conversationID), nil
+}
+
+func (r *memConversations) Messages(ctx context.Context, conversationID, userID int, spec listing.Spec) (listing.Page[models.Message], error) {
+	if err := ctx.Err(); err != nil {
+		return listing.Page[models.Message]{}, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	messages := []models.Message{}
+	for _, message := range r.db.messages {
+		if _, hidden := r.db.hidden[hiddenKey{message.ID, userID}]; message.ConversationID == conversationID && !hidden {
+			messages = append(messages, r.messageWithNames(message))
+		}
+	}
+	return listing.Apply(messages, spec, MessageListing), nil
+}
+
+// SearchMessages approximates the Postgres full-text search: every term must
+// prefix a word of the message. Snippets hold the whole text.
+func (r *memConversations) SearchMessages(ctx context.Context, userID int, query string, spec listing.Spec) (listing.Page[models.MessageSearchHit], error) {
+	if err := ctx.Err(); err != nil {
+		return listing.Page[models.MessageSearchHit]{}, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	terms := searchTerms(query)
+	hits := []models.MessageSearchHit{}
+	for _, message := range r.db.messages {
+		if len(terms) == 0 || message.DeletedAt != nil || !r.visibleTo(message, userID) {
+			continue
+		}
+		if !prefixWords(terms, searchTerms(message.Message)) {
+			continue
+		}
+
+		conversation := r.db.conversations[message.ConversationID]
+		hit := models.MessageSearchHit{
+			Message:             r.messageWithNames(message),
+			Snippet:             highlight(markMatches(unmarked(message.Message), terms)),
+			ConversationIsGroup: conversation.IsGroup,
+			ConversationTitle:   conversation.Title,
+		}
+		if peer := conversation.Peer(userID); peer != nil {
+			hit.ConversationTitle = r.db.userName(*peer)
+		}
+		hits = append(hits, hit)
+	}
+	return listing.Apply(hits, spec, MessageSearchListing), nil
+}
+
+func (r *memConversations) MessageForUser(ctx context.Context, id, userID int) (*models.Message, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	message, ok := r.db.messages[id]
+	if !ok || !r.visibleTo(message, userID) {
+		return nil, ErrNotFound
+	}
+	message = r.messageWithNames(message)
+	return &message, nil
+}
+
+func (r *memConversations) CreateMessage(ctx context.Context, message *models.Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.conversations[message.ConversationID]; !ok {
+		return ErrNotFound
+	}
+	if message.ClientID != nil {
+		for _, existing := range r.db.messages {
+			if existing.SenderID == message.SenderID && existing.ClientID != nil && *existing.ClientID == *message.ClientID {
+				return ErrDuplicate
+			}
+		}
+	}
+	now := time.Now()
+	message.ID = r.db.nextID("messages")
+	message.IsRead = false
+	message.ReadAt = nil
+	message.DeliveredAt = nil
+	message.CreatedAt = now
+	message.UpdatedAt = now
+	r.db.messages[message.ID] = *message
+	*message = r.messageWithNames(*message)
+	return nil
+}
+
+func (r *memConversations) EditMessage(ctx context.Context, id int, text string, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	message, ok := r.db.messages[id]
+	if !ok {
+		return ErrNotFound
+	}
+	edit := models.MessageEdit{
+		ID:        r.db.nextID("message_edits"),
+		MessageID: id,
+		Message:   message.Message,
+		EditedAt:  at,
+	}
+	r.db.messageEdits[edit.ID] = edit
+	message.Message = text
+	message.EditedAt = &at
+	message.UpdatedAt = at
+	r.db.messages[id] = message
+	return nil
+}
+
+func (r *memConversations) MessageEdits(ctx context.Context, messageID int) ([]models.MessageEdit, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	edits := []models.MessageEdit{}
+	for _, edit := range r.db.messageEdits {
+		if edit.MessageID == messageID {
+			edits = append(edits, edit)
+		}
+	}
+	sort.Slice(edits, func(i, j int) bool { return edits[i].ID < edits[j].ID })
+	return edits, nil
+}
+
+func (r *memConversations) DeleteMessage(ctx context.Context, id int, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	message, ok := r.db.messages[id]
+	if !ok {
+		return ErrNotFound
+	}
+	for editID, edit := range r.db.messageEdits {
+		if edit.MessageID == id {
+			delete(r.db.messageEdits, editID)
+		}
+	}
+	for key := range r.db.reactions {
+		if key.messageID == id {
+			delete(r.db.reactions, key)
+		}
+	}
+	for attachmentID, attachment := range r.db.attachments {
+		if attachment.MessageID != nil && *attachment.MessageID == id {
+			delete(r.db.attachments, attachmentID)
+		}
+	}
+	message.Message = ""
+	message.DeletedAt = &at
+	message.UpdatedAt = at
+	r.db.messages[id] = message
+	return nil
+}
+
+func (r *memConversations) HideMessage(ctx context.Context, id, userID int, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	key := hiddenKey{id, userID}
+	if _, ok := r.db.hidden[key]; !ok {
+		r.db.hidden[key] = at
+	}
+	return nil
+}
+
+func (r *memConversations) AddReaction(ctx context.Context, messageID, userID int, emoji string, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	key := reactionKey{messageID, userID, emoji}
+	if _, ok := r.db.reactions[key]; !ok {
+		r.db.reactions[key] = at
+	}
+	return nil
+}
+
+func (r *memConversations) RemoveReaction(ctx context.Context, messageID, userID int, emoji string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	delete(r.db.reactions, reactionKey{messageID, userID, emoji})
+	return nil
+}
+
+func (r *memConversations) MarkRead(ctx context.Context, conversationID, userID, upToID int, at time.Time) ([]models.Message, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	key := participantKey{conversationID, userID}
+	participant, ok := r.db.participants[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	previous := participant.LastReadMessageID
+	participant.LastReadMessageID = max(previous, min(upToID, r.lastMessageID(conversationID)))
+	r.db.participants[key] = participant
+
+	marked := []models.Message{}
+	for id, message := range r.db.messages {
+		if message.ConversationID != conversationID || id > participant.LastReadMessageID {
+			continue
+		}
+		if message.ReceiverID != nil && *message.ReceiverID == userID && !message.IsRead {
+			message.IsRead = true
+			message.ReadAt = &at
+			if message.DeliveredAt == nil {
+				message.DeliveredAt = &at
+			}
+			r.db.messages[id] = message
+		}
+		if message.SenderID != userID && id > previous {
+			marked = append(marked, r.messageWithNames(message))
+		}
+	}
+	sort.Slice(marked, func(i, j int) bool { return marked[i].ID < marked[j].ID })
+	return marked, nil
+}
+
+func (r *memConversations) MarkDelivered(ctx context.Context, receiverID int, ids []int, at time.Time) ([]models.Message, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	marked := []models.Message{}
+	for _, id := range ids {
+		message, ok := r.db.messages[id]
+		if !ok || message.ReceiverID == nil || *message.ReceiverID != receiverID || message.DeliveredAt != nil {
+			continue
+		}
+		message.DeliveredAt = &at
+		r.db.messages[id] = message
+		marked = append(marked, message)
+	}
+	return marked, nil
+}
+
+func (r *memConversations) ListMessages(ctx context.Context, userID int) ([]models.Message, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	messages := []models.Message{}
+	for _, message := range r.db.messages {
+		if userID == 0 || r.visibleTo(message, userID) {
+			messages = append(messages, r.messageWithNames(message))
+		}
+	}
+	sortMessages(messages, true)
+	return messages, nil
+}
+
+func (r *memConversations) RecentMessages(ctx context.Context, userID, limit int) ([]models.Message, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	messages, err := r.ListMessages(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(messages) > limit {
+		messages = messages[:limit]
+	}
+	return messages, nil
+}
+
+func (r *memConversations) MessagesAfter(ctx context.Context, userID, afterID, limit int) ([]models.Message, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	messages := []models.Message{}
+	for _, message := range r.db.messages {
+		if message.ID > afterID && r.visibleTo(message, userID) {
+			messages = append(messages, r.messageWithNames(message))
+		}
+	}
+	sort.Slice(messages, func(i, j int) bool { return messages[i].ID < messages[j].ID })
+	if len(messages) > limit {
+		messages = messages[:limit]
+	}
+	return messages, nil
+}
+
+func (r *memConversations) MessageByClientID(ctx context.Context, senderID int, clientID string) (*models.Message, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, message := range r.db.messages {
+		if message.SenderID == senderID && message.ClientID != nil && *message.ClientID == clientID {
+			message = r.messageWithNames(message)
+			return &message, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memConversations) UnreadCount(ctx context.Context, userID int) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	count := 0
+	for _, message := range r.db.messages {
+		participant, ok := r.db.participants[participantKey{message.ConversationID, userID}]
+		if ok && message.SenderID != userID && message.ID > participant.LastReadMessageID {
+			count++
+		}
+	}
+	return count, nil
+}
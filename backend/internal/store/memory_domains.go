@@ -0,0 +1,114 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"saas-management-api/internal/models"
+)
+
+type memDomains struct {
+	db *memoryDB
+}
+
+func (r *memDomains) Get(ctx context.Context, id int) (*models.Domain, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	domain, ok := r.db.domains[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &domain, nil
+}
+
+func (r *memDomains) GetByName(ctx context.Context, name string) (*models.Domain, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, domain := range r.db.domains {
+		if domain.DomainName == name {
+			return &domain, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memDomains) ListForBusiness(ctx context.Context, businessID int) ([]models.Domain, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	domains := []models.Domain{}
+	for _, domain := range r.db.domains {
+		if domain.BusinessID != nil && *domain.BusinessID == businessID {
+			domains = append(domains, domain)
+		}
+	}
+	sort.Slice(domains, func(i, j int) bool { return domains[i].DomainName < domains[j].DomainName })
+	return domains, nil
+}
+
+func (r *memDomains) Create(ctx context.Context, domain *models.Domain) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, existing := range r.db.domains {
+		if existing.DomainName == domain.DomainName {
+			return ErrConflict
+		}
+	}
+	now := time.Now()
+	domain.ID = r.db.nextID("domains")
+	if domain.Status == "" {
+		domain.Status = models.DomainPending
+	}
+	domain.CreatedAt = now
+	domain.UpdatedAt = now
+	r.db.domains[domain.ID] = *domain
+	return nil
+}
+
+func (r *memDomains) MarkVerified(ctx context.Context, id int, at time.Time) (*models.Domain, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	domain, ok := r.db.domains[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	domain.Status = models.DomainVerified
+	domain.VerifiedAt = &at
+	domain.UpdatedAt = at
+	r.db.domains[id] = domain
+	return &domain, nil
+}
+
+func (r *memDomains) Delete(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.domains[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.db.domains, id)
+	return nil
+}
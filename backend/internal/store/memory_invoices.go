@@ -0,0 +1,99 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"saas-management-api/internal/models"
+)
+
+type memInvoices struct {
+	db *memoryDB
+}
+
+func (r *memInvoices) ListForBusiness(ctx context.Context, businessID int) ([]models.Invoice, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	invoices := []models.Invoice{}
+	for _, invoice := range r.db.invoices {
+		if invoice.BusinessID == businessID {
+			invoices = append(invoices, invoice)
+		}
+	}
+	sort.Slice(invoices, func(i, j int) bool {
+		if !invoices[i].CreatedAt.Equal(invoices[j].CreatedAt) {
+			return invoices[i].CreatedAt.After(invoices[j].CreatedAt)
+		}
+		return invoices[i].ID > invoices[j].ID
+	})
+	return invoices, nil
+}
+
+func (r *memInvoices) Get(ctx context.Context, id int) (*models.Invoice, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	invoice, ok := r.db.invoices[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &invoice, nil
+}
+
+func (r *memInvoices) Create(ctx context.Context, invoice *models.Invoice) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if invoice.Status == "" {
+		invoice.Status = models.InvoiceUnpaid
+	}
+	now := time.Now()
+	invoice.ID = r.db.nextID("invoices")
+	invoice.CreatedAt = now
+	invoice.UpdatedAt = now
+	r.db.invoices[invoice.ID] = *invoice
+	return nil
+}
+
+func (r *memInvoices) SetStatus(ctx context.Context, id int, status string, at time.Time) (*models.Invoice, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	invoice, ok := r.db.invoices[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	invoice.Status = status
+	invoice.UpdatedAt = at
+	r.db.invoices[id] = invoice
+	return &invoice, nil
+}
+
+func (r *memInvoices) CreatePayment(ctx context.Context, payment *models.Payment) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	now := time.Now()
+	payment.ID = r.db.nextID("payments")
+	payment.CreatedAt = now
+	payment.UpdatedAt = now
+	r.db.payments[payment.ID] = *payment
+	return nil
+}
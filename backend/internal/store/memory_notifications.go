@@ -0,0 +1,134 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"saas-management-api/internal/listing"
+	"saas-management-api/internal/models"
+)
+
+type memNotifications struct {
+	db *memoryDB
+}
+
+// visible reports whether userID may see notification. Callers must hold mu.
+func (r *memNotifications) visible(notification models.Notification, userID int) bool {
+	return userID == 0 || notification.UserID == userID
+}
+
+// withNames fills in the join fields. Callers must hold mu.
+func (r *memNotifications) withNames(notification models.Notification) models.Notification {
+	notification.UserName = r.db.userName(notification.UserID)
+	notification.FromUserName = nil
+	if notification.FromUserID != nil {
+		notification.FromUserName = r.db.userName(*notification.FromUserID)
+	}
+	return notification
+}
+
+func (r *memNotifications) List(ctx context.Context, userID int, spec listing.Spec) (listing.Page[models.Notification], error) {
+	if err := ctx.Err(); err != nil {
+		return listing.Page[models.Notification]{}, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	notifications := []models.Notification{}
+	for _, notification := range r.db.notifications {
+		if r.visible(notification, userID) {
+			notifications = append(notifications, r.withNames(notification))
+		}
+	}
+	return listing.Apply(notifications, spec, NotificationListing), nil
+}
+
+func (r *memNotifications) Get(ctx context.Context, id, userID int) (*models.Notification, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	notification, ok := r.db.notifications[id]
+	if !ok || !r.visible(notification, userID) {
+		return nil, ErrNotFound
+	}
+	notification = r.withNames(notification)
+	return &notification, nil
+}
+
+func (r *memNotifications) Create(ctx context.Context, notification *models.Notification) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	now := time.Now()
+	notification.ID = r.db.nextID("notifications")
+	notification.CreatedAt = now
+	notification.UpdatedAt = now
+	notification.UserName = nil
+	notification.FromUserName = nil
+	r.db.notifications[notification.ID] = *notification
+	return nil
+}
+
+func (r *memNotifications) Update(ctx context.Context, id, userID int, update NotificationUpdate) (*models.Notification, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	notification, ok := r.db.notifications[id]
+	if !ok || !r.visible(notification, userID) {
+		return nil, ErrNotFound
+	}
+	if update.IsRead != nil {
+		notification.IsRead = *update.IsRead
+		if *update.IsRead {
+			notification.Status = "read"
+		}
+	}
+	if update.Status != nil {
+		notification.Status = *update.Status
+	}
+	notification.UpdatedAt = time.Now()
+	r.db.notifications[id] = notification
+
+	notification = r.withNames(notification)
+	return &notification, nil
+}
+
+func (r *memNotifications) Delete(ctx context.Context, id, userID int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	notification, ok := r.db.notifications[id]
+	if !ok || !r.visible(notification, userID) {
+		return ErrNotFound
+	}
+	delete(r.db.notifications, id)
+	return nil
+}
+
+func (r *memNotifications) UnreadCount(ctx context.Context, userID int) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	count := 0
+	for _, notification := range r.db.notifications {
+		if notification.UserID == userID && !notification.IsRead {
+			count++
+		}
+	}
+	return count, nil
+}
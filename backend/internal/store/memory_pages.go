@@ -0,0 +1,137 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"saas-management-api/internal/models"
+)
+
+type memPages struct {
+	db *memoryDB
+}
+
+func (r *memPages) List(ctx context.Context, websiteID int) ([]models.WebsitePage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	pages := []models.WebsitePage{}
+	for _, page := range r.db.pages {
+		if page.WebsiteID == websiteID {
+			pages = append(pages, page)
+		}
+	}
+	sort.Slice(pages, func(i, j int) bool {
+		if pages[i].Position != pages[j].Position {
+			return pages[i].Position < pages[j].Position
+		}
+		return pages[i].ID < pages[j].ID
+	})
+	return pages, nil
+}
+
+func (r *memPages) Get(ctx context.Context, websiteID, id int) (*models.WebsitePage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	page, ok := r.db.pages[id]
+	if !ok || page.WebsiteID != websiteID {
+		return nil, ErrNotFound
+	}
+	return &page, nil
+}
+
+func (r *memPages) Create(ctx context.Context, page *models.WebsitePage) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if err := r.checkSlug(page); err != nil {
+		return err
+	}
+	if page.Position <= 0 {
+		for _, existing := range r.db.pages {
+			if existing.WebsiteID == page.WebsiteID {
+				page.Position = max(page.Position, existing.Position)
+			}
+		}
+		page.Position++
+	}
+	now := time.Now()
+	page.ID = r.db.nextID("website_pages")
+	page.CreatedAt = now
+	page.UpdatedAt = now
+	r.db.pages[page.ID] = *page
+	return nil
+}
+
+func (r *memPages) Update(ctx context.Context, page *models.WebsitePage) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	existing, ok := r.db.pages[page.ID]
+	if !ok || existing.WebsiteID != page.WebsiteID {
+		return ErrNotFound
+	}
+	if err := r.checkSlug(page); err != nil {
+		return err
+	}
+	page.CreatedAt = existing.CreatedAt
+	page.UpdatedAt = time.Now()
+	r.db.pages[page.ID] = *page
+	return nil
+}
+
+func (r *memPages) Delete(ctx context.Context, websiteID, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	page, ok := r.db.pages[id]
+	if !ok || page.WebsiteID != websiteID {
+		return ErrNotFound
+	}
+	delete(r.db.pages, id)
+	return nil
+}
+
+func (r *memPages) DeleteAll(ctx context.Context, websiteID int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for id, page := range r.db.pages {
+		if page.WebsiteID == websiteID {
+			delete(r.db.pages, id)
+		}
+	}
+	return nil
+}
+
+// checkSlug enforces the per-website unique slug constraint. Callers must
+// hold mu.
+func (r *memPages) checkSlug(page *models.WebsitePage) error {
+	for id, existing := range r.db.pages {
+		if id != page.ID && existing.WebsiteID == page.WebsiteID && existing.Slug == page.Slug {
+			return fmt.Errorf("%w: page slug %q already used", ErrConflict, page.Slug)
+		}
+	}
+	return nil
+}
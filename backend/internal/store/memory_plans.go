@@ -0,0 +1,93 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"saas-management-api/internal/models"
+)
+
+type memPlans struct {
+	db *memoryDB
+}
+
+func (r *memPlans) List(ctx context.Context) ([]models.Plan, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	plans := make([]models.Plan, 0, len(r.db.plans))
+	for _, plan := range r.db.plans {
+		plans = append(plans, plan)
+	}
+	sort.Slice(plans, func(i, j int) bool {
+		if plans[i].Price != plans[j].Price {
+			return plans[i].Price < plans[j].Price
+		}
+		return plans[i].ID < plans[j].ID
+	})
+	return plans, nil
+}
+
+func (r *memPlans) Get(ctx context.Context, id int) (*models.Plan, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	plan, ok := r.db.plans[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &plan, nil
+}
+
+func (r *memPlans) Create(ctx context.Context, plan *models.Plan) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	now := time.Now()
+	plan.ID = r.db.nextID("plans")
+	plan.CreatedAt = now
+	plan.UpdatedAt = now
+	r.db.plans[plan.ID] = *plan
+	return nil
+}
+
+func (r *memPlans) Update(ctx context.Context, plan *models.Plan) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	existing, ok := r.db.plans[plan.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	plan.CreatedAt = existing.CreatedAt
+	plan.UpdatedAt = time.Now()
+	r.db.plans[plan.ID] = *plan
+	return nil
+}
+
+func (r *memPlans) Delete(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.plans[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.db.plans, id)
+	return nil
+}
@@ -0,0 +1,89 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"saas-management-api/internal/listing"
+	"saas-management-api/internal/models"
+)
+
+type memSubscriptions struct {
+	db *memoryDB
+}
+
+func (r *memSubscriptions) List(ctx context.Context, spec listing.Spec) (listing.Page[models.Subscription], error) {
+	if err := ctx.Err(); err != nil {
+		return listing.Page[models.Subscription]{}, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	subscriptions := make([]models.Subscription, 0, len(r.db.subscriptions))
+	for _, subscription := range r.db.subscriptions {
+		subscriptions = append(subscriptions, subscription)
+	}
+	return listing.Apply(subscriptions, spec, SubscriptionListing), nil
+}
+
+func (r *memSubscriptions) Get(ctx context.Context, id int) (*models.Subscription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	subscription, ok := r.db.subscriptions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &subscription, nil
+}
+
+func (r *memSubscriptions) Create(ctx context.Context, subscription *models.Subscription) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	now := time.Now()
+	subscription.ID = r.db.nextID("subscriptions")
+	subscription.CreatedAt = now
+	subscription.UpdatedAt = now
+	r.db.subscriptions[subscription.ID] = *subscription
+	return nil
+}
+
+func (r *memSubscriptions) Update(ctx context.Context, subscription *models.Subscription) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	existing, ok := r.db.subscriptions[subscription.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	existing.Status = subscription.Status
+	existing.EndsAt = subscription.EndsAt
+	existing.UpdatedAt = time.Now()
+	r.db.subscriptions[subscription.ID] = existing
+	*subscription = existing
+	return nil
+}
+
+func (r *memSubscriptions) Delete(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.subscriptions[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.db.subscriptions, id)
+	return nil
+}
@@ -0,0 +1,46 @@
+package store
+
+import (
+	"context"
+	"slices"
+)
+
+type memTLSCache struct {
+	db *memoryDB
+}
+
+func (r *memTLSCache) Get(ctx context.Context, key string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	data, ok := r.db.tlsCache[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return slices.Clone(data), nil
+}
+
+func (r *memTLSCache) Put(ctx context.Context, key string, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	r.db.tlsCache[key] = slices.Clone(data)
+	return nil
+}
+
+func (r *memTLSCache) Delete(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	delete(r.db.tlsCache, key)
+	return nil
+}
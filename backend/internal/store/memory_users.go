@@ -0,0 +1,123 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"saas-management-api/internal/models"
+)
+
+type memUsers struct {
+	db *memoryDB
+}
+
+func (r *memUsers) Get(ctx context.Context, id int) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	user, ok := r.db.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+func (r *memUsers) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, user := range r.db.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memUsers) Create(ctx context.Context, user *models.User) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, existing := range r.db.users {
+		if existing.Email == user.Email {
+			return fmt.Errorf("%w: email %q already taken", ErrConflict, user.Email)
+		}
+	}
+	now := time.Now()
+	user.ID = r.db.nextID("users")
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	r.db.users[user.ID] = *user
+	return nil
+}
+
+func (r *memUsers) Search(ctx context.Context, query string, limit int) ([]models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	users := []models.User{}
+	for _, user := range r.db.users {
+		if user.Role == "admin" {
+			continue
+		}
+		businessName := ""
+		if user.BusinessName != nil {
+			businessName = *user.BusinessName
+		}
+		if matchWildcards(user.Name, query) || matchWildcards(businessName, query) {
+			users = append(users, user)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	if len(users) > limit {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+func (r *memUsers) AdminIDs(ctx context.Context) ([]int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	var ids []int
+	for id, user := range r.db.users {
+		if user.Role == "admin" {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	return ids, nil
+}
+
+// matchWildcards reports whether s contains every space-separated part of
+// query in order, ignoring case. It mirrors the ILIKE pattern used by the
+// Postgres store.
+func matchWildcards(s, query string) bool {
+	s = strings.ToLower(s)
+	for _, part := range strings.Split(strings.ToLower(query), " ") {
+		i := strings.Index(s, part)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(part):]
+	}
+	return true
+}
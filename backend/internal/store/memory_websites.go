@@ -0,0 +1,263 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"saas-management-api/internal/listing"
+	"saas-management-api/internal/models"
+)
+
+type memWebsites struct {
+	db *memoryDB
+}
+
+func (r *memWebsites) List(ctx context.Context, spec listing.Spec) (listing.Page[models.Website], error) {
+	if err := ctx.Err(); err != nil {
+		return listing.Page[models.Website]{}, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	websites := make([]models.Website, 0, len(r.db.websites))
+	for _, website := range r.db.websites {
+		websites = append(websites, website)
+	}
+	return listing.Apply(websites, spec, WebsiteListing), nil
+}
+
+func (r *memWebsites) Get(ctx context.Context, id int) (*models.Website, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	website, ok := r.db.websites[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &website, nil
+}
+
+func (r *memWebsites) Create(ctx context.Context, website *models.Website) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	now := time.Now()
+	website.ID = r.db.nextID("websites")
+	if website.ThemeSettings == nil {
+		website.ThemeSettings = models.JSONB{}
+	}
+	website.CreatedAt = now
+	website.UpdatedAt = now
+	r.db.websites[website.ID] = *website
+	return nil
+}
+
+func (r *memWebsites) Update(ctx context.Context, website *models.Website) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	existing, ok := r.db.websites[website.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	if website.ThemeSettings == nil {
+		website.ThemeSettings = models.JSONB{}
+	}
+	website.BusinessID = existing.BusinessID
+	website.IsDemo = existing.IsDemo
+	website.IsClaimed = existing.IsClaimed
+	website.Status = existing.Status
+	website.CreatedAt = existing.CreatedAt
+	website.UpdatedAt = time.Now()
+	website.PublishedRevision = existing.PublishedRevision
+	website.PublishedAt = existing.PublishedAt
+	website.Thumbnails = existing.Thumbnails
+	r.db.websites[website.ID] = *website
+	return nil
+}
+
+func (r *memWebsites) Claim(ctx context.Context, id, businessID int, at time.Time) (*models.Website, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	website, ok := r.db.websites[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if !website.IsDemo || website.IsClaimed {
+		return nil, fmt.Errorf("%w: website %d is not an unclaimed demo", ErrConflict, id)
+	}
+	website.BusinessID = &businessID
+	website.IsClaimed = true
+	website.UpdatedAt = at
+	r.db.websites[id] = website
+	return &website, nil
+}
+
+func (r *memWebsites) CreateRevision(ctx context.Context, revision *models.WebsiteRevision) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	latest := 0
+	for _, existing := range r.db.revisions {
+		if existing.WebsiteID == revision.WebsiteID {
+			latest = max(latest, existing.Revision)
+		}
+	}
+	revision.ID = r.db.nextID("website_revisions")
+	revision.Revision = latest + 1
+	revision.CreatedAt = time.Now()
+	r.db.revisions[revision.ID] = *revision
+	return nil
+}
+
+func (r *memWebsites) Revisions(ctx context.Context, websiteID int) ([]models.WebsiteRevision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	revisions := []models.WebsiteRevision{}
+	for _, revision := range r.db.revisions {
+		if revision.WebsiteID == websiteID {
+			revisions = append(revisions, revision)
+		}
+	}
+	sort.Slice(revisions, func(i, j int) bool { return revisions[i].Revision > revisions[j].Revision })
+	return revisions, nil
+}
+
+func (r *memWebsites) Revision(ctx context.Context, websiteID, revision int) (*models.WebsiteRevision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, existing := range r.db.revisions {
+		if existing.WebsiteID == websiteID && existing.Revision == revision {
+			return &existing, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memWebsites) Publish(ctx context.Context, websiteID, revision int, at time.Time) (*models.Website, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	website, ok := r.db.websites[websiteID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	website.PublishedRevision = &revision
+	website.PublishedAt = &at
+	r.db.websites[websiteID] = website
+	return &website, nil
+}
+
+func (r *memWebsites) Published(ctx context.Context, businessID int) (*models.Website, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	var latest *models.Website
+	for _, website := range r.db.websites {
+		if website.BusinessID == nil || *website.BusinessID != businessID || website.PublishedAt == nil {
+			continue
+		}
+		if latest == nil || website.PublishedAt.After(*latest.PublishedAt) ||
+			(website.PublishedAt.Equal(*latest.PublishedAt) && website.ID > latest.ID) {
+			latest = &website
+		}
+	}
+	if latest == nil {
+		return nil, ErrNotFound
+	}
+	return latest, nil
+}
+
+func (r *memWebsites) SetThumbnails(ctx context.Context, id int, thumbnails models.JSONB, imageURL string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	website, ok := r.db.websites[id]
+	if !ok {
+		return ErrNotFound
+	}
+	generated := website.ImageURL == nil || *website.ImageURL == ""
+	for _, url := range website.Thumbnails {
+		generated = generated || website.ImageURL != nil && url == *website.ImageURL
+	}
+	if generated {
+		website.ImageURL = &imageURL
+	}
+	website.Thumbnails = thumbnails
+	r.db.websites[id] = website
+	return nil
+}
+
+func (r *memWebsites) Delete(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.websites[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.db.websites, id)
+	for revisionID, revision := range r.db.revisions {
+		if revision.WebsiteID == id {
+			delete(r.db.revisions, revisionID)
+		}
+	}
+	for pageID, page := range r.db.pages {
+		if page.WebsiteID == id {
+			delete(r.db.pages, pageID)
+		}
+	}
+	for key := range r.db.analyticsVisitors {
+		if key.websiteID == id {
+			delete(r.db.analyticsVisitors, key)
+		}
+	}
+	for key := range r.db.analyticsDays {
+		if key.websiteID == id {
+			delete(r.db.analyticsDays, key)
+		}
+	}
+	for key := range r.db.analyticsCounts {
+		if key.websiteID == id {
+			delete(r.db.analyticsCounts, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,421 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"saas-management-api/internal/listing"
+	"saas-management-api/internal/models"
+)
+
+var (
+	// ErrDuplicate is returned by SendMessage when the sender already sent
+	// a message with the same client ID.
+	ErrDuplicate = errors.New("store: duplicate message")
+	// ErrUnknownUser is returned when adding a user who doesn't exist to a
+	// conversation.
+	ErrUnknownUser = errors.New("store: unknown user")
+	// ErrNotSender is returned when a user changes a message someone else
+	// sent.
+	ErrNotSender = errors.New("store: not the message's sender")
+	// ErrAttachmentUnavailable is returned by SendMessage when an
+	// attachment isn't the sender's or was already sent.
+	ErrAttachmentUnavailable = errors.New("store: attachment unavailable")
+)
+
+// SendMessage stores message and records the activity on its conversation,
+// all in one transaction. A message with a ConversationID goes to that
+// conversation, which must include the sender, and gets the other user of
+// a direct conversation as its receiver. Otherwise it goes to the direct
+// conversation between its sender and receiver, created if needed, and its
+// ConversationID is filled in. The attachments with attachmentIDs, which
+// the sender must have uploaded and not sent yet, are attached to it. A
+// message without a MessageType is "text", or with attachments "image" if
+// they are all images and "file" otherwise. If
+// the sender already sent a message with the same ClientID, message is
+// replaced with that one and ErrDuplicate is returned. That includes a send
+// that raced this one and committed first.
+func (s *Store) SendMessage(ctx context.Context, message *models.Message, attachmentIDs ...int) error {
+	err := s.WithTx(ctx, func(tx *Store) error {
+		if message.ClientID != nil {
+			_, err := tx.Conversations.MessageByClientID(ctx, message.SenderID, *message.ClientID)
+			if err == nil {
+				return ErrDuplicate
+			}
+			if !errors.Is(err, ErrNotFound) {
+				return err
+			}
+		}
+
+		var conversation *models.Conversation
+		var err error
+		if message.ConversationID != 0 {
+			conversation, err = tx.Conversations.GetForUser(ctx, message.ConversationID, message.SenderID)
+			if err != nil {
+				return err
+			}
+			message.ReceiverID = conversation.Peer(message.SenderID)
+		} else {
+			if message.ReceiverID == nil {
+				return ErrNotFound
+			}
+			conversation, err = tx.Conversations.GetOrCreate(ctx, message.SenderID, *message.ReceiverID)
+			if err != nil {
+				return err
+			}
+		}
+
+		message.ConversationID = conversation.ID
+		if message.MessageType == "" {
+			message.MessageType, err = tx.messageType(ctx, attachmentIDs)
+			if err != nil {
+				return err
+			}
+		}
+		if err := tx.Conversations.CreateMessage(ctx, message); err != nil {
+			return err
+		}
+		if len(attachmentIDs) > 0 {
+			err := tx.Attachments.Attach(ctx, message.ID, message.SenderID, attachmentIDs)
+			if errors.Is(err, ErrNotFound) {
+				return ErrAttachmentUnavailable
+			}
+			if err != nil {
+				return err
+			}
+			sent, err := tx.Conversations.MessageForUser(ctx, message.ID, message.SenderID)
+			if err != nil {
+				return err
+			}
+			*message = *sent
+		}
+		return tx.Conversations.Touch(ctx, conversation.ID, message.CreatedAt)
+	})
+	if !errors.Is(err, ErrDuplicate) {
+		return err
+	}
+	// A racing send's insert aborts the transaction, so the original is
+	// fetched after it.
+	existing, err := s.Conversations.MessageByClientID(ctx, message.SenderID, *message.ClientID)
+	if err != nil {
+		return err
+	}
+	*message = *existing
+	return ErrDuplicate
+}
+
+// messageType returns the type of a message with attachmentIDs.
+func (s *Store) messageType(ctx context.Context, attachmentIDs []int) (string, error) {
+	if len(attachmentIDs) == 0 {
+		return "text", nil
+	}
+	for _, id := range attachmentIDs {
+		attachment, err := s.Attachments.Get(ctx, id)
+		if errors.Is(err, ErrNotFound) {
+			return "", ErrAttachmentUnavailable
+		}
+		if err != nil {
+			return "", err
+		}
+		if !attachment.IsImage() {
+			return "file", nil
+		}
+	}
+	return "image", nil
+}
+
+// AttachmentForUser returns an attachment userID may download: one they
+// uploaded and haven't sent yet, or one of a message they can see. It
+// returns ErrNotFound for any other attachment.
+func (s *Store) AttachmentForUser(ctx context.Context, id, userID int) (*models.Attachment, error) {
+	attachment, err := s.Attachments.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if attachment.MessageID == nil {
+		if attachment.OwnerID != userID {
+			return nil, ErrNotFound
+		}
+		return attachment, nil
+	}
+
+	if _, err := s.Conversations.MessageForUser(ctx, *attachment.MessageID, userID); err != nil {
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// MessageWindow is a page of a conversation's messages opened around one
+// message, oldest first. NextCursor continues to newer messages with the
+// default sort of MessageListing, and PrevCursor to older messages with
+// sort=-created_at. Each is empty when there are no more messages that way.
+type MessageWindow struct {
+	Items      []models.Message
+	NextCursor string
+	PrevCursor string
+}
+
+// MessagesAround returns about limit messages of a conversation centred on
+// messageID, with at least one on each side when there are any. It returns
+// ErrNotFound unless userID can see the message and it belongs to the
+// conversation.
+func (s *Store) MessagesAround(ctx context.Context, conversationID, userID, messageID, limit int) (*MessageWindow, error) {
+	target, err := s.Conversations.MessageForUser(ctx, messageID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if target.ConversationID != conversationID {
+		return nil, ErrNotFound
+	}
+
+	at := &listing.Cursor{Value: target.CreatedAt, ID: target.ID}
+	page := func(limit int, older bool) (listing.Page[models.Message], error) {
+		return s.Conversations.Messages(ctx, conversationID, userID, listing.Spec{
+			Limit: limit, Sort: "created_at", Desc: older, After: at,
+		})
+	}
+	older, err := page(max((limit-1)/2, 1), true)
+	if err != nil {
+		return nil, err
+	}
+	newer, err := page(max(limit-1-len(older.Items), 1), false)
+	if err != nil {
+		return nil, err
+	}
+	// Near the latest message, fill the window with older ones instead.
+	if rest := limit - 1 - len(newer.Items); rest > len(older.Items) && older.NextCursor != "" {
+		if older, err = page(rest, true); err != nil {
+			return nil, err
+		}
+	}
+
+	window := &MessageWindow{NextCursor: newer.NextCursor, PrevCursor: older.NextCursor}
+	for i := len(older.Items) - 1; i >= 0; i-- {
+		window.Items = append(window.Items, older.Items[i])
+	}
+	window.Items = append(window.Items, *target)
+	window.Items = append(window.Items, newer.Items...)
+	return window, nil
+}
+
+// ReadMessages moves userID's read marker in a conversation up to upToID,
+// returning receipts for the senders of the messages it covered. It returns
+// ErrNotFound if the user doesn't participate in the conversation.
+func (s *Store) ReadMessages(ctx context.Context, conversationID, userID, upToID int, at time.Time) ([]models.MessageReceipt, error) {
+	var messages []models.Message
+	err := s.WithTx(ctx, func(tx *Store) error {
+		if _, err := tx.Conversations.GetForUser(ctx, conversationID, userID); err != nil {
+			return err
+		}
+		var err error
+		messages, err = tx.Conversations.MarkRead(ctx, conversationID, userID, upToID, at)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return receipts(models.ReceiptRead, userID, messages, at), nil
+}
+
+// DeliverMessages records that the messages among ids addressed to
+// receiverID reached one of their connections, returning receipts for the
+// senders of those not delivered before.
+func (s *Store) DeliverMessages(ctx context.Context, receiverID int, ids []int, at time.Time) ([]models.MessageReceipt, error) {
+	messages, err := s.Conversations.MarkDelivered(ctx, receiverID, ids, at)
+	if err != nil {
+		return nil, err
+	}
+	return receipts(models.ReceiptDelivered, receiverID, messages, at), nil
+}
+
+// receipts groups the messages that reached receiverID into one receipt
+// per conversation and sender.
+func receipts(status string, receiverID int, messages []models.Message, at time.Time) []models.MessageReceipt {
+	sort.Slice(messages, func(i, j int) bool { return messages[i].ID < messages[j].ID })
+	type key struct{ conversationID, senderID int }
+	index := map[key]int{}
+	result := []models.MessageReceipt{}
+	for _, message := range messages {
+		k := key{message.ConversationID, message.SenderID}
+		i, ok := index[k]
+		if !ok {
+			i = len(result)
+			index[k] = i
+			result = append(result, models.MessageReceipt{
+				Status:         status,
+				ConversationID: message.ConversationID,
+				SenderID:       message.SenderID,
+				ReceiverID:     receiverID,
+				At:             at,
+			})
+		}
+		result[i].MessageIDs = append(result[i].MessageIDs, message.ID)
+	}
+	return result
+}
+
+// EditMessage replaces the text of a text message userID sent, keeping the
+// previous text in its edit history, and returns the edited message. It
+// returns ErrNotFound if the user can't see the message, ErrNotSender if
+// they didn't send it, and ErrConflict if it was deleted or isn't text.
+func (s *Store) EditMessage(ctx context.Context, id, userID int, text string, at time.Time) (*models.Message, error) {
+	var message *models.Message
+	err := s.WithTx(ctx, func(tx *Store) error {
+		current, err := tx.Conversations.MessageForUser(ctx, id, userID)
+		if err != nil {
+			return err
+		}
+		if current.SenderID != userID {
+			return ErrNotSender
+		}
+		if current.DeletedAt != nil || current.MessageType != "text" {
+			return ErrConflict
+		}
+		if current.Message == text {
+			message = current
+			return nil
+		}
+
+		if err := tx.Conversations.EditMessage(ctx, id, text, at); err != nil {
+			return err
+		}
+		message, err = tx.Conversations.MessageForUser(ctx, id, userID)
+		return err
+	})
+	return message, err
+}
+
+// DeleteMessage deletes a message userID can see. Deleting for everyone
+// takes the sender and leaves a tombstone, which is returned; deleting for
+// the user only hides the message from them, and returns it as it was. It
+// returns ErrNotFound if the user can't see the message and ErrNotSender if
+// they delete someone else's message for everyone.
+func (s *Store) DeleteMessage(ctx context.Context, id, userID int, forEveryone bool, at time.Time) (*models.Message, error) {
+	var message *models.Message
+	err := s.WithTx(ctx, func(tx *Store) error {
+		var err error
+		message, err = tx.Conversations.MessageForUser(ctx, id, userID)
+		if err != nil {
+			return err
+		}
+		if !forEveryone {
+			return tx.Conversations.HideMessage(ctx, id, userID, at)
+		}
+
+		if message.SenderID != userID {
+			return ErrNotSender
+		}
+		if message.DeletedAt != nil {
+			return nil
+		}
+		if err := tx.Conversations.DeleteMessage(ctx, id, at); err != nil {
+			return err
+		}
+		message, err = tx.Conversations.MessageForUser(ctx, id, userID)
+		return err
+	})
+	return message, err
+}
+
+// ReactToMessage adds or, with remove, drops userID's reaction with an
+// emoji to a message they can see, and returns the message with its
+// reactions. It returns ErrNotFound if the user can't see the message and
+// ErrConflict if it was deleted.
+func (s *Store) ReactToMessage(ctx context.Context, id, userID int, emoji string, remove bool, at time.Time) (*models.Message, error) {
+	var message *models.Message
+	err := s.WithTx(ctx, func(tx *Store) error {
+		current, err := tx.Conversations.MessageForUser(ctx, id, userID)
+		if err != nil {
+			return err
+		}
+		if current.DeletedAt != nil {
+			return ErrConflict
+		}
+
+		if remove {
+			err = tx.Conversations.RemoveReaction(ctx, id, userID, emoji)
+		} else {
+			err = tx.Conversations.AddReaction(ctx, id, userID, emoji, at)
+		}
+		if err != nil {
+			return err
+		}
+		message, err = tx.Conversations.MessageForUser(ctx, id, userID)
+		return err
+	})
+	return message, err
+}
+
+// CreateGroup creates a group conversation owned by creatorID with the
+// other members, and returns it with its participants. It returns
+// ErrUnknownUser if a member doesn't exist.
+func (s *Store) CreateGroup(ctx context.Context, creatorID int, title string, memberIDs []int) (*models.Conversation, error) {
+	var conversation *models.Conversation
+	err := s.WithTx(ctx, func(tx *Store) error {
+		if err := tx.checkUsers(ctx, memberIDs); err != nil {
+			return err
+		}
+
+		group := models.Conversation{Title: &title, CreatedBy: &creatorID}
+		if err := tx.Conversations.CreateGroup(ctx, &group); err != nil {
+			return err
+		}
+		if _, err := tx.Conversations.AddParticipants(ctx, group.ID, []int{creatorID}, models.ParticipantOwner, group.CreatedAt); err != nil {
+			return err
+		}
+		if _, err := tx.Conversations.AddParticipants(ctx, group.ID, memberIDs, models.ParticipantMember, group.CreatedAt); err != nil {
+			return err
+		}
+
+		var err error
+		conversation, err = tx.Conversations.GetForUser(ctx, group.ID, creatorID)
+		return err
+	})
+	return conversation, err
+}
+
+// AddParticipants adds users to a group conversation that userID
+// participates in, and returns the conversation with its participants and
+// the IDs of the users who joined. It returns ErrNotFound if userID doesn't
+// participate, ErrConflict if the conversation isn't a group, and
+// ErrUnknownUser if one of the users doesn't exist.
+func (s *Store) AddParticipants(ctx context.Context, conversationID, userID int, userIDs []int) (*models.Conversation, []int, error) {
+	var conversation *models.Conversation
+	var added []int
+	err := s.WithTx(ctx, func(tx *Store) error {
+		group, err := tx.Conversations.GetForUser(ctx, conversationID, userID)
+		if err != nil {
+			return err
+		}
+		if !group.IsGroup {
+			return ErrConflict
+		}
+		if err := tx.checkUsers(ctx, userIDs); err != nil {
+			return err
+		}
+
+		added, err = tx.Conversations.AddParticipants(ctx, conversationID, userIDs, models.ParticipantMember, time.Now())
+		if err != nil {
+			return err
+		}
+		conversation, err = tx.Conversations.GetForUser(ctx, conversationID, userID)
+		return err
+	})
+	return conversation, added, err
+}
+
+// checkUsers returns ErrUnknownUser unless every user exists.
+func (s *Store) checkUsers(ctx context.Context, userIDs []int) error {
+	for _, id := range userIDs {
+		if _, err := s.Users.Get(ctx, id); err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return fmt.Errorf("%w: %d", ErrUnknownUser, id)
+			}
+			return err
+		}
+	}
+	return nil
+}
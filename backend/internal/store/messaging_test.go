@@ -0,0 +1,224 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"saas-management-api/internal/models"
+)
+
+func newMessagingUsers(t *testing.T, st *Store, names ...string) []int {
+	t.Helper()
+	ids := make([]int, len(names))
+	for i, name := range names {
+		user := &models.User{Name: name, Email: name + "@example.com", Role: "owner"}
+		if err := st.Users.Create(context.Background(), user); err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = user.ID
+	}
+	return ids
+}
+
+// send stores a text message from senderID, to a conversation or, with a
+// conversationID of 0, directly to receiverID.
+func send(t *testing.T, st *Store, senderID, conversationID, receiverID int, text string) *models.Message {
+	t.Helper()
+	message := &models.Message{SenderID: senderID, ConversationID: conversationID, Message: text}
+	if conversationID == 0 {
+		message.ReceiverID = &receiverID
+	}
+	if err := st.SendMessage(context.Background(), message); err != nil {
+		t.Fatal(err)
+	}
+	return message
+}
+
+func TestReadMessagesReceiptsPerSender(t *testing.T) {
+	st := NewMemory()
+	ctx := context.Background()
+	ids := newMessagingUsers(t, st, "alice", "bob", "carol", "dave")
+	alice, bob, carol, dave := ids[0], ids[1], ids[2], ids[3]
+	group, err := st.CreateGroup(ctx, alice, "Team", []int{bob, carol})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b1 := send(t, st, bob, group.ID, 0, "one")
+	c1 := send(t, st, carol, group.ID, 0, "two")
+	send(t, st, alice, group.ID, 0, "mine")
+	b2 := send(t, st, bob, group.ID, 0, "three")
+	b3 := send(t, st, bob, group.ID, 0, "four")
+
+	at := time.Now()
+	got, err := st.ReadMessages(ctx, group.ID, alice, b2.ID, at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []models.MessageReceipt{
+		{Status: models.ReceiptRead, ConversationID: group.ID, SenderID: bob, ReceiverID: alice, MessageIDs: []int{b1.ID, b2.ID}, At: at},
+		{Status: models.ReceiptRead, ConversationID: group.ID, SenderID: carol, ReceiverID: alice, MessageIDs: []int{c1.ID}, At: at},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("receipts = %+v, want %+v", got, want)
+	}
+
+	// Reading the same messages again, or earlier ones, sends nothing.
+	for _, upTo := range []int{b2.ID, b1.ID} {
+		if got, err := st.ReadMessages(ctx, group.ID, alice, upTo, at); err != nil || len(got) != 0 {
+			t.Fatalf("reading up to %d again = %+v, %v; want no receipts", upTo, got, err)
+		}
+	}
+	// The marker stops at the latest message.
+	got, err = st.ReadMessages(ctx, group.ID, alice, b3.ID+100, at)
+	if err != nil || len(got) != 1 || !reflect.DeepEqual(got[0].MessageIDs, []int{b3.ID}) {
+		t.Fatalf("reading past the end = %+v, %v; want a receipt for %d", got, err, b3.ID)
+	}
+
+	if _, err := st.ReadMessages(ctx, group.ID, dave, b3.ID, at); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("an outsider reading = %v, want ErrNotFound", err)
+	}
+}
+
+func TestDeliverMessagesOnce(t *testing.T) {
+	st := NewMemory()
+	ctx := context.Background()
+	ids := newMessagingUsers(t, st, "alice", "bob")
+	alice, bob := ids[0], ids[1]
+	first := send(t, st, alice, 0, bob, "hello")
+	second := send(t, st, alice, 0, bob, "again")
+	reply := send(t, st, bob, 0, alice, "hi")
+
+	// Only messages addressed to the receiver are delivered to them.
+	at := time.Now()
+	got, err := st.DeliverMessages(ctx, bob, []int{first.ID, reply.ID}, at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []models.MessageReceipt{{Status: models.ReceiptDelivered, ConversationID: first.ConversationID, SenderID: alice, ReceiverID: bob, MessageIDs: []int{first.ID}, At: at}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("receipts = %+v, want %+v", got, want)
+	}
+
+	got, err = st.DeliverMessages(ctx, bob, []int{first.ID, second.ID}, at)
+	if err != nil || len(got) != 1 || !reflect.DeepEqual(got[0].MessageIDs, []int{second.ID}) {
+		t.Fatalf("second delivery = %+v, %v; want only %d", got, err, second.ID)
+	}
+
+	// Reading marks the remaining direct messages delivered too.
+	if _, err := st.ReadMessages(ctx, first.ConversationID, alice, reply.ID, at); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := st.DeliverMessages(ctx, alice, []int{reply.ID}, at); err != nil || len(got) != 0 {
+		t.Fatalf("delivering a read message = %+v, %v; want no receipts", got, err)
+	}
+}
+
+func TestEditAndDeleteBySender(t *testing.T) {
+	st := NewMemory()
+	ctx := context.Background()
+	ids := newMessagingUsers(t, st, "alice", "bob", "carol")
+	alice, bob, carol := ids[0], ids[1], ids[2]
+	message := send(t, st, alice, 0, bob, "helo")
+	at := time.Now()
+
+	if _, err := st.EditMessage(ctx, message.ID, bob, "hijacked", at); !errors.Is(err, ErrNotSender) {
+		t.Fatalf("edit by the receiver: err = %v, want ErrNotSender", err)
+	}
+	if _, err := st.EditMessage(ctx, message.ID, carol, "hijacked", at); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("edit by an outsider: err = %v, want ErrNotFound", err)
+	}
+	if _, err := st.DeleteMessage(ctx, message.ID, bob, true, at); !errors.Is(err, ErrNotSender) {
+		t.Fatalf("delete for everyone by the receiver: err = %v, want ErrNotSender", err)
+	}
+	if _, err := st.DeleteMessage(ctx, message.ID, carol, false, at); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("delete by an outsider: err = %v, want ErrNotFound", err)
+	}
+
+	edited, err := st.EditMessage(ctx, message.ID, alice, "hello", at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edited.Message != "hello" || edited.EditedAt == nil {
+		t.Fatalf("edited message = %+v, want the new text and an edit time", edited)
+	}
+	edits, err := st.Conversations.MessageEdits(ctx, message.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(edits) != 1 || edits[0].Message != "helo" {
+		t.Fatalf("edits = %+v, want the original text", edits)
+	}
+
+	// The receiver can still delete the message for themselves.
+	if _, err := st.DeleteMessage(ctx, message.ID, bob, false, at); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.Conversations.MessageForUser(ctx, message.ID, bob); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("hidden message for the receiver: err = %v, want ErrNotFound", err)
+	}
+
+	deleted, err := st.DeleteMessage(ctx, message.ID, alice, true, at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted.DeletedAt == nil || deleted.Message != "" {
+		t.Fatalf("deleted message = %+v, want a tombstone without text", deleted)
+	}
+	if _, err := st.EditMessage(ctx, message.ID, alice, "back", at); !errors.Is(err, ErrConflict) {
+		t.Fatalf("edit of a tombstone: err = %v, want ErrConflict", err)
+	}
+	if _, err := st.ReactToMessage(ctx, message.ID, alice, "👍", false, at); !errors.Is(err, ErrConflict) {
+		t.Fatalf("reaction to a tombstone: err = %v, want ErrConflict", err)
+	}
+}
+
+func TestSendMessageAttachments(t *testing.T) {
+	st := NewMemory()
+	ctx := context.Background()
+	ids := newMessagingUsers(t, st, "alice", "bob")
+	alice, bob := ids[0], ids[1]
+	attach := func(ownerID int, key, mimeType string) int {
+		t.Helper()
+		attachment := &models.Attachment{OwnerID: ownerID, FileName: key, StorageKey: key, MimeType: mimeType}
+		if err := st.Attachments.Create(ctx, attachment); err != nil {
+			t.Fatal(err)
+		}
+		return attachment.ID
+	}
+	photo := attach(alice, "photo", "image/png")
+	notes := attach(alice, "notes", "text/plain; charset=utf-8")
+	bobs := attach(bob, "bobs", "image/jpeg")
+
+	for _, tt := range []struct {
+		attachmentIDs []int
+		messageType   string
+	}{
+		{[]int{photo}, "image"},
+		{[]int{notes}, "file"},
+	} {
+		message := &models.Message{SenderID: alice, ReceiverID: &bob}
+		if err := st.SendMessage(ctx, message, tt.attachmentIDs...); err != nil {
+			t.Fatal(err)
+		}
+		if message.MessageType != tt.messageType {
+			t.Errorf("message type with %v = %q, want %q", tt.attachmentIDs, message.MessageType, tt.messageType)
+		}
+	}
+
+	// Attachments of someone else, already sent or missing can't be sent.
+	for _, attachmentID := range []int{bobs, photo, 99} {
+		message := &models.Message{SenderID: alice, ReceiverID: &bob}
+		if err := st.SendMessage(ctx, message, attachmentID); !errors.Is(err, ErrAttachmentUnavailable) {
+			t.Errorf("sending attachment %d: err = %v, want ErrAttachmentUnavailable", attachmentID, err)
+		}
+	}
+	if _, err := st.AttachmentForUser(ctx, photo, bob); err != nil {
+		t.Fatalf("sent photo for the receiver: %v", err)
+	}
+	if _, err := st.AttachmentForUser(ctx, bobs, alice); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("unsent attachment of another user: err = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,87 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"saas-management-api/internal/database"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// uniqueViolation is the Postgres SQLSTATE for unique constraint failures.
+const uniqueViolation = "23505"
+
+// querier is the subset of sqlx shared by *sqlx.DB and *sqlx.Tx.
+type querier interface {
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// NewPostgres returns a Store backed by the given database.
+func NewPostgres(db *database.DB) *Store {
+	s := newPostgres(db.DB)
+	s.withTx = func(ctx context.Context, fn func(tx *Store) error) error {
+		return db.WithTx(ctx, func(tx *sqlx.Tx) error {
+			txStore := newPostgres(tx)
+			txStore.withTx = func(_ context.Context, fn func(tx *Store) error) error {
+				return fn(txStore)
+			}
+			return fn(txStore)
+		})
+	}
+	return s
+}
+
+func newPostgres(q querier) *Store {
+	return &Store{
+		Users:         &pgUsers{q: q},
+		Businesses:    &pgBusinesses{q: q},
+		Websites:      &pgWebsites{q: q},
+		Pages:         &pgPages{q: q},
+		Domains:       &pgDomains{q: q},
+		TLSCache:      &pgTLSCache{q: q},
+		Analytics:     &pgAnalytics{q: q},
+		Plans:         &pgPlans{q: q},
+		Subscriptions: &pgSubscriptions{q: q},
+		Invoices:      &pgInvoices{q: q},
+		Notifications: &pgNotifications{q: q},
+		Conversations: &pgConversations{q: q},
+		Attachments:   &pgAttachments{q: q},
+	}
+}
+
+// pgError translates driver errors into the store's sentinel errors.
+func pgError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return fmt.Errorf("%w: %s", ErrConflict, pqErr.Message)
+	}
+	return err
+}
+
+// pgExec runs a statement that must affect at least one row.
+func pgExec(ctx context.Context, q querier, query string, args ...interface{}) error {
+	result, err := q.ExecContext(ctx, query, args...)
+	if err != nil {
+		return pgError(err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"saas-management-api/internal/models"
+)
+
+type pgAnalytics struct {
+	q querier
+}
+
+func (r *pgAnalytics) Salt(ctx context.Context, day time.Time, fresh []byte) ([]byte, error) {
+	date := day.Format(time.DateOnly)
+	result, err := r.q.ExecContext(ctx, "INSERT INTO analytics_salts (day, salt) VALUES ($1, $2) ON CONFLICT (day) DO NOTHING", date, fresh)
+	if err != nil {
+		return nil, pgError(err)
+	}
+	if inserted, err := result.RowsAffected(); err == nil && inserted > 0 {
+		if _, err := r.q.ExecContext(ctx, "DELETE FROM analytics_salts WHERE day < $1", date); err != nil {
+			return nil, pgError(err)
+		}
+		if _, err := r.q.ExecContext(ctx, "DELETE FROM analytics_visitors WHERE day < $1", date); err != nil {
+			return nil, pgError(err)
+		}
+	}
+
+	var salt []byte
+	if err := r.q.GetContext(ctx, &salt, "SELECT salt FROM analytics_salts WHERE day = $1", date); err != nil {
+		return nil, pgError(err)
+	}
+	return salt, nil
+}
+
+func (r *pgAnalytics) Record(ctx context.Context, record AnalyticsRecord) error {
+	date := record.Day.Format(time.DateOnly)
+	pageviews, visits, events := 1, 0, 0
+	page := record.Path
+	if record.Event != "" {
+		pageviews, events, page = 0, 1, ""
+	}
+	if record.Visit {
+		visits = 1
+	}
+
+	// The visitor counts once per day: only the insert that adds it to
+	// analytics_visitors returns a row.
+	_, err := r.q.ExecContext(ctx, `
+		WITH new_visitor AS (
+			INSERT INTO analytics_visitors (website_id, day, visitor)
+			VALUES ($1, $2, $3)
+			ON CONFLICT DO NOTHING
+			RETURNING 1
+		)
+		INSERT INTO analytics_daily (website_id, day, pageviews, visits, visitors, events)
+		VALUES ($1, $2, $4, $5, (SELECT COUNT(*) FROM new_visitor), $6)
+		ON CONFLICT (website_id, day) DO UPDATE SET
+			pageviews = analytics_daily.pageviews + EXCLUDED.pageviews,
+			visits = analytics_daily.visits + EXCLUDED.visits,
+			visitors = analytics_daily.visitors + EXCLUDED.visitors,
+			events = analytics_daily.events + EXCLUDED.events
+	`, record.WebsiteID, date, record.Visitor, pageviews, visits, events)
+	if err != nil {
+		return pgError(err)
+	}
+
+	_, err = r.q.ExecContext(ctx, `
+		INSERT INTO analytics_daily_counts (website_id, day, dimension, value, count)
+		SELECT $1, $2, d.dimension, d.value, 1
+		FROM (VALUES ($3::VARCHAR, $4::VARCHAR), ($5, $6), ($7, $8)) AS d(dimension, value)
+		WHERE d.value <> ''
+		ON CONFLICT (website_id, day, dimension, value) DO UPDATE SET
+			count = analytics_daily_counts.count + 1
+	`, record.WebsiteID, date,
+		models.DimensionPage, page,
+		models.DimensionReferrer, record.Referrer,
+		models.DimensionEvent, record.Event)
+	return pgError(err)
+}
+
+func (r *pgAnalytics) Days(ctx context.Context, websiteID int, from, to time.Time) ([]models.AnalyticsDay, error) {
+	days := []models.AnalyticsDay{}
+	err := r.q.SelectContext(ctx, &days, `
+		SELECT to_char(day, 'YYYY-MM-DD') AS day, pageviews, visits, visitors, events
+		FROM analytics_daily
+		WHERE website_id = $1 AND day BETWEEN $2 AND $3
+		ORDER BY day
+	`, websiteID, from.Format(time.DateOnly), to.Format(time.DateOnly))
+	return days, pgError(err)
+}
+
+func (r *pgAnalytics) Top(ctx context.Context, websiteID int, dimension string, from, to time.Time, limit int) ([]models.AnalyticsCount, error) {
+	counts := []models.AnalyticsCount{}
+	err := r.q.SelectContext(ctx, &counts, `
+		SELECT value, SUM(count) AS count
+		FROM analytics_daily_counts
+		WHERE website_id = $1 AND dimension = $2 AND day BETWEEN $3 AND $4
+		GROUP BY value
+		ORDER BY count DESC, value
+		LIMIT $5
+	`, websiteID, dimension, from.Format(time.DateOnly), to.Format(time.DateOnly), limit)
+	return counts, pgError(err)
+}
@@ -0,0 +1,50 @@
+package store
+
+import (
+	"context"
+	"slices"
+
+	"saas-management-api/internal/models"
+
+	"github.com/lib/pq"
+)
+
+type pgAttachments struct {
+	q querier
+}
+
+func (r *pgAttachments) Create(ctx context.Context, attachment *models.Attachment) error {
+	err := r.q.GetContext(ctx, attachment, `
+		INSERT INTO attachments (owner_id, file_name, storage_key, mime_type, size_bytes, width, height)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING *
+	`, attachment.OwnerID, attachment.FileName, attachment.StorageKey, attachment.MimeType, attachment.Size, attachment.Width, attachment.Height)
+	return pgError(err)
+}
+
+func (r *pgAttachments) Get(ctx context.Context, id int) (*models.Attachment, error) {
+	var attachment models.Attachment
+	if err := r.q.GetContext(ctx, &attachment, "SELECT * FROM attachments WHERE id = $1", id); err != nil {
+		return nil, pgError(err)
+	}
+	return &attachment, nil
+}
+
+func (r *pgAttachments) Attach(ctx context.Context, messageID, ownerID int, ids []int) error {
+	ids = slices.Compact(slices.Sorted(slices.Values(ids)))
+	result, err := r.q.ExecContext(ctx, `
+		UPDATE attachments SET message_id = $1
+		WHERE id = ANY($3) AND owner_id = $2 AND message_id IS NULL
+	`, messageID, ownerID, pq.Array(ids))
+	if err != nil {
+		return pgError(err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if int(n) != len(ids) {
+		return ErrNotFound
+	}
+	return nil
+}
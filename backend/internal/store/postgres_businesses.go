@@ -0,0 +1,146 @@
+package store
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"saas-management-api/internal/listing"
+	"saas-management-api/internal/models"
+)
+
+type pgBusinesses struct {
+	q querier
+}
+
+func (r *pgBusinesses) List(ctx context.Context, spec listing.Spec) (listing.Page[models.Business], error) {
+	businesses := []models.Business{}
+	where, tail, args := BusinessListing.SQL(spec, nil, nil)
+	if err := r.q.SelectContext(ctx, &businesses, "SELECT * FROM businesses"+where+tail, args...); err != nil {
+		return listing.Page[models.Business]{}, pgError(err)
+	}
+	return listing.Paginate(businesses, spec, BusinessListing), nil
+}
+
+func (r *pgBusinesses) Get(ctx context.Context, id int) (*models.Business, error) {
+	var business models.Business
+	if err := r.q.GetContext(ctx, &business, "SELECT * FROM businesses WHERE id = $1", id); err != nil {
+		return nil, pgError(err)
+	}
+	return &business, nil
+}
+
+func (r *pgBusinesses) GetBySlug(ctx context.Context, slug string) (*models.Business, error) {
+	var business models.Business
+	err := r.q.GetContext(ctx, &business, `
+		SELECT * FROM businesses WHERE slug = $1
+		UNION ALL
+		SELECT b.* FROM businesses b JOIN slug_history h ON h.business_id = b.id WHERE h.slug = $1
+		LIMIT 1
+	`, slug)
+	if err != nil {
+		return nil, pgError(err)
+	}
+	return &business, nil
+}
+
+func (r *pgBusinesses) TakenSlugs(ctx context.Context, base string, exceptID int) ([]string, error) {
+	// Slugs only contain [a-z0-9-], so base needs no LIKE escaping.
+	slugs := []string{}
+	err := r.q.SelectContext(ctx, &slugs, `
+		SELECT slug FROM businesses WHERE (slug = $1 OR slug LIKE $2) AND id <> $3
+		UNION
+		SELECT slug FROM slug_history WHERE (slug = $1 OR slug LIKE $2) AND business_id <> $3
+	`, base, base+"-%", exceptID)
+	return slugs, pgError(err)
+}
+
+func (r *pgBusinesses) RecordSlugChange(ctx context.Context, businessID int, oldSlug, newSlug string) error {
+	if _, err := r.q.ExecContext(ctx, "DELETE FROM slug_history WHERE business_id = $1 AND slug = $2", businessID, newSlug); err != nil {
+		return pgError(err)
+	}
+	_, err := r.q.ExecContext(ctx, "INSERT INTO slug_history (business_id, slug, created_at) VALUES ($1, $2, $3)", businessID, oldSlug, time.Now())
+	return pgError(err)
+}
+
+func (r *pgBusinesses) Create(ctx context.Context, business *models.Business) error {
+	err := r.q.GetContext(ctx, business, `
+		INSERT INTO businesses (user_id, name, slug, description, logo, industry, phone, address, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $10)
+		RETURNING *
+	`, business.UserID, business.Name, business.Slug, business.Description, business.Logo, business.Industry, business.Phone, business.Address, business.Status, time.Now())
+	return pgError(err)
+}
+
+func (r *pgBusinesses) Update(ctx context.Context, business *models.Business) error {
+	err := r.q.GetContext(ctx, business, `
+		UPDATE businesses
+		SET name = $1, slug = $2, description = $3, logo = $4, industry = $5, phone = $6, address = $7, status = $8, updated_at = $9
+		WHERE id = $10
+		RETURNING *
+	`, business.Name, business.Slug, business.Description, business.Logo, business.Industry, business.Phone, business.Address, business.Status, time.Now(), business.ID)
+	return pgError(err)
+}
+
+func (r *pgBusinesses) SetReview(ctx context.Context, id int, review BusinessReview) (*models.Business, error) {
+	var business models.Business
+	err := r.q.GetContext(ctx, &business, `
+		UPDATE businesses
+		SET status = $1, reviewed_by = $2, reviewed_at = $3, review_reason = $4, updated_at = $3
+		WHERE id = $5
+		RETURNING *
+	`, review.Status, review.ReviewerID, review.At, review.Reason, id)
+	if err != nil {
+		return nil, pgError(err)
+	}
+	return &business, nil
+}
+
+func (r *pgBusinesses) Delete(ctx context.Context, id int) error {
+	return pgExec(ctx, r.q, "DELETE FROM businesses WHERE id = $1", id)
+}
+
+func (r *pgBusinesses) Search(ctx context.Context, search BusinessSearch) (*models.BusinessSearchResult, error) {
+	match, rank := "TRUE", "0"
+	var args []any
+	if terms := searchTerms(search.Query); len(terms) > 0 {
+		prefixes := make([]string, len(terms))
+		for i, term := range terms {
+			prefixes[i] = term + ":*"
+		}
+		args = []any{strings.Join(prefixes, " & "), strings.Join(terms, " ")}
+		match = "(s.document @@ to_tsquery('simple', $1) OR $2 <% s.fuzzy)"
+		rank = "ts_rank_cd(s.document, to_tsquery('simple', $1)) + word_similarity($2, s.fuzzy)"
+	}
+	from := " FROM businesses b JOIN business_search s ON s.business_id = b.id WHERE " + match
+	if search.Status != "" {
+		args = append(args, search.Status)
+		from += " AND b.status = $" + strconv.Itoa(len(args))
+	}
+
+	result := &models.BusinessSearchResult{
+		Results:    []models.BusinessSearchHit{},
+		Industries: []models.Facet{},
+	}
+	err := r.q.SelectContext(ctx, &result.Industries, `
+		SELECT b.industry AS value, COUNT(*) AS count`+from+` AND b.industry IS NOT NULL
+		GROUP BY b.industry
+		ORDER BY count DESC, value
+	`, args...)
+	if err != nil {
+		return nil, pgError(err)
+	}
+
+	query := "SELECT b.*, " + rank + " AS rank" + from
+	if search.Industry != "" {
+		args = append(args, search.Industry)
+		query += " AND b.industry = $" + strconv.Itoa(len(args))
+	}
+	args = append(args, search.Limit)
+	query += " ORDER BY rank DESC, b.created_at DESC, b.id DESC LIMIT $" + strconv.Itoa(len(args))
+	if err := r.q.SelectContext(ctx, &result.Results, query, args...); err != nil {
+		return nil, pgError(err)
+	}
+	return result, nil
+}
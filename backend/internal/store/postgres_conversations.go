@@ -0,0 +1,564 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"saas-management-api/internal/listing"
+	"saas-management-api/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// selectConversations loads conversations with the names of a direct
+// conversation's users.
+const selectConversations = `
+	SELECT c.*,
+	       u1.name AS user1_name,
+	       u2.name AS user2_name
+	FROM conversations c
+	LEFT JOIN users u1 ON c.user1_id = u1.id
+	LEFT JOIN users u2 ON c.user2_id = u2.id
+`
+
+// selectParticipants loads participants with their names.
+const selectParticipants = `
+	SELECT p.*, u.name AS user_name
+	FROM conversation_participants p
+	LEFT JOIN users u ON p.user_id = u.id
+`
+
+// selectMessages loads messages with sender and receiver names.
+const selectMessages = `
+	SELECT m.*,
+	       u1.name AS sender_name,
+	       u2.name AS receiver_name
+	FROM messages m
+	LEFT JOIN users u1 ON m.sender_id = u1.id
+	LEFT JOIN users u2 ON m.receiver_id = u2.id
+`
+
+// visibleTo restricts messages to the conversations of the user in $1,
+// leaving out those they hid.
+const visibleTo = `m.conversation_id IN (SELECT conversation_id FROM conversation_participants WHERE user_id = $1)
+	AND NOT EXISTS (SELECT 1 FROM hidden_messages h WHERE h.message_id = m.id AND h.user_id = $1)`
+
+// selectSearchHits loads messages with snippets of their matches for the
+// tsquery in $2 and the titles of their conversations as seen by the user
+// in $1.
+const selectSearchHits = `
+	SELECT m.*,
+	       u1.name AS sender_name,
+	       u2.name AS receiver_name,
+	       ts_headline('simple', translate(m.message, chr(2) || chr(3), ''), to_tsquery('simple', $2),
+	                   'MaxFragments=2, MaxWords=20, MinWords=5, StartSel=' || chr(2) || ', StopSel=' || chr(3)) AS snippet,
+	       c.is_group AS conversation_is_group,
+	       CASE WHEN c.is_group THEN c.title ELSE peer.name END AS conversation_title
+	FROM messages m
+	JOIN conversations c ON c.id = m.conversation_id
+	LEFT JOIN users u1 ON m.sender_id = u1.id
+	LEFT JOIN users u2 ON m.receiver_id = u2.id
+	LEFT JOIN users peer ON peer.id = CASE WHEN c.user1_id = $1 THEN c.user2_id ELSE c.user1_id END
+`
+
+type pgConversations struct {
+	q querier
+}
+
+// orderPair normalizes a user pair so that user1_id < user2_id, matching the
+// UNIQUE(user1_id, user2_id) constraint.
+func orderPair(a, b int) (int, int) {
+	if a > b {
+		return b, a
+	}
+	return a, b
+}
+
+// withParticipants loads the participants of conversations.
+func (r *pgConversations) withParticipants(ctx context.Context, conversations ...*models.Conversation) error {
+	ids := make([]int, len(conversations))
+	for i, conversation := range conversations {
+		ids[i] = conversation.ID
+	}
+	participants := []models.ConversationParticipant{}
+	err := r.q.SelectContext(ctx, &participants, selectParticipants+" WHERE p.conversation_id = ANY($1) ORDER BY p.joined_at, p.user_id", pq.Array(ids))
+	if err != nil {
+		return pgError(err)
+	}
+
+	byConversation := map[int][]models.ConversationParticipant{}
+	for _, participant := range participants {
+		byConversation[participant.ConversationID] = append(byConversation[participant.ConversationID], participant)
+	}
+	for _, conversation := range conversations {
+		conversation.Participants = byConversation[conversation.ID]
+		if conversation.Participants == nil {
+			conversation.Participants = []models.ConversationParticipant{}
+		}
+	}
+	return nil
+}
+
+func (r *pgConversations) GetOrCreate(ctx context.Context, userA, userB int) (*models.Conversation, error) {
+	user1ID, user2ID := orderPair(userA, userB)
+	now := time.Now()
+	var id int
+	err := r.q.GetContext(ctx, &id, `
+		INSERT INTO conversations (user1_id, user2_id, created_at, updated_at, last_message_at)
+		VALUES ($1, $2, $3, $3, $3)
+		ON CONFLICT (user1_id, user2_id) DO NOTHING
+		RETURNING id
+	`, user1ID, user2ID, now)
+	switch {
+	case err == nil:
+		_, err = r.q.ExecContext(ctx, `
+			INSERT INTO conversation_participants (conversation_id, user_id, joined_at)
+			VALUES ($1, $2, $4), ($1, $3, $4)
+			ON CONFLICT DO NOTHING
+		`, id, user1ID, user2ID, now)
+		if err != nil {
+			return nil, pgError(err)
+		}
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, pgError(err)
+	}
+
+	var conversation models.Conversation
+	err = r.q.GetContext(ctx, &conversation, selectConversations+" WHERE c.user1_id = $1 AND c.user2_id = $2", user1ID, user2ID)
+	if err != nil {
+		return nil, pgError(err)
+	}
+	if err := r.withParticipants(ctx, &conversation); err != nil {
+		return nil, err
+	}
+	return &conversation, nil
+}
+
+func (r *pgConversations) GetDirect(ctx context.Context, userA, userB int) (*models.Conversation, error) {
+	user1ID, user2ID := orderPair(userA, userB)
+	var conversation models.Conversation
+	err := r.q.GetContext(ctx, &conversation, selectConversations+" WHERE c.user1_id = $1 AND c.user2_id = $2", user1ID, user2ID)
+	if err != nil {
+		return nil, pgError(err)
+	}
+	if err := r.withParticipants(ctx, &conversation); err != nil {
+		return nil, err
+	}
+	return &conversation, nil
+}
+
+func (r *pgConversations) GetForUser(ctx context.Context, id, userID int) (*models.Conversation, error) {
+	var conversation models.Conversation
+	err := r.q.GetContext(ctx, &conversation, selectConversations+`
+		WHERE c.id = $1
+		AND EXISTS (SELECT 1 FROM conversation_participants p WHERE p.conversation_id = c.id AND p.user_id = $2)
+	`, id, userID)
+	if err != nil {
+		return nil, pgError(err)
+	}
+	if err := r.withParticipants(ctx, &conversation); err != nil {
+		return nil, err
+	}
+	return &conversation, nil
+}
+
+func (r *pgConversations) ListForUser(ctx context.Context, userID int) ([]models.Conversation, error) {
+	conversations := []models.Conversation{}
+	err := r.q.SelectContext(ctx, &conversations, `
+		SELECT c.*,
+		       u1.name AS user1_name,
+		       u2.name AS user2_name,
+		       (SELECT COUNT(*) FROM messages m
+		        WHERE m.conversation_id = c.id AND m.sender_id <> $1 AND m.id > p.last_read_message_id) AS unread_count,
+		       (SELECT m.message FROM messages m
+		        WHERE m.conversation_id = c.id ORDER BY m.created_at DESC LIMIT 1) AS last_message
+		FROM conversations c
+		JOIN conversation_participants p ON p.conversation_id = c.id AND p.user_id = $1
+		LEFT JOIN users u1 ON c.user1_id = u1.id
+		LEFT JOIN users u2 ON c.user2_id = u2.id
+		ORDER BY COALESCE(c.last_message_at, c.created_at) DESC
+	`, userID)
+	if err != nil {
+		return nil, pgError(err)
+	}
+
+	refs := make([]*models.Conversation, len(conversations))
+	for i := range conversations {
+		refs[i] = &conversations[i]
+	}
+	if err := r.withParticipants(ctx, refs...); err != nil {
+		return nil, err
+	}
+	return conversations, nil
+}
+
+func (r *pgConversations) Touch(ctx context.Context, id int, at time.Time) error {
+	return pgExec(ctx, r.q, "UPDATE conversations SET last_message_at = $1, updated_at = $1 WHERE id = $2", at, id)
+}
+
+func (r *pgConversations) CreateGroup(ctx context.Context, conversation *models.Conversation) error {
+	err := r.q.GetContext(ctx, conversation, `
+		INSERT INTO conversations (is_group, title, created_by, created_at, updated_at, last_message_at)
+		VALUES (TRUE, $1, $2, $3, $3, $3)
+		RETURNING *
+	`, conversation.Title, conversation.CreatedBy, time.Now())
+	return pgError(err)
+}
+
+func (r *pgConversations) Rename(ctx context.Context, id int, title string, at time.Time) error {
+	return pgExec(ctx, r.q, "UPDATE conversations SET title = $1, updated_at = $2 WHERE id = $3 AND is_group", title, at, id)
+}
+
+func (r *pgConversations) AddParticipants(ctx context.Context, conversationID int, userIDs []int, role string, at time.Time) ([]int, error) {
+	added := []int{}
+	err := r.q.SelectContext(ctx, &added, `
+		INSERT INTO conversation_participants (conversation_id, user_id, role, last_read_message_id, joined_at)
+		SELECT $1, u.id, $3, (SELECT COALESCE(MAX(id), 0) FROM messages WHERE conversation_id = $1), $4
+		FROM unnest($2::int[]) AS u(id)
+		ON CONFLICT DO NOTHING
+		RETURNING user_id
+	`, conversationID, pq.Array(userIDs), role, at)
+	return added, pgError(err)
+}
+
+func (r *pgConversations) RemoveParticipant(ctx context.Context, conversationID, userID int) error {
+	return pgExec(ctx, r.q, "DELETE FROM conversation_participants WHERE conversation_id = $1 AND user_id = $2", conversationID, userID)
+}
+
+func (r *pgConversations) Participants(ctx context.Context, conversationID int) ([]models.ConversationParticipant, error) {
+	participants := []models.ConversationParticipant{}
+	err := r.q.SelectContext(ctx, &participants, selectParticipants+" WHERE p.conversation_id = $1 ORDER BY p.joined_at, p.user_id", conversationID)
+	return participants, pgError(err)
+}
+
+// withDetails loads the reactions to and attachments of messages.
+func (r *pgConversations) withDetails(ctx context.Context, messages []models.Message) error {
+	if err := r.withReactions(ctx, messages); err != nil {
+		return err
+	}
+	return r.withAttachments(ctx, messages)
+}
+
+// withReactions loads the reactions to messages.
+func (r *pgConversations) withReactions(ctx context.Context, messages []models.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	ids := make([]int, len(messages))
+	for i, message := range messages {
+		ids[i] = message.ID
+	}
+	var rows []struct {
+		MessageID int    `db:"message_id"`
+		UserID    int    `db:"user_id"`
+		Emoji     string `db:"emoji"`
+	}
+	err := r.q.SelectContext(ctx, &rows, `
+		SELECT message_id, user_id, emoji
+		FROM message_reactions
+		WHERE message_id = ANY($1)
+		ORDER BY created_at, user_id
+	`, pq.Array(ids))
+	if err != nil {
+		return pgError(err)
+	}
+
+	index := map[int]int{}
+	for i, message := range messages {
+		index[message.ID] = i
+		messages[i].Reactions = nil
+	}
+	for _, row := range rows {
+		message := &messages[index[row.MessageID]]
+		message.Reactions = addReaction(message.Reactions, row.Emoji, row.UserID)
+	}
+	return nil
+}
+
+// withAttachments loads the attachments of messages.
+func (r *pgConversations) withAttachments(ctx context.Context, messages []models.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	ids := make([]int, len(messages))
+	for i, message := range messages {
+		ids[i] = message.ID
+	}
+	var attachments []models.Attachment
+	err := r.q.SelectContext(ctx, &attachments, `
+		SELECT * FROM attachments
+		WHERE message_id = ANY($1)
+		ORDER BY id
+	`, pq.Array(ids))
+	if err != nil {
+		return pgError(err)
+	}
+
+	index := map[int]int{}
+	for i, message := range messages {
+		index[message.ID] = i
+		messages[i].Attachments = nil
+	}
+	for _, attachment := range attachments {
+		message := &messages[index[*attachment.MessageID]]
+		message.Attachments = append(message.Attachments, attachment)
+	}
+	return nil
+}
+
+// addReaction counts userID among the users who reacted with emoji.
+func addReaction(reactions []models.Reaction, emoji string, userID int) []models.Reaction {
+	for i := range reactions {
+		if reactions[i].Emoji == emoji {
+			reactions[i].Count++
+			reactions[i].UserIDs = append(reactions[i].UserIDs, userID)
+			return reactions
+		}
+	}
+	return append(reactions, models.Reaction{Emoji: emoji, Count: 1, UserIDs: []int{userID}})
+}
+
+func (r *pgConversations) Messages(ctx context.Context, conversationID, userID int, spec listing.Spec) (listing.Page[models.Message], error) {
+	where, tail, args := MessageListing.SQL(spec, []string{
+		"m.conversation_id = $1",
+		"NOT EXISTS (SELECT 1 FROM hidden_messages h WHERE h.message_id = m.id AND h.user_id = $2)",
+	}, []any{conversationID, userID})
+
+	messages := []models.Message{}
+	if err := r.q.SelectContext(ctx, &messages, selectMessages+where+tail, args...); err != nil {
+		return listing.Page[models.Message]{}, pgError(err)
+	}
+	if err := r.withDetails(ctx, messages); err != nil {
+		return listing.Page[models.Message]{}, err
+	}
+	return listing.Paginate(messages, spec, MessageListing), nil
+}
+
+func (r *pgConversations) SearchMessages(ctx context.Context, userID int, query string, spec listing.Spec) (listing.Page[models.MessageSearchHit], error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return listing.Page[models.MessageSearchHit]{Items: []models.MessageSearchHit{}}, nil
+	}
+	prefixes := make([]string, len(terms))
+	for i, term := range terms {
+		prefixes[i] = term + ":*"
+	}
+	where, tail, args := MessageSearchListing.SQL(spec, []string{
+		visibleTo,
+		"m.deleted_at IS NULL",
+		"to_tsvector('simple', m.message) @@ to_tsquery('simple', $2)",
+	}, []any{userID, strings.Join(prefixes, " & ")})
+
+	hits := []models.MessageSearchHit{}
+	if err := r.q.SelectContext(ctx, &hits, selectSearchHits+where+tail, args...); err != nil {
+		return listing.Page[models.MessageSearchHit]{}, pgError(err)
+	}
+	for i := range hits {
+		hits[i].Snippet = highlight(hits[i].Snippet)
+	}
+	return listing.Paginate(hits, spec, MessageSearchListing), nil
+}
+
+func (r *pgConversations) MessageForUser(ctx context.Context, id, userID int) (*models.Message, error) {
+	messages := []models.Message{}
+	err := r.q.SelectContext(ctx, &messages, selectMessages+" WHERE m.id = $2 AND "+visibleTo, userID, id)
+	if err != nil {
+		return nil, pgError(err)
+	}
+	if len(messages) == 0 {
+		return nil, ErrNotFound
+	}
+	if err := r.withDetails(ctx, messages); err != nil {
+		return nil, err
+	}
+	return &messages[0], nil
+}
+
+func (r *pgConversations) CreateMessage(ctx context.Context, message *models.Message) error {
+	err := r.q.GetContext(ctx, message, `
+		WITH m AS (
+			INSERT INTO messages (conversation_id, sender_id, receiver_id, message, message_type, client_id, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+			RETURNING *
+		)
+		SELECT m.*,
+		       u1.name AS sender_name,
+		       u2.name AS receiver_name
+		FROM m
+		LEFT JOIN users u1 ON m.sender_id = u1.id
+		LEFT JOIN users u2 ON m.receiver_id = u2.id
+	`, message.ConversationID, message.SenderID, message.ReceiverID, message.Message, message.MessageType, message.ClientID, time.Now())
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation && pqErr.Constraint == "idx_messages_sender_client_id" {
+		return ErrDuplicate
+	}
+	return pgError(err)
+}
+
+func (r *pgConversations) EditMessage(ctx context.Context, id int, text string, at time.Time) error {
+	_, err := r.q.ExecContext(ctx, `
+		INSERT INTO message_edits (message_id, message, edited_at)
+		SELECT id, message, $2 FROM messages WHERE id = $1
+	`, id, at)
+	if err != nil {
+		return pgError(err)
+	}
+	return pgExec(ctx, r.q, "UPDATE messages SET message = $1, edited_at = $2, updated_at = $2 WHERE id = $3", text, at, id)
+}
+
+func (r *pgConversations) MessageEdits(ctx context.Context, messageID int) ([]models.MessageEdit, error) {
+	edits := []models.MessageEdit{}
+	err := r.q.SelectContext(ctx, &edits, "SELECT * FROM message_edits WHERE message_id = $1 ORDER BY edited_at, id", messageID)
+	return edits, pgError(err)
+}
+
+func (r *pgConversations) DeleteMessage(ctx context.Context, id int, at time.Time) error {
+	if _, err := r.q.ExecContext(ctx, "DELETE FROM message_edits WHERE message_id = $1", id); err != nil {
+		return pgError(err)
+	}
+	if _, err := r.q.ExecContext(ctx, "DELETE FROM message_reactions WHERE message_id = $1", id); err != nil {
+		return pgError(err)
+	}
+	if _, err := r.q.ExecContext(ctx, "DELETE FROM attachments WHERE message_id = $1", id); err != nil {
+		return pgError(err)
+	}
+	return pgExec(ctx, r.q, "UPDATE messages SET message = '', deleted_at = $1, updated_at = $1 WHERE id = $2", at, id)
+}
+
+func (r *pgConversations) HideMessage(ctx context.Context, id, userID int, at time.Time) error {
+	_, err := r.q.ExecContext(ctx, `
+		INSERT INTO hidden_messages (message_id, user_id, hidden_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING
+	`, id, userID, at)
+	return pgError(err)
+}
+
+func (r *pgConversations) AddReaction(ctx context.Context, messageID, userID int, emoji string, at time.Time) error {
+	_, err := r.q.ExecContext(ctx, `
+		INSERT INTO message_reactions (message_id, user_id, emoji, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING
+	`, messageID, userID, emoji, at)
+	return pgError(err)
+}
+
+func (r *pgConversations) RemoveReaction(ctx context.Context, messageID, userID int, emoji string) error {
+	_, err := r.q.ExecContext(ctx, "DELETE FROM message_reactions WHERE message_id = $1 AND user_id = $2 AND emoji = $3", messageID, userID, emoji)
+	return pgError(err)
+}
+
+func (r *pgConversations) MarkRead(ctx context.Context, conversationID, userID, upToID int, at time.Time) ([]models.Message, error) {
+	// Joining the row to itself yields the marker from before the update.
+	var marker struct {
+		Previous int `db:"previous"`
+		Current  int `db:"current"`
+	}
+	err := r.q.GetContext(ctx, &marker, `
+		UPDATE conversation_participants p
+		SET last_read_message_id = GREATEST(p.last_read_message_id,
+			LEAST($3, (SELECT COALESCE(MAX(id), 0) FROM messages WHERE conversation_id = $1)))
+		FROM conversation_participants old
+		WHERE p.conversation_id = $1 AND p.user_id = $2
+		AND old.conversation_id = p.conversation_id AND old.user_id = p.user_id
+		RETURNING old.last_read_message_id AS previous, p.last_read_message_id AS current
+	`, conversationID, userID, upToID)
+	if err != nil {
+		return nil, pgError(err)
+	}
+
+	_, err = r.q.ExecContext(ctx, `
+		UPDATE messages
+		SET is_read = TRUE, read_at = $1, delivered_at = COALESCE(delivered_at, $1)
+		WHERE conversation_id = $2
+		AND receiver_id = $3
+		AND id <= $4
+		AND is_read = FALSE
+	`, at, conversationID, userID, marker.Current)
+	if err != nil {
+		return nil, pgError(err)
+	}
+
+	messages := []models.Message{}
+	err = r.q.SelectContext(ctx, &messages, selectMessages+`
+		WHERE m.conversation_id = $1 AND m.sender_id <> $2 AND m.id > $3 AND m.id <= $4
+		ORDER BY m.id
+	`, conversationID, userID, marker.Previous, marker.Current)
+	return messages, pgError(err)
+}
+
+func (r *pgConversations) MarkDelivered(ctx context.Context, receiverID int, ids []int, at time.Time) ([]models.Message, error) {
+	messages := []models.Message{}
+	err := r.q.SelectContext(ctx, &messages, `
+		UPDATE messages
+		SET delivered_at = $1
+		WHERE receiver_id = $2
+		AND id = ANY($3)
+		AND delivered_at IS NULL
+		RETURNING *
+	`, at, receiverID, pq.Array(ids))
+	return messages, pgError(err)
+}
+
+func (r *pgConversations) ListMessages(ctx context.Context, userID int) ([]models.Message, error) {
+	messages := []models.Message{}
+	var err error
+	if userID == 0 {
+		err = r.q.SelectContext(ctx, &messages, selectMessages+" ORDER BY m.created_at DESC")
+	} else {
+		err = r.q.SelectContext(ctx, &messages, selectMessages+" WHERE "+visibleTo+" ORDER BY m.created_at DESC", userID)
+	}
+	if err != nil {
+		return nil, pgError(err)
+	}
+	return messages, r.withDetails(ctx, messages)
+}
+
+func (r *pgConversations) RecentMessages(ctx context.Context, userID, limit int) ([]models.Message, error) {
+	messages := []models.Message{}
+	err := r.q.SelectContext(ctx, &messages, selectMessages+`
+		WHERE `+visibleTo+`
+		ORDER BY m.created_at DESC
+		LIMIT $2
+	`, userID, limit)
+	if err != nil {
+		return nil, pgError(err)
+	}
+	return messages, r.withDetails(ctx, messages)
+}
+
+func (r *pgConversations) MessagesAfter(ctx context.Context, userID, afterID, limit int) ([]models.Message, error) {
+	messages := []models.Message{}
+	err := r.q.SelectContext(ctx, &messages, selectMessages+`
+		WHERE `+visibleTo+` AND m.id > $2
+		ORDER BY m.id
+		LIMIT $3
+	`, userID, afterID, limit)
+	if err != nil {
+		return nil, pgError(err)
+	}
+	return messages, r.withDetails(ctx, messages)
+}
+
+func (r *pgConversations) MessageByClientID(ctx context.Context, senderID int, clientID string) (*models.Message, error) {
+	var message models.Message
+	err := r.q.GetContext(ctx, &message, selectMessages+" WHERE m.sender_id = $1 AND m.client_id = $2", senderID, clientID)
+	if err != nil {
+		return nil, pgError(err)
+	}
+	return &message, nil
+}
+
+func (r *pgConversations) UnreadCount(ctx context.Context, userID int) (int, error) {
+	var count int
+	err := r.q.GetContext(ctx, &count, `
+		SELECT COUNT(*)
+		FROM messages m
+		JOIN conversation_participants p ON p.conversation_id = m.conversation_id AND p.user_id = $1
+		WHERE m.sender_id <> $1 AND m.id > p.last_read_message_id
+	`, userID)
+	return count, pgError(err)
+}
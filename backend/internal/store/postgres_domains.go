@@ -0,0 +1,64 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"saas-management-api/internal/models"
+)
+
+type pgDomains struct {
+	q querier
+}
+
+func (r *pgDomains) Get(ctx context.Context, id int) (*models.Domain, error) {
+	var domain models.Domain
+	if err := r.q.GetContext(ctx, &domain, "SELECT * FROM domains WHERE id = $1", id); err != nil {
+		return nil, pgError(err)
+	}
+	return &domain, nil
+}
+
+func (r *pgDomains) GetByName(ctx context.Context, name string) (*models.Domain, error) {
+	var domain models.Domain
+	if err := r.q.GetContext(ctx, &domain, "SELECT * FROM domains WHERE domain_name = $1", name); err != nil {
+		return nil, pgError(err)
+	}
+	return &domain, nil
+}
+
+func (r *pgDomains) ListForBusiness(ctx context.Context, businessID int) ([]models.Domain, error) {
+	domains := []models.Domain{}
+	err := r.q.SelectContext(ctx, &domains, "SELECT * FROM domains WHERE business_id = $1 ORDER BY domain_name", businessID)
+	return domains, pgError(err)
+}
+
+func (r *pgDomains) Create(ctx context.Context, domain *models.Domain) error {
+	if domain.Status == "" {
+		domain.Status = models.DomainPending
+	}
+	err := r.q.GetContext(ctx, domain, `
+		INSERT INTO domains (business_id, domain_name, is_custom, status, verification_token, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $6)
+		RETURNING *
+	`, domain.BusinessID, domain.DomainName, domain.IsCustom, domain.Status, domain.VerificationToken, time.Now())
+	return pgError(err)
+}
+
+func (r *pgDomains) MarkVerified(ctx context.Context, id int, at time.Time) (*models.Domain, error) {
+	var domain models.Domain
+	err := r.q.GetContext(ctx, &domain, `
+		UPDATE domains
+		SET status = $1, verified_at = $2, updated_at = $2
+		WHERE id = $3
+		RETURNING *
+	`, models.DomainVerified, at, id)
+	if err != nil {
+		return nil, pgError(err)
+	}
+	return &domain, nil
+}
+
+func (r *pgDomains) Delete(ctx context.Context, id int) error {
+	return pgExec(ctx, r.q, "DELETE FROM domains WHERE id = $1", id)
+}
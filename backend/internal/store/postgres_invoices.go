@@ -0,0 +1,61 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"saas-management-api/internal/models"
+)
+
+type pgInvoices struct {
+	q querier
+}
+
+func (r *pgInvoices) ListForBusiness(ctx context.Context, businessID int) ([]models.Invoice, error) {
+	invoices := []models.Invoice{}
+	err := r.q.SelectContext(ctx, &invoices, "SELECT * FROM invoices WHERE business_id = $1 ORDER BY created_at DESC, id DESC", businessID)
+	return invoices, pgError(err)
+}
+
+func (r *pgInvoices) Get(ctx context.Context, id int) (*models.Invoice, error) {
+	var invoice models.Invoice
+	if err := r.q.GetContext(ctx, &invoice, "SELECT * FROM invoices WHERE id = $1", id); err != nil {
+		return nil, pgError(err)
+	}
+	return &invoice, nil
+}
+
+func (r *pgInvoices) Create(ctx context.Context, invoice *models.Invoice) error {
+	if invoice.Status == "" {
+		invoice.Status = models.InvoiceUnpaid
+	}
+	err := r.q.GetContext(ctx, invoice, `
+		INSERT INTO invoices (business_id, amount, status, due_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $5)
+		RETURNING *
+	`, invoice.BusinessID, invoice.Amount, invoice.Status, invoice.DueAt, time.Now())
+	return pgError(err)
+}
+
+func (r *pgInvoices) SetStatus(ctx context.Context, id int, status string, at time.Time) (*models.Invoice, error) {
+	var invoice models.Invoice
+	err := r.q.GetContext(ctx, &invoice, `
+		UPDATE invoices
+		SET status = $1, updated_at = $2
+		WHERE id = $3
+		RETURNING *
+	`, status, at, id)
+	if err != nil {
+		return nil, pgError(err)
+	}
+	return &invoice, nil
+}
+
+func (r *pgInvoices) CreatePayment(ctx context.Context, payment *models.Payment) error {
+	err := r.q.GetContext(ctx, payment, `
+		INSERT INTO payments (invoice_id, amount, payment_method, transaction_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $5)
+		RETURNING *
+	`, payment.InvoiceID, payment.Amount, payment.PaymentMethod, payment.TransactionID, time.Now())
+	return pgError(err)
+}
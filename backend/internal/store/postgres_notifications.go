@@ -0,0 +1,109 @@
+package store
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"saas-management-api/internal/listing"
+	"saas-management-api/internal/models"
+)
+
+// selectNotifications loads notifications with sender and recipient names.
+const selectNotifications = `
+	SELECT n.*,
+	       u1.name AS user_name,
+	       u2.name AS from_user_name
+	FROM notifications n
+	LEFT JOIN users u1 ON n.user_id = u1.id
+	LEFT JOIN users u2 ON n.from_user_id = u2.id
+`
+
+type pgNotifications struct {
+	q querier
+}
+
+func (r *pgNotifications) List(ctx context.Context, userID int, spec listing.Spec) (listing.Page[models.Notification], error) {
+	var conds []string
+	var args []any
+	if userID != 0 {
+		conds, args = []string{"n.user_id = $1"}, []any{userID}
+	}
+	where, tail, args := NotificationListing.SQL(spec, conds, args)
+
+	notifications := []models.Notification{}
+	if err := r.q.SelectContext(ctx, &notifications, selectNotifications+where+tail, args...); err != nil {
+		return listing.Page[models.Notification]{}, pgError(err)
+	}
+	return listing.Paginate(notifications, spec, NotificationListing), nil
+}
+
+func (r *pgNotifications) Get(ctx context.Context, id, userID int) (*models.Notification, error) {
+	var notification models.Notification
+	var err error
+	if userID == 0 {
+		err = r.q.GetContext(ctx, &notification, selectNotifications+" WHERE n.id = $1", id)
+	} else {
+		err = r.q.GetContext(ctx, &notification, selectNotifications+" WHERE n.id = $1 AND n.user_id = $2", id, userID)
+	}
+	if err != nil {
+		return nil, pgError(err)
+	}
+	return &notification, nil
+}
+
+func (r *pgNotifications) Create(ctx context.Context, notification *models.Notification) error {
+	now := time.Now()
+	err := r.q.GetContext(ctx, notification, `
+		INSERT INTO notifications (user_id, from_user_id, subject, message, type, status, is_read, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8)
+		RETURNING *
+	`, notification.UserID, notification.FromUserID, notification.Subject, notification.Message, notification.Type, notification.Status, notification.IsRead, now)
+	return pgError(err)
+}
+
+func (r *pgNotifications) Update(ctx context.Context, id, userID int, update NotificationUpdate) (*models.Notification, error) {
+	updates := []string{}
+	args := []interface{}{}
+	set := func(column string, value interface{}) {
+		args = append(args, value)
+		updates = append(updates, column+" = $"+strconv.Itoa(len(args)))
+	}
+
+	if update.IsRead != nil {
+		set("is_read", *update.IsRead)
+	}
+	// An explicit status wins over the one implied by is_read.
+	if update.Status != nil {
+		set("status", *update.Status)
+	} else if update.IsRead != nil && *update.IsRead {
+		set("status", "read")
+	}
+	set("updated_at", time.Now())
+
+	args = append(args, id)
+	query := "UPDATE notifications SET " + strings.Join(updates, ", ") + " WHERE id = $" + strconv.Itoa(len(args))
+	if userID != 0 {
+		args = append(args, userID)
+		query += " AND user_id = $" + strconv.Itoa(len(args))
+	}
+
+	if err := pgExec(ctx, r.q, query, args...); err != nil {
+		return nil, err
+	}
+	return r.Get(ctx, id, userID)
+}
+
+func (r *pgNotifications) Delete(ctx context.Context, id, userID int) error {
+	if userID == 0 {
+		return pgExec(ctx, r.q, "DELETE FROM notifications WHERE id = $1", id)
+	}
+	return pgExec(ctx, r.q, "DELETE FROM notifications WHERE id = $1 AND user_id = $2", id, userID)
+}
+
+func (r *pgNotifications) UnreadCount(ctx context.Context, userID int) (int, error) {
+	var count int
+	err := r.q.GetContext(ctx, &count, "SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND is_read = FALSE", userID)
+	return count, pgError(err)
+}
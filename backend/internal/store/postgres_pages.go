@@ -0,0 +1,58 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"saas-management-api/internal/models"
+)
+
+type pgPages struct {
+	q querier
+}
+
+func (r *pgPages) List(ctx context.Context, websiteID int) ([]models.WebsitePage, error) {
+	pages := []models.WebsitePage{}
+	err := r.q.SelectContext(ctx, &pages, "SELECT * FROM website_pages WHERE website_id = $1 ORDER BY position, id", websiteID)
+	return pages, pgError(err)
+}
+
+func (r *pgPages) Get(ctx context.Context, websiteID, id int) (*models.WebsitePage, error) {
+	var page models.WebsitePage
+	if err := r.q.GetContext(ctx, &page, "SELECT * FROM website_pages WHERE website_id = $1 AND id = $2", websiteID, id); err != nil {
+		return nil, pgError(err)
+	}
+	return &page, nil
+}
+
+func (r *pgPages) Create(ctx context.Context, page *models.WebsitePage) error {
+	err := r.q.GetContext(ctx, page, `
+		INSERT INTO website_pages (website_id, slug, title, position, content, created_at, updated_at)
+		SELECT $1, $2, $3,
+		       CASE WHEN $4 > 0 THEN $4 ELSE COALESCE(MAX(position), 0) + 1 END,
+		       $5, $6, $6
+		FROM website_pages
+		WHERE website_id = $1
+		RETURNING *
+	`, page.WebsiteID, page.Slug, page.Title, page.Position, page.Content, time.Now())
+	return pgError(err)
+}
+
+func (r *pgPages) Update(ctx context.Context, page *models.WebsitePage) error {
+	err := r.q.GetContext(ctx, page, `
+		UPDATE website_pages
+		SET slug = $1, title = $2, position = $3, content = $4, updated_at = $5
+		WHERE website_id = $6 AND id = $7
+		RETURNING *
+	`, page.Slug, page.Title, page.Position, page.Content, time.Now(), page.WebsiteID, page.ID)
+	return pgError(err)
+}
+
+func (r *pgPages) Delete(ctx context.Context, websiteID, id int) error {
+	return pgExec(ctx, r.q, "DELETE FROM website_pages WHERE website_id = $1 AND id = $2", websiteID, id)
+}
+
+func (r *pgPages) DeleteAll(ctx context.Context, websiteID int) error {
+	_, err := r.q.ExecContext(ctx, "DELETE FROM website_pages WHERE website_id = $1", websiteID)
+	return pgError(err)
+}
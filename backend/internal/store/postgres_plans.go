@@ -0,0 +1,49 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"saas-management-api/internal/models"
+)
+
+type pgPlans struct {
+	q querier
+}
+
+func (r *pgPlans) List(ctx context.Context) ([]models.Plan, error) {
+	plans := []models.Plan{}
+	err := r.q.SelectContext(ctx, &plans, "SELECT * FROM plans ORDER BY price ASC")
+	return plans, pgError(err)
+}
+
+func (r *pgPlans) Get(ctx context.Context, id int) (*models.Plan, error) {
+	var plan models.Plan
+	if err := r.q.GetContext(ctx, &plan, "SELECT * FROM plans WHERE id = $1", id); err != nil {
+		return nil, pgError(err)
+	}
+	return &plan, nil
+}
+
+func (r *pgPlans) Create(ctx context.Context, plan *models.Plan) error {
+	err := r.q.GetContext(ctx, plan, `
+		INSERT INTO plans (name, description, price, billing_cycle, features, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $6)
+		RETURNING *
+	`, plan.Name, plan.Description, plan.Price, plan.BillingCycle, plan.Features, time.Now())
+	return pgError(err)
+}
+
+func (r *pgPlans) Update(ctx context.Context, plan *models.Plan) error {
+	err := r.q.GetContext(ctx, plan, `
+		UPDATE plans
+		SET name = $1, description = $2, price = $3, billing_cycle = $4, features = $5, updated_at = $6
+		WHERE id = $7
+		RETURNING *
+	`, plan.Name, plan.Description, plan.Price, plan.BillingCycle, plan.Features, time.Now(), plan.ID)
+	return pgError(err)
+}
+
+func (r *pgPlans) Delete(ctx context.Context, id int) error {
+	return pgExec(ctx, r.q, "DELETE FROM plans WHERE id = $1", id)
+}
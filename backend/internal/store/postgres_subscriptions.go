@@ -0,0 +1,53 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"saas-management-api/internal/listing"
+	"saas-management-api/internal/models"
+)
+
+type pgSubscriptions struct {
+	q querier
+}
+
+func (r *pgSubscriptions) List(ctx context.Context, spec listing.Spec) (listing.Page[models.Subscription], error) {
+	subscriptions := []models.Subscription{}
+	where, tail, args := SubscriptionListing.SQL(spec, nil, nil)
+	if err := r.q.SelectContext(ctx, &subscriptions, "SELECT * FROM subscriptions"+where+tail, args...); err != nil {
+		return listing.Page[models.Subscription]{}, pgError(err)
+	}
+	return listing.Paginate(subscriptions, spec, SubscriptionListing), nil
+}
+
+func (r *pgSubscriptions) Get(ctx context.Context, id int) (*models.Subscription, error) {
+	var subscription models.Subscription
+	if err := r.q.GetContext(ctx, &subscription, "SELECT * FROM subscriptions WHERE id = $1", id); err != nil {
+		return nil, pgError(err)
+	}
+	return &subscription, nil
+}
+
+func (r *pgSubscriptions) Create(ctx context.Context, subscription *models.Subscription) error {
+	err := r.q.GetContext(ctx, subscription, `
+		INSERT INTO subscriptions (business_id, plan_id, status, ends_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $5)
+		RETURNING *
+	`, subscription.BusinessID, subscription.PlanID, subscription.Status, subscription.EndsAt, time.Now())
+	return pgError(err)
+}
+
+func (r *pgSubscriptions) Update(ctx context.Context, subscription *models.Subscription) error {
+	err := r.q.GetContext(ctx, subscription, `
+		UPDATE subscriptions
+		SET status = $1, ends_at = $2, updated_at = $3
+		WHERE id = $4
+		RETURNING *
+	`, subscription.Status, subscription.EndsAt, time.Now(), subscription.ID)
+	return pgError(err)
+}
+
+func (r *pgSubscriptions) Delete(ctx context.Context, id int) error {
+	return pgExec(ctx, r.q, "DELETE FROM subscriptions WHERE id = $1", id)
+}
@@ -0,0 +1,32 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+type pgTLSCache struct {
+	q querier
+}
+
+func (r *pgTLSCache) Get(ctx context.Context, key string) ([]byte, error) {
+	var data []byte
+	if err := r.q.GetContext(ctx, &data, "SELECT data FROM tls_cache WHERE key = $1", key); err != nil {
+		return nil, pgError(err)
+	}
+	return data, nil
+}
+
+func (r *pgTLSCache) Put(ctx context.Context, key string, data []byte) error {
+	_, err := r.q.ExecContext(ctx, `
+		INSERT INTO tls_cache (key, data, updated_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO UPDATE SET data = EXCLUDED.data, updated_at = EXCLUDED.updated_at
+	`, key, data, time.Now())
+	return pgError(err)
+}
+
+func (r *pgTLSCache) Delete(ctx context.Context, key string) error {
+	_, err := r.q.ExecContext(ctx, "DELETE FROM tls_cache WHERE key = $1", key)
+	return pgError(err)
+}
@@ -0,0 +1,57 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"saas-management-api/internal/models"
+)
+
+type pgUsers struct {
+	q querier
+}
+
+func (r *pgUsers) Get(ctx context.Context, id int) (*models.User, error) {
+	var user models.User
+	if err := r.q.GetContext(ctx, &user, "SELECT * FROM users WHERE id = $1", id); err != nil {
+		return nil, pgError(err)
+	}
+	return &user, nil
+}
+
+func (r *pgUsers) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	var user models.User
+	if err := r.q.GetContext(ctx, &user, "SELECT * FROM users WHERE email = $1", email); err != nil {
+		return nil, pgError(err)
+	}
+	return &user, nil
+}
+
+func (r *pgUsers) Create(ctx context.Context, user *models.User) error {
+	err := r.q.GetContext(ctx, user, `
+		INSERT INTO users (name, email, password, role, business_name, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $6)
+		RETURNING *
+	`, user.Name, user.Email, user.Password, user.Role, user.BusinessName, time.Now())
+	return pgError(err)
+}
+
+func (r *pgUsers) Search(ctx context.Context, query string, limit int) ([]models.User, error) {
+	pattern := "%" + strings.ReplaceAll(query, " ", "%") + "%"
+	users := []models.User{}
+	err := r.q.SelectContext(ctx, &users, `
+		SELECT *
+		FROM users
+		WHERE (name ILIKE $1 OR business_name ILIKE $1)
+		AND role != 'admin'
+		LIMIT $2
+	`, pattern, limit)
+	return users, pgError(err)
+}
+
+func (r *pgUsers) AdminIDs(ctx context.Context) ([]int, error) {
+	var ids []int
+	err := r.q.SelectContext(ctx, &ids, "SELECT id FROM users WHERE role = 'admin' ORDER BY id")
+	return ids, pgError(err)
+}
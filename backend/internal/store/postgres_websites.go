@@ -0,0 +1,143 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"saas-management-api/internal/listing"
+	"saas-management-api/internal/models"
+)
+
+type pgWebsites struct {
+	q querier
+}
+
+func (r *pgWebsites) List(ctx context.Context, spec listing.Spec) (listing.Page[models.Website], error) {
+	websites := []models.Website{}
+	where, tail, args := WebsiteListing.SQL(spec, nil, nil)
+	if err := r.q.SelectContext(ctx, &websites, "SELECT * FROM websites"+where+tail, args...); err != nil {
+		return listing.Page[models.Website]{}, pgError(err)
+	}
+	return listing.Paginate(websites, spec, WebsiteListing), nil
+}
+
+func (r *pgWebsites) Get(ctx context.Context, id int) (*models.Website, error) {
+	var website models.Website
+	if err := r.q.GetContext(ctx, &website, "SELECT * FROM websites WHERE id = $1", id); err != nil {
+		return nil, pgError(err)
+	}
+	return &website, nil
+}
+
+func (r *pgWebsites) Create(ctx context.Context, website *models.Website) error {
+	err := r.q.GetContext(ctx, website, `
+		INSERT INTO websites (business_id, title, url, image_url, theme_name, theme_settings, is_demo, is_claimed, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, COALESCE($6::jsonb, '{}'), $7, $8, $9, $10, $10)
+		RETURNING *
+	`, website.BusinessID, website.Title, website.URL, website.ImageURL, website.ThemeName, website.ThemeSettings, website.IsDemo, website.IsClaimed, website.Status, time.Now())
+	return pgError(err)
+}
+
+func (r *pgWebsites) Update(ctx context.Context, website *models.Website) error {
+	err := r.q.GetContext(ctx, website, `
+		UPDATE websites
+		SET title = $1, url = $2, image_url = $3, theme_name = $4, theme_settings = COALESCE($5::jsonb, '{}'), updated_at = $6
+		WHERE id = $7
+		RETURNING *
+	`, website.Title, website.URL, website.ImageURL, website.ThemeName, website.ThemeSettings, time.Now(), website.ID)
+	return pgError(err)
+}
+
+func (r *pgWebsites) Claim(ctx context.Context, id, businessID int, at time.Time) (*models.Website, error) {
+	// The conditions are re-checked on the locked row, so of two concurrent
+	// claims only one matches.
+	var website models.Website
+	err := r.q.GetContext(ctx, &website, `
+		UPDATE websites
+		SET business_id = $1, is_claimed = TRUE, updated_at = $2
+		WHERE id = $3 AND is_demo AND NOT is_claimed
+		RETURNING *
+	`, businessID, at, id)
+	if err == nil {
+		return &website, nil
+	}
+	if err = pgError(err); !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	if _, err := r.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("%w: website %d is not an unclaimed demo", ErrConflict, id)
+}
+
+func (r *pgWebsites) CreateRevision(ctx context.Context, revision *models.WebsiteRevision) error {
+	err := r.q.GetContext(ctx, revision, `
+		INSERT INTO website_revisions (website_id, revision, snapshot, created_by, created_at)
+		SELECT $1, COALESCE(MAX(revision), 0) + 1, $2, $3, $4
+		FROM website_revisions
+		WHERE website_id = $1
+		RETURNING *
+	`, revision.WebsiteID, revision.Snapshot, revision.CreatedBy, time.Now())
+	return pgError(err)
+}
+
+func (r *pgWebsites) Revisions(ctx context.Context, websiteID int) ([]models.WebsiteRevision, error) {
+	revisions := []models.WebsiteRevision{}
+	err := r.q.SelectContext(ctx, &revisions, "SELECT * FROM website_revisions WHERE website_id = $1 ORDER BY revision DESC", websiteID)
+	return revisions, pgError(err)
+}
+
+func (r *pgWebsites) Revision(ctx context.Context, websiteID, revision int) (*models.WebsiteRevision, error) {
+	var rev models.WebsiteRevision
+	err := r.q.GetContext(ctx, &rev, "SELECT * FROM website_revisions WHERE website_id = $1 AND revision = $2", websiteID, revision)
+	if err != nil {
+		return nil, pgError(err)
+	}
+	return &rev, nil
+}
+
+func (r *pgWebsites) Publish(ctx context.Context, websiteID, revision int, at time.Time) (*models.Website, error) {
+	var website models.Website
+	err := r.q.GetContext(ctx, &website, `
+		UPDATE websites
+		SET published_revision = $1, published_at = $2
+		WHERE id = $3
+		RETURNING *
+	`, revision, at, websiteID)
+	if err != nil {
+		return nil, pgError(err)
+	}
+	return &website, nil
+}
+
+func (r *pgWebsites) Published(ctx context.Context, businessID int) (*models.Website, error) {
+	var website models.Website
+	err := r.q.GetContext(ctx, &website, `
+		SELECT * FROM websites
+		WHERE business_id = $1 AND published_revision IS NOT NULL
+		ORDER BY published_at DESC, id DESC
+		LIMIT 1
+	`, businessID)
+	if err != nil {
+		return nil, pgError(err)
+	}
+	return &website, nil
+}
+
+func (r *pgWebsites) SetThumbnails(ctx context.Context, id int, thumbnails models.JSONB, imageURL string) error {
+	return pgExec(ctx, r.q, `
+		UPDATE websites
+		SET thumbnails = $1,
+			image_url = CASE
+				WHEN COALESCE(image_url, '') = '' OR image_url IN (SELECT value FROM jsonb_each_text(thumbnails)) THEN $2
+				ELSE image_url
+			END
+		WHERE id = $3
+	`, thumbnails, imageURL, id)
+}
+
+func (r *pgWebsites) Delete(ctx context.Context, id int) error {
+	return pgExec(ctx, r.q, "DELETE FROM websites WHERE id = $1", id)
+}
@@ -0,0 +1,312 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"saas-management-api/internal/listing"
+	"saas-management-api/internal/models"
+)
+
+// UserRepository persists users.
+type UserRepository interface {
+	Get(ctx context.Context, id int) (*models.User, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	// Create inserts user and fills in its generated fields.
+	Create(ctx context.Context, user *models.User) error
+	// Search matches non-admin users by name or business name. Spaces in
+	// query act as wildcards.
+	Search(ctx context.Context, query string, limit int) ([]models.User, error)
+	AdminIDs(ctx context.Context) ([]int, error)
+}
+
+// BusinessRepository persists businesses.
+type BusinessRepository interface {
+	// List returns one page of businesses matching spec; see BusinessListing.
+	List(ctx context.Context, spec listing.Spec) (listing.Page[models.Business], error)
+	Get(ctx context.Context, id int) (*models.Business, error)
+	Create(ctx context.Context, business *models.Business) error
+	Update(ctx context.Context, business *models.Business) error
+	Delete(ctx context.Context, id int) error
+	// GetBySlug returns the business whose current or former slug is slug.
+	GetBySlug(ctx context.Context, slug string) (*models.Business, error)
+	// TakenSlugs returns the current and former slugs equal to base or of
+	// the form base-N, ignoring those of business exceptID.
+	TakenSlugs(ctx context.Context, base string, exceptID int) ([]string, error)
+	// RecordSlugChange keeps oldSlug resolving to the business after it
+	// moved to newSlug, and drops newSlug from its former slugs.
+	RecordSlugChange(ctx context.Context, businessID int, oldSlug, newSlug string) error
+	// SetReview records an admin's review decision on a business.
+	SetReview(ctx context.Context, id int, review BusinessReview) (*models.Business, error)
+	// Search ranks businesses against a free-text query; see BusinessSearch.
+	Search(ctx context.Context, search BusinessSearch) (*models.BusinessSearchResult, error)
+}
+
+// BusinessReview is an admin's decision to approve or reject a business.
+type BusinessReview struct {
+	Status     string
+	ReviewerID int
+	Reason     string
+	At         time.Time
+}
+
+// BusinessSearch is a public business search. Query terms match as
+// prefixes of words in a business's name, industry, description, address
+// and website titles, with trigram matching as a fallback for typos. The
+// industry facet counts ignore the Industry filter so clients can offer the
+// other industries as alternatives.
+type BusinessSearch struct {
+	Query    string
+	Industry string
+	// Status restricts the search to businesses in one review status.
+	Status string
+	Limit  int
+}
+
+// WebsiteRepository persists websites.
+type WebsiteRepository interface {
+	// List returns one page of websites matching spec; see WebsiteListing.
+	List(ctx context.Context, spec listing.Spec) (listing.Page[models.Website], error)
+	Get(ctx context.Context, id int) (*models.Website, error)
+	Create(ctx context.Context, website *models.Website) error
+	Update(ctx context.Context, website *models.Website) error
+	Delete(ctx context.Context, id int) error
+	// Claim assigns an unclaimed demo website to a business. It returns
+	// ErrConflict when the website is not an unclaimed demo.
+	Claim(ctx context.Context, id, businessID int, at time.Time) (*models.Website, error)
+
+	// CreateRevision appends a revision to a website's history, numbering
+	// it after the latest one.
+	CreateRevision(ctx context.Context, revision *models.WebsiteRevision) error
+	// Revisions returns a website's revisions, newest first.
+	Revisions(ctx context.Context, websiteID int) ([]models.WebsiteRevision, error)
+	Revision(ctx context.Context, websiteID, revision int) (*models.WebsiteRevision, error)
+	// Publish makes revision the one visitors see.
+	Publish(ctx context.Context, websiteID, revision int, at time.Time) (*models.Website, error)
+	// Published returns the most recently published website of a business.
+	Published(ctx context.Context, businessID int) (*models.Website, error)
+	// SetThumbnails records generated thumbnails. It also sets ImageURL to
+	// imageURL when ImageURL is empty or one of the previous thumbnails,
+	// and keeps an image the owner set.
+	SetThumbnails(ctx context.Context, id int, thumbnails models.JSONB, imageURL string) error
+}
+
+// DomainRepository persists the host names attached to businesses.
+type DomainRepository interface {
+	Get(ctx context.Context, id int) (*models.Domain, error)
+	GetByName(ctx context.Context, name string) (*models.Domain, error)
+	// ListForBusiness returns the domains of a business by name.
+	ListForBusiness(ctx context.Context, businessID int) ([]models.Domain, error)
+	// Create inserts domain. A name already attached returns ErrConflict.
+	Create(ctx context.Context, domain *models.Domain) error
+	// MarkVerified sets the domain's status to verified.
+	MarkVerified(ctx context.Context, id int, at time.Time) (*models.Domain, error)
+	Delete(ctx context.Context, id int) error
+}
+
+// TLSCacheRepository persists opaque ACME and certificate data by key.
+type TLSCacheRepository interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Put inserts or replaces the data under key.
+	Put(ctx context.Context, key string, data []byte) error
+	// Delete removes key. Missing keys are not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// AnalyticsRepository persists cookieless website analytics as daily
+// rollups.
+type AnalyticsRepository interface {
+	// Salt returns the visitor salt of day, storing fresh if day has none
+	// yet. Storing a new day's salt forgets the salts and visitors of
+	// earlier days.
+	Salt(ctx context.Context, day time.Time, fresh []byte) ([]byte, error)
+	// Record adds a pageview or event to the rollups of its day.
+	Record(ctx context.Context, record AnalyticsRecord) error
+	// Days returns a website's daily totals from from to to, inclusive.
+	Days(ctx context.Context, websiteID int, from, to time.Time) ([]models.AnalyticsDay, error)
+	// Top returns the most frequent values of a dimension from from to to,
+	// inclusive.
+	Top(ctx context.Context, websiteID int, dimension string, from, to time.Time, limit int) ([]models.AnalyticsCount, error)
+}
+
+// AnalyticsRecord is a normalized pageview or event.
+type AnalyticsRecord struct {
+	WebsiteID int
+	Day       time.Time
+	// Visitor is the salted hash identifying the visitor for the day.
+	Visitor string
+	// Event names a custom event. Pageviews have none.
+	Event string
+	Path  string
+	// Visit marks a pageview arriving from another site or none, and
+	// Referrer is the host of that other site.
+	Visit    bool
+	Referrer string
+}
+
+// PageRepository persists the pages of websites.
+type PageRepository interface {
+	// List returns a website's pages by position.
+	List(ctx context.Context, websiteID int) ([]models.WebsitePage, error)
+	Get(ctx context.Context, websiteID, id int) (*models.WebsitePage, error)
+	// Create inserts page. A zero Position places it after the existing
+	// pages. A slug already used on the website returns ErrConflict.
+	Create(ctx context.Context, page *models.WebsitePage) error
+	Update(ctx context.Context, page *models.WebsitePage) error
+	Delete(ctx context.Context, websiteID, id int) error
+	// DeleteAll removes every page of a website.
+	DeleteAll(ctx context.Context, websiteID int) error
+}
+
+// PlanRepository persists subscription plans.
+type PlanRepository interface {
+	// List returns plans ordered by price, cheapest first.
+	List(ctx context.Context) ([]models.Plan, error)
+	Get(ctx context.Context, id int) (*models.Plan, error)
+	Create(ctx context.Context, plan *models.Plan) error
+	Update(ctx context.Context, plan *models.Plan) error
+	Delete(ctx context.Context, id int) error
+}
+
+// SubscriptionRepository persists business subscriptions.
+type SubscriptionRepository interface {
+	// List returns one page of subscriptions matching spec; see SubscriptionListing.
+	List(ctx context.Context, spec listing.Spec) (listing.Page[models.Subscription], error)
+	Get(ctx context.Context, id int) (*models.Subscription, error)
+	Create(ctx context.Context, subscription *models.Subscription) error
+	// Update changes the status and end date of a subscription.
+	Update(ctx context.Context, subscription *models.Subscription) error
+	Delete(ctx context.Context, id int) error
+}
+
+// InvoiceRepository persists the invoices of businesses and their payments.
+type InvoiceRepository interface {
+	// ListForBusiness returns the invoices of a business, newest first.
+	ListForBusiness(ctx context.Context, businessID int) ([]models.Invoice, error)
+	Get(ctx context.Context, id int) (*models.Invoice, error)
+	Create(ctx context.Context, invoice *models.Invoice) error
+	// SetStatus changes the status of an invoice.
+	SetStatus(ctx context.Context, id int, status string, at time.Time) (*models.Invoice, error)
+	// CreatePayment records a payment of an invoice.
+	CreatePayment(ctx context.Context, payment *models.Payment) error
+}
+
+// NotificationUpdate lists the notification fields a caller may change.
+// Nil fields are left untouched.
+type NotificationUpdate struct {
+	IsRead *bool
+	Status *string
+}
+
+// NotificationRepository persists notifications. Methods taking a userID
+// only see notifications addressed to that user; a userID of 0 lifts the
+// restriction for admin access.
+type NotificationRepository interface {
+	List(ctx context.Context, userID int, spec listing.Spec) (listing.Page[models.Notification], error)
+	Get(ctx context.Context, id, userID int) (*models.Notification, error)
+	Create(ctx context.Context, notification *models.Notification) error
+	Update(ctx context.Context, id, userID int, update NotificationUpdate) (*models.Notification, error)
+	Delete(ctx context.Context, id, userID int) error
+	UnreadCount(ctx context.Context, userID int) (int, error)
+}
+
+// ConversationRepository persists direct and group conversations, their
+// participants and their messages. Conversations are returned with their
+// participants.
+type ConversationRepository interface {
+	// GetOrCreate returns the direct conversation between two users,
+	// creating it if needed. The pair is unordered.
+	GetOrCreate(ctx context.Context, userA, userB int) (*models.Conversation, error)
+	// GetDirect returns the direct conversation between two users, or
+	// ErrNotFound if they haven't got one. The pair is unordered.
+	GetDirect(ctx context.Context, userA, userB int) (*models.Conversation, error)
+	// GetForUser returns a conversation only if userID participates in it.
+	GetForUser(ctx context.Context, id, userID int) (*models.Conversation, error)
+	// ListForUser returns the user's conversations, most recent first, with
+	// unread counts and last message previews.
+	ListForUser(ctx context.Context, userID int) ([]models.Conversation, error)
+	// Touch records activity on a conversation.
+	Touch(ctx context.Context, id int, at time.Time) error
+	// CreateGroup inserts a group conversation and fills in its generated
+	// fields. Participants are added separately.
+	CreateGroup(ctx context.Context, conversation *models.Conversation) error
+	// Rename sets the title of a group conversation.
+	Rename(ctx context.Context, id int, title string, at time.Time) error
+
+	// AddParticipants adds users to a conversation with role, as having
+	// read its messages so far. It returns the IDs of the users who weren't
+	// participants already.
+	AddParticipants(ctx context.Context, conversationID int, userIDs []int, role string, at time.Time) ([]int, error)
+	// RemoveParticipant removes a user from a conversation.
+	RemoveParticipant(ctx context.Context, conversationID, userID int) error
+	// Participants returns the participants of a conversation in the order
+	// they joined.
+	Participants(ctx context.Context, conversationID int) ([]models.ConversationParticipant, error)
+
+	// Messages returns one page of a conversation's messages, leaving out
+	// those userID hid; see MessageListing.
+	Messages(ctx context.Context, conversationID, userID int, spec listing.Spec) (listing.Page[models.Message], error)
+	// SearchMessages returns one page of the messages userID can see whose
+	// text has a word starting with each word of query; see
+	// MessageSearchListing. Deleted messages never match.
+	SearchMessages(ctx context.Context, userID int, query string, spec listing.Spec) (listing.Page[models.MessageSearchHit], error)
+	// MessageForUser returns a message only if userID participates in its
+	// conversation and hasn't hidden it.
+	MessageForUser(ctx context.Context, id, userID int) (*models.Message, error)
+	// CreateMessage inserts message and fills in its generated and join
+	// fields. It returns ErrDuplicate if the sender already sent a message
+	// with the same ClientID.
+	CreateMessage(ctx context.Context, message *models.Message) error
+	// EditMessage replaces the text of a message, keeping the previous text
+	// in its edit history.
+	EditMessage(ctx context.Context, id int, text string, at time.Time) error
+	// MessageEdits returns the edit history of a message, oldest first.
+	MessageEdits(ctx context.Context, messageID int) ([]models.MessageEdit, error)
+	// DeleteMessage turns a message into a tombstone, dropping its text,
+	// edit history, reactions and attachments.
+	DeleteMessage(ctx context.Context, id int, at time.Time) error
+	// HideMessage hides a message from userID. Hiding it again is a no-op.
+	HideMessage(ctx context.Context, id, userID int, at time.Time) error
+	// AddReaction records userID's reaction to a message with an emoji, if
+	// they haven't reacted with it already.
+	AddReaction(ctx context.Context, messageID, userID int, emoji string, at time.Time) error
+	// RemoveReaction drops userID's reaction with an emoji, if any.
+	RemoveReaction(ctx context.Context, messageID, userID int, emoji string) error
+	// MarkRead moves userID's read marker in a conversation forward to
+	// upToID, or to its latest message if that is older, and marks the
+	// direct messages addressed to them up to there as read and delivered.
+	// It returns the messages of others it newly covered, and ErrNotFound
+	// if the user doesn't participate.
+	MarkRead(ctx context.Context, conversationID, userID, upToID int, at time.Time) ([]models.Message, error)
+	// MarkDelivered marks the undelivered messages among ids addressed to
+	// receiverID as delivered. It returns the messages it marked.
+	MarkDelivered(ctx context.Context, receiverID int, ids []int, at time.Time) ([]models.Message, error)
+	// ListMessages returns the messages of the conversations userID
+	// participates in, newest first. A userID of 0 returns every message.
+	// Like the other message reads, it leaves out messages userID hid and
+	// fills in reactions and attachments.
+	ListMessages(ctx context.Context, userID int) ([]models.Message, error)
+	// RecentMessages returns at most limit of the user's latest messages,
+	// newest first.
+	RecentMessages(ctx context.Context, userID, limit int) ([]models.Message, error)
+	// MessagesAfter returns at most limit of the user's messages with an ID
+	// above afterID, oldest first.
+	MessagesAfter(ctx context.Context, userID, afterID, limit int) ([]models.Message, error)
+	// MessageByClientID returns the message senderID sent with clientID.
+	MessageByClientID(ctx context.Context, senderID int, clientID string) (*models.Message, error)
+	// UnreadCount counts the messages of others past the user's read
+	// markers.
+	UnreadCount(ctx context.Context, userID int) (int, error)
+}
+
+// AttachmentRepository persists the files uploaded for messages. Message
+// reads fill in the attachments of each message.
+type AttachmentRepository interface {
+	// Create inserts attachment and fills in its generated fields.
+	Create(ctx context.Context, attachment *models.Attachment) error
+	// Get returns an attachment by ID.
+	Get(ctx context.Context, id int) (*models.Attachment, error)
+	// Attach attaches attachments to a message. It returns ErrNotFound
+	// unless each of them belongs to ownerID and isn't attached yet.
+	Attach(ctx context.Context, messageID, ownerID int, ids []int) error
+}
@@ -0,0 +1,76 @@
+package store
+
+import (
+	"html"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Markers delimit the matches in search snippets until they are rendered
+// as HTML by highlight.
+const (
+	matchStart = "\x02"
+	matchStop  = "\x03"
+)
+
+// searchTerms splits a free-text query into lowercase words. Everything
+// other than letters and digits separates words, which also keeps tsquery
+// operators out of the terms.
+func searchTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !isWordRune(r)
+	})
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// unmarked drops match markers typed into text, so that only the ones a
+// search adds reach highlight.
+func unmarked(text string) string {
+	return strings.NewReplacer(matchStart, "", matchStop, "").Replace(text)
+}
+
+// highlight HTML-escapes a snippet and turns its match markers into <mark>
+// tags.
+func highlight(snippet string) string {
+	return strings.NewReplacer(matchStart, "<mark>", matchStop, "</mark>").Replace(html.EscapeString(snippet))
+}
+
+// prefixWords reports whether every term is the prefix of one of words.
+func prefixWords(terms, words []string) bool {
+	for _, term := range terms {
+		if !slices.ContainsFunc(words, func(word string) bool { return strings.HasPrefix(word, term) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// markMatches wraps the words of text that start with one of terms in
+// match markers.
+func markMatches(text string, terms []string) string {
+	var b strings.Builder
+	for text != "" {
+		start := strings.IndexFunc(text, isWordRune)
+		if start < 0 {
+			start = len(text)
+		}
+		b.WriteString(text[:start])
+		text = text[start:]
+
+		end := strings.IndexFunc(text, func(r rune) bool { return !isWordRune(r) })
+		if end < 0 {
+			end = len(text)
+		}
+		word := text[:end]
+		text = text[end:]
+		if slices.ContainsFunc(terms, func(term string) bool { return strings.HasPrefix(strings.ToLower(word), term) }) {
+			word = matchStart + word + matchStop
+		}
+		b.WriteString(word)
+	}
+	return b.String()
+}
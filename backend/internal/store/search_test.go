@@ -0,0 +1,104 @@
+package store
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"saas-management-api/internal/listing"
+)
+
+func TestSearchTerms(t *testing.T) {
+	for _, tt := range []struct {
+		query string
+		want  []string
+	}{
+		{"Lunch  Tomorrow", []string{"lunch", "tomorrow"}},
+		{"café:* & !(bar | baz)", []string{"café", "bar", "baz"}},
+		{"room 101's", []string{"room", "101", "s"}},
+		{"&|!:*", []string{}},
+	} {
+		got := searchTerms(tt.query)
+		if len(got) != len(tt.want) || (len(got) > 0 && !reflect.DeepEqual(got, tt.want)) {
+			t.Errorf("searchTerms(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestMarkMatchesAndHighlight(t *testing.T) {
+	for _, tt := range []struct {
+		text  string
+		terms []string
+		want  string
+	}{
+		{"Lunch at noon?", []string{"lun"}, "<mark>Lunch</mark> at noon?"},
+		{"lunch, LUNCH and brunch", []string{"lunch"}, "<mark>lunch</mark>, <mark>LUNCH</mark> and brunch"},
+		{"<b>bold</b> & bolder", []string{"bold"}, "&lt;b&gt;<mark>bold</mark>&lt;/b&gt; &amp; <mark>bolder</mark>"},
+		{"nothing here", []string{"x"}, "nothing here"},
+	} {
+		if got := highlight(markMatches(tt.text, tt.terms)); got != tt.want {
+			t.Errorf("highlight(markMatches(%q, %q)) = %q, want %q", tt.text, tt.terms, got, tt.want)
+		}
+	}
+
+	// Markers typed into a message can't open a <mark> tag of their own.
+	if got := highlight(markMatches(unmarked("a\x02b\x03 c"), []string{"c"})); got != "ab <mark>c</mark>" {
+		t.Fatalf("snippet of typed markers = %q", got)
+	}
+}
+
+func TestSearchMessages(t *testing.T) {
+	st := NewMemory()
+	ctx := context.Background()
+	ids := newMessagingUsers(t, st, "alice", "bob", "carol")
+	alice, bob, carol := ids[0], ids[1], ids[2]
+	older := send(t, st, bob, 0, alice, "Lunch tomorrow?")
+	send(t, st, alice, older.ConversationID, 0, "Sure, brunch works too")
+	hidden := send(t, st, bob, older.ConversationID, 0, "lunch is on me")
+	deleted := send(t, st, alice, older.ConversationID, 0, "lunch at noon")
+	send(t, st, carol, 0, bob, "lunch without alice")
+	group, err := st.CreateGroup(ctx, carol, "Lunch club", []int{alice})
+	if err != nil {
+		t.Fatal(err)
+	}
+	newer := send(t, st, carol, group.ID, 0, "<b>Lunch</b> orders")
+	at := time.Now()
+	if _, err := st.DeleteMessage(ctx, hidden.ID, alice, false, at); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.DeleteMessage(ctx, deleted.ID, alice, true, at); err != nil {
+		t.Fatal(err)
+	}
+
+	spec := listing.Spec{Limit: 10, Sort: "created_at", Desc: true}
+	page, err := st.Conversations.SearchMessages(ctx, alice, "LUNCH", spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []int
+	for _, hit := range page.Items {
+		got = append(got, hit.ID)
+	}
+	if want := []int{newer.ID, older.ID}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("hits = %v, want %v, newest first", got, want)
+	}
+	groupHit, directHit := page.Items[0], page.Items[1]
+	if groupHit.Snippet != "&lt;b&gt;<mark>Lunch</mark>&lt;/b&gt; orders" || !groupHit.ConversationIsGroup || *groupHit.ConversationTitle != "Lunch club" {
+		t.Errorf("group hit = %+v", groupHit)
+	}
+	if directHit.ConversationIsGroup || *directHit.ConversationTitle != "bob" {
+		t.Errorf("direct hit = %+v, want bob as the title", directHit)
+	}
+
+	// Every term must start a word.
+	for query, want := range map[string]int{"lunch tom": 1, "unch": 0, "lunch friday": 0, "!&": 0} {
+		page, err := st.Conversations.SearchMessages(ctx, alice, query, spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Items) != want {
+			t.Errorf("search for %q found %d hits, want %d", query, len(page.Items), want)
+		}
+	}
+}
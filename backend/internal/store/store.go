@@ -0,0 +1,48 @@
+// Package store provides typed repositories for the application's aggregates.
+//
+// Handlers depend on the repository interfaces declared here rather than on
+// raw SQL, so they can run against Postgres in production (NewPostgres) and
+// against an in-memory fake in tests (NewMemory).
+package store
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrNotFound is returned when the requested row does not exist or is not
+	// visible to the caller.
+	ErrNotFound = errors.New("store: not found")
+
+	// ErrConflict is returned when a write violates a uniqueness constraint.
+	ErrConflict = errors.New("store: conflict")
+)
+
+// Store groups the repositories of every aggregate.
+type Store struct {
+	Users         UserRepository
+	Businesses    BusinessRepository
+	Websites      WebsiteRepository
+	Pages         PageRepository
+	Domains       DomainRepository
+	TLSCache      TLSCacheRepository
+	Analytics     AnalyticsRepository
+	Plans         PlanRepository
+	Subscriptions SubscriptionRepository
+	Invoices      InvoiceRepository
+	Notifications NotificationRepository
+	Conversations ConversationRepository
+	Attachments   AttachmentRepository
+
+	withTx func(ctx context.Context, fn func(tx *Store) error) error
+}
+
+// WithTx runs fn with a Store whose repositories share one transaction. The
+// transaction commits if fn returns nil and rolls back otherwise. fn may be
+// retried after serialization failures, so it must not have side effects
+// outside the store. Calling WithTx on a transactional Store reuses the
+// transaction.
+func (s *Store) WithTx(ctx context.Context, fn func(tx *Store) error) error {
+	return s.withTx(ctx, fn)
+}
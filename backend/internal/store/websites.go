@@ -0,0 +1,347 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"saas-management-api/internal/models"
+)
+
+// TrialPeriod is the length of the trial subscription that starts when a
+// demo website is claimed.
+const TrialPeriod = 14 * 24 * time.Hour
+
+// WebsiteClaim is a request by ClaimedBy to take over a demo website for
+// one of their businesses on a trial of a plan.
+type WebsiteClaim struct {
+	WebsiteID  int
+	BusinessID int
+	PlanID     int
+	ClaimedBy  int
+}
+
+// ClaimedWebsite is the outcome of a claim.
+type ClaimedWebsite struct {
+	Website       *models.Website
+	Subscription  *models.Subscription
+	Notifications []models.Notification
+}
+
+// ClaimWebsite assigns an unclaimed demo website to a business, starts a
+// trial subscription and notifies the admins, all in one transaction. It
+// returns ErrConflict when the website has already been claimed.
+func (s *Store) ClaimWebsite(ctx context.Context, claim WebsiteClaim) (*ClaimedWebsite, error) {
+	var claimed *ClaimedWebsite
+	err := s.WithTx(ctx, func(tx *Store) error {
+		now := time.Now()
+		business, err := tx.Businesses.Get(ctx, claim.BusinessID)
+		if err != nil {
+			return err
+		}
+		plan, err := tx.Plans.Get(ctx, claim.PlanID)
+		if err != nil {
+			return err
+		}
+
+		website, err := tx.Websites.Claim(ctx, claim.WebsiteID, claim.BusinessID, now)
+		if err != nil {
+			return err
+		}
+
+		endsAt := now.Add(TrialPeriod)
+		subscription := &models.Subscription{
+			BusinessID: claim.BusinessID,
+			PlanID:     claim.PlanID,
+			Status:     "trial",
+			EndsAt:     &endsAt,
+		}
+		if err := tx.Subscriptions.Create(ctx, subscription); err != nil {
+			return err
+		}
+
+		adminIDs, err := tx.Users.AdminIDs(ctx)
+		if err != nil {
+			return err
+		}
+		notifications := make([]models.Notification, 0, len(adminIDs))
+		for _, adminID := range adminIDs {
+			notification := models.Notification{
+				UserID:     adminID,
+				FromUserID: &claim.ClaimedBy,
+				Subject:    "Demo website claimed",
+				Message:    fmt.Sprintf("%q was claimed by %s on a %s trial.", website.Title, business.Name, plan.Name),
+				Type:       "website_claim",
+				Status:     "unread",
+			}
+			if err := tx.Notifications.Create(ctx, &notification); err != nil {
+				return err
+			}
+			notifications = append(notifications, notification)
+		}
+		claimed = &ClaimedWebsite{Website: website, Subscription: subscription, Notifications: notifications}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claimed, nil
+}
+
+// SiteContent is the versioned part of a website, as stored in revision
+// snapshots. Review status and ownership are not content and survive
+// rollbacks.
+type SiteContent struct {
+	Title         string        `json:"title"`
+	URL           *string       `json:"url"`
+	ImageURL      *string       `json:"image_url"`
+	ThemeName     string        `json:"theme_name"`
+	ThemeSettings models.JSONB  `json:"theme_settings,omitempty"`
+	Pages         []PageContent `json:"pages"`
+}
+
+// PageContent is a page as stored in a revision snapshot.
+type PageContent struct {
+	Slug     string       `json:"slug"`
+	Title    string       `json:"title"`
+	Position int          `json:"position"`
+	Content  models.JSONB `json:"content"`
+}
+
+func snapshotWebsite(website *models.Website, pages []models.WebsitePage) (models.JSONB, error) {
+	content := SiteContent{
+		Title:         website.Title,
+		URL:           website.URL,
+		ImageURL:      website.ImageURL,
+		ThemeName:     website.ThemeName,
+		ThemeSettings: website.ThemeSettings,
+		Pages:         make([]PageContent, len(pages)),
+	}
+	for i, page := range pages {
+		content.Pages[i] = PageContent{Slug: page.Slug, Title: page.Title, Position: page.Position, Content: page.Content}
+	}
+	data, err := json.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+	var snapshot models.JSONB
+	err = json.Unmarshal(data, &snapshot)
+	return snapshot, err
+}
+
+// decodeSnapshot reads a revision snapshot. Revisions saved before pages or
+// theme settings existed have none.
+func decodeSnapshot(snapshot models.JSONB) (*SiteContent, error) {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	var content SiteContent
+	if err := json.Unmarshal(data, &content); err != nil {
+		return nil, err
+	}
+	return &content, nil
+}
+
+// PublishedContent returns the content of the published revision of
+// website, or ErrNotFound when nothing has been published yet.
+func (s *Store) PublishedContent(ctx context.Context, website *models.Website) (*SiteContent, error) {
+	if website.PublishedRevision == nil {
+		return nil, ErrNotFound
+	}
+	revision, err := s.Websites.Revision(ctx, website.ID, *website.PublishedRevision)
+	if err != nil {
+		return nil, err
+	}
+	return decodeSnapshot(revision.Snapshot)
+}
+
+// restoreWebsite brings the draft of website, including its pages, back to
+// the state captured in snapshot.
+func (s *Store) restoreWebsite(ctx context.Context, website *models.Website, snapshot models.JSONB) error {
+	content, err := decodeSnapshot(snapshot)
+	if err != nil {
+		return err
+	}
+	website.Title = content.Title
+	website.URL = content.URL
+	website.ImageURL = content.ImageURL
+	website.ThemeName = content.ThemeName
+	website.ThemeSettings = content.ThemeSettings
+	if err := s.Websites.Update(ctx, website); err != nil {
+		return err
+	}
+
+	if err := s.Pages.DeleteAll(ctx, website.ID); err != nil {
+		return err
+	}
+	for _, page := range content.Pages {
+		err := s.Pages.Create(ctx, &models.WebsitePage{
+			WebsiteID: website.ID,
+			Slug:      page.Slug,
+			Title:     page.Title,
+			Position:  page.Position,
+			Content:   page.Content,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveRevision records the current state of website and its pages as its
+// next revision.
+func (s *Store) saveRevision(ctx context.Context, website *models.Website, userID int) (*models.WebsiteRevision, error) {
+	pages, err := s.Pages.List(ctx, website.ID)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := snapshotWebsite(website, pages)
+	if err != nil {
+		return nil, err
+	}
+	revision := &models.WebsiteRevision{WebsiteID: website.ID, Snapshot: snapshot}
+	if userID != 0 {
+		revision.CreatedBy = &userID
+	}
+	return revision, s.Websites.CreateRevision(ctx, revision)
+}
+
+// CreateWebsite inserts website along with its first revision. Nothing is
+// published until PublishWebsite is called.
+func (s *Store) CreateWebsite(ctx context.Context, website *models.Website, userID int) error {
+	return s.WithTx(ctx, func(tx *Store) error {
+		if err := tx.Websites.Create(ctx, website); err != nil {
+			return err
+		}
+		_, err := tx.saveRevision(ctx, website, userID)
+		return err
+	})
+}
+
+// SaveWebsite updates the draft of website and records it as a new
+// revision. The published revision is unaffected.
+func (s *Store) SaveWebsite(ctx context.Context, website *models.Website, userID int) error {
+	return s.WithTx(ctx, func(tx *Store) error {
+		if err := tx.Websites.Update(ctx, website); err != nil {
+			return err
+		}
+		_, err := tx.saveRevision(ctx, website, userID)
+		return err
+	})
+}
+
+// PublishWebsite publishes the latest revision, which is the current draft.
+func (s *Store) PublishWebsite(ctx context.Context, websiteID int) (*models.Website, error) {
+	var website *models.Website
+	err := s.WithTx(ctx, func(tx *Store) error {
+		revisions, err := tx.Websites.Revisions(ctx, websiteID)
+		if err != nil {
+			return err
+		}
+		if len(revisions) == 0 {
+			return ErrNotFound
+		}
+		website, err = tx.Websites.Publish(ctx, websiteID, revisions[0].Revision, time.Now())
+		return err
+	})
+	return website, err
+}
+
+// RollbackWebsite restores the content of an earlier revision. The restored
+// state is recorded as a new revision and published right away, so history
+// only ever grows.
+func (s *Store) RollbackWebsite(ctx context.Context, websiteID, revision, userID int) (*models.Website, error) {
+	var website *models.Website
+	err := s.WithTx(ctx, func(tx *Store) error {
+		target, err := tx.Websites.Revision(ctx, websiteID, revision)
+		if err != nil {
+			return err
+		}
+		website, err = tx.Websites.Get(ctx, websiteID)
+		if err != nil {
+			return err
+		}
+		if err := tx.restoreWebsite(ctx, website, target.Snapshot); err != nil {
+			return err
+		}
+		restored, err := tx.saveRevision(ctx, website, userID)
+		if err != nil {
+			return err
+		}
+		website, err = tx.Websites.Publish(ctx, websiteID, restored.Revision, time.Now())
+		return err
+	})
+	return website, err
+}
+
+// DiffRevisions lists the snapshot fields that differ between two
+// revisions, in field order.
+func DiffRevisions(from, to *models.WebsiteRevision) []models.RevisionChange {
+	fields := map[string]bool{}
+	for field := range from.Snapshot {
+		fields[field] = true
+	}
+	for field := range to.Snapshot {
+		fields[field] = true
+	}
+
+	changes := []models.RevisionChange{}
+	for field := range fields {
+		if !reflect.DeepEqual(from.Snapshot[field], to.Snapshot[field]) {
+			changes = append(changes, models.RevisionChange{
+				Field: field,
+				From:  from.Snapshot[field],
+				To:    to.Snapshot[field],
+			})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+// CreatePage adds a page to the draft of a website and records the new
+// revision.
+func (s *Store) CreatePage(ctx context.Context, page *models.WebsitePage, userID int) error {
+	return s.WithTx(ctx, func(tx *Store) error {
+		if err := tx.Pages.Create(ctx, page); err != nil {
+			return err
+		}
+		return tx.pageChanged(ctx, page.WebsiteID, userID)
+	})
+}
+
+// UpdatePage changes a page of the draft of a website and records the new
+// revision.
+func (s *Store) UpdatePage(ctx context.Context, page *models.WebsitePage, userID int) error {
+	return s.WithTx(ctx, func(tx *Store) error {
+		if err := tx.Pages.Update(ctx, page); err != nil {
+			return err
+		}
+		return tx.pageChanged(ctx, page.WebsiteID, userID)
+	})
+}
+
+// DeletePage removes a page from the draft of a website and records the new
+// revision.
+func (s *Store) DeletePage(ctx context.Context, websiteID, id, userID int) error {
+	return s.WithTx(ctx, func(tx *Store) error {
+		if err := tx.Pages.Delete(ctx, websiteID, id); err != nil {
+			return err
+		}
+		return tx.pageChanged(ctx, websiteID, userID)
+	})
+}
+
+func (s *Store) pageChanged(ctx context.Context, websiteID, userID int) error {
+	website, err := s.Websites.Get(ctx, websiteID)
+	if err != nil {
+		return err
+	}
+	_, err = s.saveRevision(ctx, website, userID)
+	return err
+}
@@ -0,0 +1,90 @@
+package store
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"saas-management-api/internal/models"
+)
+
+func TestRollbackWebsiteRestoresPages(t *testing.T) {
+	st := NewMemory()
+	ctx := context.Background()
+	website := &models.Website{Title: "Acme", ThemeName: "default"}
+	if err := st.CreateWebsite(ctx, website, 1); err != nil {
+		t.Fatal(err)
+	}
+	home := &models.WebsitePage{WebsiteID: website.ID, Slug: "home", Title: "Home", Content: models.JSONB{"sections": []any{}}}
+	if err := st.CreatePage(ctx, home, 1); err != nil {
+		t.Fatal(err)
+	}
+	published, err := st.PublishWebsite(ctx, website.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The draft moves on: the home page is renamed and another added.
+	home.Title = "Welcome"
+	if err := st.UpdatePage(ctx, home, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.CreatePage(ctx, &models.WebsitePage{WebsiteID: website.ID, Slug: "about", Title: "About", Position: 1}, 1); err != nil {
+		t.Fatal(err)
+	}
+	content, err := st.PublishedContent(ctx, published)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(content.Pages) != 1 || content.Pages[0].Title != "Home" {
+		t.Fatalf("published pages = %+v, want only Home", content.Pages)
+	}
+
+	restored, err := st.RollbackWebsite(ctx, website.ID, *published.PublishedRevision, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *restored.PublishedRevision != 5 {
+		t.Fatalf("published revision = %d, want the new revision 5", *restored.PublishedRevision)
+	}
+	pages, err := st.Pages.List(ctx, website.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 1 || pages[0].Slug != "home" || pages[0].Title != "Home" {
+		t.Fatalf("draft pages after rollback = %+v, want only Home", pages)
+	}
+	revisions, err := st.Websites.Revisions(ctx, website.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 5 || *revisions[0].CreatedBy != 2 {
+		t.Fatalf("revisions = %+v, want 5 with the rollback by user 2 first", revisions)
+	}
+	if changes := DiffRevisions(&revisions[len(revisions)-2], &revisions[0]); len(changes) != 0 {
+		t.Fatalf("rollback differs from the published revision in %+v", changes)
+	}
+}
+
+func TestRollbackWebsiteToUnknownRevision(t *testing.T) {
+	st := NewMemory()
+	website := &models.Website{Title: "Acme"}
+	if err := st.CreateWebsite(context.Background(), website, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.RollbackWebsite(context.Background(), website.ID, 7, 1); err != ErrNotFound {
+		t.Fatalf("RollbackWebsite = %v, want ErrNotFound", err)
+	}
+}
+
+func TestDiffRevisions(t *testing.T) {
+	from := &models.WebsiteRevision{Snapshot: models.JSONB{"title": "Acme", "theme_name": "default", "url": nil}}
+	to := &models.WebsiteRevision{Snapshot: models.JSONB{"title": "Acme Inc", "theme_name": "default", "image_url": "/a.png"}}
+	want := []models.RevisionChange{
+		{Field: "image_url", From: nil, To: "/a.png"},
+		{Field: "title", From: "Acme", To: "Acme Inc"},
+	}
+	if got := DiffRevisions(from, to); !reflect.DeepEqual(got, want) {
+		t.Fatalf("DiffRevisions = %+v, want %+v", got, want)
+	}
+}
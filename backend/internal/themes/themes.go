@@ -0,0 +1,243 @@
+// Package themes holds the website themes known to the server. Each theme is
+// a directory embedded into the binary containing
+//
+//	manifest.json  name, supported section types and settings
+//	layout.html    the "layout" template wrapping every page
+//	style.css      inlined into every page
+//
+// plus optional section templates overriding the shared ones in sections/.
+// A section template for type t is named "section_t" and executed with the
+// section's content, e.g. *content.Hero for "section_hero".
+package themes
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"sync"
+
+	"saas-management-api/internal/content"
+)
+
+// DefaultName is the theme websites get when none is chosen.
+const DefaultName = "default"
+
+//go:embed sections/*.html */manifest.json */*.html */*.css
+var builtinFS embed.FS
+
+var sectionTypes = []string{content.TypeHero, content.TypeGallery, content.TypeContactForm, content.TypePricing}
+
+// Manifest describes a theme to the API and to the renderer.
+type Manifest struct {
+	Name        string    `json:"name"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Sections    []string  `json:"sections"`
+	Settings    []Setting `json:"settings"`
+}
+
+// Setting is a value a theme lets websites customize, such as a color.
+type Setting struct {
+	Key     string `json:"key"`
+	Label   string `json:"label"`
+	Type    string `json:"type"`
+	Default string `json:"default"`
+}
+
+// Setting types and the values they accept. Values end up in the theme's
+// CSS, so they are restricted to what the type needs.
+var settingPatterns = map[string]*regexp.Regexp{
+	// A hex color, e.g. "#2563eb" or "#fff".
+	"color": regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`),
+	// A font-family list, e.g. "Inter, system-ui, sans-serif".
+	"font": regexp.MustCompile(`^[A-Za-z0-9 \-]+(, ?[A-Za-z0-9 \-]+)*$`),
+}
+
+// maxSettingLength bounds setting values.
+const maxSettingLength = 100
+
+// Theme is a parsed, ready to execute theme.
+type Theme struct {
+	Manifest
+	templates  *template.Template
+	stylesheet string
+}
+
+// Supports reports whether the theme can render sections of type t.
+func (t *Theme) Supports(sectionType string) bool {
+	return slices.Contains(t.Sections, sectionType)
+}
+
+// Defaults returns the default value of every setting by key.
+func (t *Theme) Defaults() map[string]string {
+	defaults := make(map[string]string, len(t.Settings))
+	for _, setting := range t.Settings {
+		defaults[setting.Key] = setting.Default
+	}
+	return defaults
+}
+
+// Validate checks that settings only sets settings of the theme, to
+// strings valid for their type.
+func (t *Theme) Validate(settings map[string]any) error {
+	for key, value := range settings {
+		i := slices.IndexFunc(t.Settings, func(s Setting) bool { return s.Key == key })
+		if i < 0 {
+			return fmt.Errorf("theme %q has no setting %q", t.Name, key)
+		}
+		s, ok := value.(string)
+		if !ok || len(s) > maxSettingLength || !settingPatterns[t.Settings[i].Type].MatchString(s) {
+			return fmt.Errorf("invalid %s for setting %q", t.Settings[i].Type, key)
+		}
+	}
+	return nil
+}
+
+// Resolve returns the value of every setting by key: the one chosen in
+// settings if valid, and the default otherwise. Invalid and unknown
+// settings, such as those of a previously chosen theme, are ignored.
+func (t *Theme) Resolve(settings map[string]any) map[string]string {
+	resolved := t.Defaults()
+	for _, setting := range t.Settings {
+		value, ok := settings[setting.Key]
+		if !ok {
+			continue
+		}
+		if t.Validate(map[string]any{setting.Key: value}) == nil {
+			resolved[setting.Key] = value.(string)
+		}
+	}
+	return resolved
+}
+
+// Stylesheet is the theme's CSS.
+func (t *Theme) Stylesheet() template.CSS {
+	return template.CSS(t.stylesheet)
+}
+
+// Execute runs the named template of the theme.
+func (t *Theme) Execute(w io.Writer, name string, data any) error {
+	return t.templates.ExecuteTemplate(w, name, data)
+}
+
+// Registry looks up themes by name.
+type Registry struct {
+	themes map[string]*Theme
+}
+
+// Load parses every theme directory of fsys, i.e. every directory holding a
+// manifest.json. Shared section templates are read from sections/.
+func Load(fsys fs.FS) (*Registry, error) {
+	manifests, err := fs.Glob(fsys, "*/manifest.json")
+	if err != nil {
+		return nil, err
+	}
+	registry := &Registry{themes: map[string]*Theme{}}
+	for _, file := range manifests {
+		theme, err := loadTheme(fsys, path.Dir(file))
+		if err != nil {
+			return nil, fmt.Errorf("themes: %s: %w", path.Dir(file), err)
+		}
+		registry.themes[theme.Name] = theme
+	}
+	if _, ok := registry.themes[DefaultName]; !ok {
+		return nil, fmt.Errorf("themes: no %q theme", DefaultName)
+	}
+	return registry, nil
+}
+
+func loadTheme(fsys fs.FS, dir string) (*Theme, error) {
+	data, err := fs.ReadFile(fsys, path.Join(dir, "manifest.json"))
+	if err != nil {
+		return nil, err
+	}
+	theme := &Theme{}
+	if err := json.Unmarshal(data, &theme.Manifest); err != nil {
+		return nil, fmt.Errorf("manifest.json: %w", err)
+	}
+	if theme.Name != dir {
+		return nil, fmt.Errorf("manifest name %q does not match directory", theme.Name)
+	}
+
+	stylesheet, err := fs.ReadFile(fsys, path.Join(dir, "style.css"))
+	if err != nil {
+		return nil, err
+	}
+	theme.stylesheet = string(stylesheet)
+
+	// Theme templates are parsed after the shared ones so they can redefine
+	// them.
+	theme.templates, err = template.ParseFS(fsys, "sections/*.html")
+	if err != nil {
+		return nil, err
+	}
+	if theme.templates, err = theme.templates.ParseFS(fsys, path.Join(dir, "*.html")); err != nil {
+		return nil, err
+	}
+
+	if theme.templates.Lookup("layout") == nil {
+		return nil, fmt.Errorf("layout template is missing")
+	}
+	for _, setting := range theme.Settings {
+		if settingPatterns[setting.Type] == nil {
+			return nil, fmt.Errorf("setting %q has unknown type %q", setting.Key, setting.Type)
+		}
+		if theme.Validate(map[string]any{setting.Key: setting.Default}) != nil {
+			return nil, fmt.Errorf("setting %q has an invalid default", setting.Key)
+		}
+	}
+	for _, sectionType := range theme.Sections {
+		if !slices.Contains(sectionTypes, sectionType) {
+			return nil, fmt.Errorf("unknown section type %q", sectionType)
+		}
+		if theme.templates.Lookup("section_"+sectionType) == nil {
+			return nil, fmt.Errorf("section_%s template is missing", sectionType)
+		}
+	}
+	return theme, nil
+}
+
+// Get returns the theme called name.
+func (r *Registry) Get(name string) (*Theme, bool) {
+	theme, ok := r.themes[name]
+	return theme, ok
+}
+
+// GetOrDefault returns the theme called name, or the default theme when
+// there is none. Websites are rendered with this theme.
+func (r *Registry) GetOrDefault(name string) *Theme {
+	if theme, ok := r.themes[name]; ok {
+		return theme
+	}
+	return r.themes[DefaultName]
+}
+
+// List returns the manifests of all themes, sorted by name.
+func (r *Registry) List() []Manifest {
+	manifests := make([]Manifest, 0, len(r.themes))
+	for _, theme := range r.themes {
+		manifests = append(manifests, theme.Manifest)
+	}
+	sort.Slice(manifests, func(i, j int) bool { return manifests[i].Name < manifests[j].Name })
+	return manifests
+}
+
+var builtin = sync.OnceValue(func() *Registry {
+	registry, err := Load(builtinFS)
+	if err != nil {
+		panic(err)
+	}
+	return registry
+})
+
+// Builtin returns the registry of the themes compiled into the binary.
+func Builtin() *Registry {
+	return builtin()
+}
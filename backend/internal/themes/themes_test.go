@@ -0,0 +1,60 @@
+package themes
+
+import "testing"
+
+func TestBuiltinThemesLoad(t *testing.T) {
+	for _, manifest := range Builtin().List() {
+		if _, ok := Builtin().Get(manifest.Name); !ok {
+			t.Errorf("theme %q is listed but not found", manifest.Name)
+		}
+	}
+	if Builtin().GetOrDefault("missing").Name != DefaultName {
+		t.Errorf("unknown themes do not fall back to %q", DefaultName)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	theme := Builtin().GetOrDefault(DefaultName)
+	tests := []struct {
+		settings map[string]any
+		valid    bool
+	}{
+		{nil, true},
+		{map[string]any{"primary_color": "#ff0000", "font_family": "Inter, sans-serif"}, true},
+		{map[string]any{"primary_color": "#fff"}, true},
+		{map[string]any{"accent_color": "#ff0000"}, false},
+		{map[string]any{"primary_color": "red"}, false},
+		{map[string]any{"primary_color": "#fff; } body { display: none"}, false},
+		{map[string]any{"primary_color": 12}, false},
+		{map[string]any{"font_family": "Inter; color: red"}, false},
+		{map[string]any{"font_family": "x</style><script>"}, false},
+	}
+	for _, tt := range tests {
+		err := theme.Validate(tt.settings)
+		if (err == nil) != tt.valid {
+			t.Errorf("Validate(%v) = %v, want valid %v", tt.settings, err, tt.valid)
+		}
+	}
+}
+
+func TestResolveMergesOverDefaults(t *testing.T) {
+	theme := Builtin().GetOrDefault(DefaultName)
+	resolved := theme.Resolve(map[string]any{
+		"primary_color": "#ff0000",
+		"font_family":   "bad; value",
+		"accent_color":  "#00ff00",
+	})
+	want := map[string]string{
+		"primary_color":    "#ff0000",
+		"background_color": "#ffffff",
+		"font_family":      "system-ui, sans-serif",
+	}
+	if len(resolved) != len(want) {
+		t.Fatalf("Resolve = %v, want %v", resolved, want)
+	}
+	for key, value := range want {
+		if resolved[key] != value {
+			t.Errorf("Resolve[%q] = %q, want %q", key, resolved[key], value)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package thumbnails
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// chromeNames are the binaries FindChrome looks for on the PATH.
+var chromeNames = []string{"chromium", "chromium-browser", "google-chrome", "google-chrome-stable"}
+
+// chromeTimeout bounds one capture, after which Chrome is killed.
+const chromeTimeout = 30 * time.Second
+
+// Chrome captures pages with a headless Chrome or Chromium binary.
+//
+// Pages are written by website owners, so Chrome runs with its sandbox,
+// which needs an unprivileged user or user namespaces, and without network
+// access: host names other than BaseURL's do not resolve, requests go
+// through a proxy that refuses connections unless they are for BaseURL, and
+// the page's Content-Security-Policy allows only images, fonts and inline
+// styles from BaseURL.
+type Chrome struct {
+	Path string
+	// BaseURL resolves root-relative URLs in pages, such as uploaded
+	// images under /uploads, e.g. "http://localhost:8080".
+	BaseURL string
+}
+
+// FindChrome returns path if set, or else the first Chrome binary on the
+// PATH.
+func FindChrome(path string) (string, bool) {
+	if path != "" {
+		return path, true
+	}
+	for _, name := range chromeNames {
+		if found, err := exec.LookPath(name); err == nil {
+			return found, true
+		}
+	}
+	return "", false
+}
+
+func (c *Chrome) Capture(ctx context.Context, page []byte, width, height int) (image.Image, error) {
+	ctx, cancel := context.WithTimeout(ctx, chromeTimeout)
+	defer cancel()
+
+	var base *url.URL
+	if c.BaseURL != "" {
+		var err error
+		if base, err = url.Parse(c.BaseURL); err != nil || base.Host == "" {
+			return nil, fmt.Errorf("chrome: invalid base URL %q", c.BaseURL)
+		}
+	}
+
+	dir, err := os.MkdirTemp("", "thumbnail-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	page = bytes.Replace(page, []byte("<head>"), []byte("<head>"+headPolicy(base)), 1)
+	input := filepath.Join(dir, "index.html")
+	if err := os.WriteFile(input, page, 0o600); err != nil {
+		return nil, err
+	}
+	output := filepath.Join(dir, "screenshot.png")
+
+	args := append([]string{
+		"--headless",
+		"--disable-gpu",
+		"--hide-scrollbars",
+		"--no-first-run",
+		"--disable-extensions",
+		"--disable-background-networking",
+		"--disable-sync",
+		"--user-data-dir=" + filepath.Join(dir, "profile"),
+		fmt.Sprintf("--window-size=%d,%d", width, height),
+		"--screenshot=" + output,
+	}, networkFlags(base)...)
+	cmd := exec.CommandContext(ctx, c.Path, append(args, "file://"+input)...)
+	// Renderer processes may outlive a killed browser and hold its output
+	// open; stop waiting for them.
+	cmd.WaitDelay = time.Second
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return nil, fmt.Errorf("chrome: %w: %s", err, out)
+	}
+
+	f, err := os.Open(output)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return png.Decode(f)
+}
+
+// headPolicy returns the elements prepended to a page's head: a base URL
+// for root-relative asset URLs and a Content-Security-Policy that allows
+// no scripts, and images and fonts only from base or data URLs.
+func headPolicy(base *url.URL) string {
+	sources := "data:"
+	head := ""
+	if base != nil {
+		origin := base.Scheme + "://" + base.Host
+		sources += " " + origin
+		head = `<base href="` + origin + `/">`
+	}
+	policy := "default-src 'none'; style-src 'unsafe-inline'; img-src " + sources + "; font-src " + sources
+	return `<meta http-equiv="Content-Security-Policy" content="` + policy + `">` + head
+}
+
+// networkFlags confine Chrome's network access to the host of base, or
+// to nothing without one. Port 9 (discard) on the loopback interface
+// serves as a proxy that refuses every connection.
+func networkFlags(base *url.URL) []string {
+	resolver, bypass := "MAP * ~NOTFOUND", "<-loopback>"
+	if base != nil {
+		resolver += ", EXCLUDE " + base.Hostname()
+		bypass += ";" + base.Host
+	}
+	return []string{
+		"--host-resolver-rules=" + resolver,
+		"--proxy-server=http://127.0.0.1:9",
+		"--proxy-bypass-list=" + bypass,
+	}
+}
@@ -0,0 +1,39 @@
+package thumbnails
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+)
+
+// Dir stores images as files in Path, which is served at URLPrefix.
+type Dir struct {
+	Path      string
+	URLPrefix string
+}
+
+func (d Dir) Save(ctx context.Context, name string, data []byte) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(d.Path, os.ModePerm); err != nil {
+		return "", err
+	}
+	// Write to a temporary file first so readers never see a partial image.
+	tmp, err := os.CreateTemp(d.Path, ".tmp-"+name)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(d.Path, name)); err != nil {
+		return "", err
+	}
+	return d.URLPrefix + "/" + name, nil
+}
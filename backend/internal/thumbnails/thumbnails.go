@@ -0,0 +1,166 @@
+// Package thumbnails generates website thumbnails from the rendered theme
+// output of the published revision. Publishing a website enqueues it, and a
+// single worker captures the home page, scales it to every size in Sizes
+// and stores the images as the website's Thumbnails. The ImageSize
+// thumbnail also becomes the website's ImageURL, unless its owner set an
+// image of their own.
+package thumbnails
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"log"
+	"time"
+
+	"saas-management-api/internal/models"
+	"saas-management-api/internal/render"
+	"saas-management-api/internal/store"
+)
+
+// Capturer takes a screenshot of an HTML page in a viewport of the given
+// size.
+type Capturer interface {
+	Capture(ctx context.Context, page []byte, width, height int) (image.Image, error)
+}
+
+// Storage keeps generated images and returns the URL each is served at.
+type Storage interface {
+	Save(ctx context.Context, name string, data []byte) (string, error)
+}
+
+// Size is a thumbnail size.
+type Size struct {
+	Name   string
+	Width  int
+	Height int
+}
+
+// Sizes are generated for every website, largest first. The largest is the
+// viewport pages are captured in.
+var Sizes = []Size{
+	{Name: "large", Width: 1280, Height: 800},
+	{Name: "medium", Width: 640, Height: 400},
+	{Name: "small", Width: 320, Height: 200},
+}
+
+// ImageSize names the size that becomes the website's ImageURL.
+const ImageSize = "medium"
+
+// jobTimeout bounds rendering, capturing and storing one website.
+const jobTimeout = 2 * time.Minute
+
+// captureAttempts is how many times a failed capture is tried.
+const captureAttempts = 3
+
+// Pipeline generates thumbnails in the background. A nil *Pipeline
+// ignores Enqueue, so callers need not check whether thumbnails are
+// enabled.
+type Pipeline struct {
+	Store    *store.Store
+	Renderer *render.Renderer
+	Capturer Capturer
+	Storage  Storage
+	// RetryDelay is the wait before the second capture attempt; each
+	// further attempt waits as long again.
+	RetryDelay time.Duration
+
+	queue chan int
+}
+
+func NewPipeline(s *store.Store, renderer *render.Renderer, capturer Capturer, storage Storage) *Pipeline {
+	return &Pipeline{
+		Store:      s,
+		Renderer:   renderer,
+		Capturer:   capturer,
+		Storage:    storage,
+		RetryDelay: 5 * time.Second,
+		queue:      make(chan int, 64),
+	}
+}
+
+// Enqueue schedules thumbnails for the published revision of a website.
+// When the queue is full the request is dropped; the next publish retries.
+func (p *Pipeline) Enqueue(websiteID int) {
+	if p == nil {
+		return
+	}
+	select {
+	case p.queue <- websiteID:
+	default:
+		log.Printf("Thumbnail queue full, skipping website %d", websiteID)
+	}
+}
+
+// Run processes the queue one website at a time, in publish order.
+func (p *Pipeline) Run() {
+	for websiteID := range p.queue {
+		ctx, cancel := context.WithTimeout(context.Background(), jobTimeout)
+		if err := p.Generate(ctx, websiteID); err != nil {
+			log.Printf("ERROR generating thumbnails for website %d: %v", websiteID, err)
+		}
+		cancel()
+	}
+}
+
+// Generate captures the home page of the published revision of a website,
+// stores it in every size and updates the website's ImageURL.
+func (p *Pipeline) Generate(ctx context.Context, websiteID int) error {
+	website, err := p.Store.Websites.Get(ctx, websiteID)
+	if err != nil {
+		return err
+	}
+	content, err := p.Store.PublishedContent(ctx, website)
+	if err != nil {
+		return err
+	}
+	files, err := p.Renderer.Site(content)
+	if err != nil {
+		return err
+	}
+
+	screenshot, err := p.capture(ctx, files[render.IndexFile])
+	if err != nil {
+		return fmt.Errorf("thumbnails: capture: %w", err)
+	}
+
+	thumbnails := models.JSONB{}
+	var imageURL string
+	for _, size := range Sizes {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, resize(screenshot, size.Width, size.Height), &jpeg.Options{Quality: 85}); err != nil {
+			return err
+		}
+		// Names are unique per revision so caches never serve a stale image.
+		name := fmt.Sprintf("website-%d-r%d-%s.jpg", website.ID, *website.PublishedRevision, size.Name)
+		url, err := p.Storage.Save(ctx, name, buf.Bytes())
+		if err != nil {
+			return fmt.Errorf("thumbnails: save %s: %w", name, err)
+		}
+		thumbnails[size.Name] = url
+		if size.Name == ImageSize {
+			imageURL = url
+		}
+	}
+	return p.Store.Websites.SetThumbnails(ctx, website.ID, thumbnails, imageURL)
+}
+
+// capture screenshots page in the viewport of the largest size, trying
+// again after failures such as a browser that crashed or timed out.
+func (p *Pipeline) capture(ctx context.Context, page []byte) (image.Image, error) {
+	viewport := Sizes[0]
+	for attempt := 1; ; attempt++ {
+		screenshot, err := p.Capturer.Capture(ctx, page, viewport.Width, viewport.Height)
+		if err == nil || attempt == captureAttempts {
+			return screenshot, err
+		}
+		log.Printf("Thumbnail capture failed (attempt %d of %d): %v", attempt, captureAttempts, err)
+		select {
+		case <-time.After(time.Duration(attempt) * p.RetryDelay):
+		case <-ctx.Done():
+			return nil, err
+		}
+	}
+}
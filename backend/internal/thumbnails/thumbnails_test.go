@@ -0,0 +1,207 @@
+package thumbnails
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"net/url"
+	"strings"
+	"testing"
+
+	"saas-management-api/internal/models"
+	"saas-management-api/internal/render"
+	"saas-management-api/internal/store"
+	"saas-management-api/internal/themes"
+)
+
+// fakeCapturer fails as many captures as failures and then returns a page
+// that is white on the left and black on the right.
+type fakeCapturer struct {
+	failures int
+	calls    int
+	page     []byte
+}
+
+func (c *fakeCapturer) Capture(ctx context.Context, page []byte, width, height int) (image.Image, error) {
+	c.calls++
+	c.page = page
+	if c.calls <= c.failures {
+		return nil, errors.New("browser crashed")
+	}
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			if x < width/2 {
+				img.Set(x, y, color.White)
+			} else {
+				img.Set(x, y, color.Black)
+			}
+		}
+	}
+	return img, nil
+}
+
+// memStorage keeps saved images in memory.
+type memStorage map[string][]byte
+
+func (s memStorage) Save(ctx context.Context, name string, data []byte) (string, error) {
+	s[name] = data
+	return "/thumbs/" + name, nil
+}
+
+// newTestPipeline returns a pipeline and a published website with the given
+// image, if any.
+func newTestPipeline(t *testing.T, capturer Capturer, storage Storage, imageURL string) (*Pipeline, *models.Website) {
+	t.Helper()
+	st := store.NewMemory()
+	ctx := context.Background()
+	website := &models.Website{Title: "Acme"}
+	if imageURL != "" {
+		website.ImageURL = &imageURL
+	}
+	if err := st.CreateWebsite(ctx, website, 1); err != nil {
+		t.Fatal(err)
+	}
+	website, err := st.PublishWebsite(ctx, website.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := NewPipeline(st, render.New(themes.Builtin()), capturer, storage)
+	p.RetryDelay = 0
+	return p, website
+}
+
+func TestGenerateStoresEverySize(t *testing.T) {
+	capturer := &fakeCapturer{failures: captureAttempts - 1}
+	storage := memStorage{}
+	p, website := newTestPipeline(t, capturer, storage, "")
+	ctx := context.Background()
+
+	if err := p.Generate(ctx, website.ID); err != nil {
+		t.Fatal(err)
+	}
+	if capturer.calls != captureAttempts {
+		t.Fatalf("captured %d times, want %d", capturer.calls, captureAttempts)
+	}
+	if !bytes.Contains(capturer.page, []byte("Acme")) {
+		t.Fatal("the captured page is not the rendered home page")
+	}
+
+	updated, err := p.Store.Websites.Get(ctx, website.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, size := range Sizes {
+		name := "website-1-r1-" + size.Name + ".jpg"
+		if got := updated.Thumbnails[size.Name]; got != "/thumbs/"+name {
+			t.Errorf("thumbnail %s = %v, want /thumbs/%s", size.Name, got, name)
+		}
+		img, err := jpeg.Decode(bytes.NewReader(storage[name]))
+		if err != nil {
+			t.Fatalf("thumbnail %s: %v", size.Name, err)
+		}
+		if got := img.Bounds().Size(); got != image.Pt(size.Width, size.Height) {
+			t.Errorf("thumbnail %s is %v, want %dx%d", size.Name, got, size.Width, size.Height)
+		}
+		// JPEG is lossy; the halves only need to stay apart.
+		left, _, _, _ := img.At(size.Width/4, size.Height/2).RGBA()
+		right, _, _, _ := img.At(size.Width*3/4, size.Height/2).RGBA()
+		if left < 0xe000 || right > 0x2000 {
+			t.Errorf("thumbnail %s is not the scaled capture", size.Name)
+		}
+	}
+}
+
+func TestGenerateSetsImageURL(t *testing.T) {
+	ctx := context.Background()
+	for _, tt := range []struct {
+		name     string
+		imageURL string
+		want     string
+	}{
+		{"empty", "", "/thumbs/website-1-r1-medium.jpg"},
+		{"set by the owner", "/uploads/logo.png", "/uploads/logo.png"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			p, website := newTestPipeline(t, &fakeCapturer{}, memStorage{}, tt.imageURL)
+			if err := p.Generate(ctx, website.ID); err != nil {
+				t.Fatal(err)
+			}
+			updated, err := p.Store.Websites.Get(ctx, website.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if updated.ImageURL == nil || *updated.ImageURL != tt.want {
+				t.Fatalf("ImageURL = %v, want %s", updated.ImageURL, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateReplacesGeneratedImageURL(t *testing.T) {
+	p, website := newTestPipeline(t, &fakeCapturer{}, memStorage{}, "")
+	ctx := context.Background()
+	if err := p.Generate(ctx, website.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// The next revision's thumbnail replaces the previous one.
+	website, _ = p.Store.Websites.Get(ctx, website.ID)
+	website.Title = "Acme Corp"
+	if err := p.Store.SaveWebsite(ctx, website, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Store.PublishWebsite(ctx, website.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Generate(ctx, website.ID); err != nil {
+		t.Fatal(err)
+	}
+	updated, _ := p.Store.Websites.Get(ctx, website.ID)
+	if want := "/thumbs/website-1-r2-medium.jpg"; updated.ImageURL == nil || *updated.ImageURL != want {
+		t.Fatalf("ImageURL = %v, want %s", updated.ImageURL, want)
+	}
+}
+
+func TestGenerateGivesUpAfterRetries(t *testing.T) {
+	capturer := &fakeCapturer{failures: captureAttempts}
+	storage := memStorage{}
+	p, website := newTestPipeline(t, capturer, storage, "")
+
+	if err := p.Generate(context.Background(), website.ID); err == nil {
+		t.Fatal("Generate succeeded without a capture")
+	}
+	if capturer.calls != captureAttempts {
+		t.Fatalf("captured %d times, want %d", capturer.calls, captureAttempts)
+	}
+	if len(storage) != 0 {
+		t.Fatalf("stored %d images after failing", len(storage))
+	}
+	updated, _ := p.Store.Websites.Get(context.Background(), website.ID)
+	if len(updated.Thumbnails) != 0 {
+		t.Fatalf("thumbnails = %v after failing", updated.Thumbnails)
+	}
+}
+
+func TestChromeIsConfinedToBaseURL(t *testing.T) {
+	base, _ := url.Parse("http://localhost:8080")
+	flags := strings.Join(networkFlags(base), " ")
+	for _, want := range []string{"MAP * ~NOTFOUND, EXCLUDE localhost", "--proxy-bypass-list=<-loopback>;localhost:8080"} {
+		if !strings.Contains(flags, want) {
+			t.Errorf("flags %q lack %q", flags, want)
+		}
+	}
+	if flags := strings.Join(networkFlags(nil), " "); strings.Contains(flags, "EXCLUDE") {
+		t.Errorf("flags %q allow a host without a base URL", flags)
+	}
+
+	head := headPolicy(base)
+	for _, want := range []string{"default-src 'none'", "img-src data: http://localhost:8080;", `<base href="http://localhost:8080/">`} {
+		if !strings.Contains(head, want) {
+			t.Errorf("head %q lacks %q", head, want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// Kinds of envelopes carried by a Backplane.
+const (
+	// KindDeliver carries a frame for the connections of UserIDs.
+	KindDeliver = "deliver"
+	// KindPresence announces that UserIDs are connected to Node.
+	KindPresence = "presence"
+	// KindOffline announces that UserIDs have no connections left on Node.
+	KindOffline = "offline"
+)
+
+// Envelope is a frame or a presence update travelling between the hubs of
+// the API replicas. Node identifies the hub that published it.
+type Envelope struct {
+	Node    string          `json:"node"`
+	Kind    string          `json:"kind"`
+	UserIDs []int           `json:"user_ids"`
+	Message json.RawMessage `json:"message,omitempty"`
+	Track   *Track          `json:"track,omitempty"`
+}
+
+// Track asks the hubs delivering a frame to record when a chat message in
+// it reaches one of its receiver's connections.
+type Track struct {
+	MessageID  int `json:"message_id"`
+	ReceiverID int `json:"receiver_id"`
+}
+
+// Backplane fans envelopes out to the hubs of every API replica, so users
+// connected to different replicas can reach each other.
+type Backplane interface {
+	// Publish sends e to every subscribed hub, including the publisher's.
+	Publish(ctx context.Context, e Envelope) error
+	// Subscribe passes every published envelope to handle until ctx is
+	// done. It blocks and returns ctx.Err() or a permanent failure.
+	Subscribe(ctx context.Context, handle func(Envelope)) error
+}
+
+// MemoryBackplane connects hubs within a single process. It serves tests and
+// single-replica deployments.
+type MemoryBackplane struct {
+	mu          sync.Mutex
+	subscribers map[int]func(Envelope)
+	nextID      int
+}
+
+func NewMemoryBackplane() *MemoryBackplane {
+	return &MemoryBackplane{subscribers: map[int]func(Envelope){}}
+}
+
+func (b *MemoryBackplane) Publish(ctx context.Context, e Envelope) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	handlers := make([]func(Envelope), 0, len(b.subscribers))
+	for _, handle := range b.subscribers {
+		handlers = append(handlers, handle)
+	}
+	b.mu.Unlock()
+
+	for _, handle := range handlers {
+		handle(e)
+	}
+	return nil
+}
+
+func (b *MemoryBackplane) Subscribe(ctx context.Context, handle func(Envelope)) error {
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	b.subscribers[id] = handle
+	b.mu.Unlock()
+
+	<-ctx.Done()
+
+	b.mu.Lock()
+	delete(b.subscribers, id)
+	b.mu.Unlock()
+	return ctx.Err()
+}
@@ -1,12 +1,17 @@
 package ws
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"strconv"
 	"time"
 
+	"saas-management-api/internal/events"
 	"saas-management-api/internal/models"
+	"saas-management-api/internal/store"
 
 	"github.com/gorilla/websocket"
 )
@@ -22,7 +27,7 @@ const (
 	pingPeriod = (pongWait * 9) / 10
 
 	// Maximum message size allowed from peer.
-	maxMessageSize = 512
+	maxMessageSize = 4096
 )
 
 var upgrader = websocket.Upgrader{
@@ -46,11 +51,28 @@ type Client struct {
 	// User information
 	userID   int
 	username string
+
+	// version is the protocol version the client connected with; 0 for
+	// legacy clients.
+	version int
+
+	// ctx lives as long as the connection and is cancelled on disconnect,
+	// aborting any database work still running on the client's behalf.
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
-// readPump pumps messages from the websocket connection to the hub.
+// queryContext returns a context for a single database call made on behalf
+// of the client.
+func (c *Client) queryContext() (context.Context, context.CancelFunc) {
+	return c.hub.queryContext(c.ctx)
+}
+
+// readPump reads frames from the websocket connection and handles them
+// according to the client's protocol version.
 func (c *Client) readPump() {
 	defer func() {
+		c.cancel()
 		c.hub.unregister <- c
 		c.conn.Close()
 	}()
@@ -66,89 +88,235 @@ func (c *Client) readPump() {
 			break
 		}
 
-		// Parse the message to check type
-		var generic struct {
-			Type        string `json:"type"`
-			ReceiverID  int    `json:"receiver_id"`
-			Message     string `json:"message"`
-			MessageType string `json:"message_type"`
-			IsTyping    bool   `json:"is_typing"`
-		}
-		if err := json.Unmarshal(message, &generic); err != nil {
-			log.Printf("error unmarshaling incoming message: %v", err)
-			continue
+		if c.version >= ProtocolVersion {
+			c.handleFrame(message)
+		} else {
+			c.handleLegacy(message)
 		}
+	}
+}
 
-		// Handle Typing Event
-		if generic.Type == "typing" {
-			broadcastMsg, _ := json.Marshal(map[string]interface{}{
-				"type": "typing",
-				"data": map[string]interface{}{
-					"sender_id":   c.userID,
-					"receiver_id": generic.ReceiverID,
-					"is_typing":   generic.IsTyping,
-				},
-			})
-			c.hub.broadcast <- broadcastMsg
-			continue
-		}
+// handleLegacy handles a frame of clients that didn't opt into a protocol
+// version: a flat JSON object whose type defaults to a chat message. Failures
+// are only logged.
+func (c *Client) handleLegacy(message []byte) {
+	var generic struct {
+		Type        string `json:"type"`
+		ReceiverID  int    `json:"receiver_id"`
+		Message     string `json:"message"`
+		MessageType string `json:"message_type"`
+		IsTyping    bool   `json:"is_typing"`
+		ID          int    `json:"id"`
+		// Messages and typing in a conversation, and read frames
+		ConversationID int `json:"conversation_id"`
+		UpToID         int `json:"up_to_id"`
+	}
+	if err := json.Unmarshal(message, &generic); err != nil {
+		log.Printf("error unmarshaling incoming message: %v", err)
+		return
+	}
 
-		// Proceed with regular message saving
-		// Get or create conversation (simplified logic for WS)
-		user1ID := c.userID
-		user2ID := generic.ReceiverID
-		if user1ID > user2ID {
-			user1ID, user2ID = user2ID, user1ID
+	switch generic.Type {
+	case "typing":
+		if err := c.typing(generic.ReceiverID, generic.ConversationID, generic.IsTyping); err != nil {
+			log.Printf("error sending typing state in WS: %v", err)
 		}
-
-		var convID int
-		err = c.hub.db.Get(&convID, "SELECT id FROM conversations WHERE user1_id = $1 AND user2_id = $2", user1ID, user2ID)
-		if err != nil {
-			// Create conversation if not exists
-			now := time.Now()
-			err = c.hub.db.Get(&convID, "INSERT INTO conversations (user1_id, user2_id, created_at, updated_at, last_message_at) VALUES ($1, $2, $3, $3, $3) RETURNING id", user1ID, user2ID, now)
-			if err != nil {
-				log.Printf("error creating conversation in WS: %v", err)
-				continue
-			}
+	case "notification_read":
+		if _, err := c.markNotificationRead(generic.ID); err != nil {
+			log.Printf("error marking notification %d read in WS: %v", generic.ID, err)
 		}
-
-		// Save message to DB
-		now := time.Now()
-		var msg models.Message
-		msgType := generic.MessageType
-		if msgType == "" {
-			msgType = "text"
+	case "read":
+		if _, err := c.readMessages(generic.ConversationID, generic.UpToID); err != nil {
+			log.Printf("error marking messages read in WS: %v", err)
 		}
-		err = c.hub.db.Get(&msg, "INSERT INTO messages (conversation_id, sender_id, receiver_id, message, message_type, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $6) RETURNING *", convID, c.userID, generic.ReceiverID, generic.Message, msgType, now)
-		if err != nil {
+	default:
+		if _, err := c.sendMessage(generic.ReceiverID, generic.ConversationID, generic.Message, generic.MessageType, "", nil); err != nil {
 			log.Printf("error saving message in WS: %v", err)
-			continue
 		}
+	}
+}
 
-		// Update conversation last_message_at
-		c.hub.db.Exec("UPDATE conversations SET last_message_at = $1, updated_at = $1 WHERE id = $2", now, convID)
+// sendMessage stores a chat message and publishes it, which delivers it to
+// the conversation's participants. The message goes to conversationID if
+// set, and otherwise to the direct conversation with receiverID, with the
+// attachments the user uploaded as attachmentIDs. A message
+// whose clientID was sent before is returned as stored then and not
+// delivered again.
+func (c *Client) sendMessage(receiverID, conversationID int, text, messageType, clientID string, attachmentIDs []int) (*models.Message, error) {
+	msg := models.Message{
+		SenderID:    c.userID,
+		Message:     text,
+		MessageType: messageType,
+	}
+	if conversationID > 0 {
+		msg.ConversationID = conversationID
+	} else {
+		msg.ReceiverID = &receiverID
+	}
+	if clientID != "" {
+		msg.ClientID = &clientID
+	}
+	ctx, cancel := c.queryContext()
+	err := c.hub.store.SendMessage(ctx, &msg, attachmentIDs...)
+	cancel()
+	if errors.Is(err, store.ErrDuplicate) {
+		return &msg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
 
-		// Set sender name for broadcast
-		if c.username != "" {
-			msg.SenderName = &c.username
-		} else {
-			var name string
-			err = c.hub.db.Get(&name, "SELECT name FROM users WHERE id = $1", c.userID)
-			if err == nil {
-				msg.SenderName = &name
-			}
-		}
+	c.hub.events.Publish(events.MessageCreated, &msg)
+	return &msg, nil
+}
 
-		// Marshall back to broadcast
-		broadcastMsg, _ := json.Marshal(map[string]interface{}{
-			"type": "message",
-			"data": msg,
+// editMessage replaces the text of a message the client's user sent and
+// publishes the change to the conversation.
+func (c *Client) editMessage(id int, text string) (*models.Message, error) {
+	ctx, cancel := c.queryContext()
+	msg, err := c.hub.store.EditMessage(ctx, id, c.userID, text, time.Now())
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+	c.hub.events.Publish(events.MessageUpdated, msg)
+	return msg, nil
+}
+
+// deleteMessage deletes a message for the client's user or, if they sent
+// it, for everyone, and publishes the change.
+func (c *Client) deleteMessage(id int, forEveryone bool) (*models.Message, error) {
+	now := time.Now()
+	ctx, cancel := c.queryContext()
+	msg, err := c.hub.store.DeleteMessage(ctx, id, c.userID, forEveryone, now)
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+	if forEveryone {
+		c.hub.events.Publish(events.MessageUpdated, msg)
+	} else {
+		c.hub.events.Publish(events.MessageHidden, &models.HiddenMessage{
+			MessageID:      msg.ID,
+			ConversationID: msg.ConversationID,
+			UserID:         c.userID,
+			HiddenAt:       now,
 		})
-		c.hub.broadcast <- broadcastMsg
+	}
+	return msg, nil
+}
+
+// react adds or removes the client's user's reaction to a message and
+// publishes the change.
+func (c *Client) react(id int, emoji string, remove bool) (*models.Message, error) {
+	ctx, cancel := c.queryContext()
+	msg, err := c.hub.store.ReactToMessage(ctx, id, c.userID, emoji, remove, time.Now())
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+	c.hub.events.Publish(events.MessageUpdated, msg)
+	return msg, nil
+}
+
+// readMessages marks the client's messages in a conversation up to upToID
+// read and publishes receipts to their senders.
+func (c *Client) readMessages(conversationID, upToID int) ([]models.MessageReceipt, error) {
+	ctx, cancel := c.queryContext()
+	receipts, err := c.hub.store.ReadMessages(ctx, conversationID, c.userID, upToID, time.Now())
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+	for i := range receipts {
+		c.hub.events.Publish(events.MessageRead, &receipts[i])
+	}
+	return receipts, nil
+}
+
+// deliverReplayed records that the replayed messages addressed to the
+// client's user reached them, and publishes receipts to their senders.
+func (c *Client) deliverReplayed(messages []models.Message) {
+	ids := []int{}
+	for _, message := range messages {
+		if addressedTo(message, c.userID) && message.DeliveredAt == nil {
+			ids = append(ids, message.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	now := time.Now()
+	ctx, cancel := c.queryContext()
+	receipts, err := c.hub.store.DeliverMessages(ctx, c.userID, ids, now)
+	cancel()
+	if err != nil {
+		log.Printf("error recording delivery in WS: %v", err)
+		return
+	}
+	for i := range receipts {
+		c.hub.events.Publish(events.MessageDelivered, &receipts[i])
+	}
+	for i := range messages {
+		if addressedTo(messages[i], c.userID) && messages[i].DeliveredAt == nil {
+			messages[i].DeliveredAt = &now
+		}
 	}
 }
 
+// addressedTo reports whether a direct message was sent to userID.
+func addressedTo(message models.Message, userID int) bool {
+	return message.ReceiverID != nil && *message.ReceiverID == userID
+}
+
+// typing tells the other participants of a conversation, and the sender's
+// other connections, whether the sender is typing. The conversation is
+// conversationID if set, and otherwise the direct one with receiverID.
+func (c *Client) typing(receiverID, conversationID int, isTyping bool) error {
+	data := map[string]interface{}{
+		"sender_id": c.userID,
+		"is_typing": isTyping,
+	}
+	ctx, cancel := c.queryContext()
+	defer cancel()
+	var conversation *models.Conversation
+	var err error
+	if conversationID > 0 {
+		conversation, err = c.hub.store.Conversations.GetForUser(ctx, conversationID, c.userID)
+		data["conversation_id"] = conversationID
+	} else {
+		conversation, err = c.hub.store.Conversations.GetDirect(ctx, c.userID, receiverID)
+		data["receiver_id"] = receiverID
+	}
+	if err != nil {
+		return err
+	}
+
+	frame, _ := json.Marshal(map[string]interface{}{
+		"v":    ProtocolVersion,
+		"type": "typing",
+		"data": data,
+	})
+	c.hub.sendTo(frame, conversation.ParticipantIDs()...)
+	return nil
+}
+
+// markNotificationRead marks one of the client's notifications read. The
+// hub then pushes the change and the new unread count to all of the user's
+// connections.
+func (c *Client) markNotificationRead(id int) (*models.Notification, error) {
+	isRead := true
+	ctx, cancel := c.queryContext()
+	notification, err := c.hub.store.Notifications.Update(ctx, id, c.userID, store.NotificationUpdate{IsRead: &isRead})
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+	c.hub.events.Publish(events.NotificationUpdated, notification)
+	return notification, nil
+}
+
 // writePump pumps messages from the hub to the websocket connection.
 func (c *Client) writePump() {
 	ticker := time.NewTicker(pingPeriod)
@@ -166,6 +334,9 @@ func (c *Client) writePump() {
 				return
 			}
 
+			if c.version == 0 {
+				message = unversioned(message)
+			}
 			if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
 				return
 			}
@@ -178,34 +349,42 @@ func (c *Client) writePump() {
 	}
 }
 
-// ServeWs handles websocket requests from the peer.
+// ServeWs handles websocket requests from the peer. Clients opt into the
+// envelope protocol with a "v" query parameter, e.g. /api/ws?v=1; others
+// get the legacy frames.
 func ServeWs(hub *Hub, w http.ResponseWriter, r *http.Request, userID int, username string) {
+	version := 0
+	if v := r.URL.Query().Get("v"); v != "" {
+		if v != strconv.Itoa(ProtocolVersion) {
+			http.Error(w, "Unsupported protocol version", http.StatusBadRequest)
+			return
+		}
+		version = ProtocolVersion
+	}
+
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Println(err)
 		return
 	}
-	client := &Client{hub: hub, conn: conn, send: make(chan []byte, 256), userID: userID, username: username}
+	ctx, cancel := context.WithCancel(context.Background())
+	client := &Client{hub: hub, conn: conn, send: make(chan []byte, 256), userID: userID, username: username, version: version, ctx: ctx, cancel: cancel}
 	client.hub.register <- client
 
-	// Send last 50 messages
-	var lastMessages []models.Message
-	query := `
-		SELECT m.*, u1.name as sender_name, u2.name as receiver_name
-		FROM messages m
-		LEFT JOIN users u1 ON m.sender_id = u1.id
-		LEFT JOIN users u2 ON m.receiver_id = u2.id
-		WHERE m.sender_id = $1 OR m.receiver_id = $1
-		ORDER BY m.created_at DESC
-		LIMIT 50
-	`
-	err = hub.db.Select(&lastMessages, query, userID)
-	if err == nil {
-		history, _ := json.Marshal(map[string]interface{}{
-			"type": "history",
-			"data": lastMessages,
-		})
-		client.send <- history
+	// Send last 50 messages. Versioned clients ask with a resume frame
+	// instead.
+	if version == 0 {
+		queryCtx, queryCancel := client.queryContext()
+		lastMessages, err := hub.store.Conversations.RecentMessages(queryCtx, userID, historyLimit)
+		queryCancel()
+		if err == nil {
+			client.deliverReplayed(lastMessages)
+			history, _ := json.Marshal(map[string]interface{}{
+				"type": "history",
+				"data": lastMessages,
+			})
+			client.send <- history
+		}
 	}
 
 	// Allow collection of memory referenced by the caller by doing all work in
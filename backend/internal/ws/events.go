@@ -0,0 +1,170 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"saas-management-api/internal/events"
+	"saas-management-api/internal/models"
+)
+
+// Frame types pushed for domain events.
+const (
+	frameMessage             = "message"
+	frameMessageDelivered    = "message_delivered"
+	frameMessageRead         = "message_read"
+	frameMessageUpdated      = "message_updated"
+	frameMessageHidden       = "message_hidden"
+	frameConversationUpdated = "conversation_updated"
+	frameConversationRemoved = "conversation_removed"
+	frameNotification        = "notification"
+	frameNotificationUpdated = "notification_updated"
+	frameNotificationDeleted = "notification_deleted"
+	frameUnreadCount         = "notification_unread_count"
+	frameSubscriptionUpdated = "subscription_updated"
+	frameInvoicePaid         = "invoice_paid"
+)
+
+// enqueueEvent queues a domain event for dispatchEvents. Publishers run it
+// inline, so it never waits: when the hub falls behind, the event is
+// dropped and Run disconnects the clients, which no longer see every
+// change, so they reconnect and reload.
+func (h *Hub) enqueueEvent(e events.Event) {
+	select {
+	case h.queued <- e:
+	default:
+		log.Printf("ws: event queue full, dropping %s event", e.Type)
+		select {
+		case h.overflowed <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// dispatchEvents pushes queued domain events in the order they were
+// published.
+func (h *Hub) dispatchEvents() {
+	for e := range h.queued {
+		ctx, cancel := context.WithTimeout(context.Background(), eventTimeout)
+		h.handleEvent(ctx, e)
+		cancel()
+	}
+}
+
+// handleEvent pushes a domain event to the users it concerns.
+func (h *Hub) handleEvent(ctx context.Context, e events.Event) {
+	switch payload := e.Payload.(type) {
+	case *models.Message:
+		frameType := frameMessage
+		if e.Type == events.MessageUpdated {
+			frameType = frameMessageUpdated
+		}
+		frame, ok := encodeFrame(frameType, payload)
+		if !ok {
+			break
+		}
+		switch {
+		case payload.ReceiverID == nil:
+			h.sendToParticipants(ctx, frame, payload.ConversationID)
+		case e.Type == events.MessageCreated:
+			receiverID := *payload.ReceiverID
+			h.sendTracked(frame, Track{MessageID: payload.ID, ReceiverID: receiverID}, payload.SenderID, receiverID)
+		default:
+			h.sendTo(frame, payload.SenderID, *payload.ReceiverID)
+		}
+	case *models.HiddenMessage:
+		// The user's other connections drop the message too.
+		h.pushFrame(frameMessageHidden, payload, payload.UserID)
+	case *models.MessageReceipt:
+		if payload.Status == models.ReceiptDelivered {
+			h.pushFrame(frameMessageDelivered, payload, payload.SenderID)
+		} else {
+			// The reader's other connections update their unread state.
+			h.pushFrame(frameMessageRead, payload, payload.SenderID, payload.ReceiverID)
+		}
+	case *models.Conversation:
+		h.pushFrame(frameConversationUpdated, payload, payload.ParticipantIDs()...)
+	case *models.ConversationParticipant:
+		h.pushFrame(frameConversationRemoved, map[string]int{"conversation_id": payload.ConversationID}, payload.UserID)
+	case *models.Notification:
+		switch e.Type {
+		case events.NotificationCreated:
+			h.pushFrame(frameNotification, payload, payload.UserID)
+		case events.NotificationUpdated:
+			h.pushFrame(frameNotificationUpdated, payload, payload.UserID)
+		case events.NotificationDeleted:
+			h.pushFrame(frameNotificationDeleted, map[string]int{"id": payload.ID}, payload.UserID)
+		}
+		h.pushUnreadCount(ctx, payload.UserID)
+	case *models.Subscription:
+		h.pushToOwner(ctx, frameSubscriptionUpdated, payload, payload.BusinessID)
+	case *models.Invoice:
+		h.pushToOwner(ctx, frameInvoicePaid, payload, payload.BusinessID)
+	default:
+		log.Printf("ws: ignoring %s event with %T payload", e.Type, e.Payload)
+	}
+}
+
+// sendToParticipants sends a frame to the participants of a conversation.
+func (h *Hub) sendToParticipants(ctx context.Context, frame []byte, conversationID int) {
+	ctx, cancel := h.queryContext(ctx)
+	participants, err := h.store.Conversations.Participants(ctx, conversationID)
+	cancel()
+	if err != nil {
+		log.Printf("ws: loading participants of conversation %d: %v", conversationID, err)
+		return
+	}
+	userIDs := make([]int, len(participants))
+	for i, participant := range participants {
+		userIDs[i] = participant.UserID
+	}
+	h.sendTo(frame, userIDs...)
+}
+
+// pushUnreadCount pushes the number of unread notifications of a user to
+// all of their connections, so every open tab shows the same badge.
+func (h *Hub) pushUnreadCount(ctx context.Context, userID int) {
+	ctx, cancel := h.queryContext(ctx)
+	count, err := h.store.Notifications.UnreadCount(ctx, userID)
+	cancel()
+	if err != nil {
+		log.Printf("ws: counting unread notifications of user %d: %v", userID, err)
+		return
+	}
+	h.pushFrame(frameUnreadCount, map[string]int{"count": count}, userID)
+}
+
+// pushToOwner pushes a frame to the owner of a business.
+func (h *Hub) pushToOwner(ctx context.Context, frameType string, data any, businessID int) {
+	ctx, cancel := h.queryContext(ctx)
+	business, err := h.store.Businesses.Get(ctx, businessID)
+	cancel()
+	if err != nil {
+		log.Printf("ws: loading business %d for %s frame: %v", businessID, frameType, err)
+		return
+	}
+	if business.UserID != nil {
+		h.pushFrame(frameType, data, *business.UserID)
+	}
+}
+
+// pushFrame sends a {"type", "data"} frame to the connections of userIDs.
+func (h *Hub) pushFrame(frameType string, data any, userIDs ...int) {
+	if frame, ok := encodeFrame(frameType, data); ok {
+		h.sendTo(frame, userIDs...)
+	}
+}
+
+func encodeFrame(frameType string, data any) ([]byte, bool) {
+	frame, err := json.Marshal(map[string]interface{}{
+		"v":    ProtocolVersion,
+		"type": frameType,
+		"data": data,
+	})
+	if err != nil {
+		log.Printf("ws: encoding %s frame: %v", frameType, err)
+		return nil, false
+	}
+	return frame, true
+}
@@ -1,20 +1,73 @@
 package ws
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
-	"saas-management-api/internal/database"
+	"log"
+	"slices"
+	"time"
+
+	"saas-management-api/internal/events"
+	"saas-management-api/internal/store"
 )
 
-// Hub maintains the set of active clients and broadcasts messages to the clients.
+const (
+	// Interval at which a hub re-announces its connected users. Users of
+	// other replicas not announced for presenceTTL count as offline, which
+	// covers replicas that stop without saying goodbye.
+	presenceInterval = 15 * time.Second
+	presenceTTL      = 3 * presenceInterval
+
+	// Most user IDs sent in a single presence envelope, which keeps
+	// envelopes within the limits of the backplane.
+	presenceChunk = 500
+
+	// Envelopes waiting to be published before new ones are dropped.
+	outboundBuffer = 256
+
+	// Deliveries waiting to be recorded before new ones are dropped.
+	deliveredBuffer = 256
+
+	// Domain events waiting to be pushed. When it fills up, the hub drops
+	// the event and disconnects its clients so they resync.
+	eventBuffer = 1024
+
+	// Deadline for pushing a single domain event, including the database
+	// lookups of the users it concerns.
+	eventTimeout = 10 * time.Second
+
+	// Deadline for publishing a single envelope.
+	publishTimeout = 5 * time.Second
+)
+
+// delivery is a frame addressed to the connections of a set of users.
+type delivery struct {
+	userIDs []int
+	message []byte
+	track   *Track
+}
+
+// reply is a frame for a single connection.
+type reply struct {
+	client  *Client
+	message []byte
+}
+
+// Hub maintains the set of active clients and routes frames to the
+// connections of the users they are addressed to. Frames are also published
+// on the backplane, so the hubs of other replicas deliver them to their own
+// clients, and hubs share which users are online.
 type Hub struct {
-	// Registered clients.
-	clients map[*Client]bool
+	// Registered clients, indexed by user ID.
+	users map[int]map[*Client]bool
 
-	// User ID to active clients mapping
-	userClients map[int]int
+	// Frames from the clients, addressed to users.
+	deliver chan delivery
 
-	// Inbound messages from the clients.
-	broadcast chan []byte
+	// Answers to frames of a single client.
+	replies chan reply
 
 	// Register requests from the clients.
 	register chan *Client
@@ -22,68 +75,382 @@ type Hub struct {
 	// Unregister requests from clients.
 	unregister chan *Client
 
-	// Database connection
-	db *database.DB
+	// node identifies the hub on the backplane.
+	node      string
+	backplane Backplane
+	// Envelopes received from and waiting to be published on the backplane.
+	inbound  chan Envelope
+	outbound chan Envelope
+
+	// Chat messages that reached their receiver, waiting to be recorded.
+	delivered chan Track
+
+	// Users connected to other replicas, by node, with when each was last
+	// announced.
+	peers map[string]map[int]time.Time
+
+	// Users whose first connection opened or last connection closed since
+	// the last announcement.
+	joined, left []int
+
+	// online is the cluster-wide set of online users last sent to clients.
+	online []int
+
+	// Persistence for conversations and messages
+	store *store.Store
+
+	// Domain events, which the hub pushes to the users they concern
+	events *events.Bus
+	// Events published on the bus, waiting to be pushed.
+	queued chan events.Event
+	// Signalled when an event was dropped because queued was full.
+	overflowed chan struct{}
+
+	// Deadline for each database call made on behalf of a client
+	queryTimeout time.Duration
 }
 
-func NewHub(db *database.DB) *Hub {
-	return &Hub{
-		broadcast:   make(chan []byte),
-		register:    make(chan *Client),
-		unregister:  make(chan *Client),
-		clients:     make(map[*Client]bool),
-		userClients: make(map[int]int),
-		db:          db,
+func NewHub(s *store.Store, queryTimeout time.Duration, backplane Backplane, bus *events.Bus) *Hub {
+	node := make([]byte, 8)
+	rand.Read(node)
+	h := &Hub{
+		deliver:      make(chan delivery),
+		replies:      make(chan reply),
+		register:     make(chan *Client),
+		unregister:   make(chan *Client),
+		users:        make(map[int]map[*Client]bool),
+		node:         hex.EncodeToString(node),
+		backplane:    backplane,
+		inbound:      make(chan Envelope),
+		outbound:     make(chan Envelope, outboundBuffer),
+		delivered:    make(chan Track, deliveredBuffer),
+		peers:        make(map[string]map[int]time.Time),
+		online:       []int{},
+		store:        s,
+		events:       bus,
+		queued:       make(chan events.Event, eventBuffer),
+		overflowed:   make(chan struct{}, 1),
+		queryTimeout: queryTimeout,
 	}
+	bus.Subscribe(h.enqueueEvent,
+		events.MessageCreated,
+		events.MessageDelivered,
+		events.MessageRead,
+		events.MessageUpdated,
+		events.MessageHidden,
+		events.ConversationUpdated,
+		events.ParticipantRemoved,
+		events.NotificationCreated,
+		events.NotificationUpdated,
+		events.NotificationDeleted,
+		events.SubscriptionUpdated,
+		events.InvoicePaid,
+	)
+	return h
+}
+
+// queryContext returns a context derived from parent for a single database
+// call.
+func (h *Hub) queryContext(parent context.Context) (context.Context, context.CancelFunc) {
+	if h.queryTimeout <= 0 {
+		return context.WithCancel(parent)
+	}
+	return context.WithTimeout(parent, h.queryTimeout)
 }
 
 func (h *Hub) Run() {
+	go h.subscribe()
+	go h.publish()
+	go h.recordDeliveries()
+	go h.dispatchEvents()
+	heartbeat := time.NewTicker(presenceInterval)
+	defer heartbeat.Stop()
+	h.announce()
+
 	for {
 		select {
 		case client := <-h.register:
-			h.clients[client] = true
-			h.userClients[client.userID]++
-			h.broadcastOnlineUsers()
+			if h.users[client.userID] == nil {
+				h.users[client.userID] = make(map[*Client]bool)
+				h.joined = append(h.joined, client.userID)
+			}
+			h.users[client.userID][client] = true
+			// The new client needs the online users even if the set
+			// didn't change.
+			if !h.refreshOnline() {
+				h.push(client, h.onlineFrame())
+			}
 		case client := <-h.unregister:
-			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				close(client.send)
-				h.userClients[client.userID]--
-				if h.userClients[client.userID] <= 0 {
-					delete(h.userClients, client.userID)
-				}
-				h.broadcastOnlineUsers()
+			h.remove(client)
+		case d := <-h.deliver:
+			h.deliverLocal(d.userIDs, d.message, d.track)
+			h.forward(Envelope{Kind: KindDeliver, UserIDs: d.userIDs, Message: d.message, Track: d.track})
+		case r := <-h.replies:
+			// The client may have been dropped since it sent the frame.
+			if h.users[r.client.userID][r.client] {
+				h.push(r.client, r.message)
 			}
-		case message := <-h.broadcast:
-			for client := range h.clients {
-				select {
-				case client.send <- message:
-				default:
-					close(client.send)
-					delete(h.clients, client)
-				}
+		case e := <-h.inbound:
+			h.receive(e)
+		case <-h.overflowed:
+			h.resync()
+		case <-heartbeat.C:
+			h.announce()
+			h.expirePeers()
+		}
+
+		h.flushPresence()
+	}
+}
+
+// sendTo queues message for every connection of the given users on every
+// replica. Users without a connection are skipped.
+func (h *Hub) sendTo(message []byte, userIDs ...int) {
+	h.deliver <- delivery{userIDs: userIDs, message: message}
+}
+
+// replyTo queues message for a single client.
+func (h *Hub) replyTo(client *Client, message []byte) {
+	h.replies <- reply{client: client, message: message}
+}
+
+// sendTracked is sendTo for a frame carrying a chat message, whose delivery
+// to the receiver is recorded.
+func (h *Hub) sendTracked(message []byte, track Track, userIDs ...int) {
+	h.deliver <- delivery{userIDs: userIDs, message: message, track: &track}
+}
+
+// deliverLocal pushes message to the clients of userIDs connected to this
+// hub, and queues the tracked message for recording if its receiver is
+// among them.
+func (h *Hub) deliverLocal(userIDs []int, message []byte, track *Track) {
+	sent := make(map[int]bool, len(userIDs))
+	for _, userID := range userIDs {
+		if sent[userID] {
+			continue
+		}
+		sent[userID] = true
+		for client := range h.users[userID] {
+			h.push(client, message)
+		}
+	}
+	if track != nil && sent[track.ReceiverID] && len(h.users[track.ReceiverID]) > 0 {
+		select {
+		case h.delivered <- *track:
+		default:
+			log.Printf("ws: delivery queue full, not recording delivery of message %d", track.MessageID)
+		}
+	}
+}
+
+// recordDeliveries stores when queued messages reached their receivers and
+// publishes the receipts. Replicas delivering the same message race, but
+// only the first records it.
+func (h *Hub) recordDeliveries() {
+	for track := range h.delivered {
+		ctx, cancel := h.queryContext(context.Background())
+		receipts, err := h.store.DeliverMessages(ctx, track.ReceiverID, []int{track.MessageID}, time.Now())
+		cancel()
+		if err != nil {
+			log.Printf("ws: recording delivery of message %d: %v", track.MessageID, err)
+			continue
+		}
+		for i := range receipts {
+			h.events.Publish(events.MessageDelivered, &receipts[i])
+		}
+	}
+}
+
+// push queues message for client, dropping the client if its buffer is
+// full.
+func (h *Hub) push(client *Client, message []byte) {
+	select {
+	case client.send <- message:
+	default:
+		h.remove(client)
+	}
+}
+
+// remove closes client's send channel and forgets it.
+func (h *Hub) remove(client *Client) {
+	clients := h.users[client.userID]
+	if !clients[client] {
+		return
+	}
+	delete(clients, client)
+	close(client.send)
+	if len(clients) == 0 {
+		delete(h.users, client.userID)
+		h.left = append(h.left, client.userID)
+	}
+}
+
+// resync disconnects every client after domain events were dropped. The
+// clients have missed frames, so they reconnect and reload what they show.
+func (h *Hub) resync() {
+	log.Printf("ws: disconnecting %d users after dropping events", len(h.users))
+	for _, clients := range h.users {
+		for client := range clients {
+			h.remove(client)
+		}
+	}
+}
+
+// receive handles an envelope published by any hub, ignoring this hub's
+// own.
+func (h *Hub) receive(e Envelope) {
+	if e.Node == h.node {
+		return
+	}
+	switch e.Kind {
+	case KindDeliver:
+		h.deliverLocal(e.UserIDs, e.Message, e.Track)
+	case KindPresence:
+		users, known := h.peers[e.Node]
+		if !known {
+			users = make(map[int]time.Time)
+			h.peers[e.Node] = users
+			// Introduce this hub to the newcomer rather than let it
+			// wait for the next heartbeat.
+			h.announce()
+		}
+		now := time.Now()
+		for _, userID := range e.UserIDs {
+			users[userID] = now
+		}
+		h.refreshOnline()
+	case KindOffline:
+		for _, userID := range e.UserIDs {
+			delete(h.peers[e.Node], userID)
+		}
+		h.refreshOnline()
+	}
+}
+
+// announce publishes every user connected to this hub. It always publishes
+// at least one envelope, so other hubs learn about this one.
+func (h *Hub) announce() {
+	userIDs := make([]int, 0, len(h.users))
+	for userID := range h.users {
+		userIDs = append(userIDs, userID)
+	}
+	h.joined = nil
+	for {
+		chunk := userIDs[:min(len(userIDs), presenceChunk)]
+		userIDs = userIDs[len(chunk):]
+		h.forward(Envelope{Kind: KindPresence, UserIDs: chunk})
+		if len(userIDs) == 0 {
+			return
+		}
+	}
+}
+
+// flushPresence publishes the users that came online or went offline on
+// this hub and updates clients if the cluster-wide set changed.
+func (h *Hub) flushPresence() {
+	// Sending the new set can drop slow clients, whose users may have left
+	// in turn.
+	for len(h.joined) > 0 || len(h.left) > 0 {
+		if len(h.joined) > 0 {
+			h.forward(Envelope{Kind: KindPresence, UserIDs: h.joined})
+			h.joined = nil
+		}
+		if len(h.left) > 0 {
+			h.forward(Envelope{Kind: KindOffline, UserIDs: h.left})
+			h.left = nil
+		}
+		h.refreshOnline()
+	}
+}
+
+// expirePeers forgets users and hubs that weren't announced for
+// presenceTTL.
+func (h *Hub) expirePeers() {
+	cutoff := time.Now().Add(-presenceTTL)
+	for node, users := range h.peers {
+		fresh := false
+		for userID, seen := range users {
+			if seen.Before(cutoff) {
+				delete(users, userID)
+			} else {
+				fresh = true
 			}
 		}
+		if !fresh {
+			delete(h.peers, node)
+		}
 	}
+	h.refreshOnline()
 }
 
-func (h *Hub) broadcastOnlineUsers() {
-	onlineUsers := make([]int, 0, len(h.userClients))
-	for userID := range h.userClients {
-		onlineUsers = append(onlineUsers, userID)
+// refreshOnline recomputes the cluster-wide online users and, if they
+// changed, sends them to every client. It reports whether they changed.
+func (h *Hub) refreshOnline() bool {
+	online := make([]int, 0, len(h.users))
+	for userID := range h.users {
+		online = append(online, userID)
 	}
+	for _, users := range h.peers {
+		for userID := range users {
+			online = append(online, userID)
+		}
+	}
+	slices.Sort(online)
+	online = slices.Compact(online)
+	if slices.Equal(online, h.online) {
+		return false
+	}
+	h.online = online
+	h.broadcastOnlineUsers()
+	return true
+}
 
+func (h *Hub) onlineFrame() []byte {
 	msg, _ := json.Marshal(map[string]interface{}{
-		"type": "online_users",
-		"users": onlineUsers,
+		"v":     ProtocolVersion,
+		"type":  "online_users",
+		"users": h.online,
 	})
+	return msg
+}
 
-	for client := range h.clients {
-		select {
-		case client.send <- msg:
-		default:
-			close(client.send)
-			delete(h.clients, client)
+func (h *Hub) broadcastOnlineUsers() {
+	msg := h.onlineFrame()
+	for _, clients := range h.users {
+		for client := range clients {
+			h.push(client, msg)
+		}
+	}
+}
+
+// forward queues e for publishing on the backplane. It never blocks the
+// hub: when the backplane falls behind, envelopes are dropped.
+func (h *Hub) forward(e Envelope) {
+	e.Node = h.node
+	select {
+	case h.outbound <- e:
+	default:
+		log.Printf("backplane: outbound queue full, dropping %s envelope", e.Kind)
+	}
+}
+
+// publish sends queued envelopes to the backplane.
+func (h *Hub) publish() {
+	for e := range h.outbound {
+		ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+		if err := h.backplane.Publish(ctx, e); err != nil {
+			log.Printf("backplane: publishing %s envelope: %v", e.Kind, err)
 		}
+		cancel()
+	}
+}
+
+// subscribe passes envelopes from the backplane to the hub, resubscribing
+// after failures.
+func (h *Hub) subscribe() {
+	for {
+		err := h.backplane.Subscribe(context.Background(), func(e Envelope) { h.inbound <- e })
+		log.Printf("backplane: subscription ended: %v; retrying", err)
+		time.Sleep(5 * time.Second)
 	}
 }
@@ -0,0 +1,503 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"saas-management-api/internal/events"
+	"saas-management-api/internal/models"
+	"saas-management-api/internal/store"
+
+	"github.com/gorilla/websocket"
+)
+
+// frameWait bounds how long tests wait for a frame.
+const frameWait = 2 * time.Second
+
+// startHub runs a hub on backplane and serves its socket, authenticating
+// every connection as the user in the "user" query parameter.
+func startHub(t *testing.T, st *store.Store, backplane Backplane) *httptest.Server {
+	t.Helper()
+	return startHubOn(t, st, backplane, events.NewBus())
+}
+
+// startHubOn is startHub for a hub pushing the domain events of bus.
+func startHubOn(t *testing.T, st *store.Store, backplane Backplane, bus *events.Bus) *httptest.Server {
+	t.Helper()
+	hub := NewHub(st, time.Second, backplane, bus)
+	go hub.Run()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, err := strconv.Atoi(r.URL.Query().Get("user"))
+		if err != nil {
+			http.Error(w, "user is required", http.StatusBadRequest)
+			return
+		}
+		ServeWs(hub, w, r, userID, "user "+strconv.Itoa(userID))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// connect opens a versioned socket to server as user.
+func connect(t *testing.T, server *httptest.Server, user *models.User) *websocket.Conn {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/?v=1&user=" + strconv.Itoa(user.ID)
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("connecting user %d: %v", user.ID, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func newUsers(t *testing.T, st *store.Store, names ...string) []*models.User {
+	t.Helper()
+	users := make([]*models.User, len(names))
+	for i, name := range names {
+		users[i] = &models.User{Name: name, Email: name + "@example.com", Role: "owner"}
+		if err := st.Users.Create(context.Background(), users[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return users
+}
+
+// nextFrames reads frames from conn until it got one of every given type,
+// in any order, and returns the first of each.
+func nextFrames(t *testing.T, conn *websocket.Conn, frameTypes ...string) map[string]Frame {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(frameWait))
+	frames := map[string]Frame{}
+	for len(frames) < len(frameTypes) {
+		var frame Frame
+		if err := conn.ReadJSON(&frame); err != nil {
+			t.Fatalf("waiting for %v frames, got %d: %v", frameTypes, len(frames), err)
+		}
+		if _, seen := frames[frame.Type]; !seen && slices.Contains(frameTypes, frame.Type) {
+			frames[frame.Type] = frame
+		}
+	}
+	return frames
+}
+
+// waitOnline reads online_users frames from conn until the set of online
+// users includes every one of users, which means they are registered with
+// their hubs and, across hubs, that the backplane connects them.
+func waitOnline(t *testing.T, conn *websocket.Conn, users ...*models.User) {
+	t.Helper()
+	for {
+		var online struct {
+			Users []int `json:"users"`
+		}
+		conn.SetReadDeadline(time.Now().Add(frameWait))
+		if err := conn.ReadJSON(&online); err != nil {
+			t.Fatalf("waiting for users to come online: %v", err)
+		}
+		all := true
+		for _, user := range users {
+			all = all && slices.Contains(online.Users, user.ID)
+		}
+		if all {
+			return
+		}
+	}
+}
+
+// noFrame fails if conn receives a frame of the given type within wait.
+func noFrame(t *testing.T, conn *websocket.Conn, frameType string, wait time.Duration) {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(wait))
+	for {
+		var frame Frame
+		if err := conn.ReadJSON(&frame); err != nil {
+			return
+		}
+		if frame.Type == frameType {
+			t.Fatalf("received a %s frame: %s", frameType, frame.Data)
+		}
+	}
+}
+
+// sendText sends a direct message frame from conn to receiver.
+func sendText(t *testing.T, conn *websocket.Conn, receiver *models.User, text string) {
+	t.Helper()
+	data, _ := json.Marshal(map[string]any{"receiver_id": receiver.ID, "message": text, "message_type": "text"})
+	if err := conn.WriteJSON(Frame{V: ProtocolVersion, ID: "frame-1", Type: "message", Data: data}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func messageText(t *testing.T, frame Frame) string {
+	t.Helper()
+	var message models.Message
+	if err := json.Unmarshal(frame.Data, &message); err != nil {
+		t.Fatal(err)
+	}
+	return message.Message
+}
+
+// testDirectMessage has alice message bob with carol also connected. Both
+// ends get the message and alice the delivery receipt; carol gets nothing.
+func testDirectMessage(t *testing.T, alice, bob, carol *websocket.Conn, bobUser *models.User) {
+	sendText(t, alice, bobUser, "hello bob")
+
+	if got := messageText(t, nextFrames(t, bob, frameMessage)[frameMessage]); got != "hello bob" {
+		t.Fatalf("bob received %q", got)
+	}
+	frames := nextFrames(t, alice, "ack", frameMessage, frameMessageDelivered)
+	if ack := frames["ack"]; ack.ID != "frame-1" {
+		t.Fatalf("ack of frame %q, want frame-1", ack.ID)
+	}
+	if got := messageText(t, frames[frameMessage]); got != "hello bob" {
+		t.Fatalf("alice's echo is %q", got)
+	}
+	noFrame(t, carol, frameMessage, 200*time.Millisecond)
+}
+
+func TestHubDeliversToParticipantsOnly(t *testing.T) {
+	st := store.NewMemory()
+	users := newUsers(t, st, "alice", "bob", "carol")
+	server := startHub(t, st, NewMemoryBackplane())
+
+	alice := connect(t, server, users[0])
+	bob := connect(t, server, users[1])
+	carol := connect(t, server, users[2])
+	for _, conn := range []*websocket.Conn{alice, bob, carol} {
+		waitOnline(t, conn, users...)
+	}
+
+	testDirectMessage(t, alice, bob, carol, users[1])
+}
+
+func TestHubDeliversAcrossBackplane(t *testing.T) {
+	st := store.NewMemory()
+	users := newUsers(t, st, "alice", "bob", "carol")
+	backplane := NewMemoryBackplane()
+	first, second := startHub(t, st, backplane), startHub(t, st, backplane)
+
+	// The message crosses the backplane to bob, while carol shares the
+	// sender's hub.
+	alice := connect(t, first, users[0])
+	carol := connect(t, first, users[2])
+	bob := connect(t, second, users[1])
+	for _, conn := range []*websocket.Conn{alice, bob, carol} {
+		waitOnline(t, conn, users...)
+	}
+
+	testDirectMessage(t, alice, bob, carol, users[1])
+}
+
+func TestPublishDoesNotWaitForHub(t *testing.T) {
+	bus := events.NewBus()
+	NewHub(store.NewMemory(), time.Second, NewMemoryBackplane(), bus)
+
+	// The hub is not running, so nothing drains its queue.
+	done := make(chan struct{})
+	go func() {
+		for i := range eventBuffer + 1 {
+			bus.Publish(events.NotificationCreated, &models.Notification{ID: i, UserID: 1})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(frameWait):
+		t.Fatal("publishing waited for the hub")
+	}
+}
+
+func TestHubPushesPaidInvoiceToOwner(t *testing.T) {
+	st := store.NewMemory()
+	users := newUsers(t, st, "owner", "other")
+	ctx := context.Background()
+	business := &models.Business{UserID: &users[0].ID, Name: "Acme"}
+	if err := st.CreateBusiness(ctx, business); err != nil {
+		t.Fatal(err)
+	}
+	invoice := &models.Invoice{BusinessID: business.ID, Amount: 49}
+	if err := st.Invoices.Create(ctx, invoice); err != nil {
+		t.Fatal(err)
+	}
+	bus := events.NewBus()
+	server := startHubOn(t, st, NewMemoryBackplane(), bus)
+	owner := connect(t, server, users[0])
+	other := connect(t, server, users[1])
+	for _, conn := range []*websocket.Conn{owner, other} {
+		waitOnline(t, conn, users...)
+	}
+
+	paid, err := st.PayInvoice(ctx, &models.Payment{InvoiceID: invoice.ID}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus.Publish(events.InvoicePaid, paid)
+
+	var got models.Invoice
+	if err := json.Unmarshal(nextFrames(t, owner, frameInvoicePaid)[frameInvoicePaid].Data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != invoice.ID || got.Status != models.InvoicePaid {
+		t.Fatalf("owner got invoice %d with status %q", got.ID, got.Status)
+	}
+	noFrame(t, other, frameInvoicePaid, 200*time.Millisecond)
+}
+
+// stalledNotifications blocks counting unread notifications until the
+// context ends, which stalls the hub's event dispatch.
+type stalledNotifications struct {
+	store.NotificationRepository
+}
+
+func (stalledNotifications) UnreadCount(ctx context.Context, userID int) (int, error) {
+	<-ctx.Done()
+	return 0, ctx.Err()
+}
+
+func TestHubDisconnectsClientsWhenEventsOverflow(t *testing.T) {
+	st := store.NewMemory()
+	users := newUsers(t, st, "alice")
+	st.Notifications = stalledNotifications{st.Notifications}
+	bus := events.NewBus()
+	server := startHubOn(t, st, NewMemoryBackplane(), bus)
+	alice := connect(t, server, users[0])
+	waitOnline(t, alice, users...)
+
+	// The first event stalls the dispatcher, the next eventBuffer fill the
+	// queue and the last is dropped.
+	for i := range eventBuffer + 2 {
+		bus.Publish(events.NotificationCreated, &models.Notification{ID: i + 1, UserID: 2})
+	}
+
+	alice.SetReadDeadline(time.Now().Add(frameWait))
+	for {
+		_, _, err := alice.ReadMessage()
+		if err == nil {
+			continue
+		}
+		if !websocket.IsCloseError(err, websocket.CloseNoStatusReceived) {
+			t.Fatalf("the connection failed with %v, want it closed by the hub", err)
+		}
+		return
+	}
+}
+
+// sendTyping sends a direct typing frame from conn to receiver.
+func sendTyping(t *testing.T, conn *websocket.Conn, receiver *models.User) {
+	t.Helper()
+	data, _ := json.Marshal(map[string]any{"receiver_id": receiver.ID, "is_typing": true})
+	if err := conn.WriteJSON(Frame{V: ProtocolVersion, ID: "typing-" + strconv.Itoa(receiver.ID), Type: "typing", Data: data}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHubForwardsDirectTypingWithinConversations(t *testing.T) {
+	st := store.NewMemory()
+	users := newUsers(t, st, "alice", "bob", "carol")
+	if _, err := st.Conversations.GetOrCreate(context.Background(), users[0].ID, users[1].ID); err != nil {
+		t.Fatal(err)
+	}
+	server := startHub(t, st, NewMemoryBackplane())
+	alice := connect(t, server, users[0])
+	bob := connect(t, server, users[1])
+	carol := connect(t, server, users[2])
+	for _, conn := range []*websocket.Conn{alice, bob, carol} {
+		waitOnline(t, conn, users...)
+	}
+
+	// Alice and carol have no conversation, so carol hears nothing.
+	sendTyping(t, alice, users[2])
+	sendTyping(t, alice, users[1])
+	var typing struct {
+		SenderID int  `json:"sender_id"`
+		IsTyping bool `json:"is_typing"`
+	}
+	if err := json.Unmarshal(nextFrames(t, bob, "typing")["typing"].Data, &typing); err != nil {
+		t.Fatal(err)
+	}
+	if typing.SenderID != users[0].ID || !typing.IsTyping {
+		t.Fatalf("bob got %+v", typing)
+	}
+	if rejected := nextFrames(t, alice, "error")["error"]; rejected.Error == nil || rejected.Error.Code != codeNotFound {
+		t.Fatalf("alice got %+v, want a not_found error", rejected)
+	}
+	noFrame(t, carol, "typing", 200*time.Millisecond)
+}
+
+func TestLegacyFramesHaveNoVersion(t *testing.T) {
+	st := store.NewMemory()
+	users := newUsers(t, st, "alice")
+	bus := events.NewBus()
+	server := startHubOn(t, st, NewMemoryBackplane(), bus)
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/?user=" + strconv.Itoa(users[0].ID)
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	seen := map[string]bool{}
+	conn.SetReadDeadline(time.Now().Add(frameWait))
+	for !seen["history"] || !seen["online_users"] || !seen[frameNotification] {
+		var frame map[string]json.RawMessage
+		if err := conn.ReadJSON(&frame); err != nil {
+			t.Fatalf("got %v frames: %v", seen, err)
+		}
+		if _, versioned := frame["v"]; versioned {
+			t.Fatalf("legacy frame %s has a version", frame["type"])
+		}
+		var frameType string
+		json.Unmarshal(frame["type"], &frameType)
+		seen[frameType] = true
+		if frameType == "online_users" && !seen[frameNotification] {
+			bus.Publish(events.NotificationCreated, &models.Notification{ID: 1, UserID: users[0].ID})
+		}
+	}
+}
+
+func TestHubQueryContext(t *testing.T) {
+	hub := NewHub(store.NewMemory(), 10*time.Millisecond, NewMemoryBackplane(), events.NewBus())
+	ctx, cancel := hub.queryContext(context.Background())
+	defer cancel()
+	if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) > 10*time.Millisecond {
+		t.Fatalf("query deadline = %v, %v; want within 10ms", deadline, ok)
+	}
+
+	// A client going away cancels its queries.
+	parent, disconnect := context.WithCancel(context.Background())
+	ctx, cancel = NewHub(store.NewMemory(), 0, NewMemoryBackplane(), events.NewBus()).queryContext(parent)
+	defer cancel()
+	disconnect()
+	if _, err := store.NewMemory().Users.Get(ctx, 1); !errors.Is(err, context.Canceled) {
+		t.Fatalf("query of a gone client = %v, want context.Canceled", err)
+	}
+}
+
+func TestHubPresenceAcrossBackplane(t *testing.T) {
+	st := store.NewMemory()
+	users := newUsers(t, st, "alice", "bob")
+	backplane := NewMemoryBackplane()
+	first, second := startHub(t, st, backplane), startHub(t, st, backplane)
+	alice := connect(t, first, users[0])
+	bob := connect(t, second, users[1])
+	waitOnline(t, alice, users...)
+
+	// Bob's last connection closing on his replica takes him offline on
+	// alice's.
+	bob.Close()
+	for {
+		var online struct {
+			Users []int `json:"users"`
+		}
+		alice.SetReadDeadline(time.Now().Add(frameWait))
+		if err := alice.ReadJSON(&online); err != nil {
+			t.Fatalf("waiting for bob to go offline: %v", err)
+		}
+		if slices.Equal(online.Users, []int{users[0].ID}) {
+			return
+		}
+	}
+}
+
+// unreadCount decodes a notification_unread_count frame.
+func unreadCount(t *testing.T, frame Frame) int {
+	t.Helper()
+	var data struct {
+		Count int `json:"count"`
+	}
+	if err := json.Unmarshal(frame.Data, &data); err != nil {
+		t.Fatal(err)
+	}
+	return data.Count
+}
+
+func TestHubPushesNotificationsAndReadMarks(t *testing.T) {
+	st := store.NewMemory()
+	users := newUsers(t, st, "alice", "bob")
+	bus := events.NewBus()
+	server := startHubOn(t, st, NewMemoryBackplane(), bus)
+	// Alice has two tabs open.
+	tab1 := connect(t, server, users[0])
+	tab2 := connect(t, server, users[0])
+	bob := connect(t, server, users[1])
+	for _, conn := range []*websocket.Conn{tab1, tab2, bob} {
+		waitOnline(t, conn, users...)
+	}
+
+	notification := &models.Notification{UserID: users[0].ID, Subject: "Hi", Message: "Hello", Status: "unread"}
+	if err := st.Notifications.Create(context.Background(), notification); err != nil {
+		t.Fatal(err)
+	}
+	bus.Publish(events.NotificationCreated, notification)
+	for _, tab := range []*websocket.Conn{tab1, tab2} {
+		frames := nextFrames(t, tab, frameNotification, frameUnreadCount)
+		if count := unreadCount(t, frames[frameUnreadCount]); count != 1 {
+			t.Fatalf("unread count = %d, want 1", count)
+		}
+	}
+
+	// Reading it in one tab updates both.
+	data, _ := json.Marshal(map[string]int{"id": notification.ID})
+	if err := tab1.WriteJSON(Frame{V: ProtocolVersion, ID: "read-1", Type: "notification_read", Data: data}); err != nil {
+		t.Fatal(err)
+	}
+	if ack := nextFrames(t, tab1, "ack")["ack"]; ack.ID != "read-1" {
+		t.Fatalf("ack of frame %q, want read-1", ack.ID)
+	}
+	frames := nextFrames(t, tab2, frameNotificationUpdated, frameUnreadCount)
+	var updated models.Notification
+	if err := json.Unmarshal(frames[frameNotificationUpdated].Data, &updated); err != nil {
+		t.Fatal(err)
+	}
+	if !updated.IsRead || unreadCount(t, frames[frameUnreadCount]) != 0 {
+		t.Fatalf("tab 2 got %+v with %s", updated, frames[frameUnreadCount].Data)
+	}
+
+	// Bob can't read alice's notification, and hears nothing of it.
+	if err := bob.WriteJSON(Frame{V: ProtocolVersion, ID: "read-2", Type: "notification_read", Data: data}); err != nil {
+		t.Fatal(err)
+	}
+	if rejected := nextFrames(t, bob, "error")["error"]; rejected.Error == nil || rejected.Error.Code != codeNotFound {
+		t.Fatalf("bob got %+v, want a not_found error", rejected)
+	}
+	noFrame(t, bob, frameNotification, 200*time.Millisecond)
+}
+
+func TestHubPushesReadReceipts(t *testing.T) {
+	st := store.NewMemory()
+	users := newUsers(t, st, "alice", "bob")
+	server := startHub(t, st, NewMemoryBackplane())
+	alice := connect(t, server, users[0])
+	bob := connect(t, server, users[1])
+	for _, conn := range []*websocket.Conn{alice, bob} {
+		waitOnline(t, conn, users...)
+	}
+
+	sendText(t, alice, users[1], "hello bob")
+	var message models.Message
+	if err := json.Unmarshal(nextFrames(t, bob, frameMessage)[frameMessage].Data, &message); err != nil {
+		t.Fatal(err)
+	}
+	nextFrames(t, alice, frameMessageDelivered)
+
+	data, _ := json.Marshal(map[string]int{"conversation_id": message.ConversationID, "up_to_id": message.ID})
+	if err := bob.WriteJSON(Frame{V: ProtocolVersion, ID: "read-1", Type: "read", Data: data}); err != nil {
+		t.Fatal(err)
+	}
+	var receipt models.MessageReceipt
+	if err := json.Unmarshal(nextFrames(t, alice, frameMessageRead)[frameMessageRead].Data, &receipt); err != nil {
+		t.Fatal(err)
+	}
+	if receipt.Status != models.ReceiptRead || receipt.ReceiverID != users[1].ID || !slices.Equal(receipt.MessageIDs, []int{message.ID}) {
+		t.Fatalf("alice got receipt %+v", receipt)
+	}
+	// The reader's own connections learn of it too.
+	nextFrames(t, bob, "ack", frameMessageRead)
+}
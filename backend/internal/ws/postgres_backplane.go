@@ -0,0 +1,78 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// BackplaneChannel is the Postgres notification channel hubs talk on.
+const BackplaneChannel = "ws_backplane"
+
+// maxNotifyPayload is the largest payload Postgres accepts for NOTIFY.
+const maxNotifyPayload = 7999
+
+// PostgresBackplane connects the hubs of all replicas sharing a database
+// through LISTEN/NOTIFY, so no extra infrastructure is needed. Payloads are
+// limited to 8000 bytes; larger envelopes fail to publish.
+type PostgresBackplane struct {
+	db *sqlx.DB
+	// dsn opens the dedicated listening connection.
+	dsn string
+}
+
+func NewPostgresBackplane(db *sqlx.DB, dsn string) *PostgresBackplane {
+	return &PostgresBackplane{db: db, dsn: dsn}
+}
+
+func (b *PostgresBackplane) Publish(ctx context.Context, e Envelope) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if len(payload) > maxNotifyPayload {
+		return fmt.Errorf("backplane: %s envelope of %d bytes exceeds the NOTIFY limit", e.Kind, len(payload))
+	}
+	_, err = b.db.ExecContext(ctx, "SELECT pg_notify($1, $2)", BackplaneChannel, string(payload))
+	return err
+}
+
+func (b *PostgresBackplane) Subscribe(ctx context.Context, handle func(Envelope)) error {
+	listener := pq.NewListener(b.dsn, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("backplane listener: %v", err)
+		}
+	})
+	defer listener.Close()
+	if err := listener.Listen(BackplaneChannel); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case n := <-listener.Notify:
+			// A nil notification follows a reconnect, after which
+			// envelopes may have been missed. Presence heals itself
+			// through heartbeats.
+			if n == nil {
+				continue
+			}
+			var e Envelope
+			if err := json.Unmarshal([]byte(n.Extra), &e); err != nil {
+				log.Printf("backplane: ignoring malformed envelope: %v", err)
+				continue
+			}
+			handle(e)
+		case <-time.After(90 * time.Second):
+			// Detect a silently dropped connection.
+			go listener.Ping()
+		}
+	}
+}
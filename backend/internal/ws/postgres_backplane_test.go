@@ -0,0 +1,68 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+)
+
+func TestPostgresBackplaneRejectsOversizedEnvelopes(t *testing.T) {
+	// The size check comes before any database access.
+	b := NewPostgresBackplane(nil, "")
+	message, _ := json.Marshal(strings.Repeat("x", maxNotifyPayload))
+	err := b.Publish(context.Background(), Envelope{Kind: KindDeliver, UserIDs: []int{1}, Message: message})
+	if err == nil || !strings.Contains(err.Error(), "NOTIFY limit") {
+		t.Fatalf("Publish = %v, want the NOTIFY limit error", err)
+	}
+}
+
+// TestPostgresBackplaneFanOut runs against the database in
+// TEST_DATABASE_URL.
+func TestPostgresBackplaneFanOut(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := sqlx.Connect("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// Two replicas listen; either one publishing reaches both.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	received := []chan Envelope{make(chan Envelope, 4), make(chan Envelope, 4)}
+	for _, ch := range received {
+		b := NewPostgresBackplane(db, dsn)
+		go b.Subscribe(ctx, func(e Envelope) { ch <- e })
+	}
+
+	publisher := NewPostgresBackplane(db, dsn)
+	sent := Envelope{Node: "a", Kind: KindDeliver, UserIDs: []int{1, 2}, Message: json.RawMessage(`{"type":"message"}`), Track: &Track{MessageID: 3, ReceiverID: 2}}
+	// Subscribing takes a moment, so publish until both replicas hear it.
+	deadline := time.After(5 * time.Second)
+	for i, ch := range received {
+		for got := false; !got; {
+			if err := publisher.Publish(ctx, sent); err != nil {
+				t.Fatal(err)
+			}
+			select {
+			case e := <-ch:
+				if e.Node != sent.Node || e.Kind != sent.Kind || string(e.Message) != string(sent.Message) || *e.Track != *sent.Track {
+					t.Fatalf("replica %d received %+v, want %+v", i, e, sent)
+				}
+				got = true
+			case <-time.After(100 * time.Millisecond):
+			case <-deadline:
+				t.Fatalf("replica %d received nothing", i)
+			}
+		}
+	}
+}
@@ -0,0 +1,407 @@
+package ws
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+
+	"saas-management-api/internal/models"
+	"saas-management-api/internal/store"
+)
+
+// ProtocolVersion is the version of the socket protocol. In it, every frame
+// is an envelope:
+//
+//	{"v": 1, "id": "4f1c…", "type": "message", "data": {...}}
+//
+// Clients give each frame they send a unique ID of up to 64 characters, and
+// the server answers every frame with an "ack" carrying the same ID and the
+// result in "data", or an "error" carrying the ID and
+// {"code", "message"} in "error". Frames pushed by the server have no ID.
+//
+// Client frames:
+//
+//   - message {conversation_id | receiver_id, message, message_type,
+//     attachment_ids}: sends a chat message to a conversation, or to the
+//     direct conversation with a user, acked with the stored message.
+//     Attachments are uploaded over REST first. The frame ID is the
+//     message's client ID, so resending a frame after a lost ack stores the
+//     message once and acks it again.
+//   - edit {id, message}: replaces the text of a text message the user
+//     sent, acked with the message.
+//   - delete {id, for_everyone}: hides a message from the user or, if they
+//     sent it, replaces it with a tombstone for everyone, acked with the
+//     tombstone.
+//   - react {id, emoji, remove}: adds or removes the user's reaction with
+//     an emoji, acked with the message.
+//   - typing {conversation_id | receiver_id, is_typing}: tells the other
+//     participants whether the sender is typing.
+//   - notification_read {id}: marks a notification read, acked with the
+//     notification.
+//   - read {conversation_id, up_to_id}: marks the messages of others in a
+//     conversation up to up_to_id read, acked with the receipts sent to
+//     their senders.
+//   - resume {after_id}: replays the user's messages with IDs above after_id,
+//     oldest first, acked with {messages, more}. While more is true, resume
+//     again from the last message. An after_id of 0 returns the latest 50.
+//
+// Retrying any client frame is safe.
+//
+// Edits, tombstones and reactions are pushed to the conversation as
+// message_updated frames with the whole message, and messages the user
+// hid to their other connections as message_hidden frames.
+//
+// Delivery and read receipts are pushed to senders as message_delivered and
+// message_read frames. A direct message counts as delivered once it is
+// pushed to, or replayed on, one of its receiver's connections. Group
+// messages only get read receipts, one per reader.
+const ProtocolVersion = 1
+
+// Frame is the envelope of every versioned frame.
+type Frame struct {
+	V     int             `json:"v"`
+	ID    string          `json:"id,omitempty"`
+	Type  string          `json:"type"`
+	Data  json.RawMessage `json:"data,omitempty"`
+	Error *FrameError     `json:"error,omitempty"`
+}
+
+// unversioned drops "v" from a frame pushed to a legacy client, which
+// predates the field. Frames are shared by all of a user's connections, so
+// they are encoded with it.
+func unversioned(frame []byte) []byte {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(frame, &fields); err != nil || fields["v"] == nil {
+		return frame
+	}
+	delete(fields, "v")
+	if stripped, err := json.Marshal(fields); err == nil {
+		return stripped
+	}
+	return frame
+}
+
+// FrameError reports why a client frame failed.
+type FrameError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error codes of error frames.
+const (
+	codeInvalid            = "invalid"
+	codeUnsupportedVersion = "unsupported_version"
+	codeUnknownType        = "unknown_type"
+	codeNotFound           = "not_found"
+	codeForbidden          = "forbidden"
+	codeConflict           = "conflict"
+	codeInternal           = "internal"
+)
+
+const (
+	// Longest client frame ID, which is also stored as a message's client
+	// ID.
+	maxFrameID = 64
+
+	// Most attachments per message.
+	maxAttachments = 10
+
+	// Messages replayed for a new client, and at most per resume frame.
+	historyLimit = 50
+	resumeLimit  = 200
+)
+
+// handleFrame handles a frame of a versioned client and answers it with an
+// ack or error.
+func (c *Client) handleFrame(raw []byte) {
+	var frame Frame
+	if err := json.Unmarshal(raw, &frame); err != nil {
+		c.replyError("", codeInvalid, "Frame is not a JSON envelope")
+		return
+	}
+	if frame.V != ProtocolVersion {
+		c.replyError(frame.ID, codeUnsupportedVersion, "Unsupported protocol version")
+		return
+	}
+	if frame.ID == "" || len(frame.ID) > maxFrameID {
+		c.replyError(frame.ID, codeInvalid, "id is required and must be at most 64 characters")
+		return
+	}
+
+	switch frame.Type {
+	case "message":
+		var data struct {
+			ReceiverID     int    `json:"receiver_id"`
+			ConversationID int    `json:"conversation_id"`
+			Message        string `json:"message"`
+			MessageType    string `json:"message_type"`
+			AttachmentIDs  []int  `json:"attachment_ids"`
+		}
+		if !c.decode(frame, &data) {
+			return
+		}
+		if data.ReceiverID <= 0 && data.ConversationID <= 0 {
+			c.replyError(frame.ID, codeInvalid, "receiver_id or conversation_id is required")
+			return
+		}
+		if strings.TrimSpace(data.Message) == "" && len(data.AttachmentIDs) == 0 {
+			c.replyError(frame.ID, codeInvalid, "message or attachment_ids is required")
+			return
+		}
+		if len(data.AttachmentIDs) > maxAttachments {
+			c.replyError(frame.ID, codeInvalid, "At most 10 attachments can be sent at once")
+			return
+		}
+		if data.MessageType != "" && !models.ValidMessageType(data.MessageType) {
+			c.replyError(frame.ID, codeInvalid, "message_type must be text, image or file")
+			return
+		}
+		msg, err := c.sendMessage(data.ReceiverID, data.ConversationID, data.Message, data.MessageType, frame.ID, data.AttachmentIDs)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				c.replyError(frame.ID, codeNotFound, "Conversation not found")
+				return
+			}
+			if errors.Is(err, store.ErrAttachmentUnavailable) {
+				c.replyError(frame.ID, codeConflict, "Attachments must be uploaded by the sender and not sent yet")
+				return
+			}
+			log.Printf("error saving message in WS: %v", err)
+			c.replyError(frame.ID, codeInternal, "Failed to send message")
+			return
+		}
+		c.ack(frame.ID, msg)
+
+	case "edit":
+		var data struct {
+			ID      int    `json:"id"`
+			Message string `json:"message"`
+		}
+		if !c.decode(frame, &data) {
+			return
+		}
+		if data.ID <= 0 || strings.TrimSpace(data.Message) == "" {
+			c.replyError(frame.ID, codeInvalid, "id and message are required")
+			return
+		}
+		msg, err := c.editMessage(data.ID, data.Message)
+		if err != nil {
+			c.messageError(frame.ID, err, "Only text messages that weren't deleted can be edited")
+			return
+		}
+		c.ack(frame.ID, msg)
+
+	case "delete":
+		var data struct {
+			ID          int  `json:"id"`
+			ForEveryone bool `json:"for_everyone"`
+		}
+		if !c.decode(frame, &data) {
+			return
+		}
+		if data.ID <= 0 {
+			c.replyError(frame.ID, codeInvalid, "id is required")
+			return
+		}
+		msg, err := c.deleteMessage(data.ID, data.ForEveryone)
+		if err != nil {
+			c.messageError(frame.ID, err, "")
+			return
+		}
+		if !data.ForEveryone {
+			msg = nil
+		}
+		c.ack(frame.ID, msg)
+
+	case "react":
+		var data struct {
+			ID     int    `json:"id"`
+			Emoji  string `json:"emoji"`
+			Remove bool   `json:"remove"`
+		}
+		if !c.decode(frame, &data) {
+			return
+		}
+		if data.ID <= 0 || !models.ValidEmoji(data.Emoji) {
+			c.replyError(frame.ID, codeInvalid, "id and a valid emoji are required")
+			return
+		}
+		msg, err := c.react(data.ID, data.Emoji, data.Remove)
+		if err != nil {
+			c.messageError(frame.ID, err, "Deleted messages can't be reacted to")
+			return
+		}
+		c.ack(frame.ID, msg)
+
+	case "typing":
+		var data struct {
+			ReceiverID     int  `json:"receiver_id"`
+			ConversationID int  `json:"conversation_id"`
+			IsTyping       bool `json:"is_typing"`
+		}
+		if !c.decode(frame, &data) {
+			return
+		}
+		if data.ReceiverID <= 0 && data.ConversationID <= 0 {
+			c.replyError(frame.ID, codeInvalid, "receiver_id or conversation_id is required")
+			return
+		}
+		if err := c.typing(data.ReceiverID, data.ConversationID, data.IsTyping); err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				c.replyError(frame.ID, codeNotFound, "Conversation not found")
+				return
+			}
+			log.Printf("error sending typing state in WS: %v", err)
+			c.replyError(frame.ID, codeInternal, "Failed to send typing state")
+			return
+		}
+		c.ack(frame.ID, nil)
+
+	case "notification_read":
+		var data struct {
+			ID int `json:"id"`
+		}
+		if !c.decode(frame, &data) {
+			return
+		}
+		notification, err := c.markNotificationRead(data.ID)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				c.replyError(frame.ID, codeNotFound, "Notification not found")
+				return
+			}
+			log.Printf("error marking notification %d read in WS: %v", data.ID, err)
+			c.replyError(frame.ID, codeInternal, "Failed to update notification")
+			return
+		}
+		c.ack(frame.ID, notification)
+
+	case "read":
+		var data struct {
+			ConversationID int `json:"conversation_id"`
+			UpToID         int `json:"up_to_id"`
+		}
+		if !c.decode(frame, &data) {
+			return
+		}
+		if data.UpToID <= 0 {
+			c.replyError(frame.ID, codeInvalid, "up_to_id is required")
+			return
+		}
+		receipts, err := c.readMessages(data.ConversationID, data.UpToID)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				c.replyError(frame.ID, codeNotFound, "Conversation not found")
+				return
+			}
+			log.Printf("error marking messages read in WS: %v", err)
+			c.replyError(frame.ID, codeInternal, "Failed to mark messages as read")
+			return
+		}
+		c.ack(frame.ID, receipts)
+
+	case "resume":
+		var data struct {
+			AfterID int `json:"after_id"`
+		}
+		if !c.decode(frame, &data) {
+			return
+		}
+		messages, more, err := c.replay(data.AfterID)
+		if err != nil {
+			log.Printf("error replaying messages in WS: %v", err)
+			c.replyError(frame.ID, codeInternal, "Failed to load messages")
+			return
+		}
+		c.ack(frame.ID, map[string]interface{}{"messages": messages, "more": more})
+
+	default:
+		c.replyError(frame.ID, codeUnknownType, "Unknown frame type "+frame.Type)
+	}
+}
+
+// replay returns the messages of the client's user after afterID, oldest
+// first, and whether more follow.
+func (c *Client) replay(afterID int) ([]models.Message, bool, error) {
+	ctx, cancel := c.queryContext()
+	defer cancel()
+
+	if afterID <= 0 {
+		messages, err := c.hub.store.Conversations.RecentMessages(ctx, c.userID, historyLimit)
+		if err != nil {
+			return nil, false, err
+		}
+		for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+			messages[i], messages[j] = messages[j], messages[i]
+		}
+		c.deliverReplayed(messages)
+		return messages, false, nil
+	}
+
+	messages, err := c.hub.store.Conversations.MessagesAfter(ctx, c.userID, afterID, resumeLimit+1)
+	if err != nil {
+		return nil, false, err
+	}
+	more := len(messages) > resumeLimit
+	if more {
+		messages = messages[:resumeLimit]
+	}
+	c.deliverReplayed(messages)
+	return messages, more, nil
+}
+
+// messageError answers a frame that failed to change a message. conflict
+// explains store.ErrConflict.
+func (c *Client) messageError(id string, err error, conflict string) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		c.replyError(id, codeNotFound, "Message not found")
+	case errors.Is(err, store.ErrNotSender):
+		c.replyError(id, codeForbidden, "You did not send this message")
+	case errors.Is(err, store.ErrConflict) && conflict != "":
+		c.replyError(id, codeConflict, conflict)
+	default:
+		log.Printf("error changing message in WS: %v", err)
+		c.replyError(id, codeInternal, "Failed to change message")
+	}
+}
+
+// decode unmarshals a frame's data into v, answering with an error frame if
+// it doesn't fit.
+func (c *Client) decode(frame Frame, v any) bool {
+	if len(frame.Data) == 0 {
+		c.replyError(frame.ID, codeInvalid, "data is required")
+		return false
+	}
+	if err := json.Unmarshal(frame.Data, v); err != nil {
+		c.replyError(frame.ID, codeInvalid, "Invalid data for "+frame.Type+": "+err.Error())
+		return false
+	}
+	return true
+}
+
+// ack answers the client frame with ID id with a result.
+func (c *Client) ack(id string, result any) {
+	frame := Frame{V: ProtocolVersion, ID: id, Type: "ack"}
+	if result != nil {
+		data, err := json.Marshal(result)
+		if err != nil {
+			log.Printf("error encoding ack in WS: %v", err)
+			c.replyError(id, codeInternal, "Failed to encode result")
+			return
+		}
+		frame.Data = data
+	}
+	c.reply(frame)
+}
+
+// replyError answers the client frame with ID id with an error.
+func (c *Client) replyError(id, code, message string) {
+	c.reply(Frame{V: ProtocolVersion, ID: id, Type: "error", Error: &FrameError{Code: code, Message: message}})
+}
+
+func (c *Client) reply(frame Frame) {
+	message, _ := json.Marshal(frame)
+	c.hub.replyTo(c, message)
+}
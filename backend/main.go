@@ -2,14 +2,19 @@ package main
 
 import (
 	"log"
+	"net/http"
 	"os"
 
 	"saas-management-api/internal/auth"
+	"saas-management-api/internal/certs"
 	"saas-management-api/internal/config"
 	"saas-management-api/internal/database"
+	"saas-management-api/internal/events"
 	"saas-management-api/internal/router"
+	"saas-management-api/internal/store"
 
 	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/acme/autocert"
 )
 
 func main() {
@@ -43,8 +48,40 @@ func main() {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	// Initialize router
-	r := router.SetupRouter(db, cfg)
+	// Initialize router. The API and the sites server share one event bus.
+	bus := events.NewBus()
+	r := router.SetupRouter(db, cfg, bus)
+
+	// Issue certificates for custom domains when sites are served over TLS
+	var certManager *autocert.Manager
+	if cfg.SitesTLSPort != "" {
+		certManager, err = certs.NewManager(store.NewPostgres(database.NewDB(db)), cfg)
+		if err != nil {
+			log.Fatal("Failed to set up certificates:", err)
+		}
+	}
+
+	// Serve published websites on their own port
+	sites := router.SetupSites(db, cfg, certManager, bus)
+	go func() {
+		log.Printf("Sites server starting on port %s", cfg.SitesPort)
+		if err := sites.Run(":" + cfg.SitesPort); err != nil {
+			log.Fatal("Failed to start sites server:", err)
+		}
+	}()
+	if certManager != nil {
+		go func() {
+			server := &http.Server{
+				Addr:      ":" + cfg.SitesTLSPort,
+				Handler:   sites,
+				TLSConfig: certs.TLSConfig(certManager),
+			}
+			log.Printf("Sites TLS server starting on port %s", cfg.SitesTLSPort)
+			if err := server.ListenAndServeTLS("", ""); err != nil {
+				log.Fatal("Failed to start sites TLS server:", err)
+			}
+		}()
+	}
 
 	// Start server
 	port := os.Getenv("PORT")
@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS websites_search_refresh ON websites;
+DROP TRIGGER IF EXISTS businesses_search_refresh ON businesses;
+DROP FUNCTION IF EXISTS websites_search_trigger();
+DROP FUNCTION IF EXISTS businesses_search_trigger();
+DROP FUNCTION IF EXISTS refresh_business_search(INTEGER);
+DROP INDEX IF EXISTS idx_businesses_industry;
+DROP TABLE IF EXISTS business_search;
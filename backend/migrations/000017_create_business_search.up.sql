@@ -0,0 +1,61 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Search documents live in their own table so the businesses row shape stays
+-- unchanged. They are kept current by the triggers below.
+CREATE TABLE IF NOT EXISTS business_search (
+	business_id INTEGER PRIMARY KEY REFERENCES businesses(id) ON DELETE CASCADE,
+	document TSVECTOR NOT NULL,
+	fuzzy TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_business_search_document ON business_search USING GIN (document);
+CREATE INDEX IF NOT EXISTS idx_business_search_fuzzy ON business_search USING GIN (fuzzy gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_businesses_industry ON businesses(industry);
+
+CREATE OR REPLACE FUNCTION refresh_business_search(target INTEGER) RETURNS VOID AS $$
+BEGIN
+	INSERT INTO business_search (business_id, document, fuzzy)
+	SELECT b.id,
+		setweight(to_tsvector('simple', coalesce(b.name, '')), 'A') ||
+		setweight(to_tsvector('simple', coalesce(b.industry, '')), 'B') ||
+		setweight(to_tsvector('simple', coalesce(string_agg(w.title, ' '), '')), 'B') ||
+		setweight(to_tsvector('simple', coalesce(b.description, '')), 'C') ||
+		setweight(to_tsvector('simple', coalesce(b.address, '')), 'D'),
+		lower(concat_ws(' ', b.name, b.industry, string_agg(w.title, ' ')))
+	FROM businesses b
+	LEFT JOIN websites w ON w.business_id = b.id
+	WHERE b.id = target
+	GROUP BY b.id
+	ON CONFLICT (business_id) DO UPDATE
+	SET document = EXCLUDED.document, fuzzy = EXCLUDED.fuzzy;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION businesses_search_trigger() RETURNS TRIGGER AS $$
+BEGIN
+	PERFORM refresh_business_search(NEW.id);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION websites_search_trigger() RETURNS TRIGGER AS $$
+BEGIN
+	IF TG_OP <> 'INSERT' AND OLD.business_id IS NOT NULL THEN
+		PERFORM refresh_business_search(OLD.business_id);
+	END IF;
+	IF TG_OP <> 'DELETE' AND NEW.business_id IS NOT NULL THEN
+		PERFORM refresh_business_search(NEW.business_id);
+	END IF;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER businesses_search_refresh
+	AFTER INSERT OR UPDATE ON businesses
+	FOR EACH ROW EXECUTE FUNCTION businesses_search_trigger();
+
+CREATE TRIGGER websites_search_refresh
+	AFTER INSERT OR UPDATE OR DELETE ON websites
+	FOR EACH ROW EXECUTE FUNCTION websites_search_trigger();
+
+SELECT refresh_business_search(id) FROM businesses;
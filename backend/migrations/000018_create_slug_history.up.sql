@@ -0,0 +1,9 @@
+-- Former slugs of renamed businesses, so old links keep resolving.
+CREATE TABLE IF NOT EXISTS slug_history (
+	id SERIAL PRIMARY KEY,
+	business_id INTEGER NOT NULL REFERENCES businesses(id) ON DELETE CASCADE,
+	slug VARCHAR(255) UNIQUE NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_slug_history_business_id ON slug_history(business_id);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_businesses_status;
+
+UPDATE businesses SET status = 'active' WHERE status = 'approved';
+
+ALTER TABLE businesses DROP COLUMN review_reason;
+ALTER TABLE businesses DROP COLUMN reviewed_at;
+ALTER TABLE businesses DROP COLUMN reviewed_by;
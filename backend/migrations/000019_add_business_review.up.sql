@@ -0,0 +1,8 @@
+ALTER TABLE businesses ADD COLUMN reviewed_by INTEGER REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE businesses ADD COLUMN reviewed_at TIMESTAMP;
+ALTER TABLE businesses ADD COLUMN review_reason TEXT;
+
+-- Businesses created before the review workflow were marked active.
+UPDATE businesses SET status = 'approved' WHERE status = 'active';
+
+CREATE INDEX IF NOT EXISTS idx_businesses_status ON businesses(status);
@@ -0,0 +1,4 @@
+ALTER TABLE websites DROP COLUMN published_at;
+ALTER TABLE websites DROP COLUMN published_revision;
+
+DROP TABLE IF EXISTS website_revisions;
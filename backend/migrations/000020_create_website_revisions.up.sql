@@ -0,0 +1,27 @@
+-- Every saved state of a website. The websites row holds the draft; the
+-- published revision is what visitors see.
+CREATE TABLE IF NOT EXISTS website_revisions (
+	id SERIAL PRIMARY KEY,
+	website_id INTEGER NOT NULL REFERENCES websites(id) ON DELETE CASCADE,
+	revision INTEGER NOT NULL,
+	snapshot JSONB NOT NULL,
+	created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE(website_id, revision)
+);
+
+ALTER TABLE websites ADD COLUMN published_revision INTEGER;
+ALTER TABLE websites ADD COLUMN published_at TIMESTAMP;
+
+-- Existing websites are live, so their current state becomes published
+-- revision 1.
+INSERT INTO website_revisions (website_id, revision, snapshot, created_at)
+SELECT id, 1, jsonb_build_object(
+	'title', title,
+	'url', url,
+	'image_url', image_url,
+	'theme_name', theme_name
+), updated_at
+FROM websites;
+
+UPDATE websites SET published_revision = 1, published_at = updated_at;
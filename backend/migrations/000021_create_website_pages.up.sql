@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS website_pages (
+	id SERIAL PRIMARY KEY,
+	website_id INTEGER NOT NULL REFERENCES websites(id) ON DELETE CASCADE,
+	slug VARCHAR(255) NOT NULL,
+	title VARCHAR(255) NOT NULL,
+	position INTEGER NOT NULL DEFAULT 0,
+	content JSONB NOT NULL DEFAULT '{"sections": []}',
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE(website_id, slug)
+);
+
+CREATE INDEX IF NOT EXISTS idx_website_pages_website_id ON website_pages(website_id, position);
@@ -0,0 +1,6 @@
+-- Encrypted ACME account keys and TLS certificates, shared by every replica.
+CREATE TABLE IF NOT EXISTS tls_cache (
+	key VARCHAR(255) PRIMARY KEY,
+	data BYTEA NOT NULL,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,2 @@
+-- URLs of the generated thumbnails of a website's published revision, by size.
+ALTER TABLE websites ADD COLUMN IF NOT EXISTS thumbnails JSONB;
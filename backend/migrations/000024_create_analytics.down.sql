@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS analytics_daily_counts;
+DROP TABLE IF EXISTS analytics_daily;
+DROP TABLE IF EXISTS analytics_visitors;
+DROP TABLE IF EXISTS analytics_salts;
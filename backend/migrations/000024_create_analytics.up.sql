@@ -0,0 +1,36 @@
+-- Salt for hashing visitor IDs. Only the current day's salt is kept, so
+-- hashes cannot be linked across days or recomputed later.
+CREATE TABLE IF NOT EXISTS analytics_salts (
+	day DATE PRIMARY KEY,
+	salt BYTEA NOT NULL
+);
+
+-- Hashed visitors of the current day, used to count unique visitors.
+-- Purged when the next day's salt is created.
+CREATE TABLE IF NOT EXISTS analytics_visitors (
+	website_id INTEGER NOT NULL REFERENCES websites(id) ON DELETE CASCADE,
+	day DATE NOT NULL,
+	visitor CHAR(32) NOT NULL,
+	PRIMARY KEY (website_id, day, visitor)
+);
+
+-- Daily totals per website.
+CREATE TABLE IF NOT EXISTS analytics_daily (
+	website_id INTEGER NOT NULL REFERENCES websites(id) ON DELETE CASCADE,
+	day DATE NOT NULL,
+	pageviews INTEGER NOT NULL DEFAULT 0,
+	visits INTEGER NOT NULL DEFAULT 0,
+	visitors INTEGER NOT NULL DEFAULT 0,
+	events INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (website_id, day)
+);
+
+-- Daily counts per website of pages, referrers and events.
+CREATE TABLE IF NOT EXISTS analytics_daily_counts (
+	website_id INTEGER NOT NULL REFERENCES websites(id) ON DELETE CASCADE,
+	day DATE NOT NULL,
+	dimension VARCHAR(20) NOT NULL,
+	value VARCHAR(500) NOT NULL,
+	count INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (website_id, day, dimension, value)
+);
@@ -0,0 +1,4 @@
+-- IDs clients generate for the messages they send, so retried sends are
+-- stored once.
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS client_id VARCHAR(64);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_messages_sender_client_id ON messages(sender_id, client_id) WHERE client_id IS NOT NULL;
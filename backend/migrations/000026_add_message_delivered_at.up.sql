@@ -0,0 +1,2 @@
+-- When a message first reached one of its receiver's connections.
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS delivered_at TIMESTAMP;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS conversation_participants;
+DELETE FROM conversations WHERE is_group;
+ALTER TABLE conversations DROP COLUMN IF EXISTS created_by;
+ALTER TABLE conversations DROP COLUMN IF EXISTS title;
+ALTER TABLE conversations DROP COLUMN IF EXISTS is_group;
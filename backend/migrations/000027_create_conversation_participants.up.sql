@@ -0,0 +1,33 @@
+-- Group conversations have a title and no user pair.
+ALTER TABLE conversations ADD COLUMN IF NOT EXISTS is_group BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE conversations ADD COLUMN IF NOT EXISTS title VARCHAR(255);
+ALTER TABLE conversations ADD COLUMN IF NOT EXISTS created_by INTEGER REFERENCES users(id) ON DELETE SET NULL;
+
+-- The members of every conversation. A participant has read the messages
+-- of others up to last_read_message_id.
+CREATE TABLE IF NOT EXISTS conversation_participants (
+	conversation_id INTEGER NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	role VARCHAR(20) NOT NULL DEFAULT 'member',
+	last_read_message_id INTEGER NOT NULL DEFAULT 0,
+	joined_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (conversation_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_conversation_participants_user_id ON conversation_participants(user_id);
+
+-- Existing conversations are between their user pair.
+INSERT INTO conversation_participants (conversation_id, user_id, joined_at)
+SELECT id, user1_id, created_at FROM conversations WHERE user1_id IS NOT NULL
+UNION
+SELECT id, user2_id, created_at FROM conversations WHERE user2_id IS NOT NULL
+ON CONFLICT DO NOTHING;
+
+-- A participant has read everything before their oldest unread message.
+UPDATE conversation_participants p
+SET last_read_message_id = COALESCE(
+	(SELECT MIN(m.id) - 1 FROM messages m
+	 WHERE m.conversation_id = p.conversation_id AND m.receiver_id = p.user_id AND m.is_read = FALSE),
+	(SELECT MAX(m.id) FROM messages m WHERE m.conversation_id = p.conversation_id),
+	0
+);
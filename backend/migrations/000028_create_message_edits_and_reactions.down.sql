@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS message_reactions;
+DROP TABLE IF EXISTS hidden_messages;
+DROP TABLE IF EXISTS message_edits;
+ALTER TABLE messages DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE messages DROP COLUMN IF EXISTS edited_at;
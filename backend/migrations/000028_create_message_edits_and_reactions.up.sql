@@ -0,0 +1,31 @@
+-- When a message was last edited, and when its sender deleted it for
+-- everyone, leaving a tombstone.
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS edited_at TIMESTAMP;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+-- The texts a message had before each edit.
+CREATE TABLE IF NOT EXISTS message_edits (
+	id SERIAL PRIMARY KEY,
+	message_id INTEGER NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+	message TEXT NOT NULL,
+	edited_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_edits_message_id ON message_edits(message_id);
+
+-- Messages users deleted for themselves.
+CREATE TABLE IF NOT EXISTS hidden_messages (
+	message_id INTEGER NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	hidden_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (message_id, user_id)
+);
+
+-- Each user reacts with an emoji at most once per message.
+CREATE TABLE IF NOT EXISTS message_reactions (
+	message_id INTEGER NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	emoji VARCHAR(32) NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (message_id, user_id, emoji)
+);
@@ -0,0 +1,18 @@
+-- Files uploaded for messages. They are stored outside the public uploads
+-- directory under storage_key and only served to the owner and the
+-- participants of the conversation of the message they are attached to.
+CREATE TABLE IF NOT EXISTS attachments (
+	id SERIAL PRIMARY KEY,
+	owner_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	message_id INTEGER REFERENCES messages(id) ON DELETE CASCADE,
+	file_name VARCHAR(255) NOT NULL,
+	storage_key VARCHAR(64) NOT NULL UNIQUE,
+	mime_type VARCHAR(100) NOT NULL,
+	size_bytes BIGINT NOT NULL,
+	width INTEGER,
+	height INTEGER,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_message_id ON attachments(message_id);
+CREATE INDEX IF NOT EXISTS idx_attachments_owner_id ON attachments(owner_id);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_messages_conversation_created_at;
+DROP INDEX IF EXISTS idx_messages_search;
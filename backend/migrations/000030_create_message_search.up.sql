@@ -0,0 +1,6 @@
+-- Full-text index over message texts. Searches must match against the same
+-- to_tsvector('simple', message) expression for it to be used.
+CREATE INDEX IF NOT EXISTS idx_messages_search ON messages USING GIN (to_tsvector('simple', message));
+
+-- Jumping to a message pages through its conversation from there.
+CREATE INDEX IF NOT EXISTS idx_messages_conversation_created_at ON messages(conversation_id, created_at, id);
@@ -0,0 +1,3 @@
+-- Values a website chose for the settings of its theme, by key. Settings it
+-- left out use the theme's defaults.
+ALTER TABLE websites ADD COLUMN IF NOT EXISTS theme_settings JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_domains_business_id;
+ALTER TABLE domains DROP COLUMN IF EXISTS verified_at;
+ALTER TABLE domains DROP COLUMN IF EXISTS verification_token;
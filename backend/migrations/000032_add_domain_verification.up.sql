@@ -0,0 +1,6 @@
+-- A domain is verified by publishing verification_token in a TXT record at
+-- _site-verification.<domain_name>.
+ALTER TABLE domains ADD COLUMN IF NOT EXISTS verification_token VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE domains ADD COLUMN IF NOT EXISTS verified_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_domains_business_id ON domains(business_id);
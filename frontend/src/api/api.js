@@ -9,6 +9,14 @@ export const BASE_URL = API_URL.startsWith('http')
   ? API_URL.split('/api')[0] 
   : window.location.origin;
 
+// A website's image is the one uploaded as image_url, or else the thumbnail
+// generated when it was last published.
+export const websiteImage = (website) => {
+  const url = website.image_url || website.thumbnails?.medium;
+  if (!url) return null;
+  return url.startsWith('http') ? url : `${BASE_URL}${url}`;
+};
+
 const api = axios.create({
   baseURL: API_URL,
   headers: {
@@ -63,7 +71,7 @@ export const businessAPI = {
   create: (data) => api.post('/businesses', data),
   update: (id, data) => api.put(`/businesses/${id}`, data),
   delete: (id) => api.delete(`/businesses/${id}`),
-  search: () => api.get('/businesses/search'),
+  search: (params) => api.get('/businesses/search', { params }),
 };
 
 // Website API
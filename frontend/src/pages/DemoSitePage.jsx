@@ -1,6 +1,6 @@
 import { useState, useEffect } from 'react';
 import { useParams, useNavigate } from 'react-router-dom';
-import { websiteAPI, BASE_URL } from '../api/api';
+import { websiteAPI, websiteImage } from '../api/api';
 import { Navbar } from '../components/Navbar';
 import { Footer } from '../components/Footer';
 
@@ -130,9 +130,9 @@ export const DemoSitePage = () => {
             }}
           >
             <div className="aspect-video bg-gray-100 flex items-center justify-center overflow-hidden">
-              {website.image_url ? (
+              {websiteImage(website) ? (
                 <img 
-                  src={website.image_url.startsWith('http') ? website.image_url : `${BASE_URL}${website.image_url}`} 
+                  src={websiteImage(website)} 
                   alt={website.title}
                   className="w-full h-full object-cover"
                   onError={(e) => {
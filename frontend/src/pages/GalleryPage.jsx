@@ -1,6 +1,6 @@
 import { useState, useEffect } from 'react';
 import { Link } from 'react-router-dom';
-import { websiteAPI, BASE_URL } from '../api/api';
+import { websiteAPI, websiteImage } from '../api/api';
 import { Navbar } from '../components/Navbar';
 import { Footer } from '../components/Footer';
 
@@ -57,9 +57,9 @@ export const GalleryPage = () => {
                   }}
                 >
                   <div className="h-48 overflow-hidden bg-gray-100 flex items-center justify-center">
-                    {website.image_url ? (
+                    {websiteImage(website) ? (
                       <img 
-                        src={website.image_url.startsWith('http') ? website.image_url : `${BASE_URL}${website.image_url}`} 
+                        src={websiteImage(website)} 
                         alt={website.title}
                         className="w-full h-full object-cover transition-transform duration-500 hover:scale-110"
                         onError={(e) => {
@@ -47,12 +47,8 @@ export const LandingPage = () => {
       if (searchTerm.length > 0) {
         setLoading(true);
         try {
-          const response = await businessAPI.search();
-          const filtered = response.data.filter((business) =>
-            business.name.toLowerCase().includes(searchTerm.toLowerCase()) ||
-            business.industry?.toLowerCase().includes(searchTerm.toLowerCase())
-          );
-          setBusinesses(filtered);
+          const response = await businessAPI.search({ q: searchTerm });
+          setBusinesses(response.data.results);
           setShowResults(true);
         } catch (err) {
           console.error('Failed to fetch businesses:', err);
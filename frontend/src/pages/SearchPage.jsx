@@ -12,22 +12,22 @@ export const SearchPage = () => {
   const [loading, setLoading] = useState(true);
 
   useEffect(() => {
-    businessAPI.search()
-      .then((response) => {
-        setBusinesses(response.data);
-      })
-      .catch((err) => {
-        console.error('Failed to fetch businesses:', err);
-      })
-      .finally(() => {
-        setLoading(false);
-      });
-  }, []);
+    const debounceTimer = setTimeout(() => {
+      setLoading(true);
+      businessAPI.search({ q: searchTerm })
+        .then((response) => {
+          setBusinesses(response.data.results);
+        })
+        .catch((err) => {
+          console.error('Failed to fetch businesses:', err);
+        })
+        .finally(() => {
+          setLoading(false);
+        });
+    }, 300);
 
-  const filteredBusinesses = businesses.filter((business) =>
-    business.name.toLowerCase().includes(searchTerm.toLowerCase()) ||
-    business.industry?.toLowerCase().includes(searchTerm.toLowerCase())
-  );
+    return () => clearTimeout(debounceTimer);
+  }, [searchTerm]);
 
   return (
     <div className="min-h-screen relative" style={{ backgroundColor: '#111828' }}>
@@ -68,7 +68,7 @@ export const SearchPage = () => {
             <div className="text-center text-gray-400">Loading...</div>
           ) : (
             <div className="grid md:grid-cols-3 gap-6">
-              {filteredBusinesses.map((business) => (
+              {businesses.map((business) => (
                 <div
                   key={business.id}
                   className="p-6 rounded-lg transition-all"
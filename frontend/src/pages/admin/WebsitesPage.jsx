@@ -49,7 +49,7 @@ export const WebsitesPage = () => {
   const [formData, setFormData] = useState({
     title: '',
     url: '',
-    theme_name: 'professional',
+    theme_name: 'default',
     status: 'pending',
     is_demo: false,
     is_claimed: false,
@@ -292,7 +292,7 @@ export const WebsitesPage = () => {
     setFormData({
       title: '',
       url: '',
-      theme_name: 'professional',
+      theme_name: 'default',
       status: 'pending',
       is_demo: false,
       is_claimed: false,
@@ -305,7 +305,7 @@ export const WebsitesPage = () => {
     setFormData({
       title: website.title || '',
       url: website.url || website.website_url || website.domain || '',
-      theme_name: website.theme_name || 'professional',
+      theme_name: website.theme_name || 'default',
       status: website.status || 'pending',
       is_demo: website.is_demo || false,
       is_claimed: website.is_claimed || false,
@@ -400,7 +400,7 @@ export const WebsitesPage = () => {
       setFormData({
         title: '',
         url: '',
-        theme_name: 'professional',
+        theme_name: 'default',
         status: 'pending',
         is_demo: false,
         is_claimed: false,
@@ -819,11 +819,8 @@ export const WebsitesPage = () => {
               value={formData.theme_name}
               onChange={(value) => setFormData({ ...formData, theme_name: value })}
               options={[
-                { value: 'professional', label: 'Professional' },
+                { value: 'default', label: 'Default' },
                 { value: 'modern', label: 'Modern' },
-                { value: 'tech', label: 'Tech' },
-                { value: 'restaurant', label: 'Restaurant' },
-                { value: 'fitness', label: 'Fitness' },
               ]}
               placeholder="Select Theme"
             />